@@ -139,6 +139,19 @@ func (c *Checker) CheckToken(ctx context.Context, token *oauth2.Token, tokenInfo
 	return g.Id, saToken, nil
 }
 
+// ServiceAccount returns service account used for the group.
+// It returns empty if the group uses end user credential.
+func (c *Checker) ServiceAccount(group string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, g := range c.config.GetGroups() {
+		if g.Id == group {
+			return g.ServiceAccount
+		}
+	}
+	return ""
+}
+
 func checkGroup(ctx context.Context, tokenInfo *auth.TokenInfo, g *pb.Group, authDB AuthDB) bool {
 	logger := log.FromContext(ctx)
 	logger.Debugf("checking group:%s", g.Id)
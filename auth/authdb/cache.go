@@ -0,0 +1,109 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package authdb
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.chromium.org/goma/server/log"
+)
+
+// DefaultCacheTTL is default TTL of membership cache.
+const DefaultCacheTTL = 10 * time.Minute
+
+// Cache caches membership check results of Provider.
+// Failed checks are not cached, so transient failure of Provider
+// won't deny users until the cache expires.
+type Cache struct {
+	Provider Provider
+
+	// TTL is duration to keep membership results.
+	// If zero, DefaultCacheTTL is used.
+	TTL time.Duration
+
+	// Clock is used to get current time. If nil, time.Now is used.
+	Clock func() time.Time
+
+	mu        sync.Mutex
+	m         map[cacheKey]cacheEntry
+	lastSweep time.Time
+}
+
+type cacheKey struct {
+	email, group string
+}
+
+type cacheEntry struct {
+	isMember bool
+	expires  time.Time
+}
+
+func (c *Cache) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+	return c.Clock()
+}
+
+func (c *Cache) ttl() time.Duration {
+	if c.TTL == 0 {
+		return DefaultCacheTTL
+	}
+	return c.TTL
+}
+
+// IsMember checks email is in group, using cached result if it is not
+// expired.
+func (c *Cache) IsMember(ctx context.Context, email, group string) bool {
+	key := cacheKey{email: email, group: group}
+	now := c.now()
+	c.mu.Lock()
+	e, found := c.m[key]
+	c.mu.Unlock()
+	if found && now.Before(e.expires) {
+		return e.isMember
+	}
+	isMember, err := c.Provider.Membership(ctx, email, group)
+	if err != nil {
+		logger := log.FromContext(ctx)
+		logger.Errorf("check membership group:%s: %v", group, err)
+		return false
+	}
+	if ctx.Err() != nil {
+		// don't cache result of cancelled check.
+		return isMember
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = make(map[cacheKey]cacheEntry)
+		c.lastSweep = now
+	}
+	// sweep expired entries at most once per ttl, rather than
+	// on every miss.
+	if now.Sub(c.lastSweep) >= c.ttl() {
+		for k, e := range c.m {
+			if !now.Before(e.expires) {
+				delete(c.m, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.m[key] = cacheEntry{
+		isMember: isMember,
+		expires:  now.Add(c.ttl()),
+	}
+	return isMember
+}
+
+// Purge purges all cached results.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m = nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package authdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeAuthDB struct {
+	members map[string]bool
+	err     error
+	calls   int
+}
+
+func (f *fakeAuthDB) IsMember(ctx context.Context, email, group string) bool {
+	isMember, _ := f.Membership(ctx, email, group)
+	return isMember
+}
+
+func (f *fakeAuthDB) Membership(ctx context.Context, email, group string) (bool, error) {
+	f.calls++
+	if f.err != nil {
+		return false, f.err
+	}
+	return f.members[email+":"+group], nil
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	db := &fakeAuthDB{
+		members: map[string]bool{
+			"alice@example.com:goma-users": true,
+		},
+	}
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &Cache{
+		Provider: db,
+		TTL:      time.Minute,
+		Clock: func() time.Time {
+			return now
+		},
+	}
+
+	check := func(email, group string, want bool, wantCalls int) {
+		t.Helper()
+		got := c.IsMember(ctx, email, group)
+		if got != want {
+			t.Errorf("IsMember(ctx, %q, %q)=%t; want=%t", email, group, got, want)
+		}
+		if db.calls != wantCalls {
+			t.Errorf("calls=%d; want=%d", db.calls, wantCalls)
+		}
+	}
+
+	check("alice@example.com", "goma-users", true, 1)
+	check("alice@example.com", "goma-users", true, 1)
+	check("bob@example.com", "goma-users", false, 2)
+	check("bob@example.com", "goma-users", false, 2)
+
+	db.members["bob@example.com:goma-users"] = true
+	now = now.Add(30 * time.Second)
+	check("bob@example.com", "goma-users", false, 2)
+
+	now = now.Add(30 * time.Second)
+	check("bob@example.com", "goma-users", true, 3)
+
+	c.Purge()
+	check("alice@example.com", "goma-users", true, 4)
+
+	t.Logf("provider failure is not cached")
+	db.err = errors.New("ldap unavailable")
+	check("bob@example.com", "goma-bots", false, 5)
+	check("bob@example.com", "goma-bots", false, 6)
+	db.err = nil
+	db.members["bob@example.com:goma-bots"] = true
+	check("bob@example.com", "goma-bots", true, 7)
+}
+
+func TestCacheSweep(t *testing.T) {
+	ctx := context.Background()
+	db := &fakeAuthDB{}
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &Cache{
+		Provider: db,
+		TTL:      time.Minute,
+		Clock: func() time.Time {
+			return now
+		},
+	}
+	c.IsMember(ctx, "alice@example.com", "goma-users")
+	now = now.Add(59 * time.Second)
+	c.IsMember(ctx, "bob@example.com", "goma-users")
+	now = now.Add(2 * time.Second)
+	c.IsMember(ctx, "carol@example.com", "goma-users")
+	// alice expired and swept. bob is not expired yet.
+	if got, want := len(c.m), 2; got != want {
+		t.Errorf("len(c.m)=%d; want %d", got, want)
+	}
+	now = now.Add(59 * time.Second)
+	c.IsMember(ctx, "dave@example.com", "goma-users")
+	// bob expired, but not swept, since last sweep was less than
+	// ttl ago.
+	if got, want := len(c.m), 3; got != want {
+		t.Errorf("len(c.m)=%d; want %d", got, want)
+	}
+}
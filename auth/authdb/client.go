@@ -22,7 +22,16 @@ type Client struct {
 // IsMember checks email is in group.
 func (c Client) IsMember(ctx context.Context, email, group string) bool {
 	logger := log.FromContext(ctx)
+	isMember, err := c.Membership(ctx, email, group)
+	if err != nil {
+		logger.Errorf("check membership: %v", err)
+		return false
+	}
+	return isMember
+}
 
+// Membership checks email is in group.
+func (c Client) Membership(ctx context.Context, email, group string) (bool, error) {
 	req := &pb.CheckMembershipReq{
 		Email: email,
 		Group: group,
@@ -34,8 +43,7 @@ func (c Client) IsMember(ctx context.Context, email, group string) bool {
 		return c.Client.Call(ctx, req, resp)
 	})
 	if err != nil {
-		logger.Errorf("check membership: %v", err)
-		return false
+		return false, err
 	}
-	return resp.IsMember
+	return resp.IsMember, nil
 }
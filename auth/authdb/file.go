@@ -0,0 +1,139 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package authdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"gopkg.in/yaml.v3"
+
+	"go.chromium.org/goma/server/log"
+	pb "go.chromium.org/goma/server/proto/auth"
+)
+
+// FileDB is authdb backed by local groups file.
+// groups file is YAML of auth.Groups if its extension is ".yaml" or
+// ".yml", or text proto of auth.Groups otherwise.
+type FileDB struct {
+	Filename string
+
+	mu     sync.RWMutex
+	groups map[string]*pb.MembershipGroup
+}
+
+// Load loads groups file.
+// It keeps current groups if it fails to load.
+func (db *FileDB) Load(ctx context.Context) error {
+	b, err := ioutil.ReadFile(db.Filename)
+	if err != nil {
+		return err
+	}
+	groups := &pb.Groups{}
+	switch filepath.Ext(db.Filename) {
+	case ".yaml", ".yml":
+		err = unmarshalYAML(b, groups)
+	default:
+		err = prototext.Unmarshal(b, groups)
+	}
+	if err != nil {
+		return fmt.Errorf("load error %s: %v", db.Filename, err)
+	}
+	return db.Set(ctx, groups)
+}
+
+// unmarshalYAML unmarshals YAML of groups.
+// It converts YAML to JSON, and unmarshals it as JSON of auth.Groups.
+func unmarshalYAML(b []byte, groups *pb.Groups) error {
+	var v interface{}
+	err := yaml.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		// empty file.
+		return nil
+	}
+	j, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return protojson.Unmarshal(j, groups)
+}
+
+// Set sets groups in db.
+func (db *FileDB) Set(ctx context.Context, groups *pb.Groups) error {
+	m := make(map[string]*pb.MembershipGroup)
+	for _, g := range groups.GetGroups() {
+		if g.Name == "" {
+			return fmt.Errorf("empty group name: %v", g)
+		}
+		if _, found := m[g.Name]; found {
+			return fmt.Errorf("duplicate group %q", g.Name)
+		}
+		m[g.Name] = g
+	}
+	for _, g := range groups.GetGroups() {
+		for _, n := range g.Nested {
+			if _, found := m[n]; !found {
+				return fmt.Errorf("group %q: unknown nested group %q", g.Name, n)
+			}
+		}
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.groups = m
+	log.FromContext(ctx).Infof("authdb groups updated: %d groups", len(m))
+	return nil
+}
+
+// IsMember checks email is in group.
+func (db *FileDB) IsMember(ctx context.Context, email, group string) bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.isMember(email, group, make(map[string]bool))
+}
+
+// Membership checks email is in group. It never fails.
+func (db *FileDB) Membership(ctx context.Context, email, group string) (bool, error) {
+	return db.IsMember(ctx, email, group), nil
+}
+
+func (db *FileDB) isMember(email, group string, seen map[string]bool) bool {
+	if seen[group] {
+		// nested groups may have cycle.
+		return false
+	}
+	seen[group] = true
+	g, found := db.groups[group]
+	if !found {
+		return false
+	}
+	for _, m := range g.Members {
+		if matchMember(email, m) {
+			return true
+		}
+	}
+	for _, n := range g.Nested {
+		if db.isMember(email, n, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchMember(email, member string) bool {
+	if strings.HasPrefix(member, "*@") {
+		return strings.HasSuffix(email, member[1:])
+	}
+	return email == member
+}
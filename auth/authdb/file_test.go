@@ -0,0 +1,188 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package authdb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "groups.textproto")
+	err = ioutil.WriteFile(fname, []byte(`
+groups {
+  name: "goma-users"
+  members: "alice@example.com"
+  nested: "goma-bots"
+  nested: "googlers"
+}
+groups {
+  name: "goma-bots"
+  members: "bot@project.iam.gserviceaccount.com"
+  nested: "goma-users"
+}
+groups {
+  name: "googlers"
+  members: "*@google.com"
+}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	db := &FileDB{
+		Filename: fname,
+	}
+	err = db.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load=%v; want nil error", err)
+	}
+	for _, tc := range []struct {
+		email, group string
+		want         bool
+	}{
+		{
+			email: "alice@example.com",
+			group: "goma-users",
+			want:  true,
+		},
+		{
+			email: "bot@project.iam.gserviceaccount.com",
+			group: "goma-users",
+			want:  true,
+		},
+		{
+			email: "someone@google.com",
+			group: "goma-users",
+			want:  true,
+		},
+		{
+			email: "alice@example.com",
+			group: "goma-bots",
+			want:  true,
+		},
+		{
+			email: "alice@example.com",
+			group: "googlers",
+			want:  false,
+		},
+		{
+			email: "someone@google.com.example.com",
+			group: "googlers",
+			want:  false,
+		},
+		{
+			email: "bob@example.com",
+			group: "goma-users",
+			want:  false,
+		},
+		{
+			email: "alice@example.com",
+			group: "unknown",
+			want:  false,
+		},
+	} {
+		got := db.IsMember(ctx, tc.email, tc.group)
+		if got != tc.want {
+			t.Errorf("IsMember(ctx, %q, %q)=%t; want=%t", tc.email, tc.group, got, tc.want)
+		}
+	}
+
+	err = ioutil.WriteFile(fname, []byte(`
+groups {
+  name: "goma-users"
+  nested: "unknown"
+}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Load(ctx)
+	if err == nil {
+		t.Errorf("Load with unknown nested group=nil error; want error")
+	}
+	if !db.IsMember(ctx, "alice@example.com", "goma-users") {
+		t.Errorf("IsMember after failed Load=false; want=true (keep current groups)")
+	}
+}
+
+func TestFileDBYAML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "groups.yaml")
+	err = ioutil.WriteFile(fname, []byte(`
+groups:
+- name: goma-users
+  members:
+  - alice@example.com
+  nested:
+  - googlers
+- name: googlers
+  members:
+  - "*@google.com"
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	db := &FileDB{
+		Filename: fname,
+	}
+	err = db.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load=%v; want nil error", err)
+	}
+	for _, tc := range []struct {
+		email, group string
+		want         bool
+	}{
+		{
+			email: "alice@example.com",
+			group: "goma-users",
+			want:  true,
+		},
+		{
+			email: "someone@google.com",
+			group: "goma-users",
+			want:  true,
+		},
+		{
+			email: "bob@example.com",
+			group: "goma-users",
+			want:  false,
+		},
+	} {
+		got := db.IsMember(ctx, tc.email, tc.group)
+		if got != tc.want {
+			t.Errorf("IsMember(ctx, %q, %q)=%t; want=%t", tc.email, tc.group, got, tc.want)
+		}
+	}
+
+	err = ioutil.WriteFile(fname, []byte(`
+groups:
+- name: goma-users
+  unknown_field: foo
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Load(ctx)
+	if err == nil {
+		t.Errorf("Load with unknown field=nil error; want error")
+	}
+}
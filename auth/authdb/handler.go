@@ -15,6 +15,16 @@ type AuthDB interface {
 	IsMember(ctx context.Context, email, group string) bool
 }
 
+// Provider is AuthDB that reports failure of membership check.
+type Provider interface {
+	AuthDB
+
+	// Membership checks email is in group.
+	// It returns error if it failed to check, e.g. backend is
+	// unavailable, so that the result won't be cached.
+	Membership(ctx context.Context, email, group string) (bool, error)
+}
+
 // Handler handles request to AuthDB.
 type Handler struct {
 	pb.UnimplementedAuthDBServiceServer
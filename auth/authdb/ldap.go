@@ -0,0 +1,214 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package authdb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"go.chromium.org/goma/server/log"
+)
+
+const (
+	// DefaultLDAPUserFilter is default filter to find user entry by email.
+	DefaultLDAPUserFilter = "(mail=%s)"
+
+	// DefaultLDAPGroupFilter is default filter to find group entry by name.
+	DefaultLDAPGroupFilter = "(&(objectClass=groupOfNames)(cn=%s))"
+
+	// DefaultLDAPMemberAttr is default attribute of group entry for members.
+	DefaultLDAPMemberAttr = "member"
+)
+
+// LDAP is authdb backed by LDAP server.
+//
+// It finds user entry by email, and group entry by group name, and
+// checks the group entry has the user entry as member, directly or
+// via nested groups.
+type LDAP struct {
+	// URL is LDAP server's url. e.g. "ldaps://ldap.example.com".
+	URL string
+
+	// BindDN and BindPassword are used to bind before search.
+	// If BindDN is empty, it uses anonymous access.
+	BindDN       string
+	BindPassword string
+
+	// BaseDN is base DN to search user and group entries.
+	BaseDN string
+
+	// UserFilter is filter to find user entry.
+	// %s will be replaced with escaped email.
+	// If empty, DefaultLDAPUserFilter is used.
+	UserFilter string
+
+	// GroupFilter is filter to find group entry.
+	// %s will be replaced with escaped group name.
+	// If empty, DefaultLDAPGroupFilter is used.
+	GroupFilter string
+
+	// MemberAttr is attribute name of group entry for members.
+	// If empty, DefaultLDAPMemberAttr is used.
+	MemberAttr string
+
+	// Timeout is timeout for each LDAP request.
+	Timeout time.Duration
+}
+
+func (l LDAP) userFilter() string {
+	if l.UserFilter == "" {
+		return DefaultLDAPUserFilter
+	}
+	return l.UserFilter
+}
+
+func (l LDAP) groupFilter() string {
+	if l.GroupFilter == "" {
+		return DefaultLDAPGroupFilter
+	}
+	return l.GroupFilter
+}
+
+func (l LDAP) memberAttr() string {
+	if l.MemberAttr == "" {
+		return DefaultLDAPMemberAttr
+	}
+	return l.MemberAttr
+}
+
+// IsMember checks email is in group.
+func (l LDAP) IsMember(ctx context.Context, email, group string) bool {
+	logger := log.FromContext(ctx)
+	isMember, err := l.Membership(ctx, email, group)
+	if err != nil {
+		logger.Errorf("ldap check membership group:%s: %v", group, err)
+		return false
+	}
+	return isMember
+}
+
+// Membership checks email is in group.
+// It returns error if it failed to access LDAP server.
+func (l LDAP) Membership(ctx context.Context, email, group string) (bool, error) {
+	timeout := l.Timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if t := time.Until(deadline); timeout == 0 || t < timeout {
+			timeout = t
+		}
+	}
+	conn, err := ldap.DialURL(l.URL, ldap.DialWithDialer(&net.Dialer{
+		Timeout: timeout,
+	}))
+	if err != nil {
+		return false, fmt.Errorf("dial %s: %v", l.URL, err)
+	}
+	defer conn.Close()
+	if timeout > 0 {
+		conn.SetTimeout(timeout)
+	}
+	// close conn to abort in-flight request when ctx is canceled.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	if l.BindDN != "" {
+		err = conn.Bind(l.BindDN, l.BindPassword)
+		if err != nil {
+			return false, fmt.Errorf("bind %s: %v", l.BindDN, err)
+		}
+	}
+	userDN, err := l.searchDN(conn, fmt.Sprintf(l.userFilter(), ldap.EscapeFilter(email)))
+	if err != nil {
+		return false, fmt.Errorf("user: %v", err)
+	}
+	if userDN == "" {
+		return false, nil
+	}
+	groupDN, err := l.searchDN(conn, fmt.Sprintf(l.groupFilter(), ldap.EscapeFilter(group)))
+	if err != nil {
+		return false, fmt.Errorf("group: %v", err)
+	}
+	if groupDN == "" {
+		return false, fmt.Errorf("group %q not found", group)
+	}
+
+	// walk up groups from the user entry, since a user usually belongs to
+	// a few groups, while a group may have lots of members.
+	seen := make(map[string]bool)
+	queue := []string{userDN}
+	for len(queue) > 0 {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		dn := queue[0]
+		queue = queue[1:]
+		key := strings.ToLower(dn)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		groups, err := l.groupsOf(conn, dn)
+		if err != nil {
+			return false, err
+		}
+		for _, g := range groups {
+			if strings.EqualFold(g, groupDN) {
+				return true, nil
+			}
+			queue = append(queue, g)
+		}
+	}
+	return false, nil
+}
+
+// searchDN searches an entry under BaseDN by filter, and returns its DN.
+// It returns empty DN if not found.
+func (l LDAP) searchDN(conn *ldap.Conn, filter string) (string, error) {
+	resp, err := conn.Search(ldap.NewSearchRequest(
+		l.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, 0, false,
+		filter,
+		[]string{"dn"},
+		nil))
+	if err != nil {
+		return "", fmt.Errorf("search %s: %v", filter, err)
+	}
+	switch len(resp.Entries) {
+	case 0:
+		return "", nil
+	case 1:
+		return resp.Entries[0].DN, nil
+	}
+	return "", fmt.Errorf("search %s: multiple entries found", filter)
+}
+
+// groupsOf returns DNs of group entries that have dn as member.
+func (l LDAP) groupsOf(conn *ldap.Conn, dn string) ([]string, error) {
+	filter := fmt.Sprintf("(%s=%s)", l.memberAttr(), ldap.EscapeFilter(dn))
+	resp, err := conn.Search(ldap.NewSearchRequest(
+		l.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false,
+		filter,
+		[]string{"dn"},
+		nil))
+	if err != nil {
+		return nil, fmt.Errorf("search %s: %v", filter, err)
+	}
+	var groups []string
+	for _, e := range resp.Entries {
+		groups = append(groups, e.DN)
+	}
+	return groups, nil
+}
@@ -0,0 +1,295 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package authdb
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+type fakeLDAPEntry struct {
+	dn    string
+	attrs map[string][]string
+}
+
+// fakeLDAPServer is in-process LDAP server that supports simple bind
+// and search with and/or/equality/present filters.
+type fakeLDAPServer struct {
+	t            *testing.T
+	ln           net.Listener
+	bindDN       string
+	bindPassword string
+	entries      []fakeLDAPEntry
+}
+
+func newFakeLDAPServer(t *testing.T, entries []fakeLDAPEntry) *fakeLDAPServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeLDAPServer{
+		t:            t,
+		ln:           ln,
+		bindDN:       "cn=goma,dc=example,dc=com",
+		bindPassword: "secret",
+		entries:      entries,
+	}
+	go s.serve()
+	return s
+}
+
+func (s *fakeLDAPServer) URL() string {
+	return "ldap://" + s.ln.Addr().String()
+}
+
+func (s *fakeLDAPServer) Close() error {
+	return s.ln.Close()
+}
+
+func (s *fakeLDAPServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func ldapResult(id int64, op ber.Tag, code uint16) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	r := ber.Encode(ber.ClassApplication, ber.TypeConstructed, op, nil, "Result")
+	r.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "resultCode"))
+	r.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	r.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	p.AppendChild(r)
+	return p
+}
+
+func ldapSearchEntry(id int64, e fakeLDAPEntry, attrs []string) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	r := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	r.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "objectName"))
+	as := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for _, name := range attrs {
+		values, ok := e.attrs[name]
+		if !ok {
+			continue
+		}
+		a := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		a.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		vs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, v := range values {
+			vs.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "val"))
+		}
+		a.AppendChild(vs)
+		as.AppendChild(a)
+	}
+	r.AppendChild(as)
+	p.AppendChild(r)
+	return p
+}
+
+func (s *fakeLDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		p, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		if len(p.Children) < 2 {
+			s.t.Errorf("bad ldap packet: %v", p)
+			return
+		}
+		id, _ := p.Children[0].Value.(int64)
+		op := p.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			code := uint16(ldap.LDAPResultSuccess)
+			if op.Children[1].Data.String() != s.bindDN || op.Children[2].Data.String() != s.bindPassword {
+				code = ldap.LDAPResultInvalidCredentials
+			}
+			conn.Write(ldapResult(id, ldap.ApplicationBindResponse, code).Bytes())
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationSearchRequest:
+			base := strings.ToLower(op.Children[0].Data.String())
+			scope, _ := op.Children[1].Value.(int64)
+			filter := op.Children[6]
+			var attrs []string
+			for _, a := range op.Children[7].Children {
+				attrs = append(attrs, a.Data.String())
+			}
+			for _, e := range s.entries {
+				dn := strings.ToLower(e.dn)
+				switch scope {
+				case ldap.ScopeBaseObject:
+					if dn != base {
+						continue
+					}
+				default:
+					if dn != base && !strings.HasSuffix(dn, ","+base) {
+						continue
+					}
+				}
+				if !matchFilter(filter, e) {
+					continue
+				}
+				conn.Write(ldapSearchEntry(id, e, attrs).Bytes())
+			}
+			conn.Write(ldapResult(id, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess).Bytes())
+		default:
+			s.t.Errorf("unsupported ldap op: %d", op.Tag)
+			return
+		}
+	}
+}
+
+func matchFilter(f *ber.Packet, e fakeLDAPEntry) bool {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, c := range f.Children {
+			if !matchFilter(c, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, c := range f.Children {
+			if matchFilter(c, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterEqualityMatch:
+		attr := f.Children[0].Data.String()
+		value := f.Children[1].Data.String()
+		for _, v := range e.attrs[attr] {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		_, ok := e.attrs[f.Data.String()]
+		return ok
+	}
+	return false
+}
+
+func TestLDAP(t *testing.T) {
+	s := newFakeLDAPServer(t, []fakeLDAPEntry{
+		{
+			dn: "uid=alice,ou=people,dc=example,dc=com",
+			attrs: map[string][]string{
+				"mail": {"alice@example.com"},
+			},
+		},
+		{
+			dn: "uid=bob,ou=people,dc=example,dc=com",
+			attrs: map[string][]string{
+				"mail": {"bob@example.com"},
+			},
+		},
+		{
+			dn: "uid=carol,ou=people,dc=example,dc=com",
+			attrs: map[string][]string{
+				"mail": {"carol@example.com"},
+			},
+		},
+		{
+			dn: "cn=goma-users,ou=groups,dc=example,dc=com",
+			attrs: map[string][]string{
+				"objectClass": {"groupOfNames"},
+				"cn":          {"goma-users"},
+				"member": {
+					"uid=alice,ou=people,dc=example,dc=com",
+					"cn=goma-bots,ou=groups,dc=example,dc=com",
+				},
+			},
+		},
+		{
+			dn: "cn=goma-bots,ou=groups,dc=example,dc=com",
+			attrs: map[string][]string{
+				"objectClass": {"groupOfNames"},
+				"cn":          {"goma-bots"},
+				"member": {
+					"uid=bob,ou=people,dc=example,dc=com",
+					// cycle.
+					"cn=goma-users,ou=groups,dc=example,dc=com",
+				},
+			},
+		},
+	})
+	defer s.Close()
+
+	ctx := context.Background()
+	db := LDAP{
+		URL:          s.URL(),
+		BindDN:       "cn=goma,dc=example,dc=com",
+		BindPassword: "secret",
+		BaseDN:       "dc=example,dc=com",
+	}
+	for _, tc := range []struct {
+		email, group string
+		want         bool
+	}{
+		{
+			email: "alice@example.com",
+			group: "goma-users",
+			want:  true,
+		},
+		{
+			email: "bob@example.com",
+			group: "goma-users",
+			want:  true,
+		},
+		{
+			email: "alice@example.com",
+			group: "goma-bots",
+			want:  true,
+		},
+		{
+			email: "bob@example.com",
+			group: "goma-bots",
+			want:  true,
+		},
+		{
+			email: "carol@example.com",
+			group: "goma-users",
+			want:  false,
+		},
+		{
+			email: "unknown@example.com",
+			group: "goma-users",
+			want:  false,
+		},
+		{
+			email: "alice@example.com",
+			group: "unknown-group",
+			want:  false,
+		},
+	} {
+		got := db.IsMember(ctx, tc.email, tc.group)
+		if got != tc.want {
+			t.Errorf("IsMember(ctx, %q, %q)=%t; want=%t", tc.email, tc.group, got, tc.want)
+		}
+	}
+
+	db.BindPassword = "wrong"
+	if db.IsMember(ctx, "alice@example.com", "goma-users") {
+		t.Errorf("IsMember with wrong password=true; want=false")
+	}
+	if _, err := db.Membership(ctx, "alice@example.com", "goma-users"); err == nil {
+		t.Errorf("Membership with wrong password=_, nil; want error")
+	}
+}
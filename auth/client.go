@@ -15,6 +15,7 @@ import (
 	"go.opencensus.io/trace"
 	"golang.org/x/oauth2"
 	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc/metadata"
 
 	"go.chromium.org/goma/server/auth/enduser"
 	"go.chromium.org/goma/server/log"
@@ -129,6 +130,28 @@ func (a *Auth) Check(ctx context.Context, req *http.Request) (*enduser.EndUser,
 		logger.Warnf("no authorization header")
 		return nil, ErrNoAuthHeader
 	}
+	return a.check(ctx, authorization)
+}
+
+// CheckGRPC checks "authorization" metadata in an incoming gRPC context.
+// The function returns error if authentication failed.
+// ErrNoAuthHeader is returned if no authorization metadata is in ctx.
+func (a *Auth) CheckGRPC(ctx context.Context) (*enduser.EndUser, error) {
+	ctx, span := trace.StartSpan(ctx, "go.chromium.org/goma/server/auth.Auth.CheckGRPC")
+	defer span.End()
+	logger := log.FromContext(ctx)
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	v := md.Get("authorization")
+	if len(v) == 0 || v[0] == "" {
+		logger.Warnf("no authorization metadata")
+		return nil, ErrNoAuthHeader
+	}
+	return a.check(ctx, v[0])
+}
+
+func (a *Auth) check(ctx context.Context, authorization string) (*enduser.EndUser, error) {
+	logger := log.FromContext(ctx)
 	a.mu.Lock()
 	if a.cache == nil {
 		a.cache = make(map[string]*authInfo)
@@ -175,7 +198,9 @@ func (a *Auth) Check(ctx context.Context, req *http.Request) (*enduser.EndUser,
 		AccessToken: ai.resp.Token.GetAccessToken(),
 		TokenType:   ai.resp.Token.GetTokenType(),
 	}
-	return enduser.New(ai.resp.Email, ai.resp.GroupId, token), nil
+	u := enduser.New(ai.resp.Email, ai.resp.GroupId, token)
+	u.ServiceAccount = ai.resp.ServiceAccount
+	return u, nil
 }
 
 // Auth authenticates the requests and returns new context with enduser info.
@@ -18,6 +18,7 @@ import (
 	"golang.org/x/oauth2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"go.chromium.org/goma/server/auth/enduser"
@@ -422,6 +423,46 @@ func TestAuthCheck(t *testing.T) {
 	}
 }
 
+func TestAuthCheckGRPC(t *testing.T) {
+	t.Log("no authorization metadata.")
+	a := &Auth{}
+	_, err := a.CheckGRPC(context.Background())
+	if err != ErrNoAuthHeader {
+		t.Errorf("CheckGRPC(ctx) error %v; want %v", err, ErrNoAuthHeader)
+	}
+
+	t.Log("access succeed (using cache)")
+	email := "example@google.com"
+	a = &Auth{
+		cache: map[string]*authInfo{
+			"Bearer test": {
+				resp: &authpb.AuthResp{
+					Email:     email,
+					GroupId:   "toolchain-admin",
+					ExpiresAt: timestamppb.New(time.Now().Add(time.Hour)),
+					Quota:     -1,
+					Token: &authpb.Token{
+						AccessToken: "test",
+						TokenType:   "Bearer",
+					},
+				},
+			},
+		},
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer test"))
+	eu, err := a.CheckGRPC(ctx)
+	if err != nil {
+		t.Errorf("CheckGRPC(ctx) error %v; want nil", err)
+	}
+	expectedEu := enduser.New(email, "toolchain-admin", &oauth2.Token{
+		AccessToken: "test",
+		TokenType:   "Bearer",
+	})
+	if !reflect.DeepEqual(eu, expectedEu) {
+		t.Errorf("CheckGRPC(ctx)=%v; want %v", eu, expectedEu)
+	}
+}
+
 func TestAuthInfoString(t *testing.T) {
 	ai := &authInfo{
 		resp: &authpb.AuthResp{
@@ -30,7 +30,10 @@ func (e EmailString) String() string            { return "" }
 type EndUser struct {
 	Email EmailString
 	Group string
-	token *oauth2.Token
+	// ServiceAccount is service account used for backend API.
+	// empty if end user credential is used.
+	ServiceAccount string
+	token          *oauth2.Token
 }
 
 type key int
@@ -50,6 +53,8 @@ const (
 	groupKey       = "x-goma-enduser-group"
 	accessTokenKey = "x-goma-enduser-accesstoken"
 	tokenTypeKey   = "x-goma-enduser-tokentype"
+
+	serviceAccountKey = "x-goma-enduser-service-account"
 )
 
 // New creates new EndUser from email, group and oauth2 access token.
@@ -64,12 +69,16 @@ func New(email, group string, token *oauth2.Token) *EndUser {
 
 // NewContext returns a new Context that carries value u in metadata.
 func NewContext(ctx context.Context, u *EndUser) context.Context {
-	return context.WithValue(metadata.AppendToOutgoingContext(ctx,
+	kv := []string{
 		emailKey, string(u.Email),
 		groupKey, u.Group,
 		accessTokenKey, u.Token().AccessToken,
-		tokenTypeKey, u.Token().TokenType),
-		userKey, u)
+		tokenTypeKey, u.Token().TokenType,
+	}
+	if u.ServiceAccount != "" {
+		kv = append(kv, serviceAccountKey, u.ServiceAccount)
+	}
+	return context.WithValue(metadata.AppendToOutgoingContext(ctx, kv...), userKey, u)
 }
 
 // FromContext returns the EndUser value stored in ctx, if any.
@@ -101,6 +110,10 @@ func FromContext(ctx context.Context) (*EndUser, bool) {
 	if len(v) > 0 {
 		u.token.TokenType = v[0]
 	}
+	v = md[serviceAccountKey]
+	if len(v) > 0 {
+		u.ServiceAccount = v[0]
+	}
 	ok = !reflect.DeepEqual(u, &EndUser{
 		token: &oauth2.Token{},
 	})
@@ -47,6 +47,10 @@ type Service struct {
 	// error message will be used as ErrorDescription for user.
 	CheckToken func(context.Context, *oauth2.Token, *TokenInfo) (string, *oauth2.Token, error)
 
+	// ServiceAccount optionally returns service account used for group.
+	// It is reported in AuthResp for audit.
+	ServiceAccount func(group string) string
+
 	sg         singleflight.Group
 	mu         sync.Mutex
 	tokenCache map[string]*tokenCacheEntry
@@ -161,8 +165,12 @@ func (s *Service) Auth(ctx context.Context, req *authpb.AuthReq) (*authpb.AuthRe
 	expires := timestamppb.New(te.TokenInfo.ExpiresAt)
 	var errorDescription string
 	var quota int32
+	var serviceAccount string
 	if te.TokenInfo.Err == nil {
 		quota = -1 // TODO: -1 is unlimited.
+		if s.ServiceAccount != nil {
+			serviceAccount = s.ServiceAccount(te.Group)
+		}
 	} else if st, ok := status.FromError(te.TokenInfo.Err); ok {
 		switch st.Code() {
 		case codes.OK:
@@ -188,6 +196,7 @@ func (s *Service) Auth(ctx context.Context, req *authpb.AuthReq) (*authpb.AuthRe
 		ErrorDescription: errorDescription,
 		GroupId:          te.Group,
 		Token:            te.TokenProto(),
+		ServiceAccount:   serviceAccount,
 	}
 
 	return resp, nil
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"go.chromium.org/goma/server/httprpc"
 	pb "go.chromium.org/goma/server/proto/backend"
 )
 
@@ -24,12 +25,23 @@ type Backend interface {
 	StoreFile() http.Handler
 	LookupFile() http.Handler
 	Execlog() http.Handler
+	FileStream() http.Handler
 }
 
 // Option is backend option.
 type Option struct {
 	Auth      Auth
 	APIKeyDir string
+
+	// AC is admission controller checked after Auth.
+	// request context has enduser info when AC is checked.
+	AC httprpc.AdmissionController
+
+	// Auditor records requests after Auth.
+	Auditor httprpc.Auditor
+
+	// uploads keeps upload sessions of file stream across reload.
+	uploads *uploadSessions
 }
 
 // FromProto creates Backend based on cfg.
@@ -9,9 +9,12 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	bspb "google.golang.org/genproto/googleapis/bytestream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 
 	"go.chromium.org/goma/server/httprpc"
 	bytestreamrpc "go.chromium.org/goma/server/httprpc/bytestream"
@@ -37,12 +40,64 @@ type GRPC struct {
 	ByteStreamClient bspb.ByteStreamClient
 
 	Auth Auth
+	// admission controller checked after Auth.
+	AC httprpc.AdmissionController
+	// auditor to record requests after Auth.
+	Auditor httprpc.Auditor
 	// api key. used for remote backend.
 	APIKey string
 
 	// trace prefix and label. used for local backend.
 	Namespace string
 	Cluster   string
+
+	// conn is connection to remote backend. used for health check.
+	conn *grpc.ClientConn
+
+	// fileStream keeps upload sessions in memory, so it should be
+	// shared among requests, and frontend needs session affinity
+	// when it runs multiple replicas.
+	fileStream http.Handler
+}
+
+// uploadSessions keeps upload sessions of file stream handlers
+// per file service address, so they survive backend reload.
+type uploadSessions struct {
+	mu sync.Mutex
+	m  map[string]*filerpc.Sessions
+}
+
+// get returns upload sessions for addr.
+// It returns new sessions if u is nil.
+func (u *uploadSessions) get(addr string) *filerpc.Sessions {
+	if u == nil {
+		return filerpc.NewSessions()
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	s, ok := u.m[addr]
+	if !ok {
+		if u.m == nil {
+			u.m = make(map[string]*filerpc.Sessions)
+		}
+		s = filerpc.NewSessions()
+		u.m[addr] = s
+	}
+	return s
+}
+
+// checkConn checks connection state to remote backend.
+func (g GRPC) checkConn() error {
+	if g.conn == nil {
+		return nil
+	}
+	switch st := g.conn.GetState(); st {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		return fmt.Errorf("connection %s", st)
+	case connectivity.Idle:
+		g.conn.Connect()
+	}
+	return nil
 }
 
 func (g GRPC) httprpcOpts(timeout time.Duration) []httprpc.HandlerOption {
@@ -50,6 +105,8 @@ func (g GRPC) httprpcOpts(timeout time.Duration) []httprpc.HandlerOption {
 		httprpc.Timeout(timeout),
 		httprpc.WithRetry(rpc.Retry{}),
 		httprpc.WithAuth(g.Auth),
+		httprpc.WithAdmissionController(g.AC),
+		httprpc.WithAuditor(g.Auditor),
 		httprpc.WithAPIKey(g.APIKey),
 		httprpc.WithNamespace(g.Namespace),
 		httprpc.WithCluster(g.Cluster),
@@ -111,3 +168,15 @@ func (g GRPC) LookupFile() http.Handler {
 func (g GRPC) Execlog() http.Handler {
 	return execlogrpc.Handler(g.ExeclogServer, g.httprpcOpts(1*time.Minute)...)
 }
+
+// FileStream returns http handler for streaming file upload/download.
+func (g GRPC) FileStream() http.Handler {
+	if g.fileStream != nil {
+		return g.fileStream
+	}
+	return g.newFileStream(filerpc.NewSessions())
+}
+
+func (g GRPC) newFileStream(sessions *filerpc.Sessions) http.Handler {
+	return filerpc.StreamHandlerWithSessions(g.FileServer, sessions, g.httprpcOpts(10*time.Minute)...)
+}
@@ -0,0 +1,324 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package backend
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"go.chromium.org/goma/server/log"
+	pb "go.chromium.org/goma/server/proto/backend"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+	defaultUnhealthyThreshold  = 3
+	defaultHealthyThreshold    = 2
+
+	// probePath is request path to probe non-grpc backend with
+	// Ping handler.
+	probePath = "/cxx-compiler-service/ping"
+)
+
+// backendHealth is health status of a backend.
+type backendHealth struct {
+	mu        sync.Mutex
+	unhealthy bool
+	failures  int
+	successes int
+	lastProbe time.Time
+	lastErr   error
+}
+
+// Healthy reports whether the backend is healthy.
+// nil backendHealth is always healthy.
+func (h *backendHealth) Healthy() bool {
+	if h == nil {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return !h.unhealthy
+}
+
+// update updates health status by probe result, and returns true
+// if healthy status is changed.
+func (h *backendHealth) update(now time.Time, err error, unhealthyThreshold, healthyThreshold int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastProbe = now
+	h.lastErr = err
+	if err != nil {
+		h.successes = 0
+		h.failures++
+		if !h.unhealthy && h.failures >= unhealthyThreshold {
+			h.unhealthy = true
+			return true
+		}
+		return false
+	}
+	h.failures = 0
+	h.successes++
+	if h.unhealthy && h.successes >= healthyThreshold {
+		h.unhealthy = false
+		return true
+	}
+	return false
+}
+
+// probeRecorder records status code of response.
+type probeRecorder struct {
+	header http.Header
+	code   int
+}
+
+func (r *probeRecorder) Header() http.Header {
+	if r.header == nil {
+		r.header = make(http.Header)
+	}
+	return r.header
+}
+
+func (r *probeRecorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (r *probeRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
+
+// probe probes be.
+// GRPC backend is probed by grpc health check on its connection,
+// which doesn't require end user credential.
+// Other backend is probed with its Ping handler, and considered as
+// alive if it responds other than server error, since probe request
+// has no credential.
+func probe(ctx context.Context, be Backend) error {
+	if g, ok := be.(GRPC); ok {
+		return probeGRPC(ctx, g)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probePath, nil)
+	if err != nil {
+		return err
+	}
+	rec := &probeRecorder{}
+	be.Ping().ServeHTTP(rec, req)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if rec.code >= 500 {
+		return fmt.Errorf("ping: %d %s", rec.code, http.StatusText(rec.code))
+	}
+	return nil
+}
+
+// probeGRPC probes g by grpc health check.
+// g without connection (i.e. local backend) is considered as alive.
+func probeGRPC(ctx context.Context, g GRPC) error {
+	if g.conn == nil {
+		return nil
+	}
+	err := g.checkConn()
+	if err != nil {
+		return err
+	}
+	ctx = grpcService{g: g}.context(ctx)
+	resp, err := healthpb.NewHealthClient(g.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return fmt.Errorf("health check: %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("health check: %v", resp.Status)
+	}
+	return nil
+}
+
+// healthChecker probes backends periodically.
+type healthChecker struct {
+	interval           time.Duration
+	timeout            time.Duration
+	unhealthyThreshold int
+	healthyThreshold   int
+
+	backends []namedBackend
+
+	// probe is used to probe backend. replaced in test.
+	probe func(context.Context, Backend) error
+}
+
+func newHealthChecker(cfg *pb.HealthCheck) *healthChecker {
+	hc := &healthChecker{
+		interval:           cfg.GetInterval().AsDuration(),
+		timeout:            cfg.GetTimeout().AsDuration(),
+		unhealthyThreshold: int(cfg.GetUnhealthyThreshold()),
+		healthyThreshold:   int(cfg.GetHealthyThreshold()),
+		probe:              probe,
+	}
+	if hc.interval <= 0 {
+		hc.interval = defaultHealthCheckInterval
+	}
+	if hc.timeout <= 0 {
+		hc.timeout = defaultHealthCheckTimeout
+	}
+	if hc.unhealthyThreshold <= 0 {
+		hc.unhealthyThreshold = defaultUnhealthyThreshold
+	}
+	if hc.healthyThreshold <= 0 {
+		hc.healthyThreshold = defaultHealthyThreshold
+	}
+	return hc
+}
+
+// newHealth returns new health status for a backend.
+// It returns nil if hc is nil, i.e. health check is disabled.
+func (hc *healthChecker) newHealth() *backendHealth {
+	if hc == nil {
+		return nil
+	}
+	return &backendHealth{}
+}
+
+// add adds backends to be health checked.
+func (hc *healthChecker) add(backends ...namedBackend) {
+	if hc == nil {
+		return
+	}
+	hc.backends = append(hc.backends, backends...)
+}
+
+// checkAll probes all backends once.
+func (hc *healthChecker) checkAll(ctx context.Context) {
+	logger := log.FromContext(ctx)
+	var wg sync.WaitGroup
+	for _, nb := range hc.backends {
+		wg.Add(1)
+		go func(nb namedBackend) {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, hc.timeout)
+			defer cancel()
+			err := hc.probe(pctx, nb.Backend)
+			if nb.health.update(time.Now(), err, hc.unhealthyThreshold, hc.healthyThreshold) {
+				if err != nil {
+					logger.Errorf("backend %s is ejected: %v", nb.name, err)
+				} else {
+					logger.Infof("backend %s is restored", nb.name)
+				}
+			}
+		}(nb)
+	}
+	wg.Wait()
+}
+
+// run runs health check until ctx is done.
+func (hc *healthChecker) run(ctx context.Context) {
+	ticker := time.NewTicker(hc.interval)
+	defer ticker.Stop()
+	for {
+		hc.checkAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// start starts health checker, and returns func to stop it.
+func (hc *healthChecker) start() func() {
+	if hc == nil || len(hc.backends) == 0 {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		hc.run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// failoverBackend is a mixer backend that uses the first healthy
+// backend in candidates.
+type failoverBackend struct {
+	// candidates are namedBackend or weightedBackend,
+	// in order of preference.
+	candidates []mixerBackend
+}
+
+func (fb failoverBackend) resolve(key func() string, ignoreHealth bool) (namedBackend, bool) {
+	for _, c := range fb.candidates {
+		nb, ok := c.resolve(key, ignoreHealth)
+		if ok {
+			return nb, true
+		}
+	}
+	if ignoreHealth || len(fb.candidates) == 0 {
+		return namedBackend{}, false
+	}
+	// all candidates are unhealthy. use the primary.
+	return fb.candidates[0].resolve(key, true)
+}
+
+var statusTmpl = template.Must(template.New("backendz").Parse(`<html>
+<head><title>backends</title></head>
+<body>
+<h1>backends</h1>
+<table border=1>
+<tr><th>name</th><th>healthy</th><th>last probe</th><th>last error</th></tr>
+{{range .}}
+<tr><td>{{.Name}}</td><td>{{.Healthy}}</td><td>{{.LastProbe}}</td><td>{{.LastErr}}</td></tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+type backendStatus struct {
+	Name      string
+	Healthy   bool
+	LastProbe time.Time
+	LastErr   string
+}
+
+// ServeHTTP serves status page of backends.
+func (hc *healthChecker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var status []backendStatus
+	if hc != nil {
+		for _, nb := range hc.backends {
+			nb.health.mu.Lock()
+			st := backendStatus{
+				Name:      nb.name,
+				Healthy:   !nb.health.unhealthy,
+				LastProbe: nb.health.lastProbe,
+			}
+			if nb.health.lastErr != nil {
+				st.LastErr = nb.health.lastErr.Error()
+			}
+			nb.health.mu.Unlock()
+			status = append(status, st)
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := statusTmpl.Execute(w, status)
+	if err != nil {
+		logger := log.FromContext(req.Context())
+		logger.Errorf("failed to render backend status: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type pingBackend struct {
+	Backend
+	code int
+}
+
+func (b pingBackend) Ping() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, http.StatusText(b.code), b.code)
+	})
+}
+
+func TestProbe(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		code    int
+		wantErr bool
+	}{
+		{code: http.StatusOK},
+		{code: http.StatusUnauthorized},
+		{code: http.StatusBadGateway, wantErr: true},
+		{code: http.StatusServiceUnavailable, wantErr: true},
+	} {
+		err := probe(ctx, pingBackend{code: tc.code})
+		if (err != nil) != tc.wantErr {
+			t.Errorf("probe(code=%d)=%v; want err=%t", tc.code, err, tc.wantErr)
+		}
+	}
+}
+
+func TestProbeGRPC(t *testing.T) {
+	ctx := context.Background()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(s, hs)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	be := GRPC{conn: conn}
+
+	err = probe(ctx, be)
+	if err != nil {
+		t.Errorf("probe(serving)=%v; want nil", err)
+	}
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	err = probe(ctx, be)
+	if err == nil {
+		t.Errorf("probe(not serving)=nil; want err")
+	}
+
+	err = probe(ctx, GRPC{})
+	if err != nil {
+		t.Errorf("probe(local)=%v; want nil", err)
+	}
+}
+
+func TestHealthFailover(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	down := make(map[string]bool)
+	hc := newHealthChecker(nil)
+	hc.probe = func(ctx context.Context, be Backend) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if down[be.(dummyBackend).id] {
+			return errors.New("down")
+		}
+		return nil
+	}
+	primary := namedBackend{
+		Backend: dummyBackend{id: "primary"},
+		name:    "primary",
+		health:  hc.newHealth(),
+	}
+	fallback := namedBackend{
+		Backend: dummyBackend{id: "fallback"},
+		name:    "fallback",
+		health:  hc.newHealth(),
+	}
+	hc.add(primary, fallback)
+	fb := failoverBackend{
+		candidates: []mixerBackend{primary, fallback},
+	}
+	key := func() string { return "" }
+	check := func(want string) {
+		t.Helper()
+		nb, ok := fb.resolve(key, false)
+		if !ok || nb.name != want {
+			t.Errorf("resolve=%q, %t; want %q, true", nb.name, ok, want)
+		}
+	}
+
+	hc.checkAll(ctx)
+	check("primary")
+
+	mu.Lock()
+	down["primary"] = true
+	mu.Unlock()
+	for i := 0; i < defaultUnhealthyThreshold-1; i++ {
+		hc.checkAll(ctx)
+		check("primary")
+	}
+	hc.checkAll(ctx)
+	check("fallback")
+
+	// all down, use primary.
+	mu.Lock()
+	down["fallback"] = true
+	mu.Unlock()
+	for i := 0; i < defaultUnhealthyThreshold; i++ {
+		hc.checkAll(ctx)
+	}
+	check("primary")
+
+	mu.Lock()
+	down["primary"] = false
+	mu.Unlock()
+	for i := 0; i < defaultHealthyThreshold; i++ {
+		hc.checkAll(ctx)
+	}
+	check("primary")
+	if fallback.health.Healthy() {
+		t.Errorf("fallback is healthy; want unhealthy")
+	}
+
+	rec := httptest.NewRecorder()
+	hc.ServeHTTP(rec, httptest.NewRequest("GET", "/backendz", nil))
+	if !strings.Contains(rec.Body.String(), "fallback") || !strings.Contains(rec.Body.String(), "down") {
+		t.Errorf("status page=%q; want fallback status with error", rec.Body.String())
+	}
+}
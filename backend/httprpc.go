@@ -85,3 +85,8 @@ func (h HTTPRPC) LookupFile() http.Handler {
 func (h HTTPRPC) Execlog() http.Handler {
 	return h.proxy
 }
+
+// FileStream forwards requests to target.
+func (h HTTPRPC) FileStream() http.Handler {
+	return h.proxy
+}
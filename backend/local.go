@@ -69,13 +69,18 @@ func FromLocalBackend(ctx context.Context, cfg *pb.LocalBackend, opt Option) (GR
 		},
 		ByteStreamClient: bsClient,
 		Auth:             opt.Auth,
+		AC:               opt.AC,
+		Auditor:          opt.Auditor,
 	}
 	if cfg.TraceOption != nil {
 		be.Namespace = cfg.TraceOption.Namespace
 		be.Cluster = cfg.TraceOption.Cluster
 	}
+	be.fileStream = be.newFileStream(opt.uploads.get("local:" + fileAddr))
 	return be, func() {
-		bsConn.Close()
+		if bsConn != nil {
+			bsConn.Close()
+		}
 		fileConn.Close()
 	}, nil
 }
@@ -11,27 +11,118 @@ import (
 	"net/http"
 	"net/url"
 
+	"google.golang.org/protobuf/proto"
+
 	"go.chromium.org/goma/server/auth"
 	"go.chromium.org/goma/server/auth/enduser"
+	"go.chromium.org/goma/server/httprpc"
 	"go.chromium.org/goma/server/log"
+	gomapb "go.chromium.org/goma/server/proto/api"
 	pb "go.chromium.org/goma/server/proto/backend"
 )
 
-func fromBackendMapping(ctx context.Context, cfg *pb.BackendMapping, opt Option) (Backend, func(), error) {
+// mixerBackend is a backend entry in Mixer, which is resolved to
+// namedBackend for each request.
+type mixerBackend interface {
+	// resolve resolves to namedBackend.
+	// key is used to pick weighted backend.
+	// If ignoreHealth is true, it resolves regardless of health of
+	// backends.
+	// It returns false if no healthy backend is available.
+	resolve(key func() string, ignoreHealth bool) (namedBackend, bool)
+}
+
+// fromBackendMapping creates backend for cfg.
+// It is namedBackend with name, or weightedBackend.
+func fromBackendMapping(ctx context.Context, cfg *pb.BackendMapping, name string, opt Option) (mixerBackend, func(), error) {
 	groupId := cfg.GroupId
 	if groupId == "" {
 		groupId = "default group"
 	}
-	switch be := cfg.Backend.(type) {
+	if len(cfg.WeightedBackends) > 0 {
+		if cfg.Backend != nil {
+			return nil, func() {}, fmt.Errorf("both backend and weighted_backends for group:%q", groupId)
+		}
+		return fromWeightedBackends(ctx, groupId, cfg.WeightedBackends, opt)
+	}
+	var be Backend
+	var cleanup func()
+	var err error
+	switch b := cfg.Backend.(type) {
 	case *pb.BackendMapping_HttpRpc:
-		return FromHTTPRPCBackend(ctx, be.HttpRpc)
+		be, cleanup, err = FromHTTPRPCBackend(ctx, b.HttpRpc)
 	case *pb.BackendMapping_Remote:
-		return FromRemoteBackend(ctx, be.Remote, opt)
+		be, cleanup, err = FromRemoteBackend(ctx, b.Remote, opt)
 	case nil:
 		return nil, func() {}, fmt.Errorf("no backend for group:%q", groupId)
 	default:
 		return nil, func() {}, fmt.Errorf("unknown type in %s: %T", groupId, cfg.Backend)
 	}
+	if err != nil {
+		return nil, cleanup, err
+	}
+	return namedBackend{
+		Backend: be,
+		name:    name,
+	}, cleanup, nil
+}
+
+// fromBackendMappingWithFallbacks creates backend for cfg with fallbacks.
+// Created backends are added to hc for health check.
+func fromBackendMappingWithFallbacks(ctx context.Context, cfg *pb.BackendMapping, name string, opt Option, hc *healthChecker) (mixerBackend, func(), error) {
+	be, cleanup, err := fromBackendMapping(ctx, cfg, name, opt)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	cleanups := []func(){cleanup}
+	cleanupAll := func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}
+	var leaves []namedBackend
+	switch b := be.(type) {
+	case weightedBackend:
+		for i := range b.backends {
+			b.backends[i].health = hc.newHealth()
+		}
+		leaves = append(leaves, b.backends...)
+	case namedBackend:
+		b.health = hc.newHealth()
+		leaves = append(leaves, b)
+		be = b
+	}
+	if len(cfg.Fallbacks) > 0 {
+		if hc == nil {
+			logger := log.FromContext(ctx)
+			logger.Warnf("fallbacks for %s without health_check will not be used", name)
+		}
+		fb := failoverBackend{
+			candidates: []mixerBackend{be},
+		}
+		for i, f := range cfg.Fallbacks {
+			fname := f.Name
+			if fname == "" {
+				fname = fmt.Sprintf("%s/fallback[%d]", name, i)
+			}
+			b, c, err := fromBackendOneof(ctx, fname, f.GetHttpRpc(), f.GetRemote(), opt)
+			if err != nil {
+				cleanupAll()
+				return nil, func() {}, err
+			}
+			cleanups = append(cleanups, c)
+			nb := namedBackend{
+				Backend: b,
+				name:    fname,
+				health:  hc.newHealth(),
+			}
+			leaves = append(leaves, nb)
+			fb.candidates = append(fb.candidates, nb)
+		}
+		be = fb
+	}
+	hc.add(leaves...)
+	return be, cleanupAll, nil
 }
 
 // FromBackendRule creates new Mixer from cfg.
@@ -47,7 +138,13 @@ func FromBackendRule(ctx context.Context, cfg *pb.BackendRule, opt Option) (mixe
 	}()
 	logger := log.FromContext(ctx)
 	mixer.Auth = opt.Auth
-	mixer.backends = make(map[string]Backend)
+	mixer.AC = opt.AC
+	// admission is checked by mixer, not by each backend.
+	opt.AC = nil
+	if cfg.HealthCheck != nil {
+		mixer.health = newHealthChecker(cfg.HealthCheck)
+	}
+	mixer.backends = make(map[string]mixerBackend)
 	for _, backend := range cfg.Backends {
 		if backend.GroupId == "" {
 			if len(backend.QueryParams) > 0 {
@@ -56,7 +153,7 @@ func FromBackendRule(ctx context.Context, cfg *pb.BackendRule, opt Option) (mixe
 			if mixer.defaultBackend != nil {
 				return Mixer{}, func() {}, errors.New("duplicate default backend")
 			}
-			be, cleanup, err := fromBackendMapping(ctx, backend, opt)
+			be, cleanup, err := fromBackendMappingWithFallbacks(ctx, backend, "default", opt, mixer.health)
 			if err != nil {
 				logger.Warnf("ignore bad backend[default] %s: %v", backend, err)
 				continue
@@ -69,7 +166,7 @@ func FromBackendRule(ctx context.Context, cfg *pb.BackendRule, opt Option) (mixe
 		if _, found := mixer.backends[key]; found {
 			return Mixer{}, func() {}, fmt.Errorf("duplicate backend group: %s", key)
 		}
-		be, cleanup, err := fromBackendMapping(ctx, backend, opt)
+		be, cleanup, err := fromBackendMappingWithFallbacks(ctx, backend, key, opt, mixer.health)
 		if err != nil {
 			logger.Warnf("ignore bad backend %s: %v", backend, err)
 			continue
@@ -80,7 +177,17 @@ func FromBackendRule(ctx context.Context, cfg *pb.BackendRule, opt Option) (mixe
 	if len(mixer.backends) == 0 && mixer.defaultBackend == nil {
 		return Mixer{}, func() {}, fmt.Errorf("no valid backends in %s", cfg)
 	}
+	if cfg.Shadow != nil {
+		sb, cleanup, err := fromShadowBackend(ctx, cfg.Shadow, opt)
+		if err != nil {
+			return Mixer{}, func() {}, err
+		}
+		mixer.shadow = sb
+		cleanups = append(cleanups, cleanup)
+	}
+	stop := mixer.health.start()
 	return mixer, func() {
+		stop()
 		for _, c := range cleanups {
 			c()
 		}
@@ -106,41 +213,63 @@ func backendKey(group string, q url.Values) string {
 
 // Mixer is mixer backend, dispatched by group of enduser.
 type Mixer struct {
-	backends       map[string]Backend
-	defaultBackend Backend
+	backends       map[string]mixerBackend
+	defaultBackend mixerBackend
 	Auth           Auth
+
+	// AC is admission controller checked after Auth.
+	AC httprpc.AdmissionController
+
+	health *healthChecker
+
+	// shadow receives sampled copy of Exec requests, if set.
+	shadow *shadowBackend
+}
+
+// Status returns http handler to serve status page of backends.
+func (m Mixer) Status() http.Handler {
+	return m.health
 }
 
-func (m Mixer) Ping() http.Handler       { return m.dispatcher(Backend.Ping) }
-func (m Mixer) Exec() http.Handler       { return m.dispatcher(Backend.Exec) }
-func (m Mixer) ByteStream() http.Handler { return m.dispatcher(Backend.ByteStream) }
-func (m Mixer) StoreFile() http.Handler  { return m.dispatcher(Backend.StoreFile) }
-func (m Mixer) LookupFile() http.Handler { return m.dispatcher(Backend.LookupFile) }
-func (m Mixer) Execlog() http.Handler    { return m.dispatcher(Backend.Execlog) }
+func (m Mixer) Ping() http.Handler       { return m.dispatcher(Backend.Ping, nil) }
+func (m Mixer) Exec() http.Handler       { return m.shadow.mirror(m.dispatcher(Backend.Exec, newExecReq)) }
+func (m Mixer) ByteStream() http.Handler { return m.dispatcher(Backend.ByteStream, nil) }
+func (m Mixer) StoreFile() http.Handler  { return m.dispatcher(Backend.StoreFile, newStoreFileReq) }
+func (m Mixer) LookupFile() http.Handler { return m.dispatcher(Backend.LookupFile, newLookupFileReq) }
+func (m Mixer) Execlog() http.Handler    { return m.dispatcher(Backend.Execlog, nil) }
+func (m Mixer) FileStream() http.Handler { return m.dispatcher(Backend.FileStream, nil) }
 
-func (m Mixer) selectBackend(ctx context.Context, group string, q url.Values) (Backend, bool) {
+func newExecReq() proto.Message       { return &gomapb.ExecReq{} }
+func newStoreFileReq() proto.Message  { return &gomapb.StoreFileReq{} }
+func newLookupFileReq() proto.Message { return &gomapb.LookupFileReq{} }
+
+// selectBackend selects backend for group and query, and returns the backend
+// and its name.
+func (m Mixer) selectBackend(ctx context.Context, group string, q url.Values) (mixerBackend, string, bool) {
 	logger := log.FromContext(ctx)
 	key := backendKey(group, q)
 	backend, found := m.backends[key]
 	if found {
 		logger.Infof("backend %s", key)
-		return backend, true
+		return backend, key, true
 	}
 	key = backendKey(group, nil)
 	backend, found = m.backends[key]
 	if found {
 		logger.Infof("backend %s (ignore query param:%s)", key, q)
-		return backend, true
+		return backend, key, true
 	}
 	backend = m.defaultBackend
 	if backend != nil {
 		logger.Infof("backend default for %s", key)
-		return backend, true
+		return backend, "default", true
 	}
-	return nil, false
+	return nil, "", false
 }
 
-func (m Mixer) dispatcher(handler func(Backend) http.Handler) http.Handler {
+// dispatcher returns http handler to dispatch request to backend.
+// newReq is used to parse request to pick weighted backend, if any.
+func (m Mixer) dispatcher(handler func(Backend) http.Handler, newReq func() proto.Message) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		ctx := req.Context()
 		logger := log.FromContext(ctx)
@@ -166,14 +295,40 @@ func (m Mixer) dispatcher(handler func(Backend) http.Handler) http.Handler {
 			logger.Errorf("server error %s: %d %s: no enduser in context", req.URL.Path, code, http.StatusText(code))
 			return
 		}
+		if m.AC != nil {
+			err := httprpc.Admit(m.AC, w, req.WithContext(ctx))
+			if err != nil {
+				httprpc.Deny(w, req, err)
+				return
+			}
+		}
 		q := req.URL.Query()
-		backend, found := m.selectBackend(ctx, user.Group, q)
+		backend, name, found := m.selectBackend(ctx, user.Group, q)
 		if !found {
 			logger.Errorf("no backend config for group:%q query:%q", user.Group, q.Encode())
 			http.Error(w, "no backend config", http.StatusInternalServerError)
 			return
 		}
-		h := handler(backend)
+		var sticky string
+		stickyDone := false
+		key := func() string {
+			if !stickyDone {
+				sticky, req = stickyKey(ctx, req, newReq)
+				stickyDone = true
+			}
+			return sticky
+		}
+		nb, ok := backend.resolve(key, false)
+		if !ok {
+			logger.Errorf("no healthy backend for %s", name)
+			http.Error(w, "no healthy backend", http.StatusServiceUnavailable)
+			return
+		}
+		if nb.name != name {
+			logger.Infof("backend %s for %s", nb.name, name)
+		}
+		recordMixerRequest(ctx, user.Group, nb.name)
+		h := handler(nb.Backend)
 		h.ServeHTTP(w, req)
 	})
 }
@@ -30,28 +30,28 @@ func TestMixerSelectBackend(t *testing.T) {
 		t.Fatalf("win&staging: %v", err)
 	}
 	mixer := Mixer{
-		backends: map[string]Backend{
-			backendKey("goma-group1", nil): dummyBackend{
-				id: "group1-backend",
+		backends: map[string]mixerBackend{
+			backendKey("goma-group1", nil): namedBackend{
+				Backend: dummyBackend{id: "group1-backend"},
 			},
-			backendKey("goma-group2", nil): dummyBackend{
-				id: "group2-backend",
+			backendKey("goma-group2", nil): namedBackend{
+				Backend: dummyBackend{id: "group2-backend"},
 			},
-			backendKey("service-account-goma-client", nil): dummyBackend{
-				id: "prod-backend",
+			backendKey("service-account-goma-client", nil): namedBackend{
+				Backend: dummyBackend{id: "prod-backend"},
 			},
-			backendKey("service-account-goma-client", winQuery): dummyBackend{
-				id: "prod-backend",
+			backendKey("service-account-goma-client", winQuery): namedBackend{
+				Backend: dummyBackend{id: "prod-backend"},
 			},
-			backendKey("service-account-goma-client", stagingQuery): dummyBackend{
-				id: "staging-backend",
+			backendKey("service-account-goma-client", stagingQuery): namedBackend{
+				Backend: dummyBackend{id: "staging-backend"},
 			},
-			backendKey("service-account-goma-client", stagingWinQuery): dummyBackend{
-				id: "staging-backend",
+			backendKey("service-account-goma-client", stagingWinQuery): namedBackend{
+				Backend: dummyBackend{id: "staging-backend"},
 			},
 		},
-		defaultBackend: dummyBackend{
-			id: "default-backend",
+		defaultBackend: namedBackend{
+			Backend: dummyBackend{id: "default-backend"},
 		},
 	}
 
@@ -122,11 +122,11 @@ func TestMixerSelectBackend(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
-			backend, found := mixer.selectBackend(ctx, tc.group, q)
+			backend, _, found := mixer.selectBackend(ctx, tc.group, q)
 			if !found {
 				t.Fatal("not found")
 			}
-			if got, want := backend.(dummyBackend).id, tc.target; got != want {
+			if got, want := backend.(namedBackend).Backend.(dummyBackend).id, tc.target; got != want {
 				t.Errorf("selectBackend=%q; want=%q", got, want)
 			}
 		})
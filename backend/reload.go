@@ -0,0 +1,178 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package backend
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"go.chromium.org/goma/server/log"
+	pb "go.chromium.org/goma/server/proto/backend"
+)
+
+// generation is a backend graph built from a config.
+type generation struct {
+	be      Backend
+	cleanup func()
+
+	ping       http.Handler
+	exec       http.Handler
+	byteStream http.Handler
+	storeFile  http.Handler
+	lookupFile http.Handler
+	execlog    http.Handler
+	fileStream http.Handler
+
+	// service serves requests by method call.
+	service Service
+
+	// inflight counts requests being served by the generation.
+	inflight sync.WaitGroup
+}
+
+func newGeneration(be Backend, cleanup func()) *generation {
+	return &generation{
+		be:         be,
+		cleanup:    cleanup,
+		ping:       be.Ping(),
+		exec:       be.Exec(),
+		byteStream: be.ByteStream(),
+		storeFile:  be.StoreFile(),
+		lookupFile: be.LookupFile(),
+		execlog:    be.Execlog(),
+		fileStream: be.FileStream(),
+		service:    ServiceOf(be),
+	}
+}
+
+// Reloadable is a backend that can be replaced by new config
+// without dropping in-flight requests.
+type Reloadable struct {
+	// Option is used to create backend from config.
+	Option Option
+
+	mu  sync.RWMutex
+	gen *generation
+
+	// uploads is shared among generations, so upload sessions
+	// of file stream are not lost by reload.
+	uploads uploadSessions
+}
+
+// Set builds new backend from cfg, and swaps it atomically.
+// Old backend will be cleaned up once its in-flight requests finish.
+func (r *Reloadable) Set(ctx context.Context, cfg *pb.BackendConfig) error {
+	opt := r.Option
+	opt.uploads = &r.uploads
+	be, cleanup, err := FromProto(ctx, cfg, opt)
+	if err != nil {
+		cleanup()
+		return err
+	}
+	gen := newGeneration(be, cleanup)
+	r.mu.Lock()
+	old := r.gen
+	r.gen = gen
+	r.mu.Unlock()
+	logger := log.FromContext(ctx)
+	logger.Infof("backend updated: %T", be)
+	if old != nil {
+		go func() {
+			old.inflight.Wait()
+			old.cleanup()
+			logger.Infof("old backend cleaned up: %T", old.be)
+		}()
+	}
+	return nil
+}
+
+// Backend returns current backend.
+func (r *Reloadable) Backend() Backend {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.gen == nil {
+		return nil
+	}
+	return r.gen.be
+}
+
+// Close waits for in-flight requests and cleans up current backend.
+func (r *Reloadable) Close() {
+	r.mu.Lock()
+	gen := r.gen
+	r.gen = nil
+	r.mu.Unlock()
+	if gen == nil {
+		return
+	}
+	gen.inflight.Wait()
+	gen.cleanup()
+}
+
+// acquire returns current generation, and marks a request in-flight.
+// Caller must call gen.inflight.Done when the request finished.
+func (r *Reloadable) acquire() *generation {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.gen == nil {
+		return nil
+	}
+	r.gen.inflight.Add(1)
+	return r.gen
+}
+
+func (r *Reloadable) handler(h func(*generation) http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gen := r.acquire()
+		if gen == nil {
+			http.Error(w, "no backend", http.StatusServiceUnavailable)
+			return
+		}
+		defer gen.inflight.Done()
+		h(gen).ServeHTTP(w, req)
+	})
+}
+
+func (r *Reloadable) Ping() http.Handler {
+	return r.handler(func(g *generation) http.Handler { return g.ping })
+}
+
+func (r *Reloadable) Exec() http.Handler {
+	return r.handler(func(g *generation) http.Handler { return g.exec })
+}
+
+func (r *Reloadable) ByteStream() http.Handler {
+	return r.handler(func(g *generation) http.Handler { return g.byteStream })
+}
+
+func (r *Reloadable) StoreFile() http.Handler {
+	return r.handler(func(g *generation) http.Handler { return g.storeFile })
+}
+
+func (r *Reloadable) LookupFile() http.Handler {
+	return r.handler(func(g *generation) http.Handler { return g.lookupFile })
+}
+
+func (r *Reloadable) Execlog() http.Handler {
+	return r.handler(func(g *generation) http.Handler { return g.execlog })
+}
+
+func (r *Reloadable) FileStream() http.Handler {
+	return r.handler(func(g *generation) http.Handler { return g.fileStream })
+}
+
+// Status returns http handler to serve status page of current backend,
+// if it is mixer.
+func (r *Reloadable) Status() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mixer, ok := r.Backend().(Mixer)
+		if !ok {
+			http.Error(w, "backend is not mixer", http.StatusNotFound)
+			return
+		}
+		mixer.Status().ServeHTTP(w, req)
+	})
+}
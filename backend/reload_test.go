@@ -0,0 +1,223 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	gomafile "go.chromium.org/goma/server/file"
+	filerpc "go.chromium.org/goma/server/httprpc/file"
+	gomapb "go.chromium.org/goma/server/proto/api"
+	pb "go.chromium.org/goma/server/proto/backend"
+	filepb "go.chromium.org/goma/server/proto/file"
+)
+
+func TestReloadable(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		fmt.Fprint(w, "s1")
+	}))
+	defer s1.Close()
+	s2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "s2")
+	}))
+	defer s2.Close()
+
+	r := &Reloadable{}
+	defer r.Close()
+
+	rs := httptest.NewServer(r.Exec())
+	defer rs.Close()
+
+	get := func(path string) string {
+		t.Helper()
+		resp, err := http.Get(rs.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	if resp, err := http.Get(rs.URL); err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("get without backend=%v, %v; want %d", resp, err, http.StatusServiceUnavailable)
+	}
+
+	err := r.Set(ctx, &pb.BackendConfig{
+		Backend: &pb.BackendConfig_HttpRpc{
+			HttpRpc: &pb.HttpRpcBackend{
+				Target: s1.URL,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := get("/e"), "s1"; got != want {
+		t.Errorf("get=%q; want=%q", got, want)
+	}
+
+	slow := make(chan string)
+	go func() {
+		resp, err := http.Get(rs.URL + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		slow <- string(b)
+	}()
+	<-started
+	old := r.gen
+
+	err = r.Set(ctx, &pb.BackendConfig{
+		Backend: &pb.BackendConfig_HttpRpc{
+			HttpRpc: &pb.HttpRpcBackend{
+				Target: s2.URL,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := get("/e"), "s2"; got != want {
+		t.Errorf("get after reload=%q; want=%q", got, want)
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		old.inflight.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		t.Errorf("old backend drained while request in-flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	if got, want := <-slow, "s1"; got != want {
+		t.Errorf("in-flight request=%q; want=%q", got, want)
+	}
+	<-drained
+
+	err = r.Set(ctx, &pb.BackendConfig{})
+	if err == nil {
+		t.Errorf("Set(empty config)=nil; want error")
+	}
+	if got, want := get("/e"), "s2"; got != want {
+		t.Errorf("get after bad config=%q; want=%q", got, want)
+	}
+}
+
+type fakeFileServer struct {
+	filepb.UnimplementedFileServiceServer
+	c gomafile.LocalCache
+}
+
+func (s fakeFileServer) StoreFile(ctx context.Context, req *gomapb.StoreFileReq) (*gomapb.StoreFileResp, error) {
+	return s.c.StoreFile(ctx, req)
+}
+
+func (s fakeFileServer) LookupFile(ctx context.Context, req *gomapb.LookupFileReq) (*gomapb.LookupFileResp, error) {
+	return s.c.LookupFile(ctx, req)
+}
+
+func TestReloadableFileStream(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "reload-filestream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	filepb.RegisterFileServiceServer(s, fakeFileServer{
+		c: gomafile.LocalCache{
+			Dir: dir,
+		},
+	})
+	go s.Serve(lis)
+	defer s.Stop()
+
+	cfg := &pb.BackendConfig{
+		Backend: &pb.BackendConfig_Local{
+			Local: &pb.LocalBackend{
+				FileAddr: lis.Addr().String(),
+			},
+		},
+	}
+	r := &Reloadable{}
+	defer r.Close()
+	err = r.Set(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := httptest.NewServer(r.FileStream())
+	defer rs.Close()
+
+	content := make([]byte, gomafile.FileChunkSize+1234)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	post := func(offset int64, body []byte) *http.Response {
+		t.Helper()
+		url := fmt.Sprintf("%s/fs/uploads/upload-id?size=%d&offset=%d", rs.URL, len(content), offset)
+		resp, err := http.Post(url, "application/octet-stream", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := post(0, content[:gomafile.FileChunkSize+100])
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("partial upload: status=%d; want=%d", resp.StatusCode, http.StatusAccepted)
+	}
+
+	// reload in the middle of upload.
+	old := r.gen
+	err = r.Set(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.gen == old {
+		t.Fatalf("backend not reloaded")
+	}
+
+	resp = post(gomafile.FileChunkSize, content[gomafile.FileChunkSize:])
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("resume upload after reload: status=%d; want=%d", resp.StatusCode, http.StatusOK)
+	}
+	if resp.Header.Get(filerpc.HashKeyHeader) == "" {
+		t.Errorf("resume upload after reload: no hash key")
+	}
+}
@@ -89,7 +89,11 @@ func FromRemoteBackend(ctx context.Context, cfg *pb.RemoteBackend, opt Option) (
 		// TODO: propagate metadata.
 		ByteStreamClient: bspb.NewByteStreamClient(conn),
 		Auth:             opt.Auth,
+		AC:               opt.AC,
+		Auditor:          opt.Auditor,
 		APIKey:           strings.TrimSpace(string(apiKey)),
+		conn:             conn,
 	}
+	be.fileStream = be.newFileStream(opt.uploads.get("remote:" + cfg.Address))
 	return be, func() { conn.Close() }, nil
 }
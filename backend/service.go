@@ -0,0 +1,236 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package backend
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	"go.chromium.org/goma/server/log"
+	gomapb "go.chromium.org/goma/server/proto/api"
+)
+
+// Service serves goma API by method call, i.e. without httprpc.
+// It is used to serve goma API over gRPC.
+//
+// Caller is responsible for auth, admission control and audit, so
+// ctx must have enduser info.
+type Service interface {
+	Exec(context.Context, *gomapb.ExecReq) (*gomapb.ExecResp, error)
+	StoreFile(context.Context, *gomapb.StoreFileReq) (*gomapb.StoreFileResp, error)
+	LookupFile(context.Context, *gomapb.LookupFileReq) (*gomapb.LookupFileResp, error)
+	SaveLog(context.Context, *gomapb.SaveLogReq) (*gomapb.SaveLogResp, error)
+}
+
+// serviceBackend is a backend that can serve Service.
+type serviceBackend interface {
+	service() Service
+}
+
+// ServiceOf returns Service of be.
+// If be doesn't support Service (e.g. httprpc backend), returned
+// Service responds Unimplemented error.
+func ServiceOf(be Backend) Service {
+	if sb, ok := be.(serviceBackend); ok {
+		return sb.service()
+	}
+	return unsupportedService{name: fmt.Sprintf("%T", be)}
+}
+
+// unsupportedService is Service for backend that doesn't support Service.
+type unsupportedService struct {
+	name string
+}
+
+func (s unsupportedService) err() error {
+	return status.Errorf(codes.Unimplemented, "backend %s doesn't support gRPC API", s.name)
+}
+
+func (s unsupportedService) Exec(context.Context, *gomapb.ExecReq) (*gomapb.ExecResp, error) {
+	return nil, s.err()
+}
+
+func (s unsupportedService) StoreFile(context.Context, *gomapb.StoreFileReq) (*gomapb.StoreFileResp, error) {
+	return nil, s.err()
+}
+
+func (s unsupportedService) LookupFile(context.Context, *gomapb.LookupFileReq) (*gomapb.LookupFileResp, error) {
+	return nil, s.err()
+}
+
+func (s unsupportedService) SaveLog(context.Context, *gomapb.SaveLogReq) (*gomapb.SaveLogResp, error) {
+	return nil, s.err()
+}
+
+func (g GRPC) service() Service {
+	return grpcService{g: g}
+}
+
+// grpcService is Service of GRPC backend.
+type grpcService struct {
+	g GRPC
+}
+
+func (s grpcService) context(ctx context.Context) context.Context {
+	if s.g.APIKey != "" {
+		// https://cloud.google.com/endpoints/docs/grpc/restricting-api-access-with-api-keys-grpc#grpc_clients
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", s.g.APIKey)
+	}
+	return ctx
+}
+
+func (s grpcService) Exec(ctx context.Context, req *gomapb.ExecReq) (*gomapb.ExecResp, error) {
+	return s.g.ExecServer.Exec(s.context(ctx), req)
+}
+
+func (s grpcService) StoreFile(ctx context.Context, req *gomapb.StoreFileReq) (*gomapb.StoreFileResp, error) {
+	return s.g.FileServer.StoreFile(s.context(ctx), req)
+}
+
+func (s grpcService) LookupFile(ctx context.Context, req *gomapb.LookupFileReq) (*gomapb.LookupFileResp, error) {
+	return s.g.FileServer.LookupFile(s.context(ctx), req)
+}
+
+func (s grpcService) SaveLog(ctx context.Context, req *gomapb.SaveLogReq) (*gomapb.SaveLogResp, error) {
+	return s.g.ExeclogServer.SaveLog(s.context(ctx), req)
+}
+
+func (nb namedBackend) service() Service {
+	return ServiceOf(nb.Backend)
+}
+
+func (m Mixer) service() Service {
+	return mixerService{m: m}
+}
+
+// mixerService is Service of Mixer.
+// It dispatches requests by group of enduser, as Mixer does for httprpc.
+// Query parameters are not available in gRPC, so it uses backend for
+// the group without query parameters.
+type mixerService struct {
+	m Mixer
+}
+
+// pick picks Service for the request with requester info ri.
+func (s mixerService) pick(ctx context.Context, ri *gomapb.RequesterInfo) (Service, error) {
+	logger := log.FromContext(ctx)
+	user, ok := enduser.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "no enduser info available")
+	}
+	backend, name, found := s.m.selectBackend(ctx, user.Group, nil)
+	if !found {
+		logger.Errorf("no backend config for group:%q", user.Group)
+		return nil, status.Error(codes.Internal, "no backend config")
+	}
+	key := func() string {
+		return requesterStickyKey(ri)
+	}
+	nb, ok := backend.resolve(key, false)
+	if !ok {
+		logger.Errorf("no healthy backend for %s", name)
+		return nil, status.Error(codes.Unavailable, "no healthy backend")
+	}
+	if nb.name != name {
+		logger.Infof("backend %s for %s", nb.name, name)
+	}
+	recordMixerRequest(ctx, user.Group, nb.name)
+	return nb.service(), nil
+}
+
+func (s mixerService) Exec(ctx context.Context, req *gomapb.ExecReq) (*gomapb.ExecResp, error) {
+	svc, err := s.pick(ctx, req.GetRequesterInfo())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := svc.Exec(ctx, req)
+	if err == nil {
+		s.m.shadow.mirrorExec(ctx, req, resp)
+	}
+	return resp, err
+}
+
+func (s mixerService) StoreFile(ctx context.Context, req *gomapb.StoreFileReq) (*gomapb.StoreFileResp, error) {
+	svc, err := s.pick(ctx, req.GetRequesterInfo())
+	if err != nil {
+		return nil, err
+	}
+	return svc.StoreFile(ctx, req)
+}
+
+func (s mixerService) LookupFile(ctx context.Context, req *gomapb.LookupFileReq) (*gomapb.LookupFileResp, error) {
+	svc, err := s.pick(ctx, req.GetRequesterInfo())
+	if err != nil {
+		return nil, err
+	}
+	return svc.LookupFile(ctx, req)
+}
+
+func (s mixerService) SaveLog(ctx context.Context, req *gomapb.SaveLogReq) (*gomapb.SaveLogResp, error) {
+	svc, err := s.pick(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return svc.SaveLog(ctx, req)
+}
+
+func (r *Reloadable) service() Service {
+	return reloadService{r: r}
+}
+
+// reloadService is Service of Reloadable.
+type reloadService struct {
+	r *Reloadable
+}
+
+// acquire returns Service of current generation, and func to release it.
+func (s reloadService) acquire() (Service, func(), error) {
+	gen := s.r.acquire()
+	if gen == nil {
+		return nil, func() {}, status.Error(codes.Unavailable, "no backend")
+	}
+	return gen.service, gen.inflight.Done, nil
+}
+
+func (s reloadService) Exec(ctx context.Context, req *gomapb.ExecReq) (*gomapb.ExecResp, error) {
+	svc, done, err := s.acquire()
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+	return svc.Exec(ctx, req)
+}
+
+func (s reloadService) StoreFile(ctx context.Context, req *gomapb.StoreFileReq) (*gomapb.StoreFileResp, error) {
+	svc, done, err := s.acquire()
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+	return svc.StoreFile(ctx, req)
+}
+
+func (s reloadService) LookupFile(ctx context.Context, req *gomapb.LookupFileReq) (*gomapb.LookupFileResp, error) {
+	svc, done, err := s.acquire()
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+	return svc.LookupFile(ctx, req)
+}
+
+func (s reloadService) SaveLog(ctx context.Context, req *gomapb.SaveLogReq) (*gomapb.SaveLogResp, error) {
+	svc, done, err := s.acquire()
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+	return svc.SaveLog(ctx, req)
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package backend
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	gomapb "go.chromium.org/goma/server/proto/api"
+)
+
+// fakeServiceBackend is a backend that serves Service responding its id.
+type fakeServiceBackend struct {
+	Backend
+	id string
+}
+
+func (b fakeServiceBackend) service() Service {
+	return fakeService{id: b.id}
+}
+
+type fakeService struct {
+	unsupportedService
+	id string
+}
+
+func (s fakeService) Exec(ctx context.Context, req *gomapb.ExecReq) (*gomapb.ExecResp, error) {
+	return &gomapb.ExecResp{
+		ErrorMessage: []string{s.id},
+	}, nil
+}
+
+func TestServiceOfMixer(t *testing.T) {
+	mixer := Mixer{
+		backends: map[string]mixerBackend{
+			backendKey("goma-group1", nil): namedBackend{
+				Backend: fakeServiceBackend{id: "group1-backend"},
+				name:    "goma-group1",
+			},
+			backendKey("goma-group2", nil): namedBackend{
+				Backend: NewHTTPRPC(&url.URL{Scheme: "https", Host: "example.com"}),
+				name:    "goma-group2",
+			},
+		},
+		defaultBackend: namedBackend{
+			Backend: fakeServiceBackend{id: "default-backend"},
+			name:    "default",
+		},
+	}
+	s := ServiceOf(mixer)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		group string
+		want  string
+		code  codes.Code
+	}{
+		{
+			group: "goma-group1",
+			want:  "group1-backend",
+		},
+		{
+			group: "googlers",
+			want:  "default-backend",
+		},
+		{
+			group: "goma-group2",
+			code:  codes.Unimplemented,
+		},
+	} {
+		t.Run(tc.group, func(t *testing.T) {
+			ctx := enduser.NewContext(ctx, enduser.New("someone@example.com", tc.group, nil))
+			resp, err := s.Exec(ctx, &gomapb.ExecReq{})
+			if status.Code(err) != tc.code {
+				t.Fatalf("Exec(ctx, req)=%v, %v; want code %v", resp, err, tc.code)
+			}
+			if err != nil {
+				return
+			}
+			if got := resp.GetErrorMessage(); len(got) != 1 || got[0] != tc.want {
+				t.Errorf("Exec(ctx, req).ErrorMessage=%q; want %q", got, tc.want)
+			}
+		})
+	}
+
+	resp, err := s.Exec(ctx, &gomapb.ExecReq{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Exec(ctx without enduser, req)=%v, %v; want %v", resp, err, codes.Unauthenticated)
+	}
+}
+
+func TestServiceOfReloadable(t *testing.T) {
+	r := &Reloadable{}
+	s := ServiceOf(r)
+	ctx := enduser.NewContext(context.Background(), enduser.New("someone@example.com", "goma-group1", nil))
+
+	resp, err := s.Exec(ctx, &gomapb.ExecReq{})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Exec(ctx, req)=%v, %v; want %v", resp, err, codes.Unavailable)
+	}
+
+	r.gen = newGeneration(fakeServiceBackend{
+		Backend: NewHTTPRPC(&url.URL{Scheme: "https", Host: "example.com"}),
+		id:      "backend",
+	}, func() {})
+	resp, err = s.Exec(ctx, &gomapb.ExecReq{})
+	if err != nil {
+		t.Fatalf("Exec(ctx, req)=%v, %v; want nil error", resp, err)
+	}
+	if got := resp.GetErrorMessage(); len(got) != 1 || got[0] != "backend" {
+		t.Errorf("Exec(ctx, req).ErrorMessage=%q; want %q", got, "backend")
+	}
+	r.Close()
+}
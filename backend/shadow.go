@@ -0,0 +1,293 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	"go.chromium.org/goma/server/file"
+	"go.chromium.org/goma/server/httprpc"
+	"go.chromium.org/goma/server/log"
+	gomapb "go.chromium.org/goma/server/proto/api"
+	pb "go.chromium.org/goma/server/proto/backend"
+)
+
+const defaultShadowTimeout = 5 * time.Minute
+
+var (
+	shadowRequests = stats.Int64(
+		"go.chromium.org/goma/server/backend.shadow_requests",
+		"Number of Exec requests mirrored to shadow backend",
+		stats.UnitDimensionless)
+
+	shadowResultKey = tag.MustNewKey("result")
+)
+
+// shadowBackend is a backend that receives sampled copy of
+// Exec requests.
+type shadowBackend struct {
+	namedBackend
+	timeout time.Duration
+
+	// sample reports whether to mirror a request.
+	sample func() bool
+
+	// inflight counts shadow requests being processed.
+	inflight sync.WaitGroup
+}
+
+func fromShadowBackend(ctx context.Context, cfg *pb.ShadowBackend, opt Option) (*shadowBackend, func(), error) {
+	name := cfg.Name
+	if name == "" {
+		name = "shadow"
+	}
+	rate := cfg.SampleRate
+	if rate < 0 || rate > 1 {
+		return nil, func() {}, fmt.Errorf("invalid sample_rate for %s: %f", name, rate)
+	}
+	// shadow requests are not audited, since they are not
+	// requested by end user.
+	opt.Auditor = nil
+	be, cleanup, err := fromBackendOneof(ctx, name, cfg.GetHttpRpc(), cfg.GetRemote(), opt)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	sb := &shadowBackend{
+		namedBackend: namedBackend{
+			Backend: be,
+			name:    name,
+		},
+		timeout: cfg.GetTimeout().AsDuration(),
+		sample: func() bool {
+			return rand.Float64() < rate
+		},
+	}
+	if sb.timeout <= 0 {
+		sb.timeout = defaultShadowTimeout
+	}
+	return sb, func() {
+		sb.inflight.Wait()
+		cleanup()
+	}, nil
+}
+
+// teeResponseWriter is http.ResponseWriter that keeps a copy of
+// response.
+type teeResponseWriter struct {
+	http.ResponseWriter
+	code int
+	buf  bytes.Buffer
+}
+
+func (w *teeResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *teeResponseWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.buf.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *teeResponseWriter) response() *http.Response {
+	return &http.Response{
+		StatusCode: w.code,
+		Header:     w.Header().Clone(),
+		Body:       ioutil.NopCloser(bytes.NewReader(w.buf.Bytes())),
+	}
+}
+
+// mirror returns http handler that serves req by primary, and
+// mirrors sampled requests to the shadow backend asynchronously.
+// If sb is nil, it returns primary as is.
+func (sb *shadowBackend) mirror(primary http.Handler) http.Handler {
+	if sb == nil {
+		return primary
+	}
+	shadow := sb.Exec()
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !sb.sample() {
+			primary.ServeHTTP(w, req)
+			return
+		}
+		ctx := req.Context()
+		logger := log.FromContext(ctx)
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			logger.Warnf("shadow %s: failed to read request: %v", sb.name, err)
+			primary.ServeHTTP(w, req)
+			return
+		}
+		tw := &teeResponseWriter{ResponseWriter: w}
+		primary.ServeHTTP(tw, req)
+		if tw.code != http.StatusOK {
+			// don't mirror failed requests, e.g. auth error.
+			return
+		}
+		sctx, cancel := context.WithTimeout(context.Background(), sb.timeout)
+		sreq, err := http.NewRequestWithContext(sctx, req.Method, req.URL.String(), bytes.NewReader(body))
+		if err != nil {
+			cancel()
+			logger.Warnf("shadow %s: failed to create request: %v", sb.name, err)
+			return
+		}
+		sreq.Header = req.Header.Clone()
+		sreq.RemoteAddr = req.RemoteAddr
+		presp := tw.response()
+		sb.inflight.Add(1)
+		go func() {
+			defer sb.inflight.Done()
+			defer cancel()
+			sb.compare(sctx, logger, shadow, sreq, presp)
+		}()
+	})
+}
+
+// compare sends sreq to shadow, and compares its response with
+// primary's response presp.
+func (sb *shadowBackend) compare(ctx context.Context, logger log.Logger, shadow http.Handler, sreq *http.Request, presp *http.Response) {
+	req := &gomapb.ExecReq{}
+	err := httprpc.PeekRequest(ctx, sreq, req)
+	if err != nil {
+		logger.Warnf("shadow %s: failed to parse request: %v", sb.name, err)
+	}
+	id := req.GetRequesterInfo().GetCompilerProxyId()
+
+	rec := &teeResponseWriter{ResponseWriter: &probeRecorder{}}
+	shadow.ServeHTTP(rec, sreq)
+
+	primaryResp := &gomapb.ExecResp{}
+	err = httprpc.ParseResponse(ctx, presp, primaryResp)
+	if err != nil {
+		logger.Warnf("shadow %s %s: failed to parse primary response: %v", sb.name, id, err)
+		sb.record(ctx, logger, "error")
+		return
+	}
+	shadowResp := &gomapb.ExecResp{}
+	err = httprpc.ParseResponse(ctx, rec.response(), shadowResp)
+	sb.compareResp(ctx, logger, id, primaryResp, shadowResp, err)
+}
+
+// mirrorExec mirrors Exec request req to the shadow backend
+// asynchronously if sampled, and compares its response with primary's
+// response presp.
+// It is used for requests served by Service.
+// If sb is nil, it does nothing.
+func (sb *shadowBackend) mirrorExec(ctx context.Context, req *gomapb.ExecReq, presp *gomapb.ExecResp) {
+	if sb == nil || !sb.sample() {
+		return
+	}
+	logger := log.FromContext(ctx)
+	user, ok := enduser.FromContext(ctx)
+	if !ok {
+		logger.Warnf("shadow %s: no enduser info", sb.name)
+		return
+	}
+	sctx, cancel := context.WithTimeout(context.Background(), sb.timeout)
+	sctx = enduser.NewContext(sctx, user)
+	shadow := sb.service()
+	sb.inflight.Add(1)
+	go func() {
+		defer sb.inflight.Done()
+		defer cancel()
+		sresp, err := shadow.Exec(sctx, req)
+		sb.compareResp(sctx, logger, req.GetRequesterInfo().GetCompilerProxyId(), presp, sresp, err)
+	}()
+}
+
+// compareResp compares primary's response with shadow's response of
+// request id, and records the result.
+// err is an error of shadow request.
+func (sb *shadowBackend) compareResp(ctx context.Context, logger log.Logger, id string, primaryResp, shadowResp *gomapb.ExecResp, err error) {
+	if err != nil {
+		logger.Warnf("shadow %s %s: shadow request failed: %v", sb.name, id, err)
+		sb.record(ctx, logger, "error")
+		return
+	}
+	diffs := compareExecResp(primaryResp, shadowResp)
+	if len(diffs) > 0 {
+		logger.Warnf("shadow %s %s: mismatch: %s", sb.name, id, strings.Join(diffs, "; "))
+		sb.record(ctx, logger, "mismatch")
+		return
+	}
+	sb.record(ctx, logger, "match")
+}
+
+func (sb *shadowBackend) record(ctx context.Context, logger log.Logger, result string) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(backendNameKey, sb.name),
+		tag.Upsert(shadowResultKey, result))
+	if err != nil {
+		logger.Errorf("failed to record shadow request: %v", err)
+		return
+	}
+	stats.Record(ctx, shadowRequests.M(1))
+}
+
+// outputHashes returns map of output filename to its hash.
+func outputHashes(resp *gomapb.ExecResp) map[string]string {
+	m := make(map[string]string)
+	for _, out := range resp.GetResult().GetOutput() {
+		h, err := file.Key(out.GetBlob())
+		if err != nil {
+			h = fmt.Sprintf("error:%v", err)
+		}
+		m[out.GetFilename()] = h
+	}
+	return m
+}
+
+// compareExecResp compares exit status and output hashes of primary
+// and shadow responses, and returns discrepancies.
+// cache hit is not compared, since shadow request is sent after
+// primary request, so it may hit cache populated by primary.
+func compareExecResp(primary, shadow *gomapb.ExecResp) []string {
+	var diffs []string
+	if p, s := primary.GetError(), shadow.GetError(); p != s {
+		diffs = append(diffs, fmt.Sprintf("error: %v != %v", p, s))
+	}
+	if p, s := primary.GetResult().GetExitStatus(), shadow.GetResult().GetExitStatus(); p != s {
+		diffs = append(diffs, fmt.Sprintf("exit_status: %d != %d", p, s))
+	}
+	ph := outputHashes(primary)
+	sh := outputHashes(shadow)
+	var outputDiffs []string
+	for fname, p := range ph {
+		s, ok := sh[fname]
+		switch {
+		case !ok:
+			outputDiffs = append(outputDiffs, fmt.Sprintf("output %s: missing in shadow", fname))
+		case p != s:
+			outputDiffs = append(outputDiffs, fmt.Sprintf("output %s: %s != %s", fname, p, s))
+		}
+	}
+	for fname := range sh {
+		if _, ok := ph[fname]; !ok {
+			outputDiffs = append(outputDiffs, fmt.Sprintf("output %s: missing in primary", fname))
+		}
+	}
+	sort.Strings(outputDiffs)
+	return append(diffs, outputDiffs...)
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package backend
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+
+	gomapb "go.chromium.org/goma/server/proto/api"
+)
+
+func execRespHandler(t *testing.T, resp *gomapb.ExecResp, reqBody chan<- []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+		}
+		if reqBody != nil {
+			reqBody <- b
+		}
+		out, err := proto.Marshal(resp)
+		if err != nil {
+			t.Errorf("marshal: %v", err)
+		}
+		w.Header().Set("Content-Type", "binary/x-protocol-buffer")
+		w.Write(out)
+	})
+}
+
+func TestShadowMirror(t *testing.T) {
+	primaryResp := &gomapb.ExecResp{
+		Result: &gomapb.ExecResult{
+			ExitStatus: proto.Int32(0),
+		},
+	}
+	shadowResp := &gomapb.ExecResp{
+		Result: &gomapb.ExecResult{
+			ExitStatus: proto.Int32(1),
+		},
+	}
+	shadowReqBody := make(chan []byte, 1)
+	s := httptest.NewServer(execRespHandler(t, shadowResp, shadowReqBody))
+	defer s.Close()
+	target, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb := &shadowBackend{
+		namedBackend: namedBackend{
+			Backend: NewHTTPRPC(target),
+			name:    "shadow",
+		},
+		timeout: defaultShadowTimeout,
+		sample:  func() bool { return true },
+	}
+	h := sb.mirror(execRespHandler(t, primaryResp, nil))
+
+	body := []byte("exec request")
+	req := httptest.NewRequest(http.MethodPost, "/cxx-compiler-service/e", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	sb.inflight.Wait()
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("code=%d; want=%d", rec.Code, http.StatusOK)
+	}
+	got := &gomapb.ExecResp{}
+	err = proto.Unmarshal(rec.Body.Bytes(), got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, primaryResp) {
+		t.Errorf("resp=%v; want=%v", got, primaryResp)
+	}
+	select {
+	case b := <-shadowReqBody:
+		if !bytes.Equal(b, body) {
+			t.Errorf("shadow request=%q; want=%q", b, body)
+		}
+	default:
+		t.Errorf("shadow didn't receive request")
+	}
+}
+
+func TestShadowMirrorNotSampled(t *testing.T) {
+	shadowReqBody := make(chan []byte, 1)
+	s := httptest.NewServer(execRespHandler(t, &gomapb.ExecResp{}, shadowReqBody))
+	defer s.Close()
+	target, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb := &shadowBackend{
+		namedBackend: namedBackend{
+			Backend: NewHTTPRPC(target),
+			name:    "shadow",
+		},
+		timeout: defaultShadowTimeout,
+		sample:  func() bool { return false },
+	}
+	h := sb.mirror(execRespHandler(t, &gomapb.ExecResp{}, nil))
+	req := httptest.NewRequest(http.MethodPost, "/cxx-compiler-service/e", bytes.NewReader([]byte("exec request")))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	sb.inflight.Wait()
+	select {
+	case b := <-shadowReqBody:
+		t.Errorf("shadow received request %q; want no request", b)
+	default:
+	}
+}
+
+func TestCompareExecResp(t *testing.T) {
+	output := func(fname, content string) *gomapb.ExecResult_Output {
+		return &gomapb.ExecResult_Output{
+			Filename: proto.String(fname),
+			Blob: &gomapb.FileBlob{
+				BlobType: gomapb.FileBlob_FILE.Enum(),
+				Content:  []byte(content),
+				FileSize: proto.Int64(int64(len(content))),
+			},
+		}
+	}
+	resp := func(exitStatus int32, cacheHit gomapb.ExecResp_CacheSource, outputs ...*gomapb.ExecResult_Output) *gomapb.ExecResp {
+		return &gomapb.ExecResp{
+			Result: &gomapb.ExecResult{
+				ExitStatus: proto.Int32(exitStatus),
+				Output:     outputs,
+			},
+			CacheHit: cacheHit.Enum(),
+		}
+	}
+
+	for _, tc := range []struct {
+		desc    string
+		primary *gomapb.ExecResp
+		shadow  *gomapb.ExecResp
+		want    []string
+	}{
+		{
+			desc:    "match",
+			primary: resp(0, gomapb.ExecResp_NO_CACHE, output("a.o", "a")),
+			shadow:  resp(0, gomapb.ExecResp_NO_CACHE, output("a.o", "a")),
+		},
+		{
+			desc:    "exit status",
+			primary: resp(0, gomapb.ExecResp_NO_CACHE),
+			shadow:  resp(1, gomapb.ExecResp_NO_CACHE),
+			want:    []string{"exit_status: 0 != 1"},
+		},
+		{
+			desc:    "cache hit ignored",
+			primary: resp(0, gomapb.ExecResp_NO_CACHE),
+			shadow:  resp(0, gomapb.ExecResp_STORAGE_CACHE),
+		},
+		{
+			desc:    "outputs",
+			primary: resp(0, gomapb.ExecResp_NO_CACHE, output("a.o", "a"), output("b.o", "b")),
+			shadow:  resp(0, gomapb.ExecResp_NO_CACHE, output("b.o", "b"), output("c.o", "c")),
+			want: []string{
+				"output a.o: missing in shadow",
+				"output c.o: missing in primary",
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := compareExecResp(tc.primary, tc.shadow)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("compareExecResp: diff -want +got:\n%s", diff)
+			}
+		})
+	}
+}
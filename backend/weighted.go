@@ -0,0 +1,237 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package backend
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"strings"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/httprpc"
+	"go.chromium.org/goma/server/log"
+	gomapb "go.chromium.org/goma/server/proto/api"
+	pb "go.chromium.org/goma/server/proto/backend"
+)
+
+var (
+	mixerRequests = stats.Int64(
+		"go.chromium.org/goma/server/backend.mixer_requests",
+		"Number of requests dispatched by mixer",
+		stats.UnitDimensionless)
+
+	groupKey       = tag.MustNewKey("group")
+	backendNameKey = tag.MustNewKey("backend")
+
+	// DefaultViews are the default views provided by this package.
+	// You need to register the view for data to actually be collected.
+	DefaultViews = []*view.View{
+		{
+			Description: "Number of requests dispatched by mixer",
+			TagKeys: []tag.Key{
+				groupKey,
+				backendNameKey,
+			},
+			Measure:     mixerRequests,
+			Aggregation: view.Count(),
+		},
+		{
+			Description: "Number of Exec requests mirrored to shadow backend",
+			TagKeys: []tag.Key{
+				backendNameKey,
+				shadowResultKey,
+			},
+			Measure:     shadowRequests,
+			Aggregation: view.Count(),
+		},
+	}
+)
+
+// namedBackend is a backend with name for metrics and logs.
+type namedBackend struct {
+	Backend
+	name   string
+	weight uint32
+
+	// health is health status of the backend.
+	// nil means the backend is not health checked.
+	health *backendHealth
+}
+
+func (nb namedBackend) healthy() bool {
+	return nb.health.Healthy()
+}
+
+func (nb namedBackend) resolve(key func() string, ignoreHealth bool) (namedBackend, bool) {
+	return nb, ignoreHealth || nb.healthy()
+}
+
+// weightedBackend is a mixer backend that dispatches requests to one of
+// backends by weight.
+type weightedBackend struct {
+	backends []namedBackend
+}
+
+func (wb weightedBackend) resolve(key func() string, ignoreHealth bool) (namedBackend, bool) {
+	return wb.pick(key(), ignoreHealth)
+}
+
+// fromBackendOneof creates a backend from either httpRPC or remote.
+func fromBackendOneof(ctx context.Context, name string, httpRPC *pb.HttpRpcBackend, remote *pb.RemoteBackend, opt Option) (Backend, func(), error) {
+	switch {
+	case httpRPC != nil:
+		return FromHTTPRPCBackend(ctx, httpRPC)
+	case remote != nil:
+		return FromRemoteBackend(ctx, remote, opt)
+	}
+	return nil, func() {}, fmt.Errorf("no backend for %s", name)
+}
+
+func fromWeightedBackends(ctx context.Context, name string, cfg []*pb.WeightedBackend, opt Option) (weightedBackend, func(), error) {
+	var wb weightedBackend
+	var cleanups []func()
+	cleanup := func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}
+	var total uint64
+	for i, w := range cfg {
+		bname := w.Name
+		if bname == "" {
+			bname = fmt.Sprintf("%s[%d]", name, i)
+		}
+		be, c, err := fromBackendOneof(ctx, bname, w.GetHttpRpc(), w.GetRemote(), opt)
+		if err != nil {
+			cleanup()
+			return weightedBackend{}, func() {}, err
+		}
+		cleanups = append(cleanups, c)
+		wb.backends = append(wb.backends, namedBackend{
+			Backend: be,
+			name:    bname,
+			weight:  w.Weight,
+		})
+		total += uint64(w.Weight)
+	}
+	if total == 0 {
+		cleanup()
+		return weightedBackend{}, func() {}, fmt.Errorf("no weight in %s", name)
+	}
+	return wb, cleanup, nil
+}
+
+// pick picks a healthy backend for sticky key.
+// If key is empty, it picks randomly by weight.
+// If ignoreHealth is true, it picks from all backends regardless of
+// their health.
+// It returns false if no backend is available.
+func (wb weightedBackend) pick(key string, ignoreHealth bool) (namedBackend, bool) {
+	var total uint64
+	for _, b := range wb.backends {
+		if ignoreHealth || b.healthy() {
+			total += uint64(b.weight)
+		}
+	}
+	if total == 0 {
+		return namedBackend{}, false
+	}
+	var v uint64
+	if key == "" {
+		v = rand.Uint64() % total
+	} else {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		v = h.Sum64() % total
+	}
+	for _, b := range wb.backends {
+		if !ignoreHealth && !b.healthy() {
+			continue
+		}
+		if v < uint64(b.weight) {
+			return b, true
+		}
+		v -= uint64(b.weight)
+	}
+	// should not happen.
+	return namedBackend{}, false
+}
+
+// BuildIDHeader is http request header to specify build_id, which is
+// used to pick weighted backend for httprpc request that has no
+// requester info, e.g. file stream.
+const BuildIDHeader = "X-Goma-Build-Id"
+
+// stickyKey returns key to pick weighted backend for req, and req to
+// serve.
+// If newReq is given, it parses the request once, and uses its
+// requester info.  Returned req carries the parsed message, so
+// httprpc.Handler doesn't parse it again.
+// Otherwise, it uses BuildIDHeader in req if any, or path of req,
+// so resumed upload goes to the same backend.
+func stickyKey(ctx context.Context, req *http.Request, newReq func() proto.Message) (string, *http.Request) {
+	if newReq != nil {
+		msg := newReq()
+		var err error
+		req, err = httprpc.ParseRequest(ctx, req, msg)
+		if err != nil {
+			logger := log.FromContext(ctx)
+			logger.Warnf("failed to parse request %s: %v", req.URL.Path, err)
+		}
+		if m, ok := msg.(interface {
+			GetRequesterInfo() *gomapb.RequesterInfo
+		}); ok {
+			return requesterStickyKey(m.GetRequesterInfo()), req
+		}
+		return "", req
+	}
+	if buildID := req.Header.Get(BuildIDHeader); buildID != "" {
+		return "build:" + buildID, req
+	}
+	return "path:" + req.URL.Path, req
+}
+
+// requesterStickyKey returns key to pick weighted backend for the
+// request with requester info ri.
+// It uses build_id in ri if any, or compiler_proxy_id otherwise,
+// so requests of a build go to the same backend.
+// It returns empty key if ri has neither.
+func requesterStickyKey(ri *gomapb.RequesterInfo) string {
+	if buildID := ri.GetBuildId(); buildID != "" {
+		return "build:" + buildID
+	}
+	if id := ri.GetCompilerProxyId(); id != "" {
+		return "compiler_proxy:" + compilerProxyInstance(id)
+	}
+	return ""
+}
+
+// compilerProxyInstance returns compiler_proxy instance part of
+// compiler_proxy_id, i.e. "<host>:<pid>" of "<host>:<pid>/<seq>".
+func compilerProxyInstance(id string) string {
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		return id[:i]
+	}
+	return id
+}
+
+func recordMixerRequest(ctx context.Context, group, backend string) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(groupKey, group),
+		tag.Upsert(backendNameKey, backend))
+	if err != nil {
+		logger := log.FromContext(ctx)
+		logger.Errorf("failed to record mixer request: %v", err)
+		return
+	}
+	stats.Record(ctx, mixerRequests.M(1))
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	gomapb "go.chromium.org/goma/server/proto/api"
+)
+
+func TestWeightedBackendPick(t *testing.T) {
+	wb := weightedBackend{
+		backends: []namedBackend{
+			{
+				Backend: dummyBackend{id: "prod"},
+				name:    "prod",
+				weight:  95,
+			},
+			{
+				Backend: dummyBackend{id: "canary"},
+				name:    "canary",
+				weight:  5,
+			},
+			{
+				Backend: dummyBackend{id: "disabled"},
+				name:    "disabled",
+				weight:  0,
+			},
+		},
+	}
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("build:%d", i)
+		b, ok := wb.pick(key, false)
+		if !ok {
+			t.Fatalf("pick(%q, false)=_, false; want true", key)
+		}
+		counts[b.name]++
+		if got, _ := wb.pick(key, false); got.name != b.name {
+			t.Errorf("pick(%q)=%q; not sticky, got %q before", key, got.name, b.name)
+		}
+	}
+	if counts["disabled"] != 0 {
+		t.Errorf("disabled picked %d times; want 0", counts["disabled"])
+	}
+	if c := counts["canary"]; c < 300 || c > 700 {
+		t.Errorf("canary picked %d times; want ~500", c)
+	}
+}
+
+func TestStickyKey(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		desc   string
+		ri     *gomapb.RequesterInfo
+		header string
+		newReq func() proto.Message
+		want   string
+	}{
+		{
+			desc: "build_id",
+			ri: &gomapb.RequesterInfo{
+				BuildId:         proto.String("build-1"),
+				CompilerProxyId: proto.String("host:1/2"),
+			},
+			newReq: newExecReq,
+			want:   "build:build-1",
+		},
+		{
+			desc: "compiler_proxy_id",
+			ri: &gomapb.RequesterInfo{
+				CompilerProxyId: proto.String("host:1/2"),
+			},
+			newReq: newExecReq,
+			want:   "compiler_proxy:host:1",
+		},
+		{
+			desc:   "no requester info",
+			header: "build-1",
+			newReq: newExecReq,
+			want:   "",
+		},
+		{
+			desc:   "build_id header",
+			header: "build-1",
+			want:   "build:build-1",
+		},
+		{
+			desc: "path",
+			want: "path:/e",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			body, err := proto.MarshalOptions{AllowPartial: true}.Marshal(&gomapb.ExecReq{
+				RequesterInfo: tc.ri,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("POST", "/e", bytes.NewReader(body))
+			if tc.header != "" {
+				req.Header.Set(BuildIDHeader, tc.header)
+			}
+			got, req := stickyKey(ctx, req, tc.newReq)
+			if got != tc.want {
+				t.Errorf("stickyKey(ctx, req, newReq)=%q; want=%q", got, tc.want)
+			}
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil || !bytes.Equal(b, body) {
+				t.Errorf("req.Body=%q, %v; want=%q, nil", b, err, body)
+			}
+		})
+	}
+}
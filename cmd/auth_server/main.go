@@ -12,8 +12,11 @@ import (
 	"context"
 	"crypto/tls"
 	"flag"
+	"io/ioutil"
 	"net/http"
 	"path/filepath"
+	"strings"
+	"time"
 
 	rpb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
 
@@ -54,6 +57,17 @@ var (
 	aclFile               = flag.String("acl-file", "", "filename of acl proto text message")
 	serviceAccountJSONDir = flag.String("service-account-json-dir", "", "directory for service account jsons")
 
+	authDBGroupsFile = flag.String("auth-db-groups-file", "", "filename of authdb groups; YAML if it has .yaml or .yml extension, proto text message otherwise. use local groups file as authdb instead of --auth-db-addr")
+	authDBCacheTTL   = flag.Duration("auth-db-cache-ttl", authdb.DefaultCacheTTL, "TTL of authdb membership cache. 0 disables cache")
+
+	ldapURL              = flag.String("ldap-url", "", "LDAP server url. e.g. ldaps://ldap.example.com. use LDAP as authdb instead of --auth-db-addr")
+	ldapBindDN           = flag.String("ldap-bind-dn", "", "DN to bind LDAP server")
+	ldapBindPasswordFile = flag.String("ldap-bind-password-file", "", "filename of password to bind LDAP server")
+	ldapBaseDN           = flag.String("ldap-base-dn", "", "base DN to search LDAP user and group entries")
+	ldapUserFilter       = flag.String("ldap-user-filter", authdb.DefaultLDAPUserFilter, "LDAP filter to find user entry by email")
+	ldapGroupFilter      = flag.String("ldap-group-filter", authdb.DefaultLDAPGroupFilter, "LDAP filter to find group entry by group id")
+	ldapMemberAttr       = flag.String("ldap-member-attr", authdb.DefaultLDAPMemberAttr, "LDAP attribute for group members")
+
 	remoteexecAddr     = flag.String("remoteexec-addr", "", "use remoteexec API endpoint")
 	remoteInstanceName = flag.String("remote-instance-name", "", "remote instance name.")
 )
@@ -113,6 +127,106 @@ func (tc *tokenChecker) CheckToken(ctx context.Context, token *oauth2.Token, tok
 	return "", token, nil
 }
 
+// watchGroupsFile reloads groups file of db when it is updated.
+func watchGroupsFile(ctx context.Context, db *authdb.FileDB, cache *authdb.Cache) {
+	defer errorreporter.Do(nil, nil)
+	logger := log.FromContext(ctx)
+	watcher, err := fswatch.New(ctx, filepath.Dir(db.Filename))
+	if err != nil {
+		logger.Fatalf("fswatch failed: %v", err)
+	}
+	defer watcher.Close()
+	for {
+		logger.Infof("waiting for authdb groups update...")
+		ev, err := watcher.Next(ctx)
+		if err != nil {
+			logger.Fatalf("watch failed: %v", err)
+		}
+		logger.Infof("authdb groups update: %v", ev)
+		err = db.Load(ctx)
+		if err != nil {
+			logger.Errorf("authdb groups update failed: %v", err)
+			continue
+		}
+		if cache != nil {
+			cache.Purge()
+		}
+	}
+}
+
+func newAuthDB(ctx context.Context) acl.AuthDB {
+	logger := log.FromContext(ctx)
+	n := 0
+	for _, v := range []string{*authDBAddr, *authDBGroupsFile, *ldapURL} {
+		if v != "" {
+			n++
+		}
+	}
+	if n > 1 {
+		logger.Fatalf("only one of --auth-db-addr, --auth-db-groups-file or --ldap-url can be given")
+	}
+	var authDB authdb.Provider
+	var groupsDB *authdb.FileDB
+	switch {
+	case *authDBAddr != "":
+		authDB = authdb.Client{
+			Client: &httprpc.Client{
+				URL: *authDBAddr,
+			},
+		}
+		logger.Infof("use authdb: %s", *authDBAddr)
+
+	case *authDBGroupsFile != "":
+		groupsDB = &authdb.FileDB{
+			Filename: *authDBGroupsFile,
+		}
+		err := groupsDB.Load(ctx)
+		if err != nil {
+			logger.Fatalf("authdb groups: %v", err)
+		}
+		authDB = groupsDB
+		logger.Infof("use authdb groups file: %s", *authDBGroupsFile)
+
+	case *ldapURL != "":
+		l := authdb.LDAP{
+			URL:         *ldapURL,
+			BindDN:      *ldapBindDN,
+			BaseDN:      *ldapBaseDN,
+			UserFilter:  *ldapUserFilter,
+			GroupFilter: *ldapGroupFilter,
+			MemberAttr:  *ldapMemberAttr,
+			Timeout:     3 * time.Second,
+		}
+		if *ldapBindPasswordFile != "" {
+			b, err := ioutil.ReadFile(*ldapBindPasswordFile)
+			if err != nil {
+				logger.Fatalf("ldap bind password: %v", err)
+			}
+			l.BindPassword = strings.TrimSpace(string(b))
+		}
+		authDB = l
+		logger.Infof("use authdb ldap: %s", *ldapURL)
+
+	default:
+		return nil
+	}
+	var cache *authdb.Cache
+	if *authDBCacheTTL > 0 {
+		cache = &authdb.Cache{
+			Provider: authDB,
+			TTL:      *authDBCacheTTL,
+		}
+		logger.Infof("authdb cache ttl: %s", *authDBCacheTTL)
+	}
+	if groupsDB != nil {
+		go watchGroupsFile(context.Background(), groupsDB, cache)
+	}
+	if cache != nil {
+		return cache
+	}
+	return authDB
+}
+
 func main() {
 	flag.Parse()
 
@@ -132,6 +246,10 @@ func main() {
 	if err != nil {
 		logger.Fatal(err)
 	}
+	err = view.Register(httprpc.DefaultViews...)
+	if err != nil {
+		logger.Fatal(err)
+	}
 	trace.ApplyConfig(trace.Config{
 		DefaultSampler: server.NewLimitedSampler(server.DefaultTraceFraction, server.DefaultTraceQPS),
 	})
@@ -141,6 +259,7 @@ func main() {
 		logger.Fatal(err)
 	}
 	var checkToken func(context.Context, *oauth2.Token, *auth.TokenInfo) (string, *oauth2.Token, error)
+	var serviceAccount func(string) string
 	if *remoteexecAddr != "" {
 		logger.Infof("use remoteexec API: %s", *remoteexecAddr)
 		reConn, err := grpc.DialContext(ctx, *remoteexecAddr,
@@ -163,15 +282,7 @@ func main() {
 	}
 
 	if *aclFile != "" {
-		var authDB acl.AuthDB
-		if *authDBAddr != "" {
-			authDB = authdb.Client{
-				Client: &httprpc.Client{
-					URL: *authDBAddr,
-				},
-			}
-			logger.Infof("use authdb: %s", *authDBAddr)
-		}
+		authDB := newAuthDB(ctx)
 		if *serviceAccountJSONDir == "" {
 			logger.Fatalf("--service-account-json-dir must be given for acl")
 		}
@@ -233,6 +344,7 @@ func main() {
 			}
 			return account, token, nil
 		}
+		serviceAccount = a.ServiceAccount
 		logger.Infof("acl configured")
 	}
 
@@ -246,7 +358,8 @@ func main() {
 	}
 
 	as := &auth.Service{
-		CheckToken: checkToken,
+		CheckToken:     checkToken,
+		ServiceAccount: serviceAccount,
 	}
 	pb.RegisterAuthServiceServer(s.Server, as)
 
@@ -14,11 +14,10 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
-	"path"
 	"strings"
+	"sync"
 	"time"
 
 	rpb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
@@ -39,6 +38,7 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/protobuf/encoding/prototext"
 
+	"go.chromium.org/goma/server/auth"
 	"go.chromium.org/goma/server/cache/redis"
 	"go.chromium.org/goma/server/command"
 	"go.chromium.org/goma/server/exec"
@@ -46,10 +46,13 @@ import (
 	"go.chromium.org/goma/server/log"
 	"go.chromium.org/goma/server/log/errorreporter"
 	"go.chromium.org/goma/server/profiler"
+	authpb "go.chromium.org/goma/server/proto/auth"
 	cmdpb "go.chromium.org/goma/server/proto/command"
 	pb "go.chromium.org/goma/server/proto/exec"
 	filepb "go.chromium.org/goma/server/proto/file"
+	"go.chromium.org/goma/server/quota"
 	"go.chromium.org/goma/server/remoteexec"
+	"go.chromium.org/goma/server/remoteexec/actioncache"
 	"go.chromium.org/goma/server/remoteexec/digest"
 	"go.chromium.org/goma/server/rpc"
 	"go.chromium.org/goma/server/server"
@@ -62,6 +65,7 @@ var (
 	configMapURI          = flag.String("configmap_uri", "", "deprecated: configmap uri. e.g. gs://$project-toolchain-config/$name.config, text proto of command.ConfigMap.")
 	configMap             = flag.String("configmap", "", "configmap text proto")
 	toolchainConfigBucket = flag.String("toolchain-config-bucket", "", "cloud storage bucket for toolchain config")
+	toolchainConfigDir    = flag.String("toolchain-config-dir", "", "local directory for toolchain config. same layout as toolchain-config-bucket. watched for seq updates")
 	configMapFile         = flag.String("configmap_file", "", "filename for configmap text proto")
 
 	traceProjectID     = flag.String("trace-project-id", "", "project id for cloud tracing")
@@ -76,8 +80,18 @@ var (
 	execMaxRetryCount = flag.Int("exec-max-retry-count", 5, "max retry count for exec call. 0 is unlimited count, but bound to ctx timtout. Use small number for powerful clients to run local fallback quickly. Use large number for powerless clients to use remote more than local.")
 
 	cmdFilesBucket      = flag.String("cmd-files-bucket", "", "cloud storage bucket for command binary files")
+	cmdFilesDir         = flag.String("cmd-files-dir", "", "local directory for command binary files. same layout as cmd-files-bucket")
 	fetchConfigParallel = flag.Bool("fetch-config-parallel", true, "fetch toolchain configs in parallel")
 
+	toolchainAdminGroups = flag.String("toolchain-admin-groups", "", "comma separated groups allowed to register toolchain via ToolchainService. empty disables ToolchainService")
+	authAddr             = flag.String("auth-addr", "passthrough:///auth-server:5050", "auth server address. used for ToolchainService")
+
+	coalesceExec = flag.Bool("coalesce-exec", false, "coalesce concurrent exec requests of the same action, and share the result of in-flight execution.")
+
+	linkPlatformProperties = flag.String("link-platform-properties", "", "comma separated name=value of platform properties for link requests. e.g. dockerMemory=32GB")
+
+	normalizePaths = flag.Bool("normalize-paths", false, "rewrite absolute paths under input root in args into cwd relative paths to make non relocatable requests relocatable.")
+
 	// Needed for b/120582303, but will be deprecated by b/80508682.
 	fileLookupConcurrency = flag.Int("file-lookup-concurrency", 20, "concurrency to look up files from file-server")
 
@@ -87,6 +101,11 @@ var (
 	// rbe-staging1 uses 2.2M keys (< 512MB memory usage in redis).
 	maxDigestCacheEntries = flag.Int("max-digest-cache-entries", 2e6, "maximum entries in in-memory digest cache. 0 means unimited")
 
+	actionCache            = flag.Bool("action-cache", false, "cache ActionResult in memory (and redis if available) to reduce GetActionResult calls")
+	maxActionCacheEntries  = flag.Int("max-action-cache-entries", 1e5, "maximum entries in in-memory action cache. 0 means unlimited")
+	actionCacheTTL         = flag.Duration("action-cache-ttl", actioncache.DefaultTTL, "how long ActionResult is cached")
+	actionCacheNegativeTTL = flag.Duration("action-cache-negative-ttl", actioncache.DefaultNegativeTTL, "how long missing ActionResult is cached in memory. negative disables negative caching")
+
 	// nsjail is applied in hardened request.
 	// note windows and chroot reqs are out of scope for the ratio.
 	// e.g.
@@ -114,6 +133,8 @@ var (
 
 	redisMaxIdleConns   = flag.Int("redis-max-idle-conns", redis.DefaultMaxIdleConns, "maximum number of idle connections to redis.")
 	redisMaxActiveConns = flag.Int("redis-max-active-conns", redis.DefaultMaxActiveConns, "maximum number of active connections to redis.")
+
+	quotaConfig = flag.String("quota-config", "", "quota config. text proto of quota.QuotaConfig")
 )
 
 var (
@@ -190,14 +211,6 @@ func configureByLoader(ctx context.Context, loader *command.ConfigMapLoader, inv
 	return resp.VersionId, nil
 }
 
-type cmdStorageBucket struct {
-	Bucket *storage.BucketHandle
-}
-
-func (b cmdStorageBucket) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
-	return b.Bucket.Object(path.Join("sha256", hash)).NewReader(ctx)
-}
-
 type nullServer struct {
 	ch chan error
 }
@@ -213,8 +226,12 @@ type configServer struct {
 	configmap command.ConfigMap
 	psclient  *pubsub.Client
 	w         command.ConfigMapWatcher
-	loader    *command.ConfigMapLoader
 	cancel    func()
+
+	// mu protects loader, since configure is called by watcher loop
+	// and toolchain registry.
+	mu     sync.Mutex
+	loader *command.ConfigMapLoader
 }
 
 func newConfigServer(ctx context.Context, inventory *exec.Inventory, bucket, configMapFile string, cm *cmdpb.ConfigMap, gsclient *storage.Client, opts ...option.ClientOption) (*configServer, error) {
@@ -252,9 +269,33 @@ func newConfigServer(ctx context.Context, inventory *exec.Inventory, bucket, con
 	return cs, nil
 }
 
+func newDirConfigServer(ctx context.Context, inventory *exec.Inventory, dir, configMapFile string, cm *cmdpb.ConfigMap) *configServer {
+	configmap := command.ConfigMapDir{
+		Dir:            dir,
+		ConfigMap:      cm,
+		ConfigMapFile:  configMapFile,
+		RemoteexecAddr: *remoteexecAddr,
+	}
+	cs := &configServer{
+		inventory: inventory,
+		configmap: configmap,
+	}
+	cs.w = cs.configmap.Watcher(ctx)
+	cs.loader = &command.ConfigMapLoader{
+		ConfigMap: cs.configmap,
+		ConfigLoader: command.ConfigLoader{
+			StorageClient:  configmap.StorageClient(),
+			EnableParallel: *fetchConfigParallel,
+		},
+	}
+	return cs
+}
+
 func (cs *configServer) configure(ctx context.Context, force bool) error {
 	logger := log.FromContext(ctx)
+	cs.mu.Lock()
 	id, err := configureByLoader(ctx, cs.loader, cs.inventory, force)
+	cs.mu.Unlock()
 	if errors.Is(err, context.Canceled) {
 		logger.Errorf("canceled to configure: %v", err)
 		return err
@@ -271,6 +312,21 @@ func (cs *configServer) configure(ctx context.Context, force bool) error {
 	return nil
 }
 
+// newRegistry returns toolchain registry that writes descriptors in
+// bucket of client, and reconfigures cs.
+// Auth and AllowedGroups should be set by caller.
+func newRegistry(cs *configServer, client stiface.Client, bucket string, cmdFiles command.CmdFileStore) *command.Registry {
+	return &command.Registry{
+		StorageClient: client,
+		Bucket:        bucket,
+		ConfigMap:     cs.configmap,
+		CmdFiles:      cmdFiles,
+		Configure: func(ctx context.Context) error {
+			return cs.configure(ctx, false)
+		},
+	}
+}
+
 func (cs *configServer) ListenAndServe() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	cs.cancel = cancel
@@ -352,6 +408,46 @@ func newDigestCache(ctx context.Context) remoteexec.DigestCache {
 	}), *maxDigestCacheEntries)
 }
 
+func newActionCache(ctx context.Context) *actioncache.Cache {
+	logger := log.FromContext(ctx)
+	opts := actioncache.Opts{
+		MaxEntries:  *maxActionCacheEntries,
+		TTL:         *actionCacheTTL,
+		NegativeTTL: *actionCacheNegativeTTL,
+	}
+	addr, err := redis.AddrFromEnv()
+	if err != nil {
+		logger.Warnf("redis disabled for action-result: %v", err)
+		return actioncache.New(nil, opts)
+	}
+	logger.Infof("redis enabled for action-result: %v idle=%d active=%d", addr, *redisMaxIdleConns, *redisMaxActiveConns)
+	return actioncache.New(redis.NewClient(ctx, addr, redis.Opts{
+		Prefix:         "action-result:",
+		MaxIdleConns:   *redisMaxIdleConns,
+		MaxActiveConns: *redisMaxActiveConns,
+	}), opts)
+}
+
+// parsePlatformProperties parses comma separated name=value into
+// platform properties.
+func parsePlatformProperties(s string) ([]*rpb.Platform_Property, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var props []*rpb.Platform_Property
+	for _, kv := range strings.Split(s, ",") {
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("bad platform property %q: want name=value", kv)
+		}
+		props = append(props, &rpb.Platform_Property{
+			Name:  kv[:i],
+			Value: kv[i+1:],
+		})
+	}
+	return props, nil
+}
+
 func main() {
 	spanTimeout := remoteexec.DefaultSpanTimeout
 	flag.DurationVar(&spanTimeout.Inventory, "exec-inventory-timeout", spanTimeout.Inventory, "timeout of exec-inventory")
@@ -362,6 +458,7 @@ func main() {
 	flag.DurationVar(&spanTimeout.UploadBlobs, "exec-upload-blobs-timeout", spanTimeout.UploadBlobs, "timeout of exec-upload-blobs")
 	flag.DurationVar(&spanTimeout.Execute, "exec-execute-timeout", spanTimeout.Execute, "timeout of exec-execute")
 	flag.DurationVar(&spanTimeout.Response, "exec-response-timeout", spanTimeout.Response, "timeout of exec-response")
+	flag.DurationVar(&spanTimeout.Coalesce, "exec-coalesce-timeout", spanTimeout.Coalesce, "timeout to wait for in-flight execution of the same action")
 	flag.Parse()
 	rand.Seed(time.Now().UnixNano())
 
@@ -372,8 +469,14 @@ func main() {
 	logger := log.FromContext(ctx)
 	defer logger.Sync()
 
-	if (*toolchainConfigBucket == "" || *configMapFile == "") && *configMap == "" {
-		logger.Fatalf("--toolchain-config-bucket,--configmap_file or --configmap must be given")
+	if ((*toolchainConfigBucket == "" && *toolchainConfigDir == "") || *configMapFile == "") && *configMap == "" {
+		logger.Fatalf("--toolchain-config-bucket or --toolchain-config-dir with --configmap_file, or --configmap must be given")
+	}
+	if *toolchainConfigBucket != "" && *toolchainConfigDir != "" {
+		logger.Fatalf("--toolchain-config-bucket and --toolchain-config-dir are exclusive")
+	}
+	if *cmdFilesBucket != "" && *cmdFilesDir != "" {
+		logger.Fatalf("--cmd-files-bucket and --cmd-files-dir are exclusive")
 	}
 	if *remoteexecAddr == "" {
 		logger.Fatalf("--remoteexec-addr must be given")
@@ -404,6 +507,14 @@ func main() {
 	if err != nil {
 		logger.Fatal(err)
 	}
+	err = view.Register(actioncache.DefaultViews...)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	err = view.Register(quota.DefaultViews...)
+	if err != nil {
+		logger.Fatal(err)
+	}
 	trace.ApplyConfig(trace.Config{
 		DefaultSampler: server.NewLimitedSampler(server.DefaultTraceFraction, server.DefaultTraceQPS),
 	})
@@ -457,6 +568,11 @@ func main() {
 	casBlobLookupConcurrency := 20
 	outputFileConcurrency := 20
 	logger.Infof("span timeout = %#v", spanTimeout)
+	quotaManager, quotaDone, err := quota.NewManagerFromConfig(ctx, *quotaConfig)
+	if err != nil {
+		logger.Fatalf("quota config: %v", err)
+	}
+	defer quotaDone()
 	re := &remoteexec.Adapter{
 		InstancePrefix:   *remoteInstancePrefix,
 		InstanceBaseName: *remoteInstanceBaseName,
@@ -480,16 +596,36 @@ func main() {
 		HardeningRatio:    *experimentHardeningRatio,
 		NsjailRatio:       *experimentNsjailRatio,
 		DisableHardenings: strings.Split(*disableHardenings, ","),
+		Quota:             quotaManager,
+		CoalesceExec:      *coalesceExec,
+		NormalizePaths:    *normalizePaths,
+	}
+	re.LinkPlatformProperties, err = parsePlatformProperties(*linkPlatformProperties)
+	if err != nil {
+		logger.Fatalf("bad --link-platform-properties: %v", err)
+	}
+	if *actionCache {
+		logger.Infof("action cache enabled: ttl=%s negative-ttl=%s", *actionCacheTTL, *actionCacheNegativeTTL)
+		re.ActionCache = newActionCache(ctx)
 	}
 	logger.Infof("hardeniong=%f nsjail=%f", re.HardeningRatio, re.NsjailRatio)
 
-	if *cmdFilesBucket == "" {
-		logger.Warnf("--cmd-files-bucket is not given. support only ARBITRARY_TOOLCHAIN_SUPPORT enabled client")
-	} else {
+	var cmdFiles command.CmdFileStore
+	switch {
+	case *cmdFilesBucket != "":
 		logger.Infof("use gs://%s for cmd files", *cmdFilesBucket)
-		re.CmdStorage = cmdStorageBucket{
+		cmdFiles = command.CmdStorageBucket{
 			Bucket: gsclient.Bucket(*cmdFilesBucket),
 		}
+		re.CmdStorage = cmdFiles
+	case *cmdFilesDir != "":
+		logger.Infof("use %s for cmd files", *cmdFilesDir)
+		cmdFiles = command.CmdStorageDir{
+			Dir: *cmdFilesDir,
+		}
+		re.CmdStorage = cmdFiles
+	default:
+		logger.Warnf("neither --cmd-files-bucket nor --cmd-files-dir is given. support only ARBITRARY_TOOLCHAIN_SUPPORT enabled client")
 	}
 
 	inventory := &re.Inventory
@@ -503,6 +639,7 @@ func main() {
 	bspb.RegisterByteStreamServer(s.Server, bs)
 
 	var confServer server.Server
+	var registry *command.Registry
 	ready := make(chan error)
 	switch {
 	case *configMap != "":
@@ -541,6 +678,38 @@ func main() {
 			ready <- cs.configure(ctx, true)
 		}()
 		confServer = cs
+		registry = newRegistry(cs, stiface.AdaptClient(gsclient), *toolchainConfigBucket, cmdFiles)
+
+	case *toolchainConfigDir != "":
+		cm := &cmdpb.ConfigMap{}
+		cs := newDirConfigServer(ctx, inventory, *toolchainConfigDir, *configMapFile, cm)
+		go func() {
+			ready <- cs.configure(ctx, true)
+		}()
+		confServer = cs
+		configmap := cs.configmap.(command.ConfigMapDir)
+		bucket, _ := configmap.Bucket(ctx)
+		registry = newRegistry(cs, configmap.StorageClient(), bucket, cmdFiles)
+	}
+	if *toolchainAdminGroups != "" {
+		switch {
+		case registry == nil:
+			logger.Fatalf("--toolchain-admin-groups requires --toolchain-config-bucket or --toolchain-config-dir")
+		case cmdFiles == nil:
+			logger.Fatalf("--toolchain-admin-groups requires --cmd-files-bucket or --cmd-files-dir")
+		}
+		authConn, err := server.DialContext(ctx, *authAddr)
+		if err != nil {
+			logger.Fatalf("dial %s: %v", *authAddr, err)
+		}
+		defer authConn.Close()
+		a := &auth.Auth{
+			Client: authpb.NewAuthServiceClient(authConn),
+		}
+		registry.Auth = a.CheckGRPC
+		registry.AllowedGroups = strings.Split(*toolchainAdminGroups, ",")
+		logger.Infof("toolchain service enabled for %q", registry.AllowedGroups)
+		cmdpb.RegisterToolchainServiceServer(s.Server, registry)
 	}
 	http.Handle("/configz", inventory)
 	pb.RegisterExecServiceServer(s.Server, re)
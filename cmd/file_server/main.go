@@ -14,6 +14,7 @@ import (
 	"fmt"
 
 	"cloud.google.com/go/storage"
+	"go.opencensus.io/stats/view"
 	"go.opencensus.io/trace"
 	k8sapi "golang.org/x/build/kubernetes/api"
 	"google.golang.org/api/option"
@@ -27,6 +28,7 @@ import (
 	"go.chromium.org/goma/server/file"
 	"go.chromium.org/goma/server/log"
 	"go.chromium.org/goma/server/profiler"
+	"go.chromium.org/goma/server/quota"
 	"go.chromium.org/goma/server/server"
 	"go.chromium.org/goma/server/server/healthz"
 
@@ -46,6 +48,8 @@ var (
 
 	redisMaxIdleConns   = flag.Int("redis-max-idle-conns", redis.DefaultMaxIdleConns, "maximum number of idle connections to redis.")
 	redisMaxActiveConns = flag.Int("redis-max-active-conns", redis.DefaultMaxActiveConns, "maximum number of active connections to redis.")
+
+	quotaConfig = flag.String("quota-config", "", "quota config. text proto of quota.QuotaConfig")
 )
 
 type admissionController struct {
@@ -87,6 +91,10 @@ func main() {
 	if err != nil {
 		logger.Fatal(err)
 	}
+	err = view.Register(quota.DefaultViews...)
+	if err != nil {
+		logger.Fatal(err)
+	}
 	trace.ApplyConfig(trace.Config{
 		DefaultSampler: server.NewLimitedSampler(server.DefaultTraceFraction, server.DefaultTraceQPS),
 	})
@@ -150,8 +158,14 @@ func main() {
 	default:
 		logger.Fatal("no cache server")
 	}
+	quotaManager, quotaDone, err := quota.NewManagerFromConfig(ctx, *quotaConfig)
+	if err != nil {
+		logger.Fatalf("quota config: %v", err)
+	}
+	defer quotaDone()
 	fs := &file.Service{
 		Cache: cclient,
+		Quota: quotaManager,
 	}
 	pb.RegisterFileServiceServer(s.Server, fs)
 	hs := server.NewHTTP(*mport, nil)
@@ -14,31 +14,42 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"path/filepath"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/trace"
 	"go.opencensus.io/zpages"
 	k8sapi "golang.org/x/build/kubernetes/api"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/prototext"
 
 	"go.chromium.org/goma/server/auth"
 	"go.chromium.org/goma/server/backend"
 	"go.chromium.org/goma/server/frontend"
+	"go.chromium.org/goma/server/fswatch"
+	"go.chromium.org/goma/server/httprpc"
+	settingsrpc "go.chromium.org/goma/server/httprpc/settings"
 	"go.chromium.org/goma/server/log"
+	"go.chromium.org/goma/server/log/errorreporter"
 	"go.chromium.org/goma/server/profiler"
 	"go.chromium.org/goma/server/server"
 	"go.chromium.org/goma/server/server/healthz"
+	"go.chromium.org/goma/server/settings"
 
 	authpb "go.chromium.org/goma/server/proto/auth"
 	bepb "go.chromium.org/goma/server/proto/backend"
 	execpb "go.chromium.org/goma/server/proto/exec"
 	execlogpb "go.chromium.org/goma/server/proto/execlog"
 	filepb "go.chromium.org/goma/server/proto/file"
+	fepb "go.chromium.org/goma/server/proto/frontend"
+	settingspb "go.chromium.org/goma/server/proto/settings"
 )
 
 var (
@@ -46,10 +57,25 @@ var (
 	gport = flag.Int("gport", 5050, "grpc port")
 	mport = flag.Int("mport", 8081, "monitor port")
 
+	apiGport   = flag.Int("api-gport", 0, "grpc port for goma api endpoints, for gRPC-native clients. if 0, disabled.")
+	apiGRPCTLS = flag.Bool("api-grpc-tls", false, "serve goma api grpc with TLS, using cert/cert.pem and cert/key.pem in -config-dir.")
+
 	authAddr = flag.String("auth-addr", "passthrough:///auth-server:5050",
 		"auth server address")
 
-	backendConfig = flag.String("backend-config", "", "backend config. text proto of backend.BackendConfig")
+	backendConfig     = flag.String("backend-config", "", "backend config. text proto of backend.BackendConfig")
+	backendConfigFile = flag.String("backend-config-file", "", "backend config filename relative to -config-dir. text proto of backend.BackendConfig. if set, -backend-config is ignored and the file is reloaded when updated.")
+
+	settingsConfigFile = flag.String("settings-config-file", "", "settings config filename relative to -config-dir. text proto of settings.SettingsConfig. if set, settings service is served and the file is reloaded when updated.")
+
+	clientVersionPolicyFile = flag.String("client-version-policy-file", "", "client version policy filename relative to -config-dir. text proto of frontend.ClientVersionPolicy. if set, the file is reloaded when updated.")
+
+	rateLimitConfig     = flag.String("rate-limit-config", "", "rate limit config. text proto of frontend.RateLimitConfig")
+	rateLimitConfigFile = flag.String("rate-limit-config-file", "", "rate limit config filename relative to -config-dir. text proto of frontend.RateLimitConfig. if set, -rate-limit-config is ignored and the file is reloaded when updated.")
+
+	auditLogFile       = flag.String("audit-log-file", "", "audit log filename. audit log contains end user's email, so it should be in access-controlled storage")
+	auditLogMaxSize    = flag.Int64("audit-log-max-size", frontend.DefaultAuditFileMaxSize, "max size of audit log file to rotate")
+	auditLogMaxBackups = flag.Int("audit-log-max-backups", frontend.DefaultAuditFileMaxBackups, "max number of rotated audit log files to keep")
 
 	configDir = flag.String("config-dir", "/etc/goma", "config directory")
 
@@ -113,6 +139,127 @@ func newMainServer(mux *http.ServeMux) server.Server {
 	return server.NewHTTPS(hsMain, certpem, keypem)
 }
 
+// newAPIGRPCServer creates grpc server to serve goma api by g.
+func newAPIGRPCServer(g *frontend.GRPC) (server.GRPC, error) {
+	opts := []grpc.ServerOption{
+		grpc.MaxSendMsgSize(maxMsgSize),
+		grpc.MaxRecvMsgSize(maxMsgSize),
+		grpc.ChainUnaryInterceptor(g.UnaryInterceptor),
+	}
+	if *apiGRPCTLS {
+		certpem := filepath.Join(*configDir, "cert/cert.pem")
+		keypem := filepath.Join(*configDir, "cert/key.pem")
+		creds, err := credentials.NewServerTLSFromFile(certpem, keypem)
+		if err != nil {
+			return server.GRPC{}, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	s, err := server.NewGRPC(*apiGport, opts...)
+	if err != nil {
+		return server.GRPC{}, err
+	}
+	g.Register(s.Server)
+	return s, nil
+}
+
+func loadBackendConfig(fname string) (*bepb.BackendConfig, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &bepb.BackendConfig{}
+	err = prototext.Unmarshal(b, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	return cfg, nil
+}
+
+func loadSettingsConfig(fname string) (*settingspb.SettingsConfig, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &settingspb.SettingsConfig{}
+	err = prototext.Unmarshal(b, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	return cfg, nil
+}
+
+func loadClientVersionPolicy(fname string) (*fepb.ClientVersionPolicy, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	policy := &fepb.ClientVersionPolicy{}
+	err = prototext.Unmarshal(b, policy)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	return policy, nil
+}
+
+func loadRateLimitConfig(fname string) (*fepb.RateLimitConfig, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	config := &fepb.RateLimitConfig{}
+	err = prototext.Unmarshal(b, config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	return config, nil
+}
+
+// isConfigUpdate reports whether ev is an update of config file fname.
+// fname may be in configmap volume, where files are symlinks via
+// "..data", and update is done by atomic swap of "..data" symlink.
+func isConfigUpdate(ev fsnotify.Event, fname string) bool {
+	if ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) == 0 {
+		return false
+	}
+	name := filepath.Clean(ev.Name)
+	fname = filepath.Clean(fname)
+	if name == fname {
+		return true
+	}
+	// "..data" is renamed from "..data_tmp", which is notified as Create.
+	return ev.Op&fsnotify.Create != 0 && name == filepath.Join(filepath.Dir(fname), "..data")
+}
+
+// watchConfig calls update when config file fname is updated.
+func watchConfig(ctx context.Context, name, fname string, update func(context.Context) error) {
+	defer errorreporter.Do(nil, nil)
+	logger := log.FromContext(ctx)
+	watcher, err := fswatch.New(ctx, filepath.Dir(fname))
+	if err != nil {
+		logger.Fatalf("fswatch failed: %v", err)
+	}
+	defer watcher.Close()
+	for {
+		logger.Infof("waiting for %s config update...", name)
+		ev, err := watcher.Next(ctx)
+		if err != nil {
+			logger.Fatalf("watch failed: %v", err)
+		}
+		if !isConfigUpdate(ev, fname) {
+			logger.Debugf("ignore %s config event: %v", name, ev)
+			continue
+		}
+		logger.Infof("%s config update: %v", name, ev)
+		err = update(ctx)
+		if err != nil {
+			logger.Errorf("%s config update failed: %v", name, err)
+			continue
+		}
+		logger.Infof("%s config updated", name)
+	}
+}
+
 func main() {
 	flag.Parse()
 
@@ -131,6 +278,14 @@ func main() {
 	if err != nil {
 		logger.Fatal(err)
 	}
+	err = view.Register(backend.DefaultViews...)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	err = view.Register(httprpc.DefaultViews...)
+	if err != nil {
+		logger.Fatal(err)
+	}
 	trace.ApplyConfig(trace.Config{
 		DefaultSampler: server.NewLimitedSampler(server.DefaultTraceFraction, server.DefaultTraceQPS),
 	})
@@ -148,21 +303,110 @@ func main() {
 	}
 	defer authConn.Close()
 
-	beCfg := &bepb.BackendConfig{}
-	err = prototext.Unmarshal([]byte(*backendConfig), beCfg)
-	if err != nil {
-		logger.Fatal(err)
+	authClient := &auth.Auth{
+		Client: authpb.NewAuthServiceClient(authConn),
 	}
-	be, done, err := backend.FromProto(ctx, beCfg, backend.Option{
-		Auth: &auth.Auth{
-			Client: authpb.NewAuthServiceClient(authConn),
-		},
+	beOpt := backend.Option{
+		Auth:      authClient,
 		APIKeyDir: filepath.Join(*configDir, "api-keys"),
-	})
-	if err != nil {
-		logger.Fatal(err)
 	}
-	defer done()
+	var acs httprpc.AdmissionControllers
+	switch {
+	case *rateLimitConfigFile != "":
+		fname := filepath.Join(*configDir, *rateLimitConfigFile)
+		rateLimiter := &frontend.RateLimiter{}
+		update := func(ctx context.Context) error {
+			rlCfg, err := loadRateLimitConfig(fname)
+			if err != nil {
+				return err
+			}
+			return rateLimiter.Set(ctx, rlCfg)
+		}
+		err = update(ctx)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		go watchConfig(ctx, "rate limit", fname, update)
+		acs = append(acs, rateLimiter)
+	case *rateLimitConfig != "":
+		rlCfg := &fepb.RateLimitConfig{}
+		err = prototext.Unmarshal([]byte(*rateLimitConfig), rlCfg)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		rateLimiter := &frontend.RateLimiter{}
+		err = rateLimiter.Set(ctx, rlCfg)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		acs = append(acs, rateLimiter)
+	}
+	if *clientVersionPolicyFile != "" {
+		fname := filepath.Join(*configDir, *clientVersionPolicyFile)
+		checker := &frontend.ClientVersionChecker{}
+		update := func(ctx context.Context) error {
+			policy, err := loadClientVersionPolicy(fname)
+			if err != nil {
+				return err
+			}
+			return checker.Set(ctx, policy)
+		}
+		err = update(ctx)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		go watchConfig(ctx, "client version policy", fname, update)
+		acs = append(acs, checker)
+	}
+	if len(acs) > 0 {
+		beOpt.AC = acs
+	}
+	if *auditLogFile != "" {
+		logger.Infof("audit log: %s", *auditLogFile)
+		af := &frontend.AuditFile{
+			Filename:   *auditLogFile,
+			MaxSize:    *auditLogMaxSize,
+			MaxBackups: *auditLogMaxBackups,
+		}
+		defer af.Close()
+		beOpt.Auditor = frontend.AuditLogger{
+			Exporter: af,
+		}
+	}
+	var be backend.Backend
+	var reloadable *backend.Reloadable
+	if *backendConfigFile != "" {
+		fname := filepath.Join(*configDir, *backendConfigFile)
+		reloadable = &backend.Reloadable{
+			Option: beOpt,
+		}
+		update := func(ctx context.Context) error {
+			beCfg, err := loadBackendConfig(fname)
+			if err != nil {
+				return err
+			}
+			return reloadable.Set(ctx, beCfg)
+		}
+		err = update(ctx)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer reloadable.Close()
+		go watchConfig(ctx, "backend", fname, update)
+		be = reloadable
+	} else {
+		beCfg := &bepb.BackendConfig{}
+		err = prototext.Unmarshal([]byte(*backendConfig), beCfg)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		var done func()
+		be, done, err = backend.FromProto(ctx, beCfg, beOpt)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer done()
+	}
 
 	mux := http.NewServeMux()
 	var memoryChecker memoryCheck
@@ -190,8 +434,29 @@ func main() {
 			// but not availble yet. http://b/77931512
 		},
 	}
+	if *settingsConfigFile != "" {
+		fname := filepath.Join(*configDir, *settingsConfigFile)
+		ss := &settings.Service{}
+		update := func(ctx context.Context) error {
+			cfg, err := loadSettingsConfig(fname)
+			if err != nil {
+				return err
+			}
+			return ss.SetConfig(ctx, cfg)
+		}
+		err = update(ctx)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		go watchConfig(ctx, "settings", fname, update)
+		fe.Settings = settingsrpc.Handler(ss,
+			httprpc.Timeout(10*time.Second),
+			httprpc.WithAuth(beOpt.Auth))
+	}
 	frontend.Register(mux, fe)
 
+	// grpc server is registered only for static backend config,
+	// since registered services can't be replaced later.
 	if be, ok := be.(backend.GRPC); ok {
 		logger.Infof("register grpc server")
 		execpb.RegisterExecServiceServer(s.Server, be.ExecServer)
@@ -206,8 +471,28 @@ func main() {
 		w.Write([]byte("ok"))
 	})
 
+	// backend status page on monitoring port.
+	if reloadable != nil {
+		http.Handle("/backendz", reloadable.Status())
+	} else if mixer, ok := be.(backend.Mixer); ok {
+		http.Handle("/backendz", mixer.Status())
+	}
+
 	hsMain := newMainServer(mux)
 	hsMonitoring := server.NewHTTP(*mport, nil)
 	zpages.Handle(http.DefaultServeMux, "/debug")
-	server.Run(ctx, s, hsMain, hsMonitoring)
+	servers := []server.Server{s, hsMain, hsMonitoring}
+	if *apiGport != 0 {
+		logger.Infof("goma api grpc server on port %d", *apiGport)
+		g := frontend.NewGRPC(fe)
+		g.Auth = authClient.CheckGRPC
+		g.UserAC = beOpt.AC
+		g.Auditor = beOpt.Auditor
+		as, err := newAPIGRPCServer(g)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		servers = append(servers, as)
+	}
+	server.Run(ctx, servers...)
 }
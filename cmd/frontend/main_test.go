@@ -7,6 +7,8 @@ package main
 import (
 	"testing"
 
+	"github.com/fsnotify/fsnotify"
+
 	"go.chromium.org/goma/server/exec"
 	"go.chromium.org/goma/server/execlog"
 	"go.chromium.org/goma/server/file"
@@ -23,3 +25,44 @@ func TestMaxMsgSize(t *testing.T) {
 		t.Errorf("%d < %d (execlog)", maxMsgSize, execlog.DefaultMaxReqMsgSize)
 	}
 }
+
+func TestIsConfigUpdate(t *testing.T) {
+	const fname = "/etc/config/backend.config"
+	for _, tc := range []struct {
+		ev   fsnotify.Event
+		want bool
+	}{
+		{
+			ev:   fsnotify.Event{Name: "/etc/config/backend.config", Op: fsnotify.Write},
+			want: true,
+		},
+		{
+			ev:   fsnotify.Event{Name: "/etc/config//backend.config", Op: fsnotify.Create},
+			want: true,
+		},
+		{
+			ev: fsnotify.Event{Name: "/etc/config/backend.config", Op: fsnotify.Chmod},
+		},
+		{
+			ev: fsnotify.Event{Name: "/etc/config/backend.config", Op: fsnotify.Remove},
+		},
+		{
+			ev: fsnotify.Event{Name: "/etc/config/settings.config", Op: fsnotify.Write},
+		},
+		{
+			ev: fsnotify.Event{Name: "/etc/config/..2021_01_01_00_00_00.000000000", Op: fsnotify.Create},
+		},
+		{
+			ev: fsnotify.Event{Name: "/etc/config/..data_tmp", Op: fsnotify.Create},
+		},
+		{
+			// configmap update.
+			ev:   fsnotify.Event{Name: "/etc/config/..data", Op: fsnotify.Create},
+			want: true,
+		},
+	} {
+		if got := isConfigUpdate(tc.ev, fname); got != tc.want {
+			t.Errorf("isConfigUpdate(%v, %q)=%t; want %t", tc.ev, fname, got, tc.want)
+		}
+	}
+}
@@ -242,6 +242,10 @@ func (b localBackend) Execlog() http.Handler {
 	return execlogrpc.Handler(execlogService{}, httprpc.Timeout(1*time.Minute), httprpc.WithAuth(b.Auth))
 }
 
+func (b localBackend) FileStream() http.Handler {
+	return filerpc.StreamHandler(b.FileService, httprpc.Timeout(10*time.Minute), httprpc.WithAuth(b.Auth))
+}
+
 func readConfigResp(fname string) (*cmdpb.ConfigResp, error) {
 	b, err := ioutil.ReadFile(fname)
 	if err != nil {
@@ -0,0 +1,136 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+/*
+Binary toolchain_config_check validates toolchain config map and
+descriptors, with the same checks as exec_server does, for CI gating.
+
+ $ toolchain_config_check -configmap_file configmap.textproto \
+     -toolchain-config-dir ./toolchain-config \
+     -cmd-files-dir ./cmd-files
+
+It exits with non-zero status if some problem is found.
+
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"cloud.google.com/go/storage"
+	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+	"google.golang.org/api/option"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	"go.chromium.org/goma/server/command"
+	"go.chromium.org/goma/server/log"
+	cmdpb "go.chromium.org/goma/server/proto/command"
+)
+
+var (
+	configMap             = flag.String("configmap", "", "configmap text proto")
+	configMapFile         = flag.String("configmap_file", "", "filename for configmap text proto")
+	toolchainConfigBucket = flag.String("toolchain-config-bucket", "", "cloud storage bucket for toolchain config")
+	toolchainConfigDir    = flag.String("toolchain-config-dir", "", "local directory for toolchain config. same layout as toolchain-config-bucket")
+	cmdFilesBucket        = flag.String("cmd-files-bucket", "", "cloud storage bucket for command binary files")
+	cmdFilesDir           = flag.String("cmd-files-dir", "", "local directory for command binary files. same layout as cmd-files-bucket")
+	remoteexecAddr        = flag.String("remoteexec-addr", "remotebuildexecution.googleapis.com:443", "remoteexec API endpoint for runtime without service_addr")
+	serviceAccountFile    = flag.String("service-account-file", "", "service account json file")
+)
+
+func loadConfigMap() (*cmdpb.ConfigMap, error) {
+	buf := []byte(*configMap)
+	if *configMapFile != "" {
+		var err error
+		buf, err = ioutil.ReadFile(*configMapFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	cm := &cmdpb.ConfigMap{}
+	err := prototext.Unmarshal(buf, cm)
+	if err != nil {
+		return nil, fmt.Errorf("parse configmap: %v", err)
+	}
+	return cm, nil
+}
+
+func main() {
+	flag.Parse()
+	ctx := context.Background()
+	logger := log.FromContext(ctx)
+	defer logger.Sync()
+
+	if (*configMap == "") == (*configMapFile == "") {
+		logger.Fatalf("one of --configmap or --configmap_file must be given")
+	}
+	if (*toolchainConfigBucket == "") == (*toolchainConfigDir == "") {
+		logger.Fatalf("one of --toolchain-config-bucket or --toolchain-config-dir must be given")
+	}
+	if *cmdFilesBucket != "" && *cmdFilesDir != "" {
+		logger.Fatalf("--cmd-files-bucket and --cmd-files-dir are exclusive")
+	}
+	cm, err := loadConfigMap()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	v := command.Validator{
+		RemoteexecAddr: *remoteexecAddr,
+	}
+	if *toolchainConfigBucket != "" || *cmdFilesBucket != "" {
+		var opts []option.ClientOption
+		if *serviceAccountFile != "" {
+			opts = append(opts, option.WithServiceAccountFile(*serviceAccountFile))
+		}
+		gsclient, err := storage.NewClient(ctx, opts...)
+		if err != nil {
+			logger.Fatalf("storage client failed: %v", err)
+		}
+		defer gsclient.Close()
+		if *toolchainConfigBucket != "" {
+			v.StorageClient = stiface.AdaptClient(gsclient)
+			v.Bucket = *toolchainConfigBucket
+		}
+		if *cmdFilesBucket != "" {
+			v.CmdStorage = command.CmdStorageBucket{
+				Bucket: gsclient.Bucket(*cmdFilesBucket),
+			}
+		}
+	}
+	if *toolchainConfigDir != "" {
+		dir := filepath.Clean(*toolchainConfigDir)
+		v.StorageClient = command.DirStorage{
+			Dir: filepath.Dir(dir),
+		}
+		v.Bucket = filepath.Base(dir)
+	}
+	if *cmdFilesDir != "" {
+		v.CmdStorage = command.CmdStorageDir{
+			Dir: *cmdFilesDir,
+		}
+	}
+	if v.CmdStorage == nil {
+		logger.Warnf("neither --cmd-files-bucket nor --cmd-files-dir is given. don't check cmd files")
+	}
+
+	problems, err := v.Validate(ctx, cm)
+	if err != nil {
+		logger.Fatalf("validate: %v", err)
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d problems found\n", len(problems))
+		logger.Sync()
+		os.Exit(1)
+	}
+	fmt.Println("ok")
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"cloud.google.com/go/storage"
+)
+
+// CmdStorageBucket is CmdFileStore on cloud storage bucket.
+// cmd file of hash is stored in "sha256/<hash>" object.
+type CmdStorageBucket struct {
+	Bucket *storage.BucketHandle
+}
+
+// Open opens cmd file of hash.
+func (b CmdStorageBucket) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
+	return b.Bucket.Object(path.Join("sha256", hash)).NewReader(ctx)
+}
+
+// NewWriter returns a writer to store cmd file of hash.
+func (b CmdStorageBucket) NewWriter(ctx context.Context, hash string) io.WriteCloser {
+	return b.Bucket.Object(path.Join("sha256", hash)).NewWriter(ctx)
+}
+
+// CmdStorageDir is CmdFileStore on local directory, in the same
+// layout as CmdStorageBucket.
+type CmdStorageDir struct {
+	Dir string
+}
+
+// Open opens cmd file of hash.
+func (d CmdStorageDir) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(d.Dir, "sha256", hash))
+}
+
+// NewWriter returns a writer to store cmd file of hash.
+// It writes via temporary file as DirStorage does, so Open never sees
+// partial content.
+func (d CmdStorageDir) NewWriter(ctx context.Context, hash string) io.WriteCloser {
+	return DirStorage{Dir: d.Dir}.Bucket("sha256").Object(hash).NewWriter(ctx)
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCmdStorageDir(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "cmd_storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := CmdStorageDir{Dir: dir}
+	const hash = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	w := s.NewWriter(ctx, hash)
+	_, err = w.Write([]byte("cmd file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Open(ctx, hash)
+	if !os.IsNotExist(err) {
+		t.Errorf("Open(ctx, %q) before Close=_, %v; want not exist", hash, err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.Open(ctx, hash)
+	if err != nil {
+		t.Fatalf("Open(ctx, %q)=_, %v; want nil error", hash, err)
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "cmd file"; got != want {
+		t.Errorf("Open(ctx, %q)=%q; want %q", hash, got, want)
+	}
+}
@@ -246,18 +246,24 @@ func (w configMapBucketPoller) Close() error {
 }
 
 func (c ConfigMapBucket) configMap(ctx context.Context) (*cmdpb.ConfigMap, error) {
-	if c.ConfigMapFile == "" {
-		return proto.Clone(c.ConfigMap).(*cmdpb.ConfigMap), nil
+	return loadConfigMap(c.ConfigMap, c.ConfigMapFile)
+}
+
+// loadConfigMap loads config map from fname into cm if fname is given,
+// and returns a copy of cm.
+func loadConfigMap(cm *cmdpb.ConfigMap, fname string) (*cmdpb.ConfigMap, error) {
+	if fname == "" {
+		return proto.Clone(cm).(*cmdpb.ConfigMap), nil
 	}
-	buf, err := ioutil.ReadFile(c.ConfigMapFile)
+	buf, err := ioutil.ReadFile(fname)
 	if err != nil {
 		return nil, err
 	}
-	err = prototext.Unmarshal(buf, c.ConfigMap)
+	err = prototext.Unmarshal(buf, cm)
 	if err != nil {
 		return nil, err
 	}
-	return proto.Clone(c.ConfigMap).(*cmdpb.ConfigMap), nil
+	return proto.Clone(cm).(*cmdpb.ConfigMap), nil
 }
 
 func cloudStorageNotification(ctx context.Context, s stiface.Client, bucket string) (*storage.Notification, error) {
@@ -360,7 +366,6 @@ func (c ConfigMapBucket) pubsubWatcher(ctx context.Context) (ConfigMapWatcher, e
 }
 
 func (c ConfigMapBucket) Seqs(ctx context.Context) (map[string]string, error) {
-	logger := log.FromContext(ctx)
 	bucket, _, err := splitGCSPath(c.URI)
 	if err != nil {
 		return nil, err
@@ -369,10 +374,16 @@ func (c ConfigMapBucket) Seqs(ctx context.Context) (map[string]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	return readSeqs(ctx, c.StorageClient, bucket, cm)
+}
+
+// readSeqs reads seq of runtimes in cm from bucket.
+func readSeqs(ctx context.Context, client stiface.Client, bucket string, cm *cmdpb.ConfigMap) (map[string]string, error) {
+	logger := log.FromContext(ctx)
 	m := map[string]string{}
 	for _, r := range cm.Runtimes {
 		obj := path.Join(r.Name, "seq")
-		buf, err := storageReadAll(ctx, c.StorageClient, bucket, obj)
+		buf, err := storageReadAll(ctx, client, bucket, obj)
 		if err == storage.ErrObjectNotExist {
 			logger.Infof("ignore %s: %v", obj, err)
 			continue
@@ -395,14 +406,20 @@ func (c ConfigMapBucket) RuntimeConfigs(ctx context.Context) (map[string]*cmdpb.
 	if err != nil {
 		return nil, err
 	}
+	return runtimeConfigs(cm, c.RemoteexecAddr), nil
+}
+
+// runtimeConfigs returns a map of runtime configs in cm.
+// remoteexecAddr is used for runtime that has no service addr.
+func runtimeConfigs(cm *cmdpb.ConfigMap, remoteexecAddr string) map[string]*cmdpb.RuntimeConfig {
 	m := make(map[string]*cmdpb.RuntimeConfig)
 	for _, rt := range cm.Runtimes {
 		if rt.ServiceAddr == "" {
-			rt.ServiceAddr = c.RemoteexecAddr
+			rt.ServiceAddr = remoteexecAddr
 		}
 		m[rt.Name] = rt
 	}
-	return m, nil
+	return m
 }
 
 // ConfigLoader loads toolchain_config from cloud storage.
@@ -701,13 +718,24 @@ func checkSelector(rc *cmdpb.RuntimeConfig, sel *cmdpb.Selector) error {
 	return nil
 }
 
-func loadConfigs(ctx context.Context, client stiface.Client, uri string, rc *cmdpb.RuntimeConfig, platform *cmdpb.RemoteexecPlatform, parallel bool) ([]*cmdpb.Config, error) {
-	logger := log.FromContext(ctx)
-	bucket, obj, err := splitGCSPath(uri)
-	if err != nil {
-		return nil, err
+// checkDescriptor checks descriptor d is valid for rc.
+func checkDescriptor(rc *cmdpb.RuntimeConfig, d *cmdpb.CmdDescriptor) error {
+	if err := checkSelector(rc, d.Selector); err != nil {
+		return fmt.Errorf("selector: %v", err)
+	}
+	if d.Setup == nil {
+		return errors.New("no setup")
 	}
+	if d.Setup.PathType == cmdpb.CmdDescriptor_UNKNOWN_PATH_TYPE {
+		return errors.New("unknown path type")
+	}
+	return nil
+}
 
+// listDescriptors lists descriptor objects in bucket with prefix obj,
+// that are allowed by rc.
+func listDescriptors(ctx context.Context, client stiface.Client, bucket, obj string, rc *cmdpb.RuntimeConfig) ([]*storage.ObjectAttrs, error) {
+	logger := log.FromContext(ctx)
 	bkt := client.Bucket(bucket)
 	if bkt == nil {
 		return nil, fmt.Errorf("could not find storage bucket %s", bucket)
@@ -717,8 +745,6 @@ func loadConfigs(ctx context.Context, client stiface.Client, uri string, rc *cmd
 	})
 
 	// pagination?
-	var confs []*cmdpb.Config
-	logger.Infof("load from %s prefix:%s", bucket, obj)
 	start := time.Now()
 	var attrsList []*storage.ObjectAttrs
 	for {
@@ -745,7 +771,23 @@ func loadConfigs(ctx context.Context, client stiface.Client, uri string, rc *cmd
 		attrsList = append(attrsList, attrs)
 	}
 	logger.Infof("iterate over %s took %v", bucket, time.Since(start))
-	start = time.Now()
+	return attrsList, nil
+}
+
+func loadConfigs(ctx context.Context, client stiface.Client, uri string, rc *cmdpb.RuntimeConfig, platform *cmdpb.RemoteexecPlatform, parallel bool) ([]*cmdpb.Config, error) {
+	logger := log.FromContext(ctx)
+	bucket, obj, err := splitGCSPath(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	var confs []*cmdpb.Config
+	logger.Infof("load from %s prefix:%s", bucket, obj)
+	attrsList, err := listDescriptors(ctx, client, bucket, obj, rc)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
 	concurrent := 1
 	if parallel {
 		// Limit concurrent requests to NumCPU * 4.
@@ -768,16 +810,8 @@ func loadConfigs(ctx context.Context, client stiface.Client, uri string, rc *cmd
 				return err
 			}
 			ts := timestamppb.New(attrs.Updated)
-			if err = checkSelector(rc, d.Selector); err != nil {
-				logger.Errorf("selector in %s/%s: %v", bucket, attrs.Name, err)
-				return nil
-			}
-			if d.Setup == nil {
-				logger.Errorf("no setup in %s/%s", bucket, attrs.Name)
-				return nil
-			}
-			if d.Setup.PathType == cmdpb.CmdDescriptor_UNKNOWN_PATH_TYPE {
-				logger.Errorf("unknown path type in %s/%s", bucket, attrs.Name)
+			if err = checkDescriptor(rc, d); err != nil {
+				logger.Errorf("%s/%s: %v", bucket, attrs.Name, err)
 				return nil
 			}
 			// TODO: fix config definition.
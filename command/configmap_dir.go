@@ -0,0 +1,167 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+
+	"go.chromium.org/goma/server/fswatch"
+	"go.chromium.org/goma/server/log"
+	cmdpb "go.chromium.org/goma/server/proto/command"
+)
+
+// ConfigMapDir access config on local directory, for on-prem setup.
+//
+// <Dir> has the same layout as toolchain-config bucket.
+//
+//  <runtime>/
+//           seq: text, sequence number.
+//           <prebuilt-item>/descriptors/<descriptorHash>: proto CmdDescriptor
+//
+// Watcher watches */seq files via fswatch.
+// Seqs and RuntimeConfigs will read ConfigMapFile everytime.
+type ConfigMapDir struct {
+	// Dir is a directory of config data.
+	Dir string
+
+	ConfigMap     *cmdpb.ConfigMap
+	ConfigMapFile string
+
+	// Remoteexec API address.
+	// Used for runtime that has no service_addr.
+	RemoteexecAddr string
+}
+
+// StorageClient returns storage client to access Dir as a bucket.
+// It should be used for ConfigLoader with ConfigMapDir.
+func (c ConfigMapDir) StorageClient() stiface.Client {
+	return DirStorage{
+		Dir: filepath.Dir(filepath.Clean(c.Dir)),
+	}
+}
+
+func (c ConfigMapDir) configMap(ctx context.Context) (*cmdpb.ConfigMap, error) {
+	return loadConfigMap(c.ConfigMap, c.ConfigMapFile)
+}
+
+type configMapDirWatcher struct {
+	dir string
+	w   *fswatch.Watcher
+}
+
+// Watcher returns config map watcher on Dir.
+// If it fails to watch Dir, it returns poller.
+func (c ConfigMapDir) Watcher(ctx context.Context) ConfigMapWatcher {
+	logger := log.FromContext(ctx)
+	w, err := c.fsWatcher(ctx)
+	if err == nil {
+		logger.Infof("use fswatch watcher on %s", c.Dir)
+		return w
+	}
+	logger.Errorf("failed to use fswatch watcher: %v", err)
+	return configMapBucketPoller{
+		baseDelay: 1 * time.Minute,
+		done:      make(chan bool),
+	}
+}
+
+func (c ConfigMapDir) fsWatcher(ctx context.Context) (ConfigMapWatcher, error) {
+	logger := log.FromContext(ctx)
+	dir := filepath.Clean(c.Dir)
+	w, err := fswatch.New(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := c.configMap(ctx)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	for _, r := range cm.Runtimes {
+		rdir := filepath.Join(dir, r.Name)
+		if _, err := os.Stat(rdir); os.IsNotExist(err) {
+			// will be watched when it is created.
+			logger.Infof("runtime dir %s not exist yet", rdir)
+			continue
+		}
+		err := w.Add(rdir)
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+	return configMapDirWatcher{
+		dir: dir,
+		w:   w,
+	}, nil
+}
+
+// Next waits for update of <runtime>/seq, or new runtime directory.
+func (w configMapDirWatcher) Next(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+	for {
+		ev, err := w.w.Next(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Debugf("handle event: %s", ev)
+		if ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) == 0 {
+			continue
+		}
+		if filepath.Dir(ev.Name) == w.dir {
+			if ev.Op&fsnotify.Create == 0 {
+				continue
+			}
+			fi, err := os.Stat(ev.Name)
+			if err != nil || !fi.IsDir() {
+				continue
+			}
+			// new runtime directory. it may have seq already.
+			logger.Infof("%s was created", ev.Name)
+			return w.w.Add(ev.Name)
+		}
+		if filepath.Base(ev.Name) != "seq" || filepath.Dir(filepath.Dir(ev.Name)) != w.dir {
+			continue
+		}
+		logger.Infof("%s was updated: %s", ev.Name, ev.Op)
+		return nil
+	}
+}
+
+func (w configMapDirWatcher) Close() error {
+	ctx := context.Background()
+	logger := log.FromContext(ctx)
+	logger.Infof("watcher close")
+	return w.w.Close()
+}
+
+// Seqs returns a map of config name to sequence.
+func (c ConfigMapDir) Seqs(ctx context.Context) (map[string]string, error) {
+	cm, err := c.configMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return readSeqs(ctx, c.StorageClient(), filepath.Base(filepath.Clean(c.Dir)), cm)
+}
+
+// Bucket returns bucket name of Dir for StorageClient.
+func (c ConfigMapDir) Bucket(ctx context.Context) (string, error) {
+	return filepath.Base(filepath.Clean(c.Dir)), nil
+}
+
+// RuntimeConfigs returns a map of RuntimeConfigs.
+func (c ConfigMapDir) RuntimeConfigs(ctx context.Context) (map[string]*cmdpb.RuntimeConfig, error) {
+	cm, err := c.configMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return runtimeConfigs(cm, c.RemoteexecAddr), nil
+}
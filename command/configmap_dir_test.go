@@ -0,0 +1,151 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	cmdpb "go.chromium.org/goma/server/proto/command"
+)
+
+func TestConfigMapDir(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "configmapdir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	dir := filepath.Join(tmpdir, "toolchain-config")
+
+	writeFile := func(name string, data []byte) {
+		t.Helper()
+		fname := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(fname), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(fname, data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// seq is updated by rename, so watcher gets one event per update.
+	writeSeq := func(runtime, seq string) {
+		t.Helper()
+		writeFile(runtime+"/seq.tmp", []byte(seq))
+		err := os.Rename(filepath.Join(dir, runtime, "seq.tmp"), filepath.Join(dir, runtime, "seq"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeDescriptor := func(name string, sel *cmdpb.Selector) {
+		t.Helper()
+		b, err := proto.Marshal(&cmdpb.CmdDescriptor{
+			Selector: sel,
+			Setup: &cmdpb.CmdDescriptor_Setup{
+				PathType: cmdpb.CmdDescriptor_POSIX,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(name, b)
+	}
+	writeSeq("linux", "1")
+	writeDescriptor("linux/clang-r1/descriptors/d1", &cmdpb.Selector{
+		Name:    "clang",
+		Version: "r1",
+	})
+
+	cm := ConfigMapDir{
+		Dir: dir,
+		ConfigMap: &cmdpb.ConfigMap{
+			Runtimes: []*cmdpb.RuntimeConfig{
+				{
+					Name: "linux",
+				},
+				{
+					Name: "mac",
+				},
+			},
+		},
+		RemoteexecAddr: "remoteexec:443",
+	}
+	loader := &ConfigMapLoader{
+		ConfigMap: cm,
+		ConfigLoader: ConfigLoader{
+			StorageClient: cm.StorageClient(),
+		},
+	}
+	ctx := context.Background()
+	w := cm.Watcher(ctx)
+	defer w.Close()
+	if _, ok := w.(configMapDirWatcher); !ok {
+		t.Fatalf("cm.Watcher(ctx)=%T; want configMapDirWatcher", w)
+	}
+
+	loadSelectors := func() []string {
+		t.Helper()
+		resp, err := loader.Load(ctx, false)
+		if err != nil {
+			t.Fatalf("loader.Load(ctx, false)=_, %v; want nil error", err)
+		}
+		var sels []string
+		for _, c := range resp.Configs {
+			if c.Target.GetAddr() != "remoteexec:443" {
+				t.Errorf("target=%q; want %q", c.Target.GetAddr(), "remoteexec:443")
+			}
+			sels = append(sels, c.CmdDescriptor.GetSelector().GetName()+"-"+c.CmdDescriptor.GetSelector().GetVersion())
+		}
+		return sels
+	}
+	if got, want := loadSelectors(), []string{"clang-r1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("selectors=%q; want %q", got, want)
+	}
+	if _, err := loader.Load(ctx, false); err != ErrNoUpdate {
+		t.Errorf("loader.Load(ctx, false)=_, %v; want %v", err, ErrNoUpdate)
+	}
+
+	waitUpdate := func() {
+		t.Helper()
+		wctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		err := w.Next(wctx)
+		if err != nil {
+			t.Fatalf("w.Next(ctx)=%v; want nil error", err)
+		}
+	}
+
+	t.Logf("update seq")
+	writeDescriptor("linux/clang-r2/descriptors/d2", &cmdpb.Selector{
+		Name:    "clang",
+		Version: "r2",
+	})
+	writeSeq("linux", "2")
+	waitUpdate()
+	if got, want := loadSelectors(), []string{"clang-r1", "clang-r2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("selectors=%q; want %q", got, want)
+	}
+
+	t.Logf("new runtime")
+	writeDescriptor("mac/clang-r1/descriptors/d1", &cmdpb.Selector{
+		Name:    "clang",
+		Version: "r1",
+		Target:  "x86_64-apple-darwin",
+	})
+	waitUpdate()
+	writeSeq("mac", "1")
+	waitUpdate()
+	if got, want := loadSelectors(), []string{"clang-r1", "clang-r2", "clang-r1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("selectors=%q; want %q", got, want)
+	}
+}
@@ -308,6 +308,9 @@ func (d *Descriptor) relocCmdSetup() error {
 		langopt = "-xc"
 	case "g++", "clang++":
 		langopt = "-xc++"
+	case "rustc":
+		// rustc has no subprograms, but needs libraries in sysroot.
+		return d.rustcSetup()
 	default:
 		return nil
 	}
@@ -541,6 +544,22 @@ func selector(c Config) (*pb.Selector, error) {
 				return nil, fmt.Errorf("target %s [%s]: %v", c.Filename, sha256, err)
 			}
 		}
+	case "rustc":
+		v, err = rustcVersion(c.Filename, c.Runner)
+		if err != nil {
+			return nil, fmt.Errorf("version %s [%s]: %v", c.Filename, sha256, err)
+		}
+		if !c.canGetTargetFromFilename() {
+			if c.Target == "" {
+				return nil, fmt.Errorf("target not given %v", c)
+			}
+			t = c.Target
+		} else {
+			t, err = rustcTarget(c.Filename, c.Runner)
+			if err != nil {
+				return nil, fmt.Errorf("target %s [%s]: %v", c.Filename, sha256, err)
+			}
+		}
 	case "dartanalyzer":
 		v, err = dartAnalyzerVersion(c.Filename, c.Runner)
 		if err != nil {
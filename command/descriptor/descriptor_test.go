@@ -432,3 +432,118 @@ func TestResolveSymlinks(t *testing.T) {
 		t.Errorf("d.Setup.Files=%q; want %q", d.Setup.Files, expectedFiles)
 	}
 }
+
+func TestRustcVersion(t *testing.T) {
+	for _, tc := range []struct {
+		out       string
+		want      string
+		wantError bool
+	}{
+		{
+			out:       "not rustc",
+			wantError: true,
+		},
+		{
+			out:  "rustc 1.54.0 (a178d0322 2021-07-26)\n",
+			want: "1.54.0 (a178d0322 2021-07-26)",
+		},
+		{
+			out:  "rustc 1.56.0-nightly (2faabf579 2021-07-27)\r\n",
+			want: "1.56.0-nightly (2faabf579 2021-07-27)",
+		},
+	} {
+		got, err := RustcVersion([]byte(tc.out))
+		if tc.wantError && err == nil {
+			t.Errorf("RustcVersion(%q)=_,nil; want error", tc.out)
+		}
+		if !tc.wantError && err != nil {
+			t.Errorf("RustcVersion(%q)=_,%v; want nil", tc.out, err)
+		}
+		if got != tc.want {
+			t.Errorf("RustcVersion(%q)=%q; want=%q", tc.out, got, tc.want)
+		}
+	}
+}
+
+func TestRustcTarget(t *testing.T) {
+	for _, tc := range []struct {
+		out       string
+		want      string
+		wantError bool
+	}{
+		{
+			out:       "rustc 1.54.0 (a178d0322 2021-07-26)\n",
+			wantError: true,
+		},
+		{
+			out: `rustc 1.54.0 (a178d0322 2021-07-26)
+binary: rustc
+commit-hash: a178d0322ce20e33eac124758e837cbd80a6f633
+commit-date: 2021-07-26
+host: x86_64-unknown-linux-gnu
+release: 1.54.0
+LLVM version: 12.0.1
+`,
+			want: "x86_64-unknown-linux-gnu",
+		},
+	} {
+		got, err := RustcTarget([]byte(tc.out))
+		if tc.wantError && err == nil {
+			t.Errorf("RustcTarget(%q)=_,nil; want error", tc.out)
+		}
+		if !tc.wantError && err != nil {
+			t.Errorf("RustcTarget(%q)=_,%v; want nil", tc.out, err)
+		}
+		if got != tc.want {
+			t.Errorf("RustcTarget(%q)=%q; want=%q", tc.out, got, tc.want)
+		}
+	}
+}
+
+func TestRustcSysrootFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rustcsysroot")
+	if err != nil {
+		t.Fatalf("failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	const target = "x86_64-unknown-linux-gnu"
+	for _, f := range []string{
+		"bin/rustc",
+		"lib/librustc_driver-abcd.so",
+		"lib/libstd-abcd.so",
+		"lib/rustlib/components",
+		"lib/rustlib/" + target + "/lib/libcore-abcd.rlib",
+		"lib/rustlib/" + target + "/lib/libstd-abcd.rlib",
+		"lib/rustlib/" + target + "/lib/libstd-abcd.so",
+		"lib/rustlib/" + target + "/lib/self-contained/crt1.o",
+		"lib/rustlib/wasm32-unknown-unknown/lib/libcore-efgh.rlib",
+	} {
+		fname := filepath.Join(dir, filepath.FromSlash(f))
+		err := os.MkdirAll(filepath.Dir(fname), 0755)
+		if err != nil {
+			t.Fatalf("failed to mkdir: %v", err)
+		}
+		err = ioutil.WriteFile(fname, []byte("dummy"), 0644)
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", f, err)
+		}
+	}
+	got, err := rustcSysrootFiles(dir, target)
+	if err != nil {
+		t.Fatalf("rustcSysrootFiles(%q, %q)=_, %v; want nil error", dir, target, err)
+	}
+	var want []string
+	for _, f := range []string{
+		"lib/librustc_driver-abcd.so",
+		"lib/libstd-abcd.so",
+		"lib/rustlib/" + target + "/lib/libcore-abcd.rlib",
+		"lib/rustlib/" + target + "/lib/libstd-abcd.rlib",
+		"lib/rustlib/" + target + "/lib/libstd-abcd.so",
+	} {
+		want = append(want, filepath.Join(dir, filepath.FromSlash(f)))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rustcSysrootFiles(%q, %q)=%q; want %q", dir, target, got, want)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package descriptor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// RustcVersion returns version string of rustc from output of
+// `rustc --version`.
+//
+// `rustc --version` output is like the following:
+//
+//   rustc 1.54.0 (a178d0322 2021-07-26)
+func RustcVersion(out []byte) (string, error) {
+	const rustcPrefix = "rustc "
+	line := string(bytes.TrimSpace(firstLine(out)))
+	if !strings.HasPrefix(line, rustcPrefix) {
+		return "", fmt.Errorf("not starts with rustc: %s", out)
+	}
+	return line[len(rustcPrefix):], nil
+}
+
+func rustcVersion(cmd string, runner Runner) (string, error) {
+	out, err := runner(cmd, "--version")
+	if err != nil {
+		return "", fmt.Errorf("failed to take rustc version: %v", err)
+	}
+	return RustcVersion(out)
+}
+
+// RustcTarget returns host target of rustc from output of
+// `rustc -vV`.
+//
+// `rustc -vV` output is like the following:
+//
+//   rustc 1.54.0 (a178d0322 2021-07-26)
+//   binary: rustc
+//   commit-hash: a178d0322ce20e33eac124758e837cbd80a6f633
+//   commit-date: 2021-07-26
+//   host: x86_64-unknown-linux-gnu
+//   release: 1.54.0
+//   LLVM version: 12.0.1
+func RustcTarget(out []byte) (string, error) {
+	const hostPrefix = "host: "
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, hostPrefix) {
+			return strings.TrimSpace(line[len(hostPrefix):]), nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no host in rustc output: %s", out)
+}
+
+func rustcTarget(cmd string, runner Runner) (string, error) {
+	out, err := runner(cmd, "-vV")
+	if err != nil {
+		return "", fmt.Errorf("failed to take rustc target: %v", err)
+	}
+	return RustcTarget(out)
+}
+
+func rustcSysroot(cmd string, runner Runner) (string, error) {
+	out, err := runner(cmd, "--print", "sysroot")
+	if err != nil {
+		return "", fmt.Errorf("failed to take rustc sysroot: %v", err)
+	}
+	sysroot := string(bytes.TrimSpace(firstLine(out)))
+	if sysroot == "" {
+		return "", fmt.Errorf("empty rustc sysroot: %s", out)
+	}
+	return sysroot, nil
+}
+
+// isRustLibrary reports whether fname is library file in rust sysroot.
+func isRustLibrary(fname string) bool {
+	switch filepath.Ext(fname) {
+	case ".rlib", ".rmeta", ".so", ".a":
+		return true
+	}
+	return false
+}
+
+// rustcSysrootFiles returns library files in rustc sysroot for target.
+// It returns shared objects in <sysroot>/lib (e.g. librustc_driver),
+// and libraries in <sysroot>/lib/rustlib/<target>/lib (e.g. libstd).
+func rustcSysrootFiles(sysroot, target string) ([]string, error) {
+	var files []string
+	for _, dir := range []string{
+		filepath.Join(sysroot, "lib"),
+		filepath.Join(sysroot, "lib", "rustlib", target, "lib"),
+	} {
+		fis, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range fis {
+			if fi.IsDir() || !isRustLibrary(fi.Name()) {
+				continue
+			}
+			files = append(files, filepath.Join(dir, fi.Name()))
+		}
+	}
+	return files, nil
+}
+
+// rustcSetup adds rustc sysroot's libraries in cmd descriptor.
+func (d *Descriptor) rustcSetup() error {
+	fname := filepath.Join(d.cwd, d.fname)
+	sysroot, err := rustcSysroot(fname, d.Runner)
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(sysroot) {
+		sysroot = filepath.Join(d.cwd, sysroot)
+	}
+	files, err := rustcSysrootFiles(sysroot, d.CmdDescriptor.Selector.Target)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		err = d.Add(f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+	"google.golang.org/api/iterator"
+)
+
+// DirStorage is a storage client on local directory.
+// bucket is a subdirectory of Dir, and object is a file in the bucket
+// directory. object name uses '/' as path separator.
+// hidden files (i.e. filename starts with '.') are not listed, since
+// they are used as temporary files for writing.
+//
+// Only methods used to load and register toolchain config are
+// supported, i.e. Bucket, BucketHandle.Object, BucketHandle.Objects,
+// ObjectHandle.NewReader, ObjectHandle.NewWriter, ObjectIterator.Next
+// and Writer's Write and Close.
+type DirStorage struct {
+	stiface.Client
+	Dir string
+}
+
+// Bucket returns bucket handle of name.
+func (s DirStorage) Bucket(name string) stiface.BucketHandle {
+	return dirBucket{
+		dir: filepath.Join(s.Dir, name),
+	}
+}
+
+type dirBucket struct {
+	stiface.BucketHandle
+	dir string
+}
+
+func (b dirBucket) Object(name string) stiface.ObjectHandle {
+	return dirObject{
+		fname: filepath.Join(b.dir, filepath.FromSlash(name)),
+	}
+}
+
+func (b dirBucket) Objects(ctx context.Context, q *storage.Query) stiface.ObjectIterator {
+	var prefix string
+	if q != nil {
+		prefix = q.Prefix
+	}
+	iter := &dirObjIter{}
+	// filepath.Walk walks in lexical order, so objects are sorted
+	// by name as cloud storage does.
+	iter.err = filepath.Walk(b.dir, func(fname string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(b.dir, fname)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		iter.attrs = append(iter.attrs, &storage.ObjectAttrs{
+			Name:    name,
+			Size:    fi.Size(),
+			Updated: fi.ModTime(),
+		})
+		return nil
+	})
+	return iter
+}
+
+type dirObjIter struct {
+	stiface.ObjectIterator
+	attrs []*storage.ObjectAttrs
+	err   error
+}
+
+func (it *dirObjIter) Next() (*storage.ObjectAttrs, error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+	if len(it.attrs) == 0 {
+		return nil, iterator.Done
+	}
+	attrs := it.attrs[0]
+	it.attrs = it.attrs[1:]
+	return attrs, nil
+}
+
+type dirObject struct {
+	stiface.ObjectHandle
+	fname string
+}
+
+func (o dirObject) NewReader(ctx context.Context) (stiface.Reader, error) {
+	f, err := os.Open(o.fname)
+	if os.IsNotExist(err) {
+		return nil, storage.ErrObjectNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &dirObjectReader{
+		f:    f,
+		size: fi.Size(),
+	}, nil
+}
+
+type dirObjectReader struct {
+	stiface.Reader
+	f    *os.File
+	size int64
+}
+
+func (r *dirObjectReader) Size() int64 { return r.size }
+
+func (r *dirObjectReader) Read(p []byte) (int, error) {
+	return r.f.Read(p)
+}
+
+func (r *dirObjectReader) Close() error {
+	return r.f.Close()
+}
+
+func (o dirObject) NewWriter(ctx context.Context) stiface.Writer {
+	return &dirObjectWriter{
+		ctx:   ctx,
+		fname: o.fname,
+	}
+}
+
+// dirObjectWriter writes content in temporary file, and renames it to
+// the object file on Close, so readers never see partial content.
+// As storage.Writer, if ctx is canceled before Close, the write is
+// aborted and the object is not written.
+type dirObjectWriter struct {
+	stiface.Writer
+	ctx   context.Context
+	fname string
+	f     *os.File
+	err   error
+}
+
+func (w *dirObjectWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.f == nil {
+		dir := filepath.Dir(w.fname)
+		w.err = os.MkdirAll(dir, 0755)
+		if w.err != nil {
+			return 0, w.err
+		}
+		w.f, w.err = ioutil.TempFile(dir, "."+filepath.Base(w.fname)+".")
+		if w.err != nil {
+			return 0, w.err
+		}
+	}
+	n, err := w.f.Write(p)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+func (w *dirObjectWriter) Close() error {
+	if w.f == nil && w.err == nil {
+		// empty object.
+		w.Write(nil)
+	}
+	if w.err == nil {
+		w.err = w.ctx.Err()
+	}
+	if w.err != nil {
+		if w.f != nil {
+			w.f.Close()
+			os.Remove(w.f.Name())
+		}
+		return w.err
+	}
+	err := w.f.Chmod(0644)
+	if err == nil {
+		err = w.f.Close()
+	} else {
+		w.f.Close()
+	}
+	if err != nil {
+		os.Remove(w.f.Name())
+		return err
+	}
+	return os.Rename(w.f.Name(), w.fname)
+}
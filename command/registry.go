@@ -0,0 +1,337 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	"go.chromium.org/goma/server/command/normalizer"
+	"go.chromium.org/goma/server/log"
+	cmdpb "go.chromium.org/goma/server/proto/command"
+)
+
+// CmdFileStore is a storage of command binary files, keyed by sha256 hash.
+type CmdFileStore interface {
+	CmdStorage
+
+	// NewWriter returns a writer to store a file of hash.
+	// The file is stored when the writer is closed successfully.
+	// If ctx is canceled before Close, the file is not stored.
+	NewWriter(ctx context.Context, hash string) io.WriteCloser
+}
+
+// Registry is a toolchain service to register a toolchain.
+//
+// UploadFile stores cmd files in CmdFiles.
+// Register writes descriptor as <runtime>/<prebuilt>/descriptors/<hash>
+// in Bucket, and bumps <runtime>/seq, so all servers watching the bucket
+// will load it.  Then, it calls Configure to apply the change on this
+// server.
+type Registry struct {
+	cmdpb.UnimplementedToolchainServiceServer
+
+	// Auth authenticates a user of the request.
+	Auth func(context.Context) (*enduser.EndUser, error)
+
+	// AllowedGroups is a list of groups allowed to register toolchain.
+	AllowedGroups []string
+
+	StorageClient stiface.Client
+	Bucket        string
+	ConfigMap     ConfigMap
+	CmdFiles      CmdFileStore
+
+	// Configure reloads toolchain config, i.e. updates ConfigStore
+	// and Inventory.
+	Configure func(context.Context) error
+
+	// mu serializes seq updates in this server.
+	// seq updates from other servers are not serialized.
+	mu sync.Mutex
+}
+
+func (r *Registry) authorize(ctx context.Context) (*enduser.EndUser, error) {
+	u, err := r.Auth(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "auth: %v", err)
+	}
+	for _, g := range r.AllowedGroups {
+		if u.Group == g {
+			return u, nil
+		}
+	}
+	return nil, status.Errorf(codes.PermissionDenied, "group %q is not allowed to register toolchain", u.Group)
+}
+
+func descriptorHash(d *cmdpb.CmdDescriptor) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// setupFiles returns file specs in d's setup that need cmd files.
+func setupFiles(d *cmdpb.CmdDescriptor) []*cmdpb.FileSpec {
+	var specs []*cmdpb.FileSpec
+	if d.Setup.CmdFile != nil {
+		specs = append(specs, d.Setup.CmdFile)
+	}
+	for _, f := range d.Setup.Files {
+		if f.Hash == "" {
+			// symlink or directory.
+			continue
+		}
+		specs = append(specs, f)
+	}
+	return specs
+}
+
+// nextSeq returns new seq after seq.
+// it increments seq if it is a number, or uses timestamp otherwise.
+func nextSeq(seq string) string {
+	seq = strings.TrimSpace(seq)
+	if seq == "" {
+		return "1"
+	}
+	n, err := strconv.ParseInt(seq, 10, 64)
+	if err != nil {
+		return time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	return strconv.FormatInt(n+1, 10)
+}
+
+// Register registers a toolchain.
+// In dry run, it checks the request but doesn't change anything.
+func (r *Registry) Register(ctx context.Context, req *cmdpb.RegisterToolchainReq) (*cmdpb.RegisterToolchainResp, error) {
+	logger := log.FromContext(ctx)
+	u, err := r.authorize(ctx)
+	if err != nil {
+		logger.Warnf("register %s/%s: %v", req.Runtime, req.Prebuilt, err)
+		return nil, err
+	}
+
+	rcs, err := r.ConfigMap.RuntimeConfigs(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "runtime configs: %v", err)
+	}
+	rc, ok := rcs[req.Runtime]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown runtime %q", req.Runtime)
+	}
+	if req.Prebuilt == "" || strings.Contains(req.Prebuilt, "/") || strings.HasPrefix(req.Prebuilt, ".") {
+		return nil, status.Errorf(codes.InvalidArgument, "bad prebuilt %q", req.Prebuilt)
+	}
+	d := req.Descriptor_
+	if d == nil {
+		return nil, status.Error(codes.InvalidArgument, "no descriptor")
+	}
+	if err := checkDescriptor(rc, d); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "descriptor: %v", err)
+	}
+	if _, err := normalizer.Selector(d.Selector); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "normalize selector %s: %v", d.Selector, err)
+	}
+	dhash, err := descriptorHash(d)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "descriptor: %v", err)
+	}
+	name := path.Join(req.Runtime, req.Prebuilt, "descriptors", dhash)
+	if err := checkPrebuilt(rc, name); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "prebuilt: %v", err)
+	}
+
+	var missing []string
+	checked := make(map[string]bool)
+	for _, spec := range setupFiles(d) {
+		if checked[spec.Hash] {
+			continue
+		}
+		checked[spec.Hash] = true
+		if !validHash(spec.Hash) {
+			return nil, status.Errorf(codes.InvalidArgument, "file %s: bad hash %q", spec.Path, spec.Hash)
+		}
+		ok, err := r.stored(ctx, spec.Hash)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "file %s: %v", spec.Hash, err)
+		}
+		if !ok {
+			missing = append(missing, spec.Hash)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "files not stored: %q. upload them by UploadFile", missing)
+	}
+
+	resp := &cmdpb.RegisterToolchainResp{
+		DescriptorName: name,
+	}
+	if req.DryRun {
+		logger.Infof("register %s by %s: dry run", name, u.Group)
+		return resp, nil
+	}
+
+	b, err := proto.Marshal(d)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "marshal descriptor: %v", err)
+	}
+	err = r.writeObject(ctx, name, b)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "write %s: %v", name, err)
+	}
+	resp.Seq, err = r.bumpSeq(ctx, req.Runtime)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "update seq: %v", err)
+	}
+	logger.Infof("register %s by %s: seq=%s", name, u.Group, resp.Seq)
+	err = r.Configure(ctx)
+	if err != nil && err != ErrNoUpdate {
+		return nil, status.Errorf(codes.Internal, "registered %s, but failed to configure: %v", name, err)
+	}
+	return resp, nil
+}
+
+// stored reports whether file of hash is in CmdFiles.
+func (r *Registry) stored(ctx context.Context, hash string) (bool, error) {
+	rd, err := r.CmdFiles.Open(ctx, hash)
+	if err == nil {
+		rd.Close()
+		return true, nil
+	}
+	if errors.Is(err, os.ErrNotExist) || err == storage.ErrObjectNotExist {
+		return false, nil
+	}
+	return false, err
+}
+
+// validHash reports whether hash is sha256 hash in lower case hex.
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range hash {
+		if !('0' <= c && c <= '9') && !('a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// UploadFile uploads a file in CmdFiles.
+// It verifies content with the hash, and the file is stored only if
+// the hash matches.
+func (r *Registry) UploadFile(stream cmdpb.ToolchainService_UploadFileServer) error {
+	ctx := stream.Context()
+	logger := log.FromContext(ctx)
+	u, err := r.authorize(ctx)
+	if err != nil {
+		logger.Warnf("upload file: %v", err)
+		return err
+	}
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "no request")
+	}
+	if err != nil {
+		return err
+	}
+	hash := req.Hash
+	if !validHash(hash) {
+		return status.Errorf(codes.InvalidArgument, "bad hash %q", hash)
+	}
+	ok, err := r.stored(ctx, hash)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "file %s: %v", hash, err)
+	}
+	if ok {
+		logger.Infof("upload file %s by %s: already stored", hash, u.Group)
+		return stream.SendAndClose(&cmdpb.UploadToolchainFileResp{
+			AlreadyStored: true,
+		})
+	}
+
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := r.CmdFiles.NewWriter(wctx, hash)
+	abort := func(err error) error {
+		cancel()
+		w.Close()
+		return err
+	}
+	h := sha256.New()
+	var size int64
+	for {
+		if req.Hash != "" && req.Hash != hash {
+			return abort(status.Errorf(codes.InvalidArgument, "hash changed %q; want %q", req.Hash, hash))
+		}
+		h.Write(req.Data)
+		_, err = w.Write(req.Data)
+		if err != nil {
+			return abort(status.Errorf(codes.Internal, "store file %s: %v", hash, err))
+		}
+		size += int64(len(req.Data))
+		req, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return abort(err)
+		}
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != hash {
+		return abort(status.Errorf(codes.InvalidArgument, "file %s: hash mismatch: got %s", hash, got))
+	}
+	err = w.Close()
+	if err != nil {
+		return status.Errorf(codes.Internal, "store file %s: %v", hash, err)
+	}
+	logger.Infof("upload file %s by %s: size=%d", hash, u.Group, size)
+	return stream.SendAndClose(&cmdpb.UploadToolchainFileResp{})
+}
+
+func (r *Registry) writeObject(ctx context.Context, name string, data []byte) error {
+	w := r.StorageClient.Bucket(r.Bucket).Object(name).NewWriter(ctx)
+	_, err := w.Write(data)
+	if err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (r *Registry) bumpSeq(ctx context.Context, runtime string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := path.Join(runtime, "seq")
+	buf, err := storageReadAll(ctx, r.StorageClient, r.Bucket, name)
+	if err != nil && err != storage.ErrObjectNotExist {
+		return "", fmt.Errorf("read %s: %v", name, err)
+	}
+	seq := nextSeq(string(buf))
+	err = r.writeObject(ctx, name, []byte(seq))
+	if err != nil {
+		return "", fmt.Errorf("write %s: %v", name, err)
+	}
+	return seq, nil
+}
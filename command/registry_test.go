@@ -0,0 +1,468 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	cmdpb "go.chromium.org/goma/server/proto/command"
+)
+
+type fakeCmdFileStore map[string][]byte
+
+func (s fakeCmdFileStore) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
+	b, ok := s[hash]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (s fakeCmdFileStore) NewWriter(ctx context.Context, hash string) io.WriteCloser {
+	return &fakeCmdFileWriter{
+		ctx:  ctx,
+		s:    s,
+		hash: hash,
+	}
+}
+
+type fakeCmdFileWriter struct {
+	ctx  context.Context
+	s    fakeCmdFileStore
+	hash string
+	buf  bytes.Buffer
+}
+
+func (w *fakeCmdFileWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *fakeCmdFileWriter) Close() error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	w.s[w.hash] = w.buf.Bytes()
+	return nil
+}
+
+type fakeUploadFileStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	reqs []*cmdpb.UploadToolchainFileReq
+	resp *cmdpb.UploadToolchainFileResp
+}
+
+func (s *fakeUploadFileStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeUploadFileStream) Recv() (*cmdpb.UploadToolchainFileReq, error) {
+	if len(s.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+	return req, nil
+}
+
+func (s *fakeUploadFileStream) SendAndClose(resp *cmdpb.UploadToolchainFileResp) error {
+	s.resp = resp
+	return nil
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func newTestRegistry(t *testing.T, dir string, cmdFiles fakeCmdFileStore, configured *int) (*Registry, ConfigMapDir) {
+	t.Helper()
+	cm := ConfigMapDir{
+		Dir: dir,
+		ConfigMap: &cmdpb.ConfigMap{
+			Runtimes: []*cmdpb.RuntimeConfig{
+				{
+					Name:                "linux",
+					DisallowedPrebuilts: []string{"disallowed"},
+				},
+			},
+		},
+		RemoteexecAddr: "remoteexec:443",
+	}
+	return &Registry{
+		Auth: func(ctx context.Context) (*enduser.EndUser, error) {
+			u, ok := enduser.FromContext(ctx)
+			if !ok {
+				return nil, errors.New("no user")
+			}
+			return u, nil
+		},
+		AllowedGroups: []string{"release"},
+		StorageClient: cm.StorageClient(),
+		Bucket:        "toolchain-config",
+		ConfigMap:     cm,
+		CmdFiles:      cmdFiles,
+		Configure: func(ctx context.Context) error {
+			*configured++
+			return nil
+		},
+	}, cm
+}
+
+func TestRegistry(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	dir := filepath.Join(tmpdir, "toolchain-config")
+
+	storedHash := sha256Hex([]byte("stored"))
+	cmdFiles := fakeCmdFileStore{
+		storedHash: []byte("stored"),
+	}
+	configured := 0
+	r, cm := newTestRegistry(t, dir, cmdFiles, &configured)
+
+	content := []byte("clang binary")
+	hash := sha256Hex(content)
+	descriptor := func() *cmdpb.CmdDescriptor {
+		return &cmdpb.CmdDescriptor{
+			Selector: &cmdpb.Selector{
+				Name:       "clang",
+				Version:    "r1",
+				Target:     "x86_64-unknown-linux-gnu",
+				BinaryHash: hash,
+			},
+			Setup: &cmdpb.CmdDescriptor_Setup{
+				CmdFile: &cmdpb.FileSpec{
+					Path:         "bin/clang",
+					Hash:         hash,
+					Size:         int64(len(content)),
+					IsExecutable: true,
+				},
+				Files: []*cmdpb.FileSpec{
+					{
+						Path: "lib/libstored.so",
+						Hash: storedHash,
+					},
+					{
+						Path:    "bin/clang++",
+						Symlink: "clang",
+					},
+				},
+				PathType: cmdpb.CmdDescriptor_POSIX,
+			},
+		}
+	}
+	req := func() *cmdpb.RegisterToolchainReq {
+		return &cmdpb.RegisterToolchainReq{
+			Runtime:     "linux",
+			Prebuilt:    "clang-r1",
+			Descriptor_: descriptor(),
+		}
+	}
+	dhash, err := descriptorHash(descriptor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	descName := "linux/clang-r1/descriptors/" + dhash
+
+	ctx := context.Background()
+	userCtx := enduser.NewContext(ctx, enduser.New("release@example.com", "release", nil))
+
+	for _, tc := range []struct {
+		desc   string
+		ctx    context.Context
+		modify func(*cmdpb.RegisterToolchainReq)
+		want   codes.Code
+	}{
+		{
+			desc: "no auth",
+			ctx:  ctx,
+			want: codes.Unauthenticated,
+		},
+		{
+			desc: "not allowed group",
+			ctx:  enduser.NewContext(ctx, enduser.New("someone@example.com", "user", nil)),
+			want: codes.PermissionDenied,
+		},
+		{
+			desc: "unknown runtime",
+			ctx:  userCtx,
+			modify: func(req *cmdpb.RegisterToolchainReq) {
+				req.Runtime = "mac"
+			},
+			want: codes.InvalidArgument,
+		},
+		{
+			desc: "bad prebuilt",
+			ctx:  userCtx,
+			modify: func(req *cmdpb.RegisterToolchainReq) {
+				req.Prebuilt = "../clang-r1"
+			},
+			want: codes.InvalidArgument,
+		},
+		{
+			desc: "disallowed prebuilt",
+			ctx:  userCtx,
+			modify: func(req *cmdpb.RegisterToolchainReq) {
+				req.Prebuilt = "disallowed-clang"
+			},
+			want: codes.InvalidArgument,
+		},
+		{
+			desc: "no setup",
+			ctx:  userCtx,
+			modify: func(req *cmdpb.RegisterToolchainReq) {
+				req.Descriptor_.Setup = nil
+			},
+			want: codes.InvalidArgument,
+		},
+		{
+			desc: "bad hash",
+			ctx:  userCtx,
+			modify: func(req *cmdpb.RegisterToolchainReq) {
+				req.Descriptor_.Setup.Files[0].Hash = "../stored"
+			},
+			want: codes.InvalidArgument,
+		},
+		{
+			desc: "missing file",
+			ctx:  userCtx,
+			want: codes.FailedPrecondition,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			req := req()
+			if tc.modify != nil {
+				tc.modify(req)
+			}
+			resp, err := r.Register(tc.ctx, req)
+			if status.Code(err) != tc.want {
+				t.Errorf("Register(ctx, req)=%v, %v; want %v", resp, err, tc.want)
+			}
+		})
+	}
+
+	t.Logf("upload file")
+	stream := &fakeUploadFileStream{
+		ctx: userCtx,
+		reqs: []*cmdpb.UploadToolchainFileReq{
+			{
+				Hash: hash,
+				Data: content[:5],
+			},
+			{
+				Data: content[5:],
+			},
+		},
+	}
+	err = r.UploadFile(stream)
+	if err != nil {
+		t.Fatalf("UploadFile(stream)=%v; want nil error", err)
+	}
+	if stream.resp == nil || stream.resp.AlreadyStored {
+		t.Errorf("UploadFile(stream) resp=%v; want not already stored", stream.resp)
+	}
+	if got := cmdFiles[hash]; !reflect.DeepEqual(got, content) {
+		t.Errorf("stored %s=%q; want %q", hash, got, content)
+	}
+
+	t.Logf("upload file again")
+	stream = &fakeUploadFileStream{
+		ctx: userCtx,
+		reqs: []*cmdpb.UploadToolchainFileReq{
+			{
+				Hash: hash,
+				Data: content,
+			},
+		},
+	}
+	err = r.UploadFile(stream)
+	if err != nil {
+		t.Fatalf("UploadFile(stream)=%v; want nil error", err)
+	}
+	if stream.resp == nil || !stream.resp.AlreadyStored {
+		t.Errorf("UploadFile(stream) resp=%v; want already stored", stream.resp)
+	}
+
+	t.Logf("dry run")
+	dryReq := req()
+	dryReq.DryRun = true
+	resp, err := r.Register(userCtx, dryReq)
+	if err != nil {
+		t.Fatalf("Register(ctx, dry-run)=%v, %v; want nil error", resp, err)
+	}
+	want := &cmdpb.RegisterToolchainResp{
+		DescriptorName: descName,
+	}
+	if !proto.Equal(resp, want) {
+		t.Errorf("Register(ctx, dry-run)=%v; want %v", resp, want)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("dry run created %s: %v", dir, err)
+	}
+	if configured != 0 {
+		t.Errorf("dry run configured %d times; want 0", configured)
+	}
+
+	t.Logf("register")
+	resp, err = r.Register(userCtx, req())
+	if err != nil {
+		t.Fatalf("Register(ctx, req)=%v, %v; want nil error", resp, err)
+	}
+	want.Seq = "1"
+	if !proto.Equal(resp, want) {
+		t.Errorf("Register(ctx, req)=%v; want %v", resp, want)
+	}
+	if configured != 1 {
+		t.Errorf("configured %d times; want 1", configured)
+	}
+
+	loader := &ConfigMapLoader{
+		ConfigMap: cm,
+		ConfigLoader: ConfigLoader{
+			StorageClient: cm.StorageClient(),
+		},
+	}
+	cresp, err := loader.Load(ctx, false)
+	if err != nil {
+		t.Fatalf("loader.Load(ctx, false)=_, %v; want nil error", err)
+	}
+	if len(cresp.Configs) != 1 || !proto.Equal(cresp.Configs[0].CmdDescriptor, descriptor()) {
+		t.Errorf("loader.Load(ctx, false)=%v; want %v", cresp.Configs, descriptor())
+	}
+
+	t.Logf("register again")
+	resp, err = r.Register(userCtx, req())
+	if err != nil {
+		t.Fatalf("Register(ctx, req)=%v, %v; want nil error", resp, err)
+	}
+	want.Seq = "2"
+	if !proto.Equal(resp, want) {
+		t.Errorf("Register(ctx, req)=%v; want %v", resp, want)
+	}
+}
+
+func TestRegistryUploadFileError(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	cmdFiles := fakeCmdFileStore{}
+	configured := 0
+	r, _ := newTestRegistry(t, filepath.Join(tmpdir, "toolchain-config"), cmdFiles, &configured)
+
+	content := []byte("clang binary")
+	hash := sha256Hex(content)
+	ctx := context.Background()
+	userCtx := enduser.NewContext(ctx, enduser.New("release@example.com", "release", nil))
+
+	for _, tc := range []struct {
+		desc string
+		ctx  context.Context
+		reqs []*cmdpb.UploadToolchainFileReq
+		want codes.Code
+	}{
+		{
+			desc: "no auth",
+			ctx:  ctx,
+			reqs: []*cmdpb.UploadToolchainFileReq{
+				{Hash: hash, Data: content},
+			},
+			want: codes.Unauthenticated,
+		},
+		{
+			desc: "not allowed group",
+			ctx:  enduser.NewContext(ctx, enduser.New("someone@example.com", "user", nil)),
+			reqs: []*cmdpb.UploadToolchainFileReq{
+				{Hash: hash, Data: content},
+			},
+			want: codes.PermissionDenied,
+		},
+		{
+			desc: "no request",
+			ctx:  userCtx,
+			want: codes.InvalidArgument,
+		},
+		{
+			desc: "bad hash",
+			ctx:  userCtx,
+			reqs: []*cmdpb.UploadToolchainFileReq{
+				{Hash: "../" + hash[3:], Data: content},
+			},
+			want: codes.InvalidArgument,
+		},
+		{
+			desc: "hash mismatch",
+			ctx:  userCtx,
+			reqs: []*cmdpb.UploadToolchainFileReq{
+				{Hash: hash, Data: content},
+				{Data: []byte("broken")},
+			},
+			want: codes.InvalidArgument,
+		},
+		{
+			desc: "hash changed",
+			ctx:  userCtx,
+			reqs: []*cmdpb.UploadToolchainFileReq{
+				{Hash: hash, Data: content[:5]},
+				{Hash: sha256Hex([]byte("other")), Data: content[5:]},
+			},
+			want: codes.InvalidArgument,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			stream := &fakeUploadFileStream{
+				ctx:  tc.ctx,
+				reqs: tc.reqs,
+			}
+			err := r.UploadFile(stream)
+			if status.Code(err) != tc.want {
+				t.Errorf("UploadFile(stream)=%v; want %v", err, tc.want)
+			}
+			if len(cmdFiles) != 0 {
+				t.Errorf("UploadFile(stream) stored %d files; want 0", len(cmdFiles))
+			}
+		})
+	}
+}
+
+func TestNextSeq(t *testing.T) {
+	for _, tc := range []struct {
+		seq  string
+		want string
+	}{
+		{seq: "", want: "1"},
+		{seq: "1", want: "2"},
+		{seq: "41\n", want: "42"},
+	} {
+		if got := nextSeq(tc.seq); got != tc.want {
+			t.Errorf("nextSeq(%q)=%q; want %q", tc.seq, got, tc.want)
+		}
+	}
+	if got := nextSeq("2021-01-01T00:00:00Z"); got == "2021-01-01T00:00:00Z" {
+		t.Errorf("nextSeq(%q)=%q; want different seq", "2021-01-01T00:00:00Z", got)
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/command/normalizer"
+	"go.chromium.org/goma/server/log"
+	cmdpb "go.chromium.org/goma/server/proto/command"
+)
+
+// CmdStorage is an interface to retrieve cmd file contents by hash.
+type CmdStorage interface {
+	Open(ctx context.Context, hash string) (io.ReadCloser, error)
+}
+
+// Problem is a problem in toolchain config found by Validator.
+type Problem struct {
+	// Runtime is a name of runtime config.
+	Runtime string
+
+	// Name is an object name of descriptor in the bucket.
+	// empty if the problem is not in a descriptor.
+	Name string
+
+	Err error
+}
+
+func (p Problem) String() string {
+	if p.Name == "" {
+		return fmt.Sprintf("%s: %v", p.Runtime, p.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", p.Runtime, p.Name, p.Err)
+}
+
+// Validator validates toolchain config map and descriptors
+// with the same checks that exec_server does when it loads them.
+type Validator struct {
+	// StorageClient is a client to access Bucket.
+	// It can be DirStorage for local directory.
+	StorageClient stiface.Client
+
+	// Bucket is toolchain-config bucket.
+	Bucket string
+
+	// CmdStorage is a storage of cmd files.
+	// If nil, cmd files are not checked.
+	CmdStorage CmdStorage
+
+	// RemoteexecAddr is used for runtime that has no service_addr,
+	// as exec_server's --remoteexec-addr.
+	RemoteexecAddr string
+}
+
+// selectorKey is a key of normalized selector.
+type selectorKey struct {
+	name, version, target, binaryHash string
+}
+
+// selectorLoc is a location of descriptor.
+type selectorLoc struct {
+	runtime string
+	name    string
+}
+
+// Validate validates config map cm and descriptors in the bucket,
+// and returns problems found, sorted by runtime and name.
+// It returns error if it failed to access the bucket.
+//
+// It reports
+//  runtime that will be ignored (no name, no service addr, no seq).
+//  runtime name that is prefix of other runtime name.
+//  descriptor that can't be loaded, or is rejected by runtime config.
+//  selector conflicts: the same selector is served by the same addr.
+//  cmd files missing in CmdStorage.
+//  dimension overlaps: platform runtime config that will never be
+//  selected because dimensions of earlier runtime cover its dimensions.
+func (v Validator) Validate(ctx context.Context, cm *cmdpb.ConfigMap) ([]Problem, error) {
+	logger := log.FromContext(ctx)
+	var problems []Problem
+	report := func(runtime, name string, err error) {
+		problems = append(problems, Problem{
+			Runtime: runtime,
+			Name:    name,
+			Err:     err,
+		})
+	}
+
+	runtimes := make(map[string]bool)
+	// key: addr -> selector.
+	selectors := make(map[string]map[selectorKey]selectorLoc)
+	// key: hash. value: error to open.
+	cmdFiles := make(map[string]error)
+	var loaded []string
+	var platforms []*cmdpb.RuntimeConfig
+	for _, rc := range cm.GetRuntimes() {
+		if rc.Name == "" {
+			report("", "", errors.New("no runtime name"))
+			continue
+		}
+		if runtimes[rc.Name] {
+			report(rc.Name, "", errors.New("duplicate runtime name"))
+			continue
+		}
+		runtimes[rc.Name] = true
+		rc = proto.Clone(rc).(*cmdpb.RuntimeConfig)
+		if rc.ServiceAddr == "" {
+			rc.ServiceAddr = v.RemoteexecAddr
+		}
+		if rc.ServiceAddr == "" {
+			report(rc.Name, "", errors.New("no service addr. runtime will be ignored"))
+			continue
+		}
+		_, err := storageReadAll(ctx, v.StorageClient, v.Bucket, rc.Name+"/seq")
+		if err == storage.ErrObjectNotExist {
+			report(rc.Name, "", errors.New("no seq. runtime will be ignored"))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: seq: %v", rc.Name, err)
+		}
+		loaded = append(loaded, rc.Name)
+		if rc.PlatformRuntimeConfig != nil {
+			platforms = append(platforms, rc)
+		}
+
+		attrsList, err := listDescriptors(ctx, v.StorageClient, v.Bucket, rc.Name, rc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", rc.Name, err)
+		}
+		for _, attrs := range attrsList {
+			d, err := loadDescriptor(ctx, v.StorageClient, v.Bucket, attrs.Name)
+			if err != nil {
+				// loadConfigs fails in this case.
+				report(rc.Name, attrs.Name, fmt.Errorf("%v. runtime will fail to load", err))
+				continue
+			}
+			if err := checkDescriptor(rc, d); err != nil {
+				report(rc.Name, attrs.Name, err)
+				continue
+			}
+			sel, err := normalizer.Selector(d.Selector)
+			if err != nil {
+				report(rc.Name, attrs.Name, fmt.Errorf("normalize selector %s: %v", d.Selector, err))
+				continue
+			}
+			m := selectors[rc.ServiceAddr]
+			if m == nil {
+				m = make(map[selectorKey]selectorLoc)
+				selectors[rc.ServiceAddr] = m
+			}
+			key := selectorKey{
+				name:       sel.Name,
+				version:    sel.Version,
+				target:     sel.Target,
+				binaryHash: sel.BinaryHash,
+			}
+			if loc, ok := m[key]; ok {
+				report(rc.Name, attrs.Name, fmt.Errorf("selector conflict with %s in %s on %s: %s", loc.name, loc.runtime, rc.ServiceAddr, sel))
+			} else {
+				m[key] = selectorLoc{runtime: rc.Name, name: attrs.Name}
+			}
+			if v.CmdStorage == nil {
+				continue
+			}
+			files := append([]*cmdpb.FileSpec{d.Setup.CmdFile}, d.Setup.Files...)
+			for _, f := range files {
+				if f.GetHash() == "" {
+					continue
+				}
+				err, ok := cmdFiles[f.Hash]
+				if !ok {
+					err = checkCmdFile(ctx, v.CmdStorage, f.Hash)
+					cmdFiles[f.Hash] = err
+				}
+				if err != nil {
+					report(rc.Name, attrs.Name, fmt.Errorf("cmd file %s: hash %s: %v", f.Path, f.Hash, err))
+				}
+			}
+		}
+		logger.Infof("%s: %d descriptors checked", rc.Name, len(attrsList))
+	}
+	// descriptors are loaded by runtime name prefix, so runtime
+	// also loads descriptors of other runtime that has its name
+	// as prefix.
+	for _, name := range loaded {
+		for _, other := range loaded {
+			if other != name && strings.HasPrefix(other, name) {
+				report(name, "", fmt.Errorf("runtime name is prefix of %s. descriptors in %s are also loaded", other, other))
+			}
+		}
+	}
+	problems = append(problems, dimensionOverlaps(platforms)...)
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Runtime != problems[j].Runtime {
+			return problems[i].Runtime < problems[j].Runtime
+		}
+		return problems[i].Name < problems[j].Name
+	})
+	return problems, nil
+}
+
+func checkCmdFile(ctx context.Context, cmdStorage CmdStorage, hash string) error {
+	rd, err := cmdStorage.Open(ctx, hash)
+	if err != nil {
+		return err
+	}
+	return rd.Close()
+}
+
+// dimensionOverlaps checks dimensions of platform runtime configs.
+// exec_server picks the first platform runtime config (in order of
+// runtime name) whose dimensions cover all dimensions in request, so
+// runtime config is never selected if earlier runtime config has all
+// of its dimensions with the same acl.
+func dimensionOverlaps(platforms []*cmdpb.RuntimeConfig) []Problem {
+	platforms = append([]*cmdpb.RuntimeConfig{}, platforms...)
+	sort.Slice(platforms, func(i, j int) bool {
+		return platforms[i].Name < platforms[j].Name
+	})
+	var problems []Problem
+	for j, rc := range platforms {
+		dims := rc.PlatformRuntimeConfig.GetDimensions()
+		if len(dims) == 0 {
+			problems = append(problems, Problem{
+				Runtime: rc.Name,
+				Err:     errors.New("no dimensions. platform runtime config will never be selected"),
+			})
+			continue
+		}
+		for _, prc := range platforms[:j] {
+			if !proto.Equal(prc.Acl, rc.Acl) {
+				continue
+			}
+			pdims := prc.PlatformRuntimeConfig.GetDimensions()
+			if !coverDimensions(pdims, dims) {
+				continue
+			}
+			problems = append(problems, Problem{
+				Runtime: rc.Name,
+				Err:     fmt.Errorf("dimensions %q overlap with %s %q. platform runtime config will never be selected", dims, prc.Name, pdims),
+			})
+			break
+		}
+	}
+	return problems
+}
+
+// coverDimensions reports whether dims has all dimensions in sub.
+func coverDimensions(dims, sub []string) bool {
+	m := make(map[string]bool)
+	for _, d := range dims {
+		m[d] = true
+	}
+	for _, d := range sub {
+		if !m[d] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,234 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	cmdpb "go.chromium.org/goma/server/proto/command"
+)
+
+type fakeCmdStorage map[string]bool
+
+func (s fakeCmdStorage) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
+	if !s[hash] {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(nil), nil
+}
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile := func(name string, data []byte) {
+		t.Helper()
+		fname := filepath.Join(dir, "toolchain-config", filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(fname), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(fname, data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeDescriptor := func(name string, d *cmdpb.CmdDescriptor) {
+		t.Helper()
+		b, err := proto.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(name, b)
+	}
+	descriptor := func(name, version, hash string) *cmdpb.CmdDescriptor {
+		return &cmdpb.CmdDescriptor{
+			Selector: &cmdpb.Selector{
+				Name:       name,
+				Version:    version,
+				Target:     "x86_64-unknown-linux-gnu",
+				BinaryHash: hash,
+			},
+			Setup: &cmdpb.CmdDescriptor_Setup{
+				CmdFile: &cmdpb.FileSpec{
+					Path:         name,
+					Hash:         hash,
+					IsExecutable: true,
+				},
+				PathType: cmdpb.CmdDescriptor_POSIX,
+			},
+		}
+	}
+
+	writeFile("linux/seq", []byte("1"))
+	writeDescriptor("linux/clang-r1/descriptors/d1", descriptor("clang", "r1", "h1"))
+	writeDescriptor("linux/clang-r1/descriptors/d2", descriptor("clang++", "r1", "h2"))
+	writeDescriptor("linux/gcc-r1/descriptors/d3", descriptor("gcc", "r1", "missing"))
+	writeDescriptor("linux/clang-r2/descriptors/d4", &cmdpb.CmdDescriptor{
+		Selector: &cmdpb.Selector{
+			Name: "clang",
+		},
+	})
+	writeFile("linux/clang-r3/descriptors/d5", []byte("broken"))
+	writeDescriptor("linux/disallowed/descriptors/d6", descriptor("clang", "r0", "h0"))
+
+	writeFile("linux-nsjail/seq", []byte("1"))
+	writeDescriptor("linux-nsjail/clang-r1/descriptors/d1", descriptor("clang", "r1", "h1"))
+
+	writeDescriptor("mac/clang-r1/descriptors/d1", descriptor("clang", "r1", "h1"))
+
+	cm := &cmdpb.ConfigMap{
+		Runtimes: []*cmdpb.RuntimeConfig{
+			{
+				Name:                "linux",
+				DisallowedPrebuilts: []string{"disallowed"},
+				PlatformRuntimeConfig: &cmdpb.PlatformRuntimeConfig{
+					Dimensions: []string{"os:linux", "pool:default"},
+				},
+			},
+			{
+				Name: "linux-nsjail",
+				PlatformRuntimeConfig: &cmdpb.PlatformRuntimeConfig{
+					Dimensions: []string{"os:linux"},
+				},
+			},
+			{
+				Name: "mac",
+			},
+		},
+	}
+	v := Validator{
+		StorageClient: DirStorage{
+			Dir: dir,
+		},
+		Bucket:         "toolchain-config",
+		CmdStorage:     fakeCmdStorage{"h1": true, "h2": true},
+		RemoteexecAddr: "remoteexec:443",
+	}
+	ctx := context.Background()
+	problems, err := v.Validate(ctx, cm)
+	if err != nil {
+		t.Fatalf("Validate(ctx, cm)=_, %v; want nil error", err)
+	}
+	type problem struct {
+		runtime, name string
+	}
+	var got []problem
+	for _, p := range problems {
+		t.Logf("%s", p)
+		got = append(got, problem{runtime: p.Runtime, name: p.Name})
+	}
+	want := []problem{
+		// runtime name is prefix of linux-nsjail.
+		{runtime: "linux"},
+		// selector conflict with linux/clang-r1/descriptors/d1,
+		// loaded by prefix.
+		{runtime: "linux", name: "linux-nsjail/clang-r1/descriptors/d1"},
+		// no setup.
+		{runtime: "linux", name: "linux/clang-r2/descriptors/d4"},
+		// broken descriptor.
+		{runtime: "linux", name: "linux/clang-r3/descriptors/d5"},
+		// missing cmd file.
+		{runtime: "linux", name: "linux/gcc-r1/descriptors/d3"},
+		// dimensions overlap with linux.
+		{runtime: "linux-nsjail"},
+		// selector conflict with linux-nsjail/clang-r1/descriptors/d1
+		// loaded in linux.
+		{runtime: "linux-nsjail", name: "linux-nsjail/clang-r1/descriptors/d1"},
+		// no seq.
+		{runtime: "mac"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate(ctx, cm)=%v; want %v", got, want)
+	}
+}
+
+func TestDimensionOverlaps(t *testing.T) {
+	rc := func(name string, dims ...string) *cmdpb.RuntimeConfig {
+		return &cmdpb.RuntimeConfig{
+			Name: name,
+			PlatformRuntimeConfig: &cmdpb.PlatformRuntimeConfig{
+				Dimensions: dims,
+			},
+		}
+	}
+	for _, tc := range []struct {
+		desc      string
+		platforms []*cmdpb.RuntimeConfig
+		want      []string
+	}{
+		{
+			desc: "disjoint",
+			platforms: []*cmdpb.RuntimeConfig{
+				rc("a", "os:linux"),
+				rc("b", "os:win"),
+			},
+		},
+		{
+			desc: "more specific later",
+			platforms: []*cmdpb.RuntimeConfig{
+				rc("a", "os:linux"),
+				rc("b", "os:linux", "pool:nsjail"),
+			},
+		},
+		{
+			desc: "less specific later",
+			platforms: []*cmdpb.RuntimeConfig{
+				rc("b", "os:linux"),
+				rc("a", "os:linux", "pool:nsjail"),
+			},
+			want: []string{"b"},
+		},
+		{
+			desc: "same dimensions",
+			platforms: []*cmdpb.RuntimeConfig{
+				rc("a", "os:linux"),
+				rc("b", "os:linux"),
+			},
+			want: []string{"b"},
+		},
+		{
+			desc: "different acl",
+			platforms: []*cmdpb.RuntimeConfig{
+				rc("a", "os:linux"),
+				func() *cmdpb.RuntimeConfig {
+					r := rc("b", "os:linux")
+					r.Acl = &cmdpb.ACL{
+						AllowedGroups: []string{"chrome-bot"},
+					}
+					return r
+				}(),
+			},
+		},
+		{
+			desc: "no dimensions",
+			platforms: []*cmdpb.RuntimeConfig{
+				rc("a"),
+			},
+			want: []string{"a"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got []string
+			for _, p := range dimensionOverlaps(tc.platforms) {
+				got = append(got, p.Runtime)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("dimensionOverlaps(...)=%q; want %q", got, tc.want)
+			}
+		})
+	}
+}
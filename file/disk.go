@@ -57,20 +57,10 @@ func toLocal(ctx context.Context, fc filepb.FileServiceClient, blob *gomapb.File
 		size = int64(n)
 
 	case gomapb.FileBlob_FILE_META:
-		// TODO: streaming?
 		for i, hk := range blob.GetHashKey() {
-			cresp, err := fc.LookupFile(ctx, &gomapb.LookupFileReq{
-				HashKey: []string{hk},
-			})
+			chunk, err := lookupChunk(ctx, fc, i, hk)
 			if err != nil {
-				return fmt.Errorf("chunk error: %d: %s: %v", i, hk, err)
-			}
-			if len(cresp.Blob) == 0 || !IsValid(cresp.Blob[0]) {
-				return fmt.Errorf("missing chunk: %d %s", i, hk)
-			}
-			chunk := cresp.Blob[0]
-			if chunk.GetBlobType() != gomapb.FileBlob_FILE_CHUNK {
-				return fmt.Errorf("wrong blob type: %d: %s type=%v", i, hk, chunk.GetBlobType())
+				return err
 			}
 			content := chunk.GetContent()
 			n, err := w.WriteAt(content, chunk.GetOffset())
@@ -122,32 +112,17 @@ func FromReader(ctx context.Context, fc filepb.FileServiceClient, r io.Reader, b
 	blob.BlobType = gomapb.FileBlob_FILE_META.Enum()
 	var offset int64
 	for offset < blob.GetFileSize() {
-		chunk := &gomapb.FileBlob{
-			BlobType: gomapb.FileBlob_FILE_CHUNK.Enum(),
-			FileSize: blob.FileSize,
-			Offset:   proto.Int64(offset),
-		}
-		size := blob.GetFileSize() - offset
-		if size > FileChunkSize {
-			size = FileChunkSize
-		}
-		chunk.Content = make([]byte, size)
-		_, err := io.ReadFull(r, chunk.Content)
+		chunk, err := readChunk(r, blob.GetFileSize(), offset)
 		if err != nil {
 			return err
 		}
+		size := int64(len(chunk.Content))
 		var hk string
 		if fc != nil {
-			cresp, err := fc.StoreFile(ctx, &gomapb.StoreFileReq{
-				Blob: []*gomapb.FileBlob{chunk},
-			})
+			hk, err = storeBlob(ctx, fc, chunk)
 			if err != nil {
-				return err
-			}
-			if len(cresp.HashKey) == 0 || cresp.HashKey[0] == "" {
-				return fmt.Errorf("failed to store file offset=%d size=%d", offset, size)
+				return fmt.Errorf("failed to store file offset=%d size=%d: %v", offset, size, err)
 			}
-			hk = cresp.HashKey[0]
 		} else {
 			hk, err = hash.SHA256Proto(chunk)
 			if err != nil {
@@ -160,14 +135,9 @@ func FromReader(ctx context.Context, fc filepb.FileServiceClient, r io.Reader, b
 	if fc == nil {
 		return nil
 	}
-	cresp, err := fc.StoreFile(ctx, &gomapb.StoreFileReq{
-		Blob: []*gomapb.FileBlob{blob},
-	})
+	_, err := storeBlob(ctx, fc, blob)
 	if err != nil {
-		return err
-	}
-	if len(cresp.HashKey) == 0 || cresp.HashKey[0] == "" {
-		return fmt.Errorf("failed to store file_meta filesize=%d", blob.GetFileSize())
+		return fmt.Errorf("failed to store file_meta filesize=%d: %v", blob.GetFileSize(), err)
 	}
 	return nil
 }
@@ -15,8 +15,10 @@ import (
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 
+	"go.chromium.org/goma/server/auth/enduser"
 	"go.chromium.org/goma/server/hash"
 	"go.chromium.org/goma/server/log"
+	"go.chromium.org/goma/server/quota"
 
 	gomapb "go.chromium.org/goma/server/proto/api"
 	cachepb "go.chromium.org/goma/server/proto/cache"
@@ -35,6 +37,21 @@ type Service struct {
 	filepb.UnimplementedFileServiceServer
 	// Cache is a fileblob storage.
 	Cache cachepb.CacheServiceClient
+
+	// Quota accounts upload and download bytes per group.
+	Quota *quota.Manager
+}
+
+// recordUsage records usage of end user's group in ctx.
+func (s *Service) recordUsage(ctx context.Context, u quota.Usage) {
+	if s.Quota == nil {
+		return
+	}
+	user, ok := enduser.FromContext(ctx)
+	if !ok {
+		return
+	}
+	s.Quota.Record(ctx, user.Group, u)
 }
 
 // StoreFile stores FileBlob.
@@ -53,6 +70,12 @@ func (s *Service) StoreFile(ctx context.Context, req *gomapb.StoreFileReq) (*gom
 	// if it contains one blob only, report error for blob as rpc error.
 	single := len(req.GetBlob()) == 1
 
+	var uploadBytes int64
+	for _, blob := range req.GetBlob() {
+		uploadBytes += int64(len(blob.GetContent()))
+	}
+	s.recordUsage(ctx, quota.Usage{UploadBytes: uploadBytes})
+
 	errg, ctx := errgroup.WithContext(ctx)
 
 	for i, blob := range req.GetBlob() {
@@ -165,5 +188,11 @@ func (s *Service) LookupFile(ctx context.Context, req *gomapb.LookupFileReq) (*g
 	wg.Wait()
 	logger.Debugf("lookup %d blobs %s", len(req.GetHashKey()), time.Since(start))
 
+	var downloadBytes int64
+	for _, blob := range resp.Blob {
+		downloadBytes += int64(len(blob.GetContent()))
+	}
+	s.recordUsage(ctx, quota.Usage{DownloadBytes: downloadBytes})
+
 	return resp, nil
 }
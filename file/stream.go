@@ -0,0 +1,206 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	gomapb "go.chromium.org/goma/server/proto/api"
+	filepb "go.chromium.org/goma/server/proto/file"
+)
+
+// readChunk reads FILE_CHUNK at offset from r.
+func readChunk(r io.Reader, fileSize, offset int64) (*gomapb.FileBlob, error) {
+	size := fileSize - offset
+	if size > FileChunkSize {
+		size = FileChunkSize
+	}
+	chunk := &gomapb.FileBlob{
+		BlobType: gomapb.FileBlob_FILE_CHUNK.Enum(),
+		FileSize: proto.Int64(fileSize),
+		Offset:   proto.Int64(offset),
+		Content:  make([]byte, size),
+	}
+	_, err := io.ReadFull(r, chunk.Content)
+	if err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// storeBlob stores blob in fc, and returns its hash key.
+func storeBlob(ctx context.Context, fc filepb.FileServiceClient, blob *gomapb.FileBlob) (string, error) {
+	resp, err := fc.StoreFile(ctx, &gomapb.StoreFileReq{
+		Blob: []*gomapb.FileBlob{blob},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.HashKey) == 0 || resp.HashKey[0] == "" {
+		return "", errors.New("no hash key in response")
+	}
+	return resp.HashKey[0], nil
+}
+
+// lookupChunk looks up i-th FILE_CHUNK of hash key hk.
+func lookupChunk(ctx context.Context, fc filepb.FileServiceClient, i int, hk string) (*gomapb.FileBlob, error) {
+	resp, err := fc.LookupFile(ctx, &gomapb.LookupFileReq{
+		HashKey: []string{hk},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("chunk error: %d: %s: %v", i, hk, err)
+	}
+	if len(resp.Blob) == 0 || !IsValid(resp.Blob[0]) {
+		return nil, fmt.Errorf("missing chunk: %d %s", i, hk)
+	}
+	chunk := resp.Blob[0]
+	if chunk.GetBlobType() != gomapb.FileBlob_FILE_CHUNK {
+		return nil, fmt.Errorf("wrong blob type: %d: %s type=%v", i, hk, chunk.GetBlobType())
+	}
+	return chunk, nil
+}
+
+// Upload is a resumable upload of a file to file service.
+// Content is stored in FILE_CHUNK as it arrives, so upload can be
+// resumed from Offset when interrupted.
+type Upload struct {
+	// FileSize is total size of the file.
+	FileSize int64
+
+	// HashKeys are hash keys of stored chunks.
+	HashKeys []string
+
+	// HashKey is hash key of the file. It is set when upload
+	// has completed.
+	HashKey string
+}
+
+// Offset returns offset to resume upload.
+func (u *Upload) Offset() int64 {
+	if u.Done() {
+		return u.FileSize
+	}
+	return int64(len(u.HashKeys)) * FileChunkSize
+}
+
+// Done reports whether upload has completed.
+func (u *Upload) Done() bool {
+	return u.HashKey != ""
+}
+
+// Write reads content at Offset from r, and stores it in fc.
+// Content is committed per chunk, so if r ends in the middle of
+// a chunk, the partial chunk is discarded and need to be sent again
+// from Offset.  It returns io.ErrUnexpectedEOF or io.EOF in this case.
+// Once all content is stored, it stores file blob and sets HashKey.
+// File smaller than LargeFileThreshold is stored as a FILE blob
+// when all content is given at once.
+func (u *Upload) Write(ctx context.Context, fc filepb.FileServiceClient, r io.Reader) error {
+	if u.Done() {
+		return nil
+	}
+	if u.FileSize < LargeFileThreshold {
+		blob := &gomapb.FileBlob{
+			BlobType: gomapb.FileBlob_FILE.Enum(),
+			FileSize: proto.Int64(u.FileSize),
+			Content:  make([]byte, u.FileSize),
+		}
+		_, err := io.ReadFull(r, blob.Content)
+		if err != nil {
+			return err
+		}
+		hk, err := storeBlob(ctx, fc, blob)
+		if err != nil {
+			return fmt.Errorf("failed to store file filesize=%d: %v", u.FileSize, err)
+		}
+		u.HashKey = hk
+		return nil
+	}
+	for u.Offset() < u.FileSize {
+		offset := u.Offset()
+		chunk, err := readChunk(r, u.FileSize, offset)
+		if err != nil {
+			return err
+		}
+		hk, err := storeBlob(ctx, fc, chunk)
+		if err != nil {
+			return fmt.Errorf("failed to store file offset=%d size=%d: %v", offset, len(chunk.Content), err)
+		}
+		u.HashKeys = append(u.HashKeys, hk)
+	}
+	hk, err := storeBlob(ctx, fc, &gomapb.FileBlob{
+		BlobType: gomapb.FileBlob_FILE_META.Enum(),
+		FileSize: proto.Int64(u.FileSize),
+		HashKey:  u.HashKeys,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store file_meta filesize=%d: %v", u.FileSize, err)
+	}
+	u.HashKey = hk
+	return nil
+}
+
+// CopyTo writes content of blob from offset to w.
+// If blob is FILE_META, it fetches FILE_CHUNK one by one using fc.
+// It returns number of bytes written.
+func CopyTo(ctx context.Context, fc filepb.FileServiceClient, w io.Writer, blob *gomapb.FileBlob, offset int64) (int64, error) {
+	if offset < 0 || offset > blob.GetFileSize() {
+		return 0, fmt.Errorf("offset %d out of range: size=%d", offset, blob.GetFileSize())
+	}
+	switch blobType := blob.GetBlobType(); blobType {
+	case gomapb.FileBlob_FILE:
+		n, err := w.Write(blob.GetContent()[offset:])
+		return int64(n), err
+
+	case gomapb.FileBlob_FILE_META:
+		hashKeys := blob.GetHashKey()
+		// chunks are FileChunkSize usually, so guess the chunk
+		// that contains offset.
+		start := int(offset / FileChunkSize)
+		if start >= len(hashKeys) {
+			start = 0
+		}
+		var written int64
+		for i := start; i < len(hashKeys); i++ {
+			chunk, err := lookupChunk(ctx, fc, i, hashKeys[i])
+			if err != nil {
+				return written, err
+			}
+			content := chunk.GetContent()
+			if i == start && chunk.GetOffset() > offset {
+				if start == 0 {
+					return written, fmt.Errorf("bad chunk %d: offset %d > %d", i, chunk.GetOffset(), offset)
+				}
+				// wrong guess. scan from the first chunk.
+				start, i = 0, -1
+				continue
+			}
+			end := chunk.GetOffset() + int64(len(content))
+			if end <= offset {
+				continue
+			}
+			if chunk.GetOffset() < offset {
+				content = content[offset-chunk.GetOffset():]
+			}
+			n, err := w.Write(content)
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+		}
+		if offset+written != blob.GetFileSize() {
+			return written, fmt.Errorf("partial written: %d != %d", offset+written, blob.GetFileSize())
+		}
+		return written, nil
+
+	default:
+		return 0, fmt.Errorf("missing blob: %v", blobType)
+	}
+}
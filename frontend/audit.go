@@ -0,0 +1,229 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	"go.chromium.org/goma/server/httprpc"
+	"go.chromium.org/goma/server/log"
+	gomapb "go.chromium.org/goma/server/proto/api"
+)
+
+// AuditEntry is an entry of audit log.
+// Unlike normal logs, it has end user's email as is, so
+// audit log should be stored in access-controlled storage.
+type AuditEntry struct {
+	Time           time.Time `json:"time"`
+	Email          string    `json:"email"`
+	Group          string    `json:"group"`
+	ServiceAccount string    `json:"service_account,omitempty"`
+	Endpoint       string    `json:"endpoint"`
+	RemoteAddr     string    `json:"remote_addr"`
+	// RequestID is compiler_proxy_id of the request.
+	RequestID string `json:"request_id,omitempty"`
+	BuildID   string `json:"build_id,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"`
+}
+
+// AuditExporter exports audit entries.
+type AuditExporter interface {
+	Export(context.Context, AuditEntry) error
+}
+
+// AuditLogger is httprpc.Auditor that records who invoked which endpoint.
+type AuditLogger struct {
+	Exporter AuditExporter
+
+	// Clock is used to get current time. If nil, time.Now is used.
+	Clock func() time.Time
+}
+
+var _ httprpc.Auditor = AuditLogger{}
+
+// Audit records request req with its message msg.
+func (a AuditLogger) Audit(ctx context.Context, req *http.Request, msg proto.Message) {
+	user, ok := enduser.FromContext(ctx)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if a.Clock != nil {
+		now = a.Clock()
+	}
+	entry := AuditEntry{
+		Time:           now,
+		Email:          string(user.Email),
+		Group:          user.Group,
+		ServiceAccount: user.ServiceAccount,
+		Endpoint:       req.URL.Path,
+		RemoteAddr:     httprpc.RemoteAddr(req),
+	}
+	if m, ok := msg.(interface {
+		GetRequesterInfo() *gomapb.RequesterInfo
+	}); ok {
+		entry.RequestID = m.GetRequesterInfo().GetCompilerProxyId()
+		entry.BuildID = m.GetRequesterInfo().GetBuildId()
+	}
+	if span := trace.FromContext(ctx); span != nil {
+		entry.TraceID = span.SpanContext().TraceID.String()
+	}
+	err := a.Exporter.Export(ctx, entry)
+	if err != nil {
+		logger := log.FromContext(ctx)
+		logger.Errorf("failed to export audit log %s: %v", entry.Endpoint, err)
+	}
+}
+
+const (
+	// DefaultAuditFileMaxSize is default max size of an audit log file.
+	DefaultAuditFileMaxSize = 100 * 1024 * 1024
+
+	// DefaultAuditFileMaxBackups is default number of rotated audit log
+	// files to keep.
+	DefaultAuditFileMaxBackups = 10
+)
+
+// AuditFile is AuditExporter that writes entries in local file as
+// JSON lines.
+// The file is created with permission only for the owner,
+// and rotated when it exceeds MaxSize.
+type AuditFile struct {
+	// Filename is audit log filename.
+	// Rotated files have suffix of rotated timestamp.
+	Filename string
+
+	// MaxSize is max size of the file in bytes.
+	// If zero, DefaultAuditFileMaxSize is used.
+	MaxSize int64
+
+	// MaxBackups is max number of rotated files to keep.
+	// If zero, DefaultAuditFileMaxBackups is used.
+	MaxBackups int
+
+	// Clock is used to get current time. If nil, time.Now is used.
+	Clock func() time.Time
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+func (af *AuditFile) now() time.Time {
+	if af.Clock == nil {
+		return time.Now()
+	}
+	return af.Clock()
+}
+
+func (af *AuditFile) maxSize() int64 {
+	if af.MaxSize == 0 {
+		return DefaultAuditFileMaxSize
+	}
+	return af.MaxSize
+}
+
+func (af *AuditFile) maxBackups() int {
+	if af.MaxBackups == 0 {
+		return DefaultAuditFileMaxBackups
+	}
+	return af.MaxBackups
+}
+
+// Export writes entry in the file.
+func (af *AuditFile) Export(ctx context.Context, entry AuditEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	if af.f != nil && af.size+int64(len(b)) > af.maxSize() {
+		err = af.rotate()
+		if err != nil {
+			return err
+		}
+	}
+	if af.f == nil {
+		err = af.open()
+		if err != nil {
+			return err
+		}
+	}
+	n, err := af.f.Write(b)
+	af.size += int64(n)
+	return err
+}
+
+// Close closes the file.
+func (af *AuditFile) Close() error {
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	if af.f == nil {
+		return nil
+	}
+	err := af.f.Close()
+	af.f = nil
+	return err
+}
+
+func (af *AuditFile) open() error {
+	err := os.MkdirAll(filepath.Dir(af.Filename), 0700)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(af.Filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	af.f = f
+	af.size = fi.Size()
+	return nil
+}
+
+func (af *AuditFile) rotate() error {
+	err := af.f.Close()
+	af.f = nil
+	if err != nil {
+		return err
+	}
+	backup := fmt.Sprintf("%s.%s", af.Filename, af.now().UTC().Format("20060102-150405.000000000"))
+	err = os.Rename(af.Filename, backup)
+	if err != nil {
+		return err
+	}
+	backups, err := filepath.Glob(af.Filename + ".*")
+	if err != nil {
+		return err
+	}
+	// timestamp suffix is sorted in time order.
+	sort.Strings(backups)
+	for len(backups) > af.maxBackups() {
+		err = os.Remove(backups[0])
+		if err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
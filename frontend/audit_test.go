@@ -0,0 +1,131 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package frontend
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	gomapb "go.chromium.org/goma/server/proto/api"
+)
+
+type fakeAuditExporter struct {
+	entries []AuditEntry
+}
+
+func (f *fakeAuditExporter) Export(ctx context.Context, entry AuditEntry) error {
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func TestAuditLogger(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	exporter := &fakeAuditExporter{}
+	a := AuditLogger{
+		Exporter: exporter,
+		Clock: func() time.Time {
+			return now
+		},
+	}
+	user := enduser.New("alice@example.com", "users", &oauth2.Token{})
+	user.ServiceAccount = "compute"
+	ctx := enduser.NewContext(context.Background(), user)
+	req := httptest.NewRequest("POST", "/cxx-compiler-service/e", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	a.Audit(ctx, req, &gomapb.ExecReq{
+		RequesterInfo: &gomapb.RequesterInfo{
+			CompilerProxyId: proto.String("host:1/1"),
+			BuildId:         proto.String("build-1"),
+		},
+	})
+	// no enduser.
+	a.Audit(context.Background(), req, &gomapb.ExecReq{})
+
+	want := []AuditEntry{
+		{
+			Time:           now,
+			Email:          "alice@example.com",
+			Group:          "users",
+			ServiceAccount: "compute",
+			Endpoint:       "/cxx-compiler-service/e",
+			RemoteAddr:     "192.0.2.1:1234",
+			RequestID:      "host:1/1",
+			BuildID:        "build-1",
+		},
+	}
+	if diff := cmp.Diff(want, exporter.entries); diff != "" {
+		t.Errorf("audit entries diff -want +got:\n%s", diff)
+	}
+}
+
+func TestAuditFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	af := &AuditFile{
+		Filename:   filepath.Join(dir, "audit.log"),
+		MaxSize:    200,
+		MaxBackups: 2,
+		Clock: func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		},
+	}
+	defer af.Close()
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		err := af.Export(ctx, AuditEntry{
+			Email:    "alice@example.com",
+			Endpoint: "/e",
+		})
+		if err != nil {
+			t.Fatalf("Export %d: %v", i, err)
+		}
+	}
+	fi, err := os.Stat(af.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fi.Mode().Perm(), os.FileMode(0600); got != want {
+		t.Errorf("mode=%v; want=%v", got, want)
+	}
+	if fi.Size() > af.MaxSize {
+		t.Errorf("size=%d; want <= %d", fi.Size(), af.MaxSize)
+	}
+	backups, err := filepath.Glob(af.Filename + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Errorf("backups=%q; want 2 backups", backups)
+	}
+
+	f, err := os.Open(af.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var entry AuditEntry
+		err := json.Unmarshal(s.Bytes(), &entry)
+		if err != nil {
+			t.Errorf("unmarshal %q: %v", s.Text(), err)
+		}
+		if entry.Email != "alice@example.com" {
+			t.Errorf("email=%q; want=%q", entry.Email, "alice@example.com")
+		}
+	}
+}
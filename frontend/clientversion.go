@@ -0,0 +1,172 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	epb "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	"go.chromium.org/goma/server/httprpc"
+	"go.chromium.org/goma/server/log"
+	pb "go.chromium.org/goma/server/proto/frontend"
+)
+
+var (
+	clientVersionViolations = stats.Int64(
+		"go.chromium.org/goma/server/frontend.client_version_violations",
+		"Number of requests violating client version policy",
+		stats.UnitDimensionless)
+
+	// reason is "too_old", "blocked" or "unknown_client".
+	clientVersionReasonKey = tag.MustNewKey("reason")
+	// action is "rejected" or "warned".
+	clientVersionActionKey = tag.MustNewKey("action")
+)
+
+// ClientVersionChecker is an admission controller to check goma
+// client version in user-agent by ClientVersionPolicy.
+type ClientVersionChecker struct {
+	mu     sync.RWMutex
+	policy *pb.ClientVersionPolicy
+}
+
+// Set sets policy in the checker.
+func (c *ClientVersionChecker) Set(ctx context.Context, policy *pb.ClientVersionPolicy) error {
+	if policy.MinCommitTime != nil {
+		err := policy.MinCommitTime.CheckValid()
+		if err != nil {
+			return fmt.Errorf("bad min_commit_time: %v", err)
+		}
+	}
+	for i, h := range policy.GetBlockedCommitHash() {
+		if h == "" {
+			return fmt.Errorf("blocked_commit_hash[%d]: empty", i)
+		}
+		if !isHex(h) {
+			return fmt.Errorf("blocked_commit_hash[%d]: not hex %q", i, h)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.policy = proto.Clone(policy).(*pb.ClientVersionPolicy)
+	logger := log.FromContext(ctx)
+	logger.Infof("client version policy updated: %s", policy)
+	return nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *ClientVersionChecker) getPolicy() *pb.ClientVersionPolicy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.policy
+}
+
+// violation checks user-agent by policy, and returns reason and
+// description of the violation. It returns empty reason if no
+// violation.
+func violation(policy *pb.ClientVersionPolicy, userAgent string) (reason, desc string) {
+	commitHash, commitTime, err := parseUserAgent(userAgent)
+	if err != nil {
+		if policy.RejectUnknownClient {
+			return "unknown_client", fmt.Sprintf("unknown client %q", userAgent)
+		}
+		return "", ""
+	}
+	for _, h := range policy.BlockedCommitHash {
+		if strings.HasPrefix(commitHash, strings.ToLower(h)) {
+			return "blocked", fmt.Sprintf("goma client %s is blocked", commitHash)
+		}
+	}
+	if policy.MinCommitTime != nil {
+		sec, err := strconv.ParseInt(commitTime, 10, 64)
+		if err != nil {
+			return "unknown_client", fmt.Sprintf("bad commit time %q", commitTime)
+		}
+		t := time.Unix(sec, 0)
+		if minTime := policy.MinCommitTime.AsTime(); t.Before(minTime) {
+			return "too_old", fmt.Sprintf("goma client %s@%s is older than %s", commitHash, t.UTC().Format(time.RFC3339), minTime.UTC().Format(time.RFC3339))
+		}
+	}
+	return "", ""
+}
+
+// Admit checks goma client version in user-agent of req.
+// It returns FailedPrecondition error if the client violates the policy,
+// or httprpc.Warning if the policy is warn only.
+func (c *ClientVersionChecker) Admit(req *http.Request) error {
+	policy := c.getPolicy()
+	if policy == nil {
+		return nil
+	}
+	ctx := req.Context()
+	var group string
+	if user, ok := enduser.FromContext(ctx); ok {
+		group = user.Group
+	}
+	for _, g := range policy.ExemptGroupId {
+		if g == group {
+			return nil
+		}
+	}
+	reason, desc := violation(policy, req.Header.Get("User-Agent"))
+	if reason == "" {
+		return nil
+	}
+	msg := desc
+	if policy.Message != "" {
+		msg += ": " + policy.Message
+	}
+	if policy.WarnOnly {
+		recordClientVersionViolation(ctx, reason, "warned")
+		return httprpc.Warning{Message: msg}
+	}
+	recordClientVersionViolation(ctx, reason, "rejected")
+	st, err := status.New(codes.FailedPrecondition, msg).WithDetails(&epb.PreconditionFailure{
+		Violations: []*epb.PreconditionFailure_Violation{
+			{
+				Type:        "CLIENT_VERSION",
+				Subject:     reason,
+				Description: desc,
+			},
+		},
+	})
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, msg)
+	}
+	return st.Err()
+}
+
+func recordClientVersionViolation(ctx context.Context, reason, action string) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(clientVersionReasonKey, reason),
+		tag.Upsert(clientVersionActionKey, action))
+	if err != nil {
+		logger := log.FromContext(ctx)
+		logger.Errorf("failed to record client version violation: %v", err)
+		return
+	}
+	stats.Record(ctx, clientVersionViolations.M(1))
+}
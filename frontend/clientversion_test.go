@@ -0,0 +1,133 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	"go.chromium.org/goma/server/httprpc"
+	pb "go.chromium.org/goma/server/proto/frontend"
+)
+
+const (
+	// 2018-10-12T08:02:23Z
+	oldClient = "compiler-proxy built by chrome-bot at 7f258745e612a85dcc7896ccc09c22785ecccdb8@1539331343 on 2018-10-12T08:06:33.429908Z"
+	// 2021-06-01T00:00:00Z
+	newClient     = "compiler-proxy built by chrome-bot at 3c0d8e5b2a1f4e6d7c8b9a0f1e2d3c4b5a697887@1622505600 on 2021-06-01T00:10:00.000000Z"
+	blockedClient = "compiler-proxy built by chrome-bot at deadbeef2a1f4e6d7c8b9a0f1e2d3c4b5a697887@1622505600 on 2021-06-01T00:10:00.000000Z"
+	unknownClient = "curl/7.64.0"
+)
+
+func TestClientVersionChecker(t *testing.T) {
+	ctx := context.Background()
+	c := &ClientVersionChecker{}
+	err := c.Set(ctx, &pb.ClientVersionPolicy{
+		MinCommitTime:       timestamppb.New(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)),
+		BlockedCommitHash:   []string{"DEADBEEF"},
+		RejectUnknownClient: true,
+		ExemptGroupId:       []string{"legacy"},
+		Message:             "please upgrade goma client",
+	})
+	if err != nil {
+		t.Fatalf("Set=%v; want nil error", err)
+	}
+
+	for _, tc := range []struct {
+		userAgent string
+		group     string
+		want      codes.Code
+	}{
+		{
+			userAgent: newClient,
+			group:     "googler",
+			want:      codes.OK,
+		},
+		{
+			userAgent: oldClient,
+			group:     "googler",
+			want:      codes.FailedPrecondition,
+		},
+		{
+			userAgent: blockedClient,
+			group:     "googler",
+			want:      codes.FailedPrecondition,
+		},
+		{
+			userAgent: unknownClient,
+			group:     "googler",
+			want:      codes.FailedPrecondition,
+		},
+		{
+			userAgent: oldClient,
+			group:     "legacy",
+			want:      codes.OK,
+		},
+	} {
+		req := httptest.NewRequest("POST", "/e", nil)
+		req.Header.Set("User-Agent", tc.userAgent)
+		req = req.WithContext(enduser.NewContext(ctx, enduser.New("user@example.com", tc.group, &oauth2.Token{})))
+		err := c.Admit(req)
+		if got := status.Code(err); got != tc.want {
+			t.Errorf("Admit(%q, group=%q)=%v; want %v", tc.userAgent, tc.group, err, tc.want)
+		}
+	}
+}
+
+func TestClientVersionCheckerWarnOnly(t *testing.T) {
+	ctx := context.Background()
+	c := &ClientVersionChecker{}
+	err := c.Set(ctx, &pb.ClientVersionPolicy{
+		MinCommitTime: timestamppb.New(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)),
+		WarnOnly:      true,
+	})
+	if err != nil {
+		t.Fatalf("Set=%v; want nil error", err)
+	}
+
+	req := httptest.NewRequest("POST", "/e", nil)
+	req.Header.Set("User-Agent", oldClient)
+	err = c.Admit(req)
+	var w httprpc.Warning
+	if !errors.As(err, &w) {
+		t.Errorf("Admit(old client)=%v; want warning", err)
+	}
+
+	req.Header.Set("User-Agent", unknownClient)
+	err = c.Admit(req)
+	if err != nil {
+		t.Errorf("Admit(unknown client)=%v; want nil error", err)
+	}
+}
+
+func TestClientVersionCheckerSetError(t *testing.T) {
+	ctx := context.Background()
+	for _, policy := range []*pb.ClientVersionPolicy{
+		{
+			BlockedCommitHash: []string{""},
+		},
+		{
+			BlockedCommitHash: []string{"not-a-hash"},
+		},
+		{
+			MinCommitTime: &timestamppb.Timestamp{Nanos: -1},
+		},
+	} {
+		c := &ClientVersionChecker{}
+		err := c.Set(ctx, policy)
+		if err == nil {
+			t.Errorf("Set(%s)=nil; want error", policy)
+		}
+	}
+}
@@ -63,6 +63,25 @@ var (
 			Measure:     pingRequests,
 			Aggregation: view.Count(),
 		},
+		{
+			Description: "requests rejected by rate limit",
+			TagKeys: []tag.Key{
+				endpointKey,
+				groupKey,
+				rateLimitKey,
+			},
+			Measure:     rateLimitedRequests,
+			Aggregation: view.Count(),
+		},
+		{
+			Description: "requests violating client version policy",
+			TagKeys: []tag.Key{
+				clientVersionReasonKey,
+				clientVersionActionKey,
+			},
+			Measure:     clientVersionViolations,
+			Aggregation: view.Count(),
+		},
 	}
 )
 
@@ -93,7 +112,7 @@ func checkUserAgent(ctx context.Context, req *http.Request) error {
 			tag.Upsert(userAgentCommitHashKey, "error"),
 			tag.Upsert(userAgentCommitTimeKey, "error"))
 	} else {
-		// TODO: reject requests from too old client? http://b/110381625
+		// client version is checked by ClientVersionChecker.
 		tags = append(tags,
 			tag.Upsert(userAgentCommitHashKey, commitHash),
 			tag.Upsert(userAgentCommitTimeKey, commitTime))
@@ -114,6 +133,7 @@ type Backend interface {
 	StoreFile() http.Handler
 	LookupFile() http.Handler
 	Execlog() http.Handler
+	FileStream() http.Handler
 }
 
 // Frontend represents goma frontend.
@@ -121,6 +141,9 @@ type Frontend struct {
 	AC      httprpc.AdmissionController
 	Backend Backend
 
+	// Settings serves settings request, if set.
+	Settings http.Handler
+
 	TraceLabels map[string]string
 
 	// TODO: health status?
@@ -155,6 +178,10 @@ func Handler(f Frontend) http.Handler {
 	mux.Handle("/s", f.Backend.StoreFile())
 	mux.Handle("/l", f.Backend.LookupFile())
 	mux.Handle("/sl", f.Backend.Execlog())
+	mux.Handle("/fs/", f.Backend.FileStream())
+	if f.Settings != nil {
+		mux.Handle("/settings", f.Settings)
+	}
 	// TODO: /downloadurl etc?
 
 	h := httprpc.AdmissionControl(f.AC, mux)
@@ -0,0 +1,208 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	"go.chromium.org/goma/server/backend"
+	"go.chromium.org/goma/server/httprpc"
+	"go.chromium.org/goma/server/log"
+	"go.chromium.org/goma/server/log/errorreporter"
+	gomapb "go.chromium.org/goma/server/proto/api"
+	execpb "go.chromium.org/goma/server/proto/exec"
+	execlogpb "go.chromium.org/goma/server/proto/execlog"
+	filepb "go.chromium.org/goma/server/proto/file"
+)
+
+// grpcEndpoints maps gRPC method to httprpc endpoint path.
+// endpoint path is used by admission controllers and auditor, so
+// rate limit rules and audit logs are shared with httprpc.
+var grpcEndpoints = map[string]string{
+	"/devtools_goma.ExecService/Exec":       "/e",
+	"/devtools_goma.FileService/StoreFile":  "/s",
+	"/devtools_goma.FileService/LookupFile": "/l",
+	"/devtools_goma.LogService/SaveLog":     "/sl",
+}
+
+// GRPC serves goma API over gRPC.
+//
+// Requests are served by backend.Service of Frontend's backend.
+// Auth, admission control and audit are done by UnaryInterceptor,
+// which must be installed in the grpc server.
+type GRPC struct {
+	execpb.UnimplementedExecServiceServer
+	filepb.UnimplementedFileServiceServer
+	execlogpb.UnimplementedLogServiceServer
+
+	Service backend.Service
+
+	// AC is admission controller checked before Auth.
+	AC httprpc.AdmissionController
+
+	// Auth authenticates the request by "authorization" metadata.
+	Auth func(context.Context) (*enduser.EndUser, error)
+
+	// UserAC is admission controller checked after Auth.
+	// request context has enduser info when UserAC is checked.
+	UserAC httprpc.AdmissionController
+
+	// Auditor records requests after Auth.
+	Auditor httprpc.Auditor
+
+	TraceLabels map[string]string
+}
+
+// NewGRPC creates GRPC for Frontend.
+// Caller should set Auth, UserAC and Auditor, as backend.Option.
+func NewGRPC(f Frontend) *GRPC {
+	return &GRPC{
+		Service:     backend.ServiceOf(f.Backend),
+		AC:          f.AC,
+		TraceLabels: f.TraceLabels,
+	}
+}
+
+// Register registers goma API services in s.
+func (g *GRPC) Register(s *grpc.Server) {
+	execpb.RegisterExecServiceServer(s, g)
+	filepb.RegisterFileServiceServer(s, g)
+	execlogpb.RegisterLogServiceServer(s, g)
+}
+
+// admissionRequest returns http request to check admission controller
+// and auditor for gRPC method.
+// It has endpoint path, metadata as header, and peer address, but no body.
+func admissionRequest(ctx context.Context, path string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, vs := range md {
+		if strings.HasPrefix(k, ":") || strings.HasPrefix(k, "grpc-") {
+			continue
+		}
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		req.RemoteAddr = p.Addr.String()
+	}
+	return req, nil
+}
+
+// admit checks admission of req by ac.
+// If req is admitted with warning, it sends the warning in
+// httprpc.WarningHeader metadata.
+func admit(ctx context.Context, ac httprpc.AdmissionController, req *http.Request) error {
+	err := ac.Admit(req)
+	var warning httprpc.Warning
+	if errors.As(err, &warning) {
+		grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(httprpc.WarningHeader), warning.Message))
+		logger := log.FromContext(ctx)
+		logger.Warnf("admit %s with %v", req.URL.Path, err)
+		return nil
+	}
+	if err != nil {
+		if _, ok := status.FromError(err); !ok {
+			err = status.Error(codes.Unavailable, err.Error())
+		}
+	}
+	return err
+}
+
+// UnaryInterceptor checks auth, admission and audit for goma API
+// requests, and adds trace labels and reports errors.
+func (g *GRPC) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	path, ok := grpcEndpoints[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+	defer errorreporter.Do(nil, &err)
+	span := trace.FromContext(ctx)
+	var attrs []trace.Attribute
+	for k, v := range g.TraceLabels {
+		if v == "" {
+			continue
+		}
+		attrs = append(attrs, trace.StringAttribute(k, v))
+	}
+	span.AddAttributes(attrs...)
+	logger := log.FromContext(ctx)
+	defer func() {
+		switch status.Code(err) {
+		case codes.OK, codes.Canceled:
+			return
+		}
+		logger.Errorf("grpc %s: %v", info.FullMethod, err)
+	}()
+
+	if g.AC != nil {
+		areq, err := admissionRequest(ctx, path)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "admission request: %v", err)
+		}
+		err = admit(ctx, g.AC, areq)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if g.Auth != nil {
+		user, err := g.Auth(ctx)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "auth failed: %v", err)
+		}
+		ctx = enduser.NewContext(ctx, user)
+	}
+	areq, err := admissionRequest(ctx, path)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "admission request: %v", err)
+	}
+	if g.Auditor != nil {
+		msg, _ := req.(proto.Message)
+		g.Auditor.Audit(ctx, areq, msg)
+	}
+	if g.UserAC != nil {
+		err := admit(ctx, g.UserAC, areq)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+// Exec handles Exec request.
+func (g *GRPC) Exec(ctx context.Context, req *gomapb.ExecReq) (*gomapb.ExecResp, error) {
+	return g.Service.Exec(ctx, req)
+}
+
+// StoreFile handles StoreFile request.
+func (g *GRPC) StoreFile(ctx context.Context, req *gomapb.StoreFileReq) (*gomapb.StoreFileResp, error) {
+	return g.Service.StoreFile(ctx, req)
+}
+
+// LookupFile handles LookupFile request.
+func (g *GRPC) LookupFile(ctx context.Context, req *gomapb.LookupFileReq) (*gomapb.LookupFileResp, error) {
+	return g.Service.LookupFile(ctx, req)
+}
+
+// SaveLog handles SaveLog request.
+func (g *GRPC) SaveLog(ctx context.Context, req *gomapb.SaveLogReq) (*gomapb.SaveLogResp, error) {
+	return g.Service.SaveLog(ctx, req)
+}
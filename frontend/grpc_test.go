@@ -0,0 +1,152 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	gomapb "go.chromium.org/goma/server/proto/api"
+)
+
+type fakeBackend struct{}
+
+func (b fakeBackend) Ping() http.Handler       { return http.NotFoundHandler() }
+func (b fakeBackend) Exec() http.Handler       { return http.NotFoundHandler() }
+func (b fakeBackend) ByteStream() http.Handler { return http.NotFoundHandler() }
+func (b fakeBackend) StoreFile() http.Handler  { return http.NotFoundHandler() }
+func (b fakeBackend) LookupFile() http.Handler { return http.NotFoundHandler() }
+func (b fakeBackend) Execlog() http.Handler    { return http.NotFoundHandler() }
+func (b fakeBackend) FileStream() http.Handler { return http.NotFoundHandler() }
+
+// fakeService responds Exec request with email of enduser.
+type fakeService struct{}
+
+func (s fakeService) Exec(ctx context.Context, req *gomapb.ExecReq) (*gomapb.ExecResp, error) {
+	user, ok := enduser.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "no enduser")
+	}
+	return &gomapb.ExecResp{
+		ErrorMessage: []string{string(user.Email)},
+	}, nil
+}
+
+func (s fakeService) StoreFile(ctx context.Context, req *gomapb.StoreFileReq) (*gomapb.StoreFileResp, error) {
+	return nil, status.Error(codes.Unimplemented, "StoreFile")
+}
+
+func (s fakeService) LookupFile(ctx context.Context, req *gomapb.LookupFileReq) (*gomapb.LookupFileResp, error) {
+	return nil, status.Error(codes.Unimplemented, "LookupFile")
+}
+
+func (s fakeService) SaveLog(ctx context.Context, req *gomapb.SaveLogReq) (*gomapb.SaveLogResp, error) {
+	return nil, status.Error(codes.Unimplemented, "SaveLog")
+}
+
+type admissionFunc func(*http.Request) error
+
+func (f admissionFunc) Admit(req *http.Request) error {
+	return f(req)
+}
+
+type fakeAuditor struct {
+	endpoints []string
+	ids       []string
+}
+
+func (a *fakeAuditor) Audit(ctx context.Context, req *http.Request, msg proto.Message) {
+	a.endpoints = append(a.endpoints, req.URL.Path)
+	if m, ok := msg.(*gomapb.ExecReq); ok {
+		a.ids = append(a.ids, m.GetRequesterInfo().GetCompilerProxyId())
+	}
+}
+
+func TestGRPCExec(t *testing.T) {
+	auditor := &fakeAuditor{}
+	g := &GRPC{
+		Service: fakeService{},
+		Auth: func(ctx context.Context) (*enduser.EndUser, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			if v := md.Get("authorization"); len(v) == 0 || v[0] != "Bearer token" {
+				return nil, errors.New("bad token")
+			}
+			return enduser.New("someone@example.com", "user", nil), nil
+		},
+		UserAC: admissionFunc(func(req *http.Request) error {
+			if req.Header.Get("User-Agent") == "blocked" {
+				return status.Error(codes.FailedPrecondition, "blocked")
+			}
+			if _, ok := enduser.FromContext(req.Context()); !ok {
+				return errors.New("no enduser")
+			}
+			return nil
+		}),
+		Auditor: auditor,
+	}
+	info := &grpc.UnaryServerInfo{
+		FullMethod: "/devtools_goma.ExecService/Exec",
+	}
+	exec := func(ctx context.Context, req *gomapb.ExecReq) (*gomapb.ExecResp, error) {
+		resp, err := g.UnaryInterceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return g.Exec(ctx, req.(*gomapb.ExecReq))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resp.(*gomapb.ExecResp), nil
+	}
+	req := &gomapb.ExecReq{
+		RequesterInfo: &gomapb.RequesterInfo{
+			CompilerProxyId: proto.String("compiler-proxy-id"),
+		},
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer token"))
+	resp, err := exec(ctx, req)
+	if err != nil {
+		t.Fatalf("Exec(ctx, req)=%v, %v; want nil error", resp, err)
+	}
+	if got, want := resp.GetErrorMessage(), []string{"someone@example.com"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("Exec(ctx, req).ErrorMessage=%q; want=%q", got, want)
+	}
+	if got, want := auditor.endpoints, []string{"/e"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("audit endpoints=%q; want %q", got, want)
+	}
+	if got, want := auditor.ids, []string{"compiler-proxy-id"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("audit ids=%q; want %q", got, want)
+	}
+
+	resp, err = exec(context.Background(), req)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Exec(ctx without auth, req)=%v, %v; want %v", resp, err, codes.Unauthenticated)
+	}
+
+	blockedCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer token", "user-agent", "blocked"))
+	resp, err = exec(blockedCtx, req)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Exec(blocked ctx, req)=%v, %v; want %v", resp, err, codes.FailedPrecondition)
+	}
+}
+
+func TestGRPCUnsupportedBackend(t *testing.T) {
+	g := NewGRPC(Frontend{
+		Backend: fakeBackend{},
+	})
+	ctx := enduser.NewContext(context.Background(), enduser.New("someone@example.com", "user", nil))
+	_, err := g.LookupFile(ctx, &gomapb.LookupFileReq{})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("LookupFile(ctx, req)=%v; want %v", err, codes.Unimplemented)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	epb "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	"go.chromium.org/goma/server/log"
+	pb "go.chromium.org/goma/server/proto/frontend"
+)
+
+var (
+	rateLimitedRequests = stats.Int64(
+		"go.chromium.org/goma/server/frontend.rate_limited",
+		"Number of requests rejected by rate limit",
+		stats.UnitDimensionless)
+
+	endpointKey  = tag.MustNewKey("endpoint")
+	groupKey     = tag.MustNewKey("group")
+	rateLimitKey = tag.MustNewKey("limit")
+)
+
+// endpointFromPath returns endpoint of request path.
+// It returns false if path is not rate limited endpoint.
+func endpointFromPath(path string) (pb.Endpoint, bool) {
+	switch strings.TrimPrefix(path, PathPrefix[:len(PathPrefix)-1]) {
+	case "/e":
+		return pb.Endpoint_EXEC, true
+	case "/s":
+		return pb.Endpoint_STORE_FILE, true
+	case "/l":
+		return pb.Endpoint_LOOKUP_FILE, true
+	case "/sl":
+		return pb.Endpoint_EXECLOG, true
+	}
+	return pb.Endpoint_ANY_ENDPOINT, false
+}
+
+// tokenBucket is token bucket for rate limit.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func bucketSize(l *pb.RateLimit) float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.Qps))
+}
+
+// refill refills tokens in the bucket at now.
+func (b *tokenBucket) refill(now time.Time, l *pb.RateLimit) {
+	size := bucketSize(l)
+	if b.last.IsZero() {
+		b.tokens = size
+		b.last = now
+		return
+	}
+	elapsed := now.Sub(b.last)
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens = math.Min(size, b.tokens+elapsed.Seconds()*l.Qps)
+	b.last = now
+}
+
+// delay returns duration until a token becomes available.
+func (b *tokenBucket) delay(l *pb.RateLimit) time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	if l.Qps <= 0 {
+		// never refilled.
+		return time.Hour
+	}
+	return time.Duration((1 - b.tokens) / l.Qps * float64(time.Second))
+}
+
+// full reports whether the bucket is full, i.e. idle.
+func (b *tokenBucket) full(l *pb.RateLimit) bool {
+	return b.tokens >= bucketSize(l)
+}
+
+type bucketKey struct {
+	rule int
+	// "user" or "group".
+	kind string
+	// email or group id.
+	id string
+}
+
+// RateLimiter is an admission controller to limit request rate
+// per end user and per group with token buckets.
+// It must be checked after auth, i.e. request context should have
+// enduser info.
+type RateLimiter struct {
+	// Clock is used to get current time. If nil, time.Now is used.
+	Clock func() time.Time
+
+	mu        sync.Mutex
+	config    *pb.RateLimitConfig
+	buckets   map[bucketKey]*tokenBucket
+	lastSweep time.Time
+}
+
+func (r *RateLimiter) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock()
+}
+
+// Set sets config in the rate limiter.
+// It resets all token buckets.
+func (r *RateLimiter) Set(ctx context.Context, config *pb.RateLimitConfig) error {
+	for i, rule := range config.GetRules() {
+		for _, l := range []*pb.RateLimit{rule.PerUser, rule.PerGroup} {
+			if l == nil {
+				continue
+			}
+			if l.Qps < 0 || l.Burst < 0 {
+				return fmt.Errorf("rule[%d]: negative rate limit: %s", i, l)
+			}
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = proto.Clone(config).(*pb.RateLimitConfig)
+	r.buckets = make(map[bucketKey]*tokenBucket)
+	logger := log.FromContext(ctx)
+	logger.Infof("rate limit updated: %d rules", len(config.GetRules()))
+	return nil
+}
+
+func (r *RateLimiter) findRule(endpoint pb.Endpoint, group string) (int, *pb.RateLimitRule) {
+	for i, rule := range r.config.GetRules() {
+		if rule.Endpoint != pb.Endpoint_ANY_ENDPOINT && rule.Endpoint != endpoint {
+			continue
+		}
+		if rule.GroupId != "" && rule.GroupId != group {
+			continue
+		}
+		return i, rule
+	}
+	return -1, nil
+}
+
+func (r *RateLimiter) bucket(key bucketKey) *tokenBucket {
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &tokenBucket{}
+		r.buckets[key] = b
+	}
+	return b
+}
+
+// sweep removes idle buckets. Must be called with r.mu held.
+func (r *RateLimiter) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < time.Minute {
+		return
+	}
+	r.lastSweep = now
+	rules := r.config.GetRules()
+	for key, b := range r.buckets {
+		rule := rules[key.rule]
+		l := rule.PerUser
+		if key.kind == "group" {
+			l = rule.PerGroup
+		}
+		b.refill(now, l)
+		if b.full(l) {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+// Admit checks request rate of end user in req's context.
+// It returns ResourceExhausted error with RetryInfo if request
+// rate exceeds the limit.
+func (r *RateLimiter) Admit(req *http.Request) error {
+	ctx := req.Context()
+	user, ok := enduser.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	endpoint, ok := endpointFromPath(req.URL.Path)
+	if !ok {
+		return nil
+	}
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, rule := r.findRule(endpoint, user.Group)
+	if rule == nil {
+		return nil
+	}
+	r.sweep(now)
+
+	type limit struct {
+		kind   string
+		bucket *tokenBucket
+		rate   *pb.RateLimit
+	}
+	var limits []limit
+	if rule.PerUser != nil {
+		limits = append(limits, limit{
+			kind:   "user",
+			bucket: r.bucket(bucketKey{rule: i, kind: "user", id: string(user.Email)}),
+			rate:   rule.PerUser,
+		})
+	}
+	if rule.PerGroup != nil {
+		limits = append(limits, limit{
+			kind:   "group",
+			bucket: r.bucket(bucketKey{rule: i, kind: "group", id: user.Group}),
+			rate:   rule.PerGroup,
+		})
+	}
+	for _, l := range limits {
+		l.bucket.refill(now, l.rate)
+		delay := l.bucket.delay(l.rate)
+		if delay == 0 {
+			continue
+		}
+		recordRateLimited(ctx, endpoint, user.Group, l.kind)
+		st, err := status.New(codes.ResourceExhausted, fmt.Sprintf("rate limit exceeded: %s", l.kind)).WithDetails(&epb.RetryInfo{
+			RetryDelay: durationpb.New(delay),
+		})
+		if err != nil {
+			return status.Errorf(codes.ResourceExhausted, "rate limit exceeded: %s", l.kind)
+		}
+		return st.Err()
+	}
+	// consume tokens only if all limits allow the request.
+	for _, l := range limits {
+		l.bucket.tokens--
+	}
+	return nil
+}
+
+func recordRateLimited(ctx context.Context, endpoint pb.Endpoint, group, kind string) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(endpointKey, endpoint.String()),
+		tag.Upsert(groupKey, group),
+		tag.Upsert(rateLimitKey, kind))
+	if err != nil {
+		logger := log.FromContext(ctx)
+		logger.Errorf("failed to record rate limited: %v", err)
+		return
+	}
+	stats.Record(ctx, rateLimitedRequests.M(1))
+}
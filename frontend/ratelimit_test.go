@@ -0,0 +1,129 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	epb "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	pb "go.chromium.org/goma/server/proto/frontend"
+)
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &RateLimiter{
+		Clock: func() time.Time {
+			return now
+		},
+	}
+	err := r.Set(ctx, &pb.RateLimitConfig{
+		Rules: []*pb.RateLimitRule{
+			{
+				Endpoint: pb.Endpoint_EXEC,
+				GroupId:  "bots",
+				PerGroup: &pb.RateLimit{
+					Qps:   1,
+					Burst: 3,
+				},
+			},
+			{
+				Endpoint: pb.Endpoint_EXEC,
+				PerUser: &pb.RateLimit{
+					Qps:   1,
+					Burst: 2,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Set=%v; want nil error", err)
+	}
+
+	admit := func(email, group, path string) error {
+		t.Helper()
+		req := httptest.NewRequest("POST", path, nil)
+		req = req.WithContext(enduser.NewContext(ctx, enduser.New(email, group, &oauth2.Token{})))
+		return r.Admit(req)
+	}
+
+	for i := 0; i < 2; i++ {
+		err := admit("alice@example.com", "users", "/e")
+		if err != nil {
+			t.Errorf("admit alice %d: %v; want nil error", i, err)
+		}
+	}
+	err = admit("alice@example.com", "users", "/e")
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("admit alice: %v; want ResourceExhausted", err)
+	}
+	st, _ := status.FromError(err)
+	var delay time.Duration
+	for _, d := range st.Details() {
+		if ri, ok := d.(*epb.RetryInfo); ok {
+			delay = ri.GetRetryDelay().AsDuration()
+		}
+	}
+	if delay != 1*time.Second {
+		t.Errorf("retry delay=%s; want=%s", delay, 1*time.Second)
+	}
+
+	// other user has own bucket.
+	err = admit("bob@example.com", "users", "/e")
+	if err != nil {
+		t.Errorf("admit bob: %v; want nil error", err)
+	}
+	// other endpoint is not limited.
+	err = admit("alice@example.com", "users", "/s")
+	if err != nil {
+		t.Errorf("admit alice /s: %v; want nil error", err)
+	}
+
+	now = now.Add(1 * time.Second)
+	err = admit("alice@example.com", "users", "/e")
+	if err != nil {
+		t.Errorf("admit alice after 1s: %v; want nil error", err)
+	}
+
+	// bots share group bucket.
+	for _, email := range []string{"bot1@example.com", "bot2@example.com", "bot3@example.com"} {
+		err := admit(email, "bots", "/e")
+		if err != nil {
+			t.Errorf("admit %s: %v; want nil error", email, err)
+		}
+	}
+	err = admit("bot4@example.com", "bots", "/e")
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("admit bot4: %v; want ResourceExhausted", err)
+	}
+}
+
+func TestEndpointFromPath(t *testing.T) {
+	for _, tc := range []struct {
+		path   string
+		want   pb.Endpoint
+		wantOK bool
+	}{
+		{path: "/e", want: pb.Endpoint_EXEC, wantOK: true},
+		{path: "/cxx-compiler-service/e", want: pb.Endpoint_EXEC, wantOK: true},
+		{path: "/s", want: pb.Endpoint_STORE_FILE, wantOK: true},
+		{path: "/l", want: pb.Endpoint_LOOKUP_FILE, wantOK: true},
+		{path: "/sl", want: pb.Endpoint_EXECLOG, wantOK: true},
+		{path: "/ping", want: pb.Endpoint_ANY_ENDPOINT, wantOK: false},
+	} {
+		got, ok := endpointFromPath(tc.path)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("endpointFromPath(%q)=%v, %t; want %v, %t", tc.path, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
@@ -52,6 +52,11 @@ func New(ctx context.Context, dir string) (*Watcher, error) {
 	return watcher, nil
 }
 
+// Add adds directory to watch.
+func (w *Watcher) Add(dir string) error {
+	return w.w.Add(dir)
+}
+
 // Close stops watcher.
 func (w *Watcher) Close() error {
 	w.cancel()
@@ -11,9 +11,12 @@ require (
 	cloud.google.com/go/storage v1.18.2
 	cloud.google.com/go/trace v1.0.0 // indirect
 	contrib.go.opencensus.io/exporter/stackdriver v0.13.8
+	github.com/andybalholm/brotli v1.0.3
 	github.com/bazelbuild/remote-apis v0.0.0-20210520160108-3e385366f152
 	github.com/bazelbuild/remote-apis-sdks v0.0.0-20201118210229-b732553f9d45
 	github.com/fsnotify/fsnotify v1.5.1
+	github.com/go-asn1-ber/asn1-ber v1.5.1
+	github.com/go-ldap/ldap/v3 v3.4.1
 	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e
 	github.com/gomodule/redigo v1.8.5
 	github.com/google/go-cmp v0.5.6
@@ -21,6 +24,7 @@ require (
 	github.com/googleapis/gax-go/v2 v2.1.1
 	github.com/googleapis/google-cloud-go-testing v0.0.0-20190904031503-2d24dde44ba5
 	github.com/grpc-ecosystem/go-grpc-middleware v1.3.0
+	github.com/klauspost/compress v1.13.6
 	go.opencensus.io v0.23.0
 	go.uber.org/zap v1.19.1
 	golang.org/x/build v0.0.0-20191031202223-0706ea4fce0c
@@ -31,4 +35,5 @@ require (
 	google.golang.org/genproto v0.0.0-20211019152133-63b7e35f4404
 	google.golang.org/grpc v1.41.0
 	google.golang.org/protobuf v1.27.1
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
@@ -5,7 +5,14 @@
 package httprpc
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+
+	epb "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"go.chromium.org/goma/server/log"
 )
@@ -15,21 +22,98 @@ type AdmissionController interface {
 	Admit(*http.Request) error
 }
 
+// WarningHeader is response header to tell warning to the client.
+const WarningHeader = "X-Goma-Warning"
+
+// Warning is an error returned by AdmissionController to admit
+// the request with warning.
+type Warning struct {
+	Message string
+}
+
+func (w Warning) Error() string {
+	return "warning: " + w.Message
+}
+
+// AdmissionControllers admits a request if all admission controllers
+// admit it.
+type AdmissionControllers []AdmissionController
+
+// Admit checks req by all admission controllers.
+// It returns the first Warning if req is admitted with warnings.
+func (acs AdmissionControllers) Admit(req *http.Request) error {
+	var warning error
+	for _, ac := range acs {
+		if ac == nil {
+			continue
+		}
+		err := ac.Admit(req)
+		var w Warning
+		if errors.As(err, &w) {
+			if warning == nil {
+				warning = err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return warning
+}
+
+// Admit checks req by ac.
+// If req is admitted with Warning, it sets warning message in
+// WarningHeader of w, and returns nil.
+func Admit(ac AdmissionController, w http.ResponseWriter, req *http.Request) error {
+	err := ac.Admit(req)
+	var warning Warning
+	if errors.As(err, &warning) {
+		w.Header().Set(WarningHeader, warning.Message)
+		logger := log.FromContext(req.Context())
+		logger.Warnf("admit %s with %v", req.URL.Path, err)
+		return nil
+	}
+	return err
+}
+
 // AdmissionControl adds admission controller to h.
 func AdmissionControl(ac AdmissionController, h http.Handler) http.Handler {
 	if ac == nil {
 		return h
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		ctx := req.Context()
-		err := ac.Admit(req)
+		err := Admit(ac, w, req)
 		if err != nil {
-			code, msg := httpStatus(err)
-			http.Error(w, msg, code)
-			logger := log.FromContext(ctx)
-			logger.Errorf("deny %s: %d %s: %v", req.URL.Path, code, msg, err)
+			Deny(w, req, err)
 			return
 		}
 		h.ServeHTTP(w, req)
 	})
 }
+
+// Deny writes error response for req denied by admission controller.
+// If err has errdetails.RetryInfo, it sets Retry-After header.
+// If err is FailedPrecondition, its message is sent to the client.
+func Deny(w http.ResponseWriter, req *http.Request, err error) {
+	ctx := req.Context()
+	if st, ok := status.FromError(err); ok {
+		for _, d := range st.Details() {
+			ri, ok := d.(*epb.RetryInfo)
+			if !ok {
+				continue
+			}
+			delay := ri.GetRetryDelay().AsDuration()
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(delay.Seconds())), 10))
+		}
+	}
+	code, msg := httpStatus(err)
+	if status.Code(err) == codes.FailedPrecondition {
+		// client needs to fix it, e.g. upgrade client,
+		// so tell the reason.
+		msg = status.Convert(err).Message()
+	}
+	http.Error(w, msg, code)
+	logger := log.FromContext(ctx)
+	logger.Errorf("deny %s: %d %s: %v", req.URL.Path, code, msg, err)
+}
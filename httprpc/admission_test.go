@@ -0,0 +1,95 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package httprpc
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type admissionFunc func(*http.Request) error
+
+func (f admissionFunc) Admit(req *http.Request) error {
+	return f(req)
+}
+
+func TestAdmissionControl(t *testing.T) {
+	warn := admissionFunc(func(*http.Request) error {
+		return Warning{Message: "client is old"}
+	})
+	deny := admissionFunc(func(*http.Request) error {
+		return status.Error(codes.FailedPrecondition, "client is blocked")
+	})
+	ok := admissionFunc(func(*http.Request) error {
+		return nil
+	})
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	for _, tc := range []struct {
+		desc        string
+		ac          AdmissionController
+		wantCode    int
+		wantWarning string
+		wantBody    string
+	}{
+		{
+			desc:     "ok",
+			ac:       AdmissionControllers{ok, nil},
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+		},
+		{
+			desc:        "warning",
+			ac:          AdmissionControllers{warn, ok},
+			wantCode:    http.StatusOK,
+			wantWarning: "client is old",
+			wantBody:    "ok",
+		},
+		{
+			desc:     "deny",
+			ac:       AdmissionControllers{warn, deny},
+			wantCode: http.StatusBadRequest,
+			wantBody: "client is blocked",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			AdmissionControl(tc.ac, h).ServeHTTP(w, httptest.NewRequest("POST", "/e", nil))
+			if w.Code != tc.wantCode {
+				t.Errorf("code=%d; want=%d", w.Code, tc.wantCode)
+			}
+			if got := w.Header().Get(WarningHeader); got != tc.wantWarning {
+				t.Errorf("warning=%q; want=%q", got, tc.wantWarning)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tc.wantBody {
+				t.Errorf("body=%q; want=%q", got, tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestAdmissionControllersWarning(t *testing.T) {
+	acs := AdmissionControllers{
+		admissionFunc(func(*http.Request) error {
+			return Warning{Message: "first"}
+		}),
+		admissionFunc(func(*http.Request) error {
+			return Warning{Message: "second"}
+		}),
+	}
+	err := acs.Admit(httptest.NewRequest("POST", "/e", nil))
+	var w Warning
+	if !errors.As(err, &w) || w.Message != "first" {
+		t.Errorf("Admit=%v; want warning first", err)
+	}
+}
@@ -6,17 +6,18 @@ package httprpc
 
 import (
 	"bytes"
-	"compress/flate"
-	"compress/gzip"
 	"context"
-	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
 
 	"go.opencensus.io/trace"
+	epb "google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 // Client is httprpc client.
@@ -26,7 +27,8 @@ type Client struct {
 	// endpoint URL.
 	URL string
 
-	// ContentEncoding of the request. "identity", "gzip" or "deflate".
+	// ContentEncoding of the request. "identity", "gzip", "deflate",
+	// "br" or "zstd".
 	// "deflate" uses "deflate" compressed data (RFC1951) without
 	// zlib header, different from RFC7230 says, for histrical reason.
 	// default is "deflate" for backward compatibility.
@@ -41,46 +43,15 @@ func serializeToHTTPRequest(ctx context.Context, url string, req proto.Message,
 	if err != nil {
 		return nil, err
 	}
-	buf := bytes.NewBuffer(nil)
-	switch contentEncoding {
-	case "identity":
-		buf.Write(reqMsg)
-
-	case "deflate":
-		// RFC7230 says deflate coding is "zlib" (RFC1950) containing
-		// "deflate" compressed data (RFC1951).
-		// but goma client just used "deflate" compressed data
-		// for "Content-Encoding: deflate" wrongly.
-		// TODO: use gzip
-		w, err := flate.NewWriter(buf, flate.BestSpeed)
-		if err != nil {
-			return nil, err
-		}
-		_, err = w.Write(reqMsg)
-		if err != nil {
-			return nil, err
-		}
-		err = w.Close()
-		if err != nil {
-			return nil, err
-		}
-
-	case "gzip":
-		w, err := gzip.NewWriterLevel(buf, gzip.BestSpeed)
-		if err != nil {
-			return nil, err
-		}
-		_, err = w.Write(reqMsg)
-		if err != nil {
-			return nil, err
-		}
-		err = w.Close()
-		if err != nil {
-			return nil, err
-		}
-	default:
+	e := encodingFromHeader(contentEncoding)
+	if e == unknownEncoding || e.String() != contentEncoding {
 		return nil, status.Errorf(codes.InvalidArgument, "unsupported content-encoding: %s", contentEncoding)
 	}
+	buf := bytes.NewBuffer(nil)
+	_, err = encode(ctx, e, buf, reqMsg)
+	if err != nil {
+		return nil, err
+	}
 
 	len := int64(len(buf.Bytes()))
 	post, err := http.NewRequest("POST", url, bytes.NewReader(buf.Bytes()))
@@ -90,7 +61,7 @@ func serializeToHTTPRequest(ctx context.Context, url string, req proto.Message,
 	post = post.WithContext(ctx)
 	post.Header.Set("Content-Type", "binary/x-protocol-buffer")
 	post.ContentLength = len
-	post.Header.Set("Accept-Encoding", "gzip, deflate")
+	post.Header.Set("Accept-Encoding", acceptEncodings)
 	post.Header.Set("Content-Encoding", contentEncoding)
 	return post, nil
 }
@@ -145,28 +116,23 @@ func parseFromHTTPResponse(ctx context.Context, response *http.Response, resp pr
 		span.AddAttributes(trace.StringAttribute("goma_error", response.Header.Get("X-Goma-Error")))
 		b, err := ioutil.ReadAll(response.Body)
 
-		return status.Errorf(fromHTTPStatus(response.StatusCode), "%d: %s: %s: %v", response.StatusCode, response.Header.Get("X-Goma-Error"), string(b), err)
-	}
-	var r io.Reader = response.Body
-	switch response.Header.Get("Content-Encoding") {
-	case "identity", "":
-
-	case "gzip":
-		var err error
-		r, err = gzip.NewReader(response.Body)
-		if err != nil {
-			return err
+		st := status.Newf(fromHTTPStatus(response.StatusCode), "%d: %s: %s: %v", response.StatusCode, response.Header.Get("X-Goma-Error"), string(b), err)
+		if delay, ok := retryAfter(response.Header.Get("Retry-After")); ok {
+			// rpc.Retry will honor RetryInfo.
+			dst, err := st.WithDetails(&epb.RetryInfo{
+				RetryDelay: durationpb.New(delay),
+			})
+			if err == nil {
+				st = dst
+			}
 		}
-	case "deflate":
-		// RFC7230 says deflate coding is "zlib" (RFC1950) containing
-		// "deflate" compressed data (RFC1951).
-		// but goma client just used "deflate" compressed data
-		// for "Content-Encoding: deflate" wrongly.
-		r = flate.NewReader(response.Body)
-	default:
+		return st.Err()
+	}
+	e := encodingFromHeader(response.Header.Get("Content-Encoding"))
+	if e == unknownEncoding {
 		return status.Errorf(codes.InvalidArgument, "unknown content-encoding: %s", response.Header.Get("Content-Encoding"))
 	}
-	respMsg, err := ioutil.ReadAll(r)
+	respMsg, err := decode(ctx, e, response.Body)
 	if err != nil {
 		return err
 	}
@@ -177,6 +143,25 @@ func parseFromHTTPResponse(ctx context.Context, response *http.Response, resp pr
 	return nil
 }
 
+// ParseResponse parses response's body into resp.
+// It returns error if response's status code is not 200.
+// It closes response's body.
+func ParseResponse(ctx context.Context, response *http.Response, resp proto.Message) error {
+	return parseFromHTTPResponse(ctx, response, resp)
+}
+
+// retryAfter parses Retry-After header in delay-seconds.
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	sec, err := strconv.Atoi(header)
+	if err != nil || sec < 0 {
+		return 0, false
+	}
+	return time.Duration(sec) * time.Second, true
+}
+
 // Call calls remote services over http.
 func (c *Client) Call(ctx context.Context, req proto.Message, resp proto.Message) error {
 	client := c.Client
@@ -5,11 +5,18 @@
 package httprpc
 
 import (
+	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	epb "google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "go.chromium.org/goma/server/proto/api"
 )
 
 func TestCanonicalCode(t *testing.T) {
@@ -86,3 +93,41 @@ func TestCanonicalCode(t *testing.T) {
 		}
 	}
 }
+
+type denyAdmission struct {
+	delay time.Duration
+}
+
+func (d denyAdmission) Admit(*http.Request) error {
+	st, err := status.New(codes.ResourceExhausted, "rate limit").WithDetails(&epb.RetryInfo{
+		RetryDelay: durationpb.New(d.delay),
+	})
+	if err != nil {
+		return err
+	}
+	return st.Err()
+}
+
+func TestClientRetryInfo(t *testing.T) {
+	s := httptest.NewServer(AdmissionControl(denyAdmission{delay: 1500 * time.Millisecond}, http.NotFoundHandler()))
+	defer s.Close()
+
+	c := &Client{
+		Client: s.Client(),
+		URL:    s.URL,
+	}
+	err := c.Call(context.Background(), &pb.LookupFileReq{}, &pb.LookupFileResp{})
+	st, _ := status.FromError(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("Call=%v; want ResourceExhausted", err)
+	}
+	var delay time.Duration
+	for _, d := range st.Details() {
+		if ri, ok := d.(*epb.RetryInfo); ok {
+			delay = ri.GetRetryDelay().AsDuration()
+		}
+	}
+	if want := 2 * time.Second; delay != want {
+		t.Errorf("retry delay=%s; want=%s", delay, want)
+	}
+}
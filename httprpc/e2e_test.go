@@ -19,7 +19,7 @@ import (
 )
 
 func TestHandlerAndClient(t *testing.T) {
-	for _, contentEncoding := range []string{"identity", "deflate", "gzip"} {
+	for _, contentEncoding := range []string{"identity", "deflate", "gzip", "br", "zstd"} {
 		t.Run(contentEncoding, func(t *testing.T) {
 			resp := &pb.SettingsResp{
 				Settings: &pb.Settings{
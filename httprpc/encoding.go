@@ -0,0 +1,340 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package httprpc
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"go.chromium.org/goma/server/log"
+)
+
+// maxDecodedSize is max size of decoded message, same as max message
+// size of frontend, to protect from decompression bomb.
+const maxDecodedSize = 64 * 1024 * 1024
+
+var (
+	brotliCompressionLevel = brotli.BestSpeed
+	zstdCompressionLevel   = zstd.SpeedFastest
+
+	uncompressedBytes = stats.Int64(
+		"go.chromium.org/goma/server/httprpc.uncompressed_bytes",
+		"Size of message before compression",
+		stats.UnitBytes)
+	compressedBytes = stats.Int64(
+		"go.chromium.org/goma/server/httprpc.compressed_bytes",
+		"Size of message after compression",
+		stats.UnitBytes)
+	codecLatency = stats.Float64(
+		"go.chromium.org/goma/server/httprpc.codec_latency",
+		"Time to encode or decode message",
+		stats.UnitMilliseconds)
+
+	encodingKey = tag.MustNewKey("encoding")
+	// codecOpKey is "encode" or "decode".
+	codecOpKey = tag.MustNewKey("op")
+
+	// DefaultViews are the default views provided by this package.
+	// You need to register the view for data to actually be collected.
+	DefaultViews = []*view.View{
+		{
+			Description: "Size of message before compression",
+			TagKeys: []tag.Key{
+				encodingKey,
+				codecOpKey,
+			},
+			Measure:     uncompressedBytes,
+			Aggregation: view.Sum(),
+		},
+		{
+			Description: "Size of message after compression",
+			TagKeys: []tag.Key{
+				encodingKey,
+				codecOpKey,
+			},
+			Measure:     compressedBytes,
+			Aggregation: view.Sum(),
+		},
+		{
+			Description: "Time to encode or decode message",
+			TagKeys: []tag.Key{
+				encodingKey,
+				codecOpKey,
+			},
+			Measure:     codecLatency,
+			Aggregation: view.Distribution(0.01, 0.02, 0.05, 0.1, 0.2, 0.5, 1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000),
+		},
+	}
+
+	// encoders and decoders are reused, since creating them is
+	// expensive, especially for zstd.
+	deflateWriters sync.Pool
+	gzipWriters    sync.Pool
+	brotliWriters  sync.Pool
+	brotliReaders  sync.Pool
+	zstdEncoders   sync.Pool
+
+	// zstdDecoder is shared by all requests, and used only for
+	// DecodeAll. Pooled decoders would leak goroutines started by
+	// streaming Reset when dropped from the pool, since they are
+	// stopped only by Close.
+	zstdDecoderOnce sync.Once
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderErr  error
+)
+
+func sharedZstdDecoder() (*zstd.Decoder, error) {
+	zstdDecoderOnce.Do(func() {
+		// allow concurrent DecodeAll up to GOMAXPROCS.
+		zstdDecoder, zstdDecoderErr = zstd.NewReader(nil,
+			zstd.WithDecoderConcurrency(runtime.GOMAXPROCS(0)),
+			zstd.WithDecoderMaxMemory(maxDecodedSize))
+	})
+	return zstdDecoder, zstdDecoderErr
+}
+
+// acceptEncodings is value of Accept-Encoding header to say
+// all supported encodings.
+const acceptEncodings = "zstd, br, gzip, deflate"
+
+// encodingFromHeader returns the most preferred encoding in
+// Content-Encoding or Accept-Encoding header.
+// Preference is zstd, br, gzip, deflate, then identity.
+func encodingFromHeader(header string) encodingType {
+	if header == "" {
+		return noEncoding
+	}
+	accepts := make(map[encodingType]bool)
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if i := strings.IndexByte(v, ';'); i >= 0 {
+			param := strings.TrimSpace(v[i+1:])
+			v = strings.TrimSpace(v[:i])
+			if param == "q=0" {
+				continue
+			}
+		}
+		switch v {
+		case "zstd":
+			accepts[encodingZstd] = true
+		case "br":
+			accepts[encodingBrotli] = true
+		case "gzip":
+			accepts[encodingGzip] = true
+		case "deflate":
+			accepts[encodingDeflate] = true
+		case "identity":
+			accepts[noEncoding] = true
+		}
+	}
+	for _, e := range []encodingType{encodingZstd, encodingBrotli, encodingGzip, encodingDeflate, noEncoding} {
+		if accepts[e] {
+			return e
+		}
+	}
+	return unknownEncoding
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.n += n
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += n
+	return n, err
+}
+
+// readAllLimit reads all data from r up to maxDecodedSize.
+func readAllLimit(r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxDecodedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDecodedSize {
+		return nil, status.Errorf(codes.ResourceExhausted, "message larger than max %d", maxDecodedSize)
+	}
+	return data, nil
+}
+
+func writeAndClose(w io.WriteCloser, data []byte) error {
+	_, err := w.Write(data)
+	cerr := w.Close()
+	if err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// encode writes data in w with encoding e.
+// it returns raw data size.
+func encode(ctx context.Context, e encodingType, w io.Writer, data []byte) (int, error) {
+	start := time.Now()
+	cw := &countingWriter{w: w}
+	var err error
+	switch e {
+	case noEncoding, unknownEncoding:
+		e = noEncoding
+		_, err = cw.Write(data)
+	case encodingDeflate:
+		fw, ok := deflateWriters.Get().(*flate.Writer)
+		if ok {
+			fw.Reset(cw)
+		} else {
+			fw, err = flate.NewWriter(cw, deflateCompressionLevel)
+			if err != nil {
+				return 0, err
+			}
+		}
+		err = writeAndClose(fw, data)
+		deflateWriters.Put(fw)
+	case encodingGzip:
+		gw, ok := gzipWriters.Get().(*gzip.Writer)
+		if ok {
+			gw.Reset(cw)
+		} else {
+			gw, err = gzip.NewWriterLevel(cw, gzipCompressionLevel)
+			if err != nil {
+				return 0, err
+			}
+		}
+		err = writeAndClose(gw, data)
+		gzipWriters.Put(gw)
+	case encodingBrotli:
+		bw, ok := brotliWriters.Get().(*brotli.Writer)
+		if ok {
+			bw.Reset(cw)
+		} else {
+			bw = brotli.NewWriterLevel(cw, brotliCompressionLevel)
+		}
+		err = writeAndClose(bw, data)
+		brotliWriters.Put(bw)
+	case encodingZstd:
+		zw, ok := zstdEncoders.Get().(*zstd.Encoder)
+		if ok {
+			zw.Reset(cw)
+		} else {
+			zw, err = zstd.NewWriter(cw,
+				zstd.WithEncoderLevel(zstdCompressionLevel),
+				zstd.WithEncoderConcurrency(1))
+			if err != nil {
+				return 0, err
+			}
+		}
+		err = writeAndClose(zw, data)
+		zstdEncoders.Put(zw)
+	}
+	if err != nil {
+		return 0, err
+	}
+	recordCodec(ctx, e, "encode", len(data), cw.n, time.Since(start))
+	return len(data), nil
+}
+
+// decode reads data from r with encoding e.
+func decode(ctx context.Context, e encodingType, r io.Reader) ([]byte, error) {
+	start := time.Now()
+	cr := &countingReader{r: r}
+	var data []byte
+	var err error
+	switch e {
+	case noEncoding:
+		data, err = readAllLimit(cr)
+	case encodingDeflate:
+		// RFC7230 says deflate coding is "zlib" (RFC1950) containing
+		// "deflate" compressed data (RFC1951).
+		// but goma client just used "deflate" compressed data
+		// for "Content-Encoding: deflate" wrongly.
+		data, err = readAllLimit(flate.NewReader(cr))
+	case encodingGzip:
+		var gr *gzip.Reader
+		gr, err = gzip.NewReader(cr)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "gzip %v", err)
+		}
+		data, err = readAllLimit(gr)
+	case encodingBrotli:
+		br, ok := brotliReaders.Get().(*brotli.Reader)
+		if ok {
+			err = br.Reset(cr)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			br = brotli.NewReader(cr)
+		}
+		data, err = readAllLimit(br)
+		brotliReaders.Put(br)
+	case encodingZstd:
+		var zr *zstd.Decoder
+		zr, err = sharedZstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		var compressed []byte
+		compressed, err = readAllLimit(cr)
+		if err != nil {
+			return nil, err
+		}
+		data, err = zr.DecodeAll(compressed, nil)
+		// decoder checks max memory before decoding last block
+		// only, so check decoded size too.
+		if err == zstd.ErrDecoderSizeExceeded || len(data) > maxDecodedSize {
+			return nil, status.Errorf(codes.ResourceExhausted, "message larger than max %d", maxDecodedSize)
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "zstd %v", err)
+		}
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown encoding: %s", e)
+	}
+	if err != nil {
+		return nil, err
+	}
+	recordCodec(ctx, e, "decode", len(data), cr.n, time.Since(start))
+	return data, nil
+}
+
+func recordCodec(ctx context.Context, e encodingType, op string, raw, compressed int, d time.Duration) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(encodingKey, e.String()),
+		tag.Upsert(codecOpKey, op))
+	if err != nil {
+		logger := log.FromContext(ctx)
+		logger.Errorf("failed to record codec stats: %v", err)
+		return
+	}
+	stats.Record(ctx,
+		uncompressedBytes.M(int64(raw)),
+		compressedBytes.M(int64(compressed)),
+		codecLatency.M(float64(d.Nanoseconds())/1e6))
+}
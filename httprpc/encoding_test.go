@@ -0,0 +1,127 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestEncodingFromHeader(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   encodingType
+	}{
+		{"", noEncoding},
+		{"identity", noEncoding},
+		{"deflate", encodingDeflate},
+		{"gzip", encodingGzip},
+		{"gzip, deflate", encodingGzip},
+		{"deflate, gzip", encodingGzip},
+		{"br", encodingBrotli},
+		{"gzip, br", encodingBrotli},
+		{"zstd", encodingZstd},
+		{"zstd, br, gzip, deflate", encodingZstd},
+		{"zstd;q=0, gzip", encodingGzip},
+		{"gzip;q=0.5, deflate", encodingGzip},
+		{"compress", unknownEncoding},
+	} {
+		if got := encodingFromHeader(tc.header); got != tc.want {
+			t.Errorf("encodingFromHeader(%q)=%v; want=%v", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	ctx := context.Background()
+	data := []byte(strings.Repeat("goma compiler proxy ", 1000))
+	for _, e := range []encodingType{noEncoding, encodingDeflate, encodingGzip, encodingBrotli, encodingZstd} {
+		t.Run(e.String(), func(t *testing.T) {
+			// twice to use pooled encoder/decoder.
+			for i := 0; i < 2; i++ {
+				var buf bytes.Buffer
+				n, err := encode(ctx, e, &buf, data)
+				if err != nil || n != len(data) {
+					t.Fatalf("encode(ctx, %v, buf, data)=%d, %v; want=%d, nil", e, n, err, len(data))
+				}
+				if e != noEncoding && buf.Len() >= len(data) {
+					t.Errorf("encode(ctx, %v, buf, data): size=%d; want < %d", e, buf.Len(), len(data))
+				}
+				got, err := decode(ctx, e, &buf)
+				if err != nil {
+					t.Fatalf("decode(ctx, %v, buf)=_, %v; want nil error", e, err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Errorf("decode(ctx, %v, encode(data))=%q; want=%q", e, got, data)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeTooLarge(t *testing.T) {
+	ctx := context.Background()
+	data := make([]byte, maxDecodedSize+1)
+	for _, e := range []encodingType{noEncoding, encodingDeflate, encodingGzip, encodingBrotli, encodingZstd} {
+		t.Run(e.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			_, err := encode(ctx, e, &buf, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = decode(ctx, e, &buf)
+			if status.Code(err) != codes.ResourceExhausted {
+				t.Errorf("decode(ctx, %v, buf)=_, %v; want %v", e, err, codes.ResourceExhausted)
+			}
+		})
+	}
+}
+
+func TestDecodeZstdNoGoroutineLeak(t *testing.T) {
+	ctx := context.Background()
+	data := []byte(strings.Repeat("goma compiler proxy ", 1000))
+	var buf bytes.Buffer
+	_, err := encode(ctx, encodingZstd, &buf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := buf.Bytes()
+	// decode concurrently, so that pooled decoders (if any) are
+	// created, and dropped by GC.
+	decodeN := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			var wg sync.WaitGroup
+			for j := 0; j < 8; j++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := decode(ctx, encodingZstd, bytes.NewReader(compressed))
+					if err != nil {
+						t.Errorf("decode(ctx, zstd, buf)=_, %v; want nil error", err)
+					}
+				}()
+			}
+			wg.Wait()
+			runtime.GC()
+			runtime.GC()
+		}
+	}
+	// initialize shared decoder.
+	decodeN(1)
+	before := runtime.NumGoroutine()
+	decodeN(20)
+	// allow some slack for test goroutines that may not exit yet.
+	if after := runtime.NumGoroutine(); after > before+8 {
+		t.Errorf("goroutines=%d after decode; want <= %d", after, before+8)
+	}
+}
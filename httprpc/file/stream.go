@@ -0,0 +1,317 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	gomafile "go.chromium.org/goma/server/file"
+	"go.chromium.org/goma/server/httprpc"
+	"go.chromium.org/goma/server/log"
+	pb "go.chromium.org/goma/server/proto/api"
+	filepb "go.chromium.org/goma/server/proto/file"
+)
+
+const (
+	// OffsetHeader is response header for committed offset of upload.
+	OffsetHeader = "X-Goma-Upload-Offset"
+
+	// HashKeyHeader is response header for hash key of uploaded file.
+	HashKeyHeader = "X-Goma-Hash-Key"
+
+	// uploadTTL is how long idle upload session is kept.
+	uploadTTL = 1 * time.Hour
+)
+
+// serverClient is filepb.FileServiceClient calling filepb.FileServiceServer.
+type serverClient struct {
+	s filepb.FileServiceServer
+}
+
+func (c serverClient) StoreFile(ctx context.Context, req *pb.StoreFileReq, opts ...grpc.CallOption) (*pb.StoreFileResp, error) {
+	return c.s.StoreFile(ctx, req)
+}
+
+func (c serverClient) LookupFile(ctx context.Context, req *pb.LookupFileReq, opts ...grpc.CallOption) (*pb.LookupFileResp, error) {
+	return c.s.LookupFile(ctx, req)
+}
+
+type upload struct {
+	mu sync.Mutex
+	gomafile.Upload
+	lastAccess time.Time
+}
+
+// Sessions is a table of upload sessions.
+// It could be shared among stream handlers of the same file service,
+// e.g. to keep upload sessions when the handler is recreated by
+// backend reload.
+type Sessions struct {
+	// clock is used to get current time.
+	clock func() time.Time
+
+	mu sync.Mutex
+	// uploads keeps upload sessions in memory of this server.
+	// Sessions are not shared with other replicas, nor survive
+	// server restart.
+	uploads map[string]*upload
+}
+
+// NewSessions creates new empty upload sessions.
+func NewSessions() *Sessions {
+	return &Sessions{
+		clock:   time.Now,
+		uploads: make(map[string]*upload),
+	}
+}
+
+// streamServer serves streaming upload and download of files.
+type streamServer struct {
+	fc       filepb.FileServiceClient
+	sessions *Sessions
+}
+
+// StreamHandler returns http.Handler to serve streaming upload and
+// download of large files, without splitting them into FILE_META and
+// FILE_CHUNK in client side.
+//
+// Upload:
+//
+//	POST .../uploads/<upload-id>?size=<file-size>&offset=<offset>
+//
+// Body is raw file content from offset.  Content is committed per
+// FILE_CHUNK, and committed offset is returned in X-Goma-Upload-Offset
+// header.  If upload is interrupted, client can get committed offset
+// by HEAD request to the same URL, and resume from the offset.
+// Once all content is uploaded, it responds 200 with hash key of the
+// file in X-Goma-Hash-Key header.  It responds 202 if body ended
+// before the end of the file.  upload-id is chosen by client, and
+// upload session is kept for an hour since last access.
+//
+// Upload sessions are kept in memory of the handler (or Sessions
+// given to StreamHandlerWithSessions), so all requests
+// of an upload must be routed to the same server replica, e.g. by
+// session affinity on load balancer, or by running single replica.
+// If a request reaches other replica (or the server restarted), it
+// responds 404 for HEAD and POST with non-zero offset, and client
+// needs to upload from offset 0.
+//
+// Download:
+//
+//	GET .../blobs/<hash-key>
+//
+// It responds raw file content.  Client can resume download with
+// "Range: bytes=<offset>-" header.
+//
+// Requests are checked by auth, auditor and admission controller
+// in opts, if any.
+func StreamHandler(s filepb.FileServiceServer, opts ...httprpc.HandlerOption) http.Handler {
+	return StreamHandlerWithSessions(s, NewSessions(), opts...)
+}
+
+// StreamHandlerWithSessions is like StreamHandler, but keeps upload
+// sessions in sessions.
+func StreamHandlerWithSessions(s filepb.FileServiceServer, sessions *Sessions, opts ...httprpc.HandlerOption) http.Handler {
+	ss := &streamServer{
+		fc:       serverClient{s: s},
+		sessions: sessions,
+	}
+	opts = append(opts, httprpc.WithStreamAuth())
+	return httprpc.StreamHandler("FileService.Stream", ss.handle, opts...)
+}
+
+func (ss *streamServer) handle(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	p := path.Clean(r.URL.Path)
+	switch {
+	case strings.Contains(p, "/uploads/"):
+		id := p[strings.LastIndex(p, "/uploads/")+len("/uploads/"):]
+		switch r.Method {
+		case http.MethodPost:
+			return ss.upload(ctx, w, r, id)
+		case http.MethodHead:
+			return ss.uploadStatus(ctx, w, id)
+		}
+	case strings.Contains(p, "/blobs/"):
+		hk := p[strings.LastIndex(p, "/blobs/")+len("/blobs/"):]
+		if r.Method == http.MethodGet {
+			return ss.download(ctx, w, r, hk)
+		}
+	default:
+		return status.Errorf(codes.NotFound, "unknown path %s", r.URL.Path)
+	}
+	return status.Errorf(codes.Unimplemented, "method %s not allowed for %s", r.Method, r.URL.Path)
+}
+
+// uploadKey returns key of upload session for id.
+// upload session is per end user.
+func uploadKey(ctx context.Context, id string) string {
+	user, _ := enduser.FromContext(ctx)
+	return string(user.Email) + "/" + id
+}
+
+// get returns upload session for id.
+// If create is true, it creates new session if not exist.
+func (s *Sessions) get(ctx context.Context, id string, create bool) *upload {
+	key := uploadKey(ctx, id)
+	now := s.clock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[key]
+	if !ok {
+		if !create {
+			return nil
+		}
+		for k, u := range s.uploads {
+			if now.Sub(u.lastAccess) > uploadTTL {
+				delete(s.uploads, k)
+			}
+		}
+		u = &upload{}
+		s.uploads[key] = u
+	}
+	u.lastAccess = now
+	return u
+}
+
+func (s *Sessions) finish(ctx context.Context, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uploads, uploadKey(ctx, id))
+}
+
+func queryInt(r *http.Request, name string) (int64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "bad %s=%q", name, v)
+	}
+	return n, nil
+}
+
+func (ss *streamServer) upload(ctx context.Context, w http.ResponseWriter, r *http.Request, id string) error {
+	logger := log.FromContext(ctx)
+	if id == "" {
+		return status.Error(codes.InvalidArgument, "no upload id")
+	}
+	if ce := r.Header.Get("Content-Encoding"); ce != "" && ce != "identity" {
+		return status.Errorf(codes.InvalidArgument, "unsupported content-encoding: %s", ce)
+	}
+	size, err := queryInt(r, "size")
+	if err != nil {
+		return err
+	}
+	offset, err := queryInt(r, "offset")
+	if err != nil {
+		return err
+	}
+	u := ss.sessions.get(ctx, id, offset == 0)
+	if u == nil {
+		return status.Errorf(codes.NotFound, "upload %s not found. restart from offset 0", id)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.HashKeys) == 0 {
+		// nothing committed yet.
+		u.FileSize = size
+	}
+	if u.FileSize != size {
+		return status.Errorf(codes.InvalidArgument, "size mismatch for upload %s: %d != %d", id, size, u.FileSize)
+	}
+	w.Header().Set(OffsetHeader, strconv.FormatInt(u.Offset(), 10))
+	if offset != u.Offset() {
+		return status.Errorf(codes.Aborted, "offset mismatch for upload %s: %d != %d", id, offset, u.Offset())
+	}
+	err = u.Write(ctx, ss.fc, r.Body)
+	w.Header().Set(OffsetHeader, strconv.FormatInt(u.Offset(), 10))
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		logger.Infof("upload %s: partial %d/%d", id, u.Offset(), u.FileSize)
+		w.WriteHeader(http.StatusAccepted)
+		return nil
+	case err != nil:
+		return err
+	}
+	logger.Infof("upload %s: done %s size=%d", id, u.HashKey, u.FileSize)
+	ss.sessions.finish(ctx, id)
+	w.Header().Set(HashKeyHeader, u.HashKey)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (ss *streamServer) uploadStatus(ctx context.Context, w http.ResponseWriter, id string) error {
+	u := ss.sessions.get(ctx, id, false)
+	if u == nil {
+		return status.Errorf(codes.NotFound, "upload %s not found", id)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	w.Header().Set(OffsetHeader, strconv.FormatInt(u.Offset(), 10))
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// rangeOffset parses Range header "bytes=<offset>-".
+func rangeOffset(header string) (int64, error) {
+	if header == "" {
+		return 0, nil
+	}
+	if !strings.HasPrefix(header, "bytes=") || !strings.HasSuffix(header, "-") {
+		return 0, status.Errorf(codes.OutOfRange, "unsupported range %q", header)
+	}
+	offset, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(header, "bytes="), "-"), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, status.Errorf(codes.OutOfRange, "bad range %q", header)
+	}
+	return offset, nil
+}
+
+func (ss *streamServer) download(ctx context.Context, w http.ResponseWriter, r *http.Request, hk string) error {
+	offset, err := rangeOffset(r.Header.Get("Range"))
+	if err != nil {
+		return err
+	}
+	resp, err := ss.fc.LookupFile(ctx, &pb.LookupFileReq{
+		HashKey: []string{hk},
+	})
+	if err != nil {
+		return err
+	}
+	if len(resp.Blob) == 0 || !gomafile.IsValid(resp.Blob[0]) {
+		return status.Errorf(codes.NotFound, "blob %s not found", hk)
+	}
+	blob := resp.Blob[0]
+	if blob.GetBlobType() != pb.FileBlob_FILE && blob.GetBlobType() != pb.FileBlob_FILE_META {
+		return status.Errorf(codes.InvalidArgument, "blob %s is not a file: %v", hk, blob.GetBlobType())
+	}
+	size := blob.GetFileSize()
+	if offset > 0 && offset >= size {
+		return status.Errorf(codes.OutOfRange, "offset %d out of range: size=%d", offset, size)
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(size-offset, 10))
+	if offset > 0 {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, size-1, size))
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	_, err = gomafile.CopyTo(ctx, ss.fc, w, blob, offset)
+	return err
+}
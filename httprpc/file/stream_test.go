@@ -0,0 +1,169 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package file
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	gomafile "go.chromium.org/goma/server/file"
+	pb "go.chromium.org/goma/server/proto/api"
+	filepb "go.chromium.org/goma/server/proto/file"
+)
+
+type fakeFileServer struct {
+	filepb.UnimplementedFileServiceServer
+	c gomafile.LocalCache
+}
+
+func (s fakeFileServer) StoreFile(ctx context.Context, req *pb.StoreFileReq) (*pb.StoreFileResp, error) {
+	return s.c.StoreFile(ctx, req)
+}
+
+func (s fakeFileServer) LookupFile(ctx context.Context, req *pb.LookupFileReq) (*pb.LookupFileResp, error) {
+	return s.c.LookupFile(ctx, req)
+}
+
+func TestStreamHandler(t *testing.T) {
+	s := httptest.NewServer(StreamHandler(fakeFileServer{
+		c: gomafile.LocalCache{
+			Dir: t.TempDir(),
+		},
+	}))
+	defer s.Close()
+
+	content := make([]byte, 2*gomafile.FileChunkSize+1234)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	size := int64(len(content))
+	uploadURL := func(offset int64) string {
+		return fmt.Sprintf("%s/fs/uploads/upload-id?size=%d&offset=%d", s.URL, size, offset)
+	}
+	post := func(offset int64, body []byte) *http.Response {
+		t.Helper()
+		resp, err := http.Post(uploadURL(offset), "application/octet-stream", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// interrupted in the middle of the second chunk.
+	resp := post(0, content[:gomafile.FileChunkSize+100])
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("partial upload: status=%d; want=%d", resp.StatusCode, http.StatusAccepted)
+	}
+	if got, want := resp.Header.Get(OffsetHeader), fmt.Sprint(gomafile.FileChunkSize); got != want {
+		t.Errorf("partial upload: offset=%q; want=%q", got, want)
+	}
+
+	req, err := http.NewRequest(http.MethodHead, uploadURL(0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get(OffsetHeader) != fmt.Sprint(gomafile.FileChunkSize) {
+		t.Errorf("upload status: status=%d offset=%q; want=%d offset=%d", resp.StatusCode, resp.Header.Get(OffsetHeader), http.StatusNoContent, gomafile.FileChunkSize)
+	}
+
+	resp = post(100, content[100:])
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("wrong offset: status=%d; want=%d", resp.StatusCode, http.StatusConflict)
+	}
+
+	resp = post(gomafile.FileChunkSize, content[gomafile.FileChunkSize:])
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("resume upload: status=%d; want=%d", resp.StatusCode, http.StatusOK)
+	}
+	hk := resp.Header.Get(HashKeyHeader)
+	if hk == "" {
+		t.Fatalf("resume upload: no hash key")
+	}
+
+	get := func(rangeHeader string) ([]byte, int) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, s.URL+"/fs/blobs/"+hk, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b, resp.StatusCode
+	}
+	got, code := get("")
+	if code != http.StatusOK || !bytes.Equal(got, content) {
+		t.Errorf("download: status=%d size=%d; want=%d size=%d", code, len(got), http.StatusOK, len(content))
+	}
+	offset := gomafile.FileChunkSize + 10
+	got, code = get(fmt.Sprintf("bytes=%d-", offset))
+	if code != http.StatusPartialContent || !bytes.Equal(got, content[offset:]) {
+		t.Errorf("resume download: status=%d size=%d; want=%d size=%d", code, len(got), http.StatusPartialContent, len(content[offset:]))
+	}
+}
+
+func TestCopyToBadChunkOffset(t *testing.T) {
+	ctx := context.Background()
+	fc := serverClient{s: fakeFileServer{
+		c: gomafile.LocalCache{
+			Dir: t.TempDir(),
+		},
+	}}
+	resp, err := fc.StoreFile(ctx, &pb.StoreFileReq{
+		Blob: []*pb.FileBlob{
+			{
+				BlobType: pb.FileBlob_FILE_CHUNK.Enum(),
+				Offset:   proto.Int64(10),
+				Content:  []byte("chunk"),
+				FileSize: proto.Int64(15),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob := &pb.FileBlob{
+		BlobType: pb.FileBlob_FILE_META.Enum(),
+		FileSize: proto.Int64(15),
+		HashKey:  resp.HashKey,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := gomafile.CopyTo(ctx, fc, ioutil.Discard, blob, 0)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("CopyTo(ctx, fc, w, blob, 0)=nil; want error")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("CopyTo(ctx, fc, w, blob, 0) didn't return")
+	}
+}
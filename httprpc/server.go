@@ -5,11 +5,11 @@
 package httprpc
 
 import (
+	"bytes"
 	"compress/flate"
 	"compress/gzip"
 	"context"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -41,6 +41,8 @@ const (
 	noEncoding encodingType = iota
 	encodingDeflate
 	encodingGzip
+	encodingBrotli
+	encodingZstd
 	unknownEncoding
 )
 
@@ -52,66 +54,83 @@ func (e encodingType) String() string {
 		return "deflate"
 	case encodingGzip:
 		return "gzip"
+	case encodingBrotli:
+		return "br"
+	case encodingZstd:
+		return "zstd"
 	default:
 		return fmt.Sprintf("unknownEncoding[%d]", e)
 	}
 }
 
-func encodingFromHeader(header string) encodingType {
-	switch {
-	case strings.Contains(header, "gzip"):
-		return encodingGzip
-	case strings.Contains(header, "deflate"):
-		return encodingDeflate
-	case header == "", strings.Contains(header, "identity"):
-		return noEncoding
-	default:
-		return unknownEncoding
-	}
-}
-
 func parseFromHTTPServerRequest(ctx context.Context, req *http.Request, msg proto.Message) (int, error) {
 	ctx, span := trace.StartSpan(ctx, "go.chromium.org/goma/server/httprpc.parseFromHTTPServerRequest")
 	defer span.End()
 	contentEncoding := encodingFromHeader(req.Header.Get("Content-Encoding"))
-	var r io.Reader
-	switch contentEncoding {
-	case noEncoding:
-		r = req.Body
-	case encodingDeflate:
-		// RFC7230 says deflate coding is "zlib" (RFC1950) containing
-		// "deflate" compressed data (RFC1951).
-		// but goma client just used "deflate" compressed data
-		// for "Content-Encoding: deflate" wrongly.
-		r = flate.NewReader(req.Body)
-	case encodingGzip:
-		var err error
-		r, err = gzip.NewReader(req.Body)
-		if err != nil {
-			return 0, status.Errorf(codes.InvalidArgument, "gzip %v", err)
-		}
-	case unknownEncoding:
+	if contentEncoding == unknownEncoding {
 		return 0, status.Errorf(codes.InvalidArgument, "unknown encoding: %s", req.Header.Get("Content-Encoding"))
 	}
-	data, err := ioutil.ReadAll(r)
+	data, err := decode(ctx, contentEncoding, req.Body)
 	if err != nil {
 		return 0, err
 	}
 	return len(data), proto.Unmarshal(data, msg)
 }
 
+// PeekRequest parses req's body into msg, and rewinds req's body so
+// that the handler can read the same body again.
+func PeekRequest(ctx context.Context, req *http.Request, msg proto.Message) error {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r := *req
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	_, err = parseFromHTTPServerRequest(ctx, &r, msg)
+	return err
+}
+
+type parsedRequestKey struct{}
+
+// ParseRequest is like PeekRequest, but also returns req with msg in
+// its context, so that Handler uses msg instead of parsing the body
+// again, if msg is the same type as its request message.
+func ParseRequest(ctx context.Context, req *http.Request, msg proto.Message) (*http.Request, error) {
+	err := PeekRequest(ctx, req, msg)
+	if err != nil {
+		return req, err
+	}
+	return req.WithContext(context.WithValue(req.Context(), parsedRequestKey{}, msg)), nil
+}
+
+// parsedRequest returns request message in ctx set by ParseRequest
+// and true, if it is the same type as msg.
+// Otherwise, it returns clone of msg and false.
+func parsedRequest(ctx context.Context, msg proto.Message) (proto.Message, bool) {
+	m, ok := ctx.Value(parsedRequestKey{}).(proto.Message)
+	if !ok || m.ProtoReflect().Descriptor() != msg.ProtoReflect().Descriptor() {
+		return proto.Clone(msg), false
+	}
+	return m, true
+}
+
 // serializeToResponseWriter serialize msg to w.
 // it returns raw message size, so might differ to actual size if compressed.
 func serializeToResponseWriter(ctx context.Context, w http.ResponseWriter, msg proto.Message, acceptEncoding encodingType) (n int, err error) {
 	ctx, span := trace.StartSpan(ctx, "go.chromium.org/goma/server/httprpc.serializeToResponseWriter")
 	defer span.End()
 	w.Header().Set("Content-Type", "binary/x-protocol-buffer")
-	// Accept-Encoding: deflate only if client didn't say gzip,
+	// Accept-Encoding: deflate only if client didn't say gzip or newer,
 	// since old goma client only recognizes "Accept-Encoding: deflate".
-	// TODO: always accept gzip, deflate once new goma client released.
-	if acceptEncoding == encodingGzip {
+	// TODO: always accept all encodings once new goma client released.
+	switch acceptEncoding {
+	case encodingGzip:
 		w.Header().Set("Accept-Encoding", "gzip, deflate")
-	} else {
+	case encodingBrotli, encodingZstd:
+		w.Header().Set("Accept-Encoding", acceptEncodings)
+	default:
 		w.Header().Set("Accept-Encoding", "deflate")
 	}
 
@@ -119,40 +138,14 @@ func serializeToResponseWriter(ctx context.Context, w http.ResponseWriter, msg p
 	if err != nil {
 		return 0, err
 	}
-	var wr io.Writer
-	wr = w
-	if len(resp) > 0 {
-		switch acceptEncoding {
-		case noEncoding, unknownEncoding:
-			wr = w
-			w.Header().Set("Content-Encoding", "identity")
-		case encodingDeflate:
-			wr, err = flate.NewWriter(w, deflateCompressionLevel)
-			if err != nil {
-				return 0, err
-			}
-			defer func() {
-				ferr := wr.(*flate.Writer).Close()
-				if err == nil {
-					err = ferr
-				}
-			}()
-			w.Header().Set("Content-Encoding", "deflate")
-		case encodingGzip:
-			wr, err = gzip.NewWriterLevel(w, gzipCompressionLevel)
-			if err != nil {
-				return 0, err
-			}
-			defer func() {
-				ferr := wr.(*gzip.Writer).Close()
-				if err == nil {
-					err = ferr
-				}
-			}()
-			w.Header().Set("Content-Encoding", "gzip")
-		}
+	if len(resp) == 0 {
+		return 0, nil
 	}
-	return wr.Write(resp)
+	if acceptEncoding == unknownEncoding {
+		acceptEncoding = noEncoding
+	}
+	w.Header().Set("Content-Encoding", acceptEncoding.String())
+	return encode(ctx, acceptEncoding, w, resp)
 }
 
 // RemoteAddr returns http's remote (client) addr.
@@ -176,6 +169,10 @@ type option struct {
 	cluster   string
 	namespace string
 	Auth      Auth
+	ac        AdmissionController
+	auditor   Auditor
+
+	streamAuth bool
 }
 
 // HandlerOption sets option for handler.
@@ -297,6 +294,40 @@ func httpStatus(err error) (int, string) {
 	return hc, msg
 }
 
+// WithAdmissionController sets admission controller to the handler.
+// It is checked after auth, so request context has enduser info.
+func WithAdmissionController(ac AdmissionController) HandlerOption {
+	return func(o *option) {
+		o.ac = ac
+	}
+}
+
+// Auditor records requests for audit.
+type Auditor interface {
+	// Audit records req with its message.
+	// msg is nil for stream handler.
+	// ctx has enduser info.
+	Audit(ctx context.Context, req *http.Request, msg proto.Message)
+}
+
+// WithStreamAuth makes StreamHandler check Auth, Auditor and
+// AdmissionController before calling stream handler.
+// Without it, StreamHandler ignores them, as handlers like bytestream
+// are served without end user auth.
+func WithStreamAuth() HandlerOption {
+	return func(o *option) {
+		o.streamAuth = true
+	}
+}
+
+// WithAuditor sets auditor to the handler.
+// It is called once per request after auth.
+func WithAuditor(a Auditor) HandlerOption {
+	return func(o *option) {
+		o.auditor = a
+	}
+}
+
 // Handler returns http.Handler to serve http rpc handler.
 func Handler(name string, req, resp proto.Message, h func(context.Context, proto.Message) (proto.Message, error), opts ...HandlerOption) http.Handler {
 	opt := &option{
@@ -323,11 +354,13 @@ func Handler(name string, req, resp proto.Message, h func(context.Context, proto
 		)
 		logger := log.FromContext(ctx)
 
-		req := proto.Clone(req)
-
 		// appengine/rp sets Accept-Encoding: gzip?
 		acceptEncoding := encodingFromHeader(r.Header.Get("Accept-Encoding"))
-		_, err := parseFromHTTPServerRequest(ctx, r, req)
+		req, parsed := parsedRequest(ctx, req)
+		var err error
+		if !parsed {
+			_, err = parseFromHTTPServerRequest(ctx, r, req)
+		}
 		if err != nil {
 			code := http.StatusBadRequest
 			http.Error(w, "bad request", code)
@@ -347,6 +380,9 @@ func Handler(name string, req, resp proto.Message, h func(context.Context, proto
 		timeouts := []time.Duration{50 * time.Second, 90 * time.Second, 3 * time.Minute, 5 * time.Minute}
 		var resp proto.Message
 		authOK := false
+		admitted := opt.ac == nil
+		audited := opt.auditor == nil
+		var denyErr error
 		err = opt.retry.Do(ctx, func() error {
 			pctx := ctx
 			ctx, cancel := context.WithTimeout(ctx, timeouts[0])
@@ -369,6 +405,18 @@ func Handler(name string, req, resp proto.Message, h func(context.Context, proto
 				}
 				authOK = true
 			}
+			if !audited {
+				opt.auditor.Audit(ctx, r, req)
+				audited = true
+			}
+			if !admitted {
+				err = Admit(opt.ac, w, r.WithContext(ctx))
+				if err != nil {
+					denyErr = err
+					return nil
+				}
+				admitted = true
+			}
 			resp, err = h(ctx, req)
 			if err != nil {
 				logger.Warnf("handler error %v; ctx.Err()=%v", err, ctx.Err())
@@ -397,6 +445,10 @@ func Handler(name string, req, resp proto.Message, h func(context.Context, proto
 			}
 			return err
 		})
+		if denyErr != nil {
+			Deny(w, r, denyErr)
+			return
+		}
 		if err != nil {
 			span.SetStatus(trace.Status{
 				Code:    int32(grpc.Code(err)),
@@ -424,7 +476,8 @@ func Handler(name string, req, resp proto.Message, h func(context.Context, proto
 	return handler
 }
 
-// Handler returns http.Handler to serve http stream.
+// StreamHandler returns http.Handler to serve http stream.
+// Auth, Auditor and AdmissionController are used only with WithStreamAuth.
 func StreamHandler(name string, h func(ctx context.Context, w http.ResponseWriter, req *http.Request) error, opts ...HandlerOption) http.Handler {
 	opt := &option{
 		timeout: 1 * time.Minute,
@@ -439,6 +492,28 @@ func StreamHandler(name string, h func(ctx context.Context, w http.ResponseWrite
 
 		ctx, span := trace.StartSpan(ctx, "go.chromium.org/goma/server/httprpc.StreamHandler:"+name)
 		defer span.End()
+		logger := log.FromContext(ctx)
+
+		if opt.streamAuth && opt.Auth != nil {
+			var err error
+			ctx, err = opt.Auth.Auth(ctx, r)
+			if err != nil {
+				code := http.StatusUnauthorized
+				http.Error(w, fmt.Sprintf("auth failed %s: %v", RemoteAddr(r), err), code)
+				logger.Errorf("auth error %s: %d %s: %v", r.URL.Path, code, http.StatusText(code), err)
+				return
+			}
+		}
+		if opt.streamAuth && opt.auditor != nil {
+			opt.auditor.Audit(ctx, r, nil)
+		}
+		if opt.streamAuth && opt.ac != nil {
+			err := Admit(opt.ac, w, r.WithContext(ctx))
+			if err != nil {
+				Deny(w, r, err)
+				return
+			}
+		}
 
 		err := h(ctx, w, r)
 		if err != nil {
@@ -448,7 +523,6 @@ func StreamHandler(name string, h func(ctx context.Context, w http.ResponseWrite
 			})
 			code, msg := httpStatus(err)
 			http.Error(w, msg, code)
-			logger := log.FromContext(ctx)
 			logger.Errorf("server error %s: %d %s: %v", r.URL.Path, code, msg, err)
 			return
 		}
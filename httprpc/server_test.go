@@ -5,9 +5,11 @@
 package httprpc
 
 import (
+	"bytes"
 	"compress/flate"
 	"compress/gzip"
 	"context"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -87,3 +89,84 @@ func TestHandler(t *testing.T) {
 		t.Errorf("http.Get err: %v", err)
 	}
 }
+
+func TestParseRequest(t *testing.T) {
+	ctx := context.Background()
+	want := &healthpb.HealthCheckRequest{
+		Service: "foo",
+	}
+	body, err := proto.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/health", bytes.NewReader(body))
+	msg := &healthpb.HealthCheckRequest{}
+	req, err = ParseRequest(ctx, req, msg)
+	if err != nil {
+		t.Fatalf("ParseRequest(req, msg)=_, %v; want nil error", err)
+	}
+	if !proto.Equal(msg, want) {
+		t.Errorf("ParseRequest(req, msg): msg=%v; want=%v", msg, want)
+	}
+
+	var got proto.Message
+	handler := Handler(
+		"Health",
+		&healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{},
+		func(ctx context.Context, req proto.Message) (proto.Message, error) {
+			got = req
+			return &healthpb.HealthCheckResponse{}, nil
+		})
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got != msg {
+		t.Errorf("handler req=%p %v; want parsed msg %p", got, got, msg)
+	}
+
+	// body is still available, e.g. for reverse proxy.
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil || !bytes.Equal(b, body) {
+		t.Errorf("req.Body=%q, %v; want=%q, nil", b, err, body)
+	}
+}
+
+type denyAuth struct{}
+
+func (denyAuth) Auth(ctx context.Context, req *http.Request) (context.Context, error) {
+	return ctx, errors.New("denied")
+}
+
+func TestStreamHandlerAuth(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		opts []HandlerOption
+		want int
+	}{
+		{
+			desc: "no stream auth",
+			opts: []HandlerOption{WithAuth(denyAuth{})},
+			want: http.StatusOK,
+		},
+		{
+			desc: "stream auth",
+			opts: []HandlerOption{WithAuth(denyAuth{}), WithStreamAuth()},
+			want: http.StatusUnauthorized,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			handler := StreamHandler("Stream", func(ctx context.Context, w http.ResponseWriter, req *http.Request) error {
+				return nil
+			}, tc.opts...)
+			s := httptest.NewServer(handler)
+			defer s.Close()
+
+			resp, err := http.Get(s.URL)
+			if err != nil {
+				t.Fatalf("http.Get err: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.want {
+				t.Errorf("http.Get status=%d; want %d", resp.StatusCode, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        v3.19.0
+// source: auth/groups.proto
+
+package auth
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// MembershipGroup defines members of a group used as external group
+// in ACL.
+type MembershipGroup struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// name of the group. it is matched with Group.id in ACL.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// emails of members.
+	// "*@domain" matches any email in the domain.
+	Members []string `protobuf:"bytes,2,rep,name=members,proto3" json:"members,omitempty"`
+	// names of other groups. members of nested groups are also
+	// members of this group.
+	Nested []string `protobuf:"bytes,3,rep,name=nested,proto3" json:"nested,omitempty"`
+}
+
+func (x *MembershipGroup) Reset() {
+	*x = MembershipGroup{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_auth_groups_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MembershipGroup) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MembershipGroup) ProtoMessage() {}
+
+func (x *MembershipGroup) ProtoReflect() protoreflect.Message {
+	mi := &file_auth_groups_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MembershipGroup.ProtoReflect.Descriptor instead.
+func (*MembershipGroup) Descriptor() ([]byte, []int) {
+	return file_auth_groups_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MembershipGroup) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *MembershipGroup) GetMembers() []string {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *MembershipGroup) GetNested() []string {
+	if x != nil {
+		return x.Nested
+	}
+	return nil
+}
+
+// Groups is a list of membership groups, used by local file authdb.
+type Groups struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Groups []*MembershipGroup `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`
+}
+
+func (x *Groups) Reset() {
+	*x = Groups{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_auth_groups_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Groups) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Groups) ProtoMessage() {}
+
+func (x *Groups) ProtoReflect() protoreflect.Message {
+	mi := &file_auth_groups_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Groups.ProtoReflect.Descriptor instead.
+func (*Groups) Descriptor() ([]byte, []int) {
+	return file_auth_groups_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Groups) GetGroups() []*MembershipGroup {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+var File_auth_groups_proto protoreflect.FileDescriptor
+
+var file_auth_groups_proto_rawDesc = []byte{
+	0x0a, 0x11, 0x61, 0x75, 0x74, 0x68, 0x2f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x04, 0x61, 0x75, 0x74, 0x68, 0x22, 0x57, 0x0a, 0x0f, 0x4d, 0x65, 0x6d,
+	0x62, 0x65, 0x72, 0x73, 0x68, 0x69, 0x70, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x12, 0x0a, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x07, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x6e, 0x65,
+	0x73, 0x74, 0x65, 0x64, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x6e, 0x65, 0x73, 0x74,
+	0x65, 0x64, 0x22, 0x37, 0x0a, 0x06, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x12, 0x2d, 0x0a, 0x06,
+	0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x61,
+	0x75, 0x74, 0x68, 0x2e, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x68, 0x69, 0x70, 0x47, 0x72,
+	0x6f, 0x75, 0x70, 0x52, 0x06, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x42, 0x28, 0x5a, 0x26, 0x67,
+	0x6f, 0x2e, 0x63, 0x68, 0x72, 0x6f, 0x6d, 0x69, 0x75, 0x6d, 0x2e, 0x6f, 0x72, 0x67, 0x2f, 0x67,
+	0x6f, 0x6d, 0x61, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2f, 0x61, 0x75, 0x74, 0x68, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_auth_groups_proto_rawDescOnce sync.Once
+	file_auth_groups_proto_rawDescData = file_auth_groups_proto_rawDesc
+)
+
+func file_auth_groups_proto_rawDescGZIP() []byte {
+	file_auth_groups_proto_rawDescOnce.Do(func() {
+		file_auth_groups_proto_rawDescData = protoimpl.X.CompressGZIP(file_auth_groups_proto_rawDescData)
+	})
+	return file_auth_groups_proto_rawDescData
+}
+
+var file_auth_groups_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_auth_groups_proto_goTypes = []interface{}{
+	(*MembershipGroup)(nil), // 0: auth.MembershipGroup
+	(*Groups)(nil),          // 1: auth.Groups
+}
+var file_auth_groups_proto_depIdxs = []int32{
+	0, // 0: auth.Groups.groups:type_name -> auth.MembershipGroup
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_auth_groups_proto_init() }
+func file_auth_groups_proto_init() {
+	if File_auth_groups_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_auth_groups_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MembershipGroup); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_auth_groups_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Groups); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_auth_groups_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_auth_groups_proto_goTypes,
+		DependencyIndexes: file_auth_groups_proto_depIdxs,
+		MessageInfos:      file_auth_groups_proto_msgTypes,
+	}.Build()
+	File_auth_groups_proto = out.File
+	file_auth_groups_proto_rawDesc = nil
+	file_auth_groups_proto_goTypes = nil
+	file_auth_groups_proto_depIdxs = nil
+}
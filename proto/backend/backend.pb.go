@@ -13,6 +13,7 @@ package backend
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
 	reflect "reflect"
 	sync "sync"
 )
@@ -239,6 +240,17 @@ type BackendMapping struct {
 	//	*BackendMapping_HttpRpc
 	//	*BackendMapping_Remote
 	Backend isBackendMapping_Backend `protobuf_oneof:"backend"`
+	// weighted backends for the group. e.g. to send 5% to canary.
+	// backend is selected by weight, sticky per build_id in
+	// requester_info (or compiler_proxy instance if build_id is not
+	// available), so one build won't straddle backends. requests without
+	// requester_info (e.g. file stream) use X-Goma-Build-Id header.
+	// if weighted_backends is specified, backend must not be set.
+	WeightedBackends []*WeightedBackend `protobuf:"bytes,5,rep,name=weighted_backends,json=weightedBackends,proto3" json:"weighted_backends,omitempty"`
+	// fallback backends in order of preference.
+	// they are used when the backend (or all weighted_backends) is
+	// unhealthy. health_check must be set in BackendRule.
+	Fallbacks []*FallbackBackend `protobuf:"bytes,6,rep,name=fallbacks,proto3" json:"fallbacks,omitempty"`
 }
 
 func (x *BackendMapping) Reset() {
@@ -308,6 +320,20 @@ func (x *BackendMapping) GetRemote() *RemoteBackend {
 	return nil
 }
 
+func (x *BackendMapping) GetWeightedBackends() []*WeightedBackend {
+	if x != nil {
+		return x.WeightedBackends
+	}
+	return nil
+}
+
+func (x *BackendMapping) GetFallbacks() []*FallbackBackend {
+	if x != nil {
+		return x.Fallbacks
+	}
+	return nil
+}
+
 type isBackendMapping_Backend interface {
 	isBackendMapping_Backend()
 }
@@ -324,18 +350,287 @@ func (*BackendMapping_HttpRpc) isBackendMapping_Backend() {}
 
 func (*BackendMapping_Remote) isBackendMapping_Backend() {}
 
+type WeightedBackend struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// name of the backend. used in metrics and logs.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// relative weight of the backend.
+	Weight uint32 `protobuf:"varint,2,opt,name=weight,proto3" json:"weight,omitempty"`
+	// Types that are assignable to Backend:
+	//	*WeightedBackend_HttpRpc
+	//	*WeightedBackend_Remote
+	Backend isWeightedBackend_Backend `protobuf_oneof:"backend"`
+}
+
+func (x *WeightedBackend) Reset() {
+	*x = WeightedBackend{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_backend_backend_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WeightedBackend) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WeightedBackend) ProtoMessage() {}
+
+func (x *WeightedBackend) ProtoReflect() protoreflect.Message {
+	mi := &file_backend_backend_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WeightedBackend.ProtoReflect.Descriptor instead.
+func (*WeightedBackend) Descriptor() ([]byte, []int) {
+	return file_backend_backend_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WeightedBackend) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *WeightedBackend) GetWeight() uint32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (m *WeightedBackend) GetBackend() isWeightedBackend_Backend {
+	if m != nil {
+		return m.Backend
+	}
+	return nil
+}
+
+func (x *WeightedBackend) GetHttpRpc() *HttpRpcBackend {
+	if x, ok := x.GetBackend().(*WeightedBackend_HttpRpc); ok {
+		return x.HttpRpc
+	}
+	return nil
+}
+
+func (x *WeightedBackend) GetRemote() *RemoteBackend {
+	if x, ok := x.GetBackend().(*WeightedBackend_Remote); ok {
+		return x.Remote
+	}
+	return nil
+}
+
+type isWeightedBackend_Backend interface {
+	isWeightedBackend_Backend()
+}
+
+type WeightedBackend_HttpRpc struct {
+	HttpRpc *HttpRpcBackend `protobuf:"bytes,3,opt,name=http_rpc,json=httpRpc,proto3,oneof"`
+}
+
+type WeightedBackend_Remote struct {
+	Remote *RemoteBackend `protobuf:"bytes,4,opt,name=remote,proto3,oneof"`
+}
+
+func (*WeightedBackend_HttpRpc) isWeightedBackend_Backend() {}
+
+func (*WeightedBackend_Remote) isWeightedBackend_Backend() {}
+
+type FallbackBackend struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// name of the backend. used in metrics, logs and status page.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Types that are assignable to Backend:
+	//	*FallbackBackend_HttpRpc
+	//	*FallbackBackend_Remote
+	Backend isFallbackBackend_Backend `protobuf_oneof:"backend"`
+}
+
+func (x *FallbackBackend) Reset() {
+	*x = FallbackBackend{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_backend_backend_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FallbackBackend) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FallbackBackend) ProtoMessage() {}
+
+func (x *FallbackBackend) ProtoReflect() protoreflect.Message {
+	mi := &file_backend_backend_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FallbackBackend.ProtoReflect.Descriptor instead.
+func (*FallbackBackend) Descriptor() ([]byte, []int) {
+	return file_backend_backend_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *FallbackBackend) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (m *FallbackBackend) GetBackend() isFallbackBackend_Backend {
+	if m != nil {
+		return m.Backend
+	}
+	return nil
+}
+
+func (x *FallbackBackend) GetHttpRpc() *HttpRpcBackend {
+	if x, ok := x.GetBackend().(*FallbackBackend_HttpRpc); ok {
+		return x.HttpRpc
+	}
+	return nil
+}
+
+func (x *FallbackBackend) GetRemote() *RemoteBackend {
+	if x, ok := x.GetBackend().(*FallbackBackend_Remote); ok {
+		return x.Remote
+	}
+	return nil
+}
+
+type isFallbackBackend_Backend interface {
+	isFallbackBackend_Backend()
+}
+
+type FallbackBackend_HttpRpc struct {
+	HttpRpc *HttpRpcBackend `protobuf:"bytes,2,opt,name=http_rpc,json=httpRpc,proto3,oneof"`
+}
+
+type FallbackBackend_Remote struct {
+	Remote *RemoteBackend `protobuf:"bytes,3,opt,name=remote,proto3,oneof"`
+}
+
+func (*FallbackBackend_HttpRpc) isFallbackBackend_Backend() {}
+
+func (*FallbackBackend_Remote) isFallbackBackend_Backend() {}
+
+type HealthCheck struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// interval to probe backends. default 10 seconds.
+	Interval *durationpb.Duration `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`
+	// timeout of each probe. default 5 seconds.
+	Timeout *durationpb.Duration `protobuf:"bytes,2,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	// number of consecutive failures to eject the backend. default 3.
+	UnhealthyThreshold int32 `protobuf:"varint,3,opt,name=unhealthy_threshold,json=unhealthyThreshold,proto3" json:"unhealthy_threshold,omitempty"`
+	// number of consecutive successes to restore the ejected backend.
+	// default 2.
+	HealthyThreshold int32 `protobuf:"varint,4,opt,name=healthy_threshold,json=healthyThreshold,proto3" json:"healthy_threshold,omitempty"`
+}
+
+func (x *HealthCheck) Reset() {
+	*x = HealthCheck{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_backend_backend_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HealthCheck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthCheck) ProtoMessage() {}
+
+func (x *HealthCheck) ProtoReflect() protoreflect.Message {
+	mi := &file_backend_backend_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthCheck.ProtoReflect.Descriptor instead.
+func (*HealthCheck) Descriptor() ([]byte, []int) {
+	return file_backend_backend_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *HealthCheck) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+func (x *HealthCheck) GetTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.Timeout
+	}
+	return nil
+}
+
+func (x *HealthCheck) GetUnhealthyThreshold() int32 {
+	if x != nil {
+		return x.UnhealthyThreshold
+	}
+	return 0
+}
+
+func (x *HealthCheck) GetHealthyThreshold() int32 {
+	if x != nil {
+		return x.HealthyThreshold
+	}
+	return 0
+}
+
 type BackendRule struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
 	Backends []*BackendMapping `protobuf:"bytes,1,rep,name=backends,proto3" json:"backends,omitempty"`
+	// health check of backends.
+	// if not set, all backends are considered as healthy.
+	HealthCheck *HealthCheck `protobuf:"bytes,2,opt,name=health_check,json=healthCheck,proto3" json:"health_check,omitempty"`
+	// shadow backend to mirror Exec requests to.
+	// used to validate new exec_server version or RBE instance.
+	Shadow *ShadowBackend `protobuf:"bytes,3,opt,name=shadow,proto3" json:"shadow,omitempty"`
 }
 
 func (x *BackendRule) Reset() {
 	*x = BackendRule{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_backend_backend_proto_msgTypes[4]
+		mi := &file_backend_backend_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -348,7 +643,7 @@ func (x *BackendRule) String() string {
 func (*BackendRule) ProtoMessage() {}
 
 func (x *BackendRule) ProtoReflect() protoreflect.Message {
-	mi := &file_backend_backend_proto_msgTypes[4]
+	mi := &file_backend_backend_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -361,7 +656,7 @@ func (x *BackendRule) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BackendRule.ProtoReflect.Descriptor instead.
 func (*BackendRule) Descriptor() ([]byte, []int) {
-	return file_backend_backend_proto_rawDescGZIP(), []int{4}
+	return file_backend_backend_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *BackendRule) GetBackends() []*BackendMapping {
@@ -371,6 +666,131 @@ func (x *BackendRule) GetBackends() []*BackendMapping {
 	return nil
 }
 
+func (x *BackendRule) GetHealthCheck() *HealthCheck {
+	if x != nil {
+		return x.HealthCheck
+	}
+	return nil
+}
+
+func (x *BackendRule) GetShadow() *ShadowBackend {
+	if x != nil {
+		return x.Shadow
+	}
+	return nil
+}
+
+// ShadowBackend receives sampled copy of Exec requests.
+// The shadow's response is compared with the primary's response,
+// and discrepancies are logged and counted.  Only the primary's
+// response is returned to the client.
+type ShadowBackend struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// name of the backend. used in metrics and logs.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Types that are assignable to Backend:
+	//	*ShadowBackend_HttpRpc
+	//	*ShadowBackend_Remote
+	Backend isShadowBackend_Backend `protobuf_oneof:"backend"`
+	// ratio of Exec requests to mirror, in [0, 1].
+	SampleRate float64 `protobuf:"fixed64,4,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	// timeout of a shadow request.  default 5 minutes.
+	Timeout *durationpb.Duration `protobuf:"bytes,5,opt,name=timeout,proto3" json:"timeout,omitempty"`
+}
+
+func (x *ShadowBackend) Reset() {
+	*x = ShadowBackend{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_backend_backend_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ShadowBackend) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShadowBackend) ProtoMessage() {}
+
+func (x *ShadowBackend) ProtoReflect() protoreflect.Message {
+	mi := &file_backend_backend_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShadowBackend.ProtoReflect.Descriptor instead.
+func (*ShadowBackend) Descriptor() ([]byte, []int) {
+	return file_backend_backend_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ShadowBackend) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (m *ShadowBackend) GetBackend() isShadowBackend_Backend {
+	if m != nil {
+		return m.Backend
+	}
+	return nil
+}
+
+func (x *ShadowBackend) GetHttpRpc() *HttpRpcBackend {
+	if x, ok := x.GetBackend().(*ShadowBackend_HttpRpc); ok {
+		return x.HttpRpc
+	}
+	return nil
+}
+
+func (x *ShadowBackend) GetRemote() *RemoteBackend {
+	if x, ok := x.GetBackend().(*ShadowBackend_Remote); ok {
+		return x.Remote
+	}
+	return nil
+}
+
+func (x *ShadowBackend) GetSampleRate() float64 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *ShadowBackend) GetTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.Timeout
+	}
+	return nil
+}
+
+type isShadowBackend_Backend interface {
+	isShadowBackend_Backend()
+}
+
+type ShadowBackend_HttpRpc struct {
+	HttpRpc *HttpRpcBackend `protobuf:"bytes,2,opt,name=http_rpc,json=httpRpc,proto3,oneof"`
+}
+
+type ShadowBackend_Remote struct {
+	Remote *RemoteBackend `protobuf:"bytes,3,opt,name=remote,proto3,oneof"`
+}
+
+func (*ShadowBackend_HttpRpc) isShadowBackend_Backend() {}
+
+func (*ShadowBackend_Remote) isShadowBackend_Backend() {}
+
 type BackendConfig struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -387,7 +807,7 @@ type BackendConfig struct {
 func (x *BackendConfig) Reset() {
 	*x = BackendConfig{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_backend_backend_proto_msgTypes[5]
+		mi := &file_backend_backend_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -400,7 +820,7 @@ func (x *BackendConfig) String() string {
 func (*BackendConfig) ProtoMessage() {}
 
 func (x *BackendConfig) ProtoReflect() protoreflect.Message {
-	mi := &file_backend_backend_proto_msgTypes[5]
+	mi := &file_backend_backend_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -413,7 +833,7 @@ func (x *BackendConfig) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BackendConfig.ProtoReflect.Descriptor instead.
 func (*BackendConfig) Descriptor() ([]byte, []int) {
-	return file_backend_backend_proto_rawDescGZIP(), []int{5}
+	return file_backend_backend_proto_rawDescGZIP(), []int{9}
 }
 
 func (m *BackendConfig) GetBackend() isBackendConfig_Backend {
@@ -495,7 +915,7 @@ type LocalBackend_TraceOption struct {
 func (x *LocalBackend_TraceOption) Reset() {
 	*x = LocalBackend_TraceOption{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_backend_backend_proto_msgTypes[6]
+		mi := &file_backend_backend_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -508,7 +928,7 @@ func (x *LocalBackend_TraceOption) String() string {
 func (*LocalBackend_TraceOption) ProtoMessage() {}
 
 func (x *LocalBackend_TraceOption) ProtoReflect() protoreflect.Message {
-	mi := &file_backend_backend_proto_msgTypes[6]
+	mi := &file_backend_backend_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -543,6 +963,8 @@ var File_backend_backend_proto protoreflect.FileDescriptor
 var file_backend_backend_proto_rawDesc = []byte{
 	0x0a, 0x15, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2f, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e,
 	0x64, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64,
+	0x1a, 0x1e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2f, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 	0x22, 0xa5, 0x02, 0x0a, 0x0c, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e,
 	0x64, 0x12, 0x1b, 0x0a, 0x09, 0x65, 0x78, 0x65, 0x63, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x18, 0x01,
 	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x65, 0x78, 0x65, 0x63, 0x41, 0x64, 0x64, 0x72, 0x12, 0x1b,
@@ -569,7 +991,7 @@ var file_backend_backend_proto_rawDesc = []byte{
 	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x20, 0x0a,
 	0x0c, 0x61, 0x70, 0x69, 0x5f, 0x6b, 0x65, 0x79, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20,
 	0x01, 0x28, 0x09, 0x52, 0x0a, 0x61, 0x70, 0x69, 0x4b, 0x65, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x22,
-	0xc1, 0x01, 0x0a, 0x0e, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x4d, 0x61, 0x70, 0x70, 0x69,
+	0xc0, 0x02, 0x0a, 0x0e, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x4d, 0x61, 0x70, 0x70, 0x69,
 	0x6e, 0x67, 0x12, 0x19, 0x0a, 0x08, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x5f, 0x69, 0x64, 0x18, 0x01,
 	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x49, 0x64, 0x12, 0x21, 0x0a,
 	0x0c, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x18, 0x04, 0x20,
@@ -580,12 +1002,76 @@ var file_backend_backend_proto_rawDesc = []byte{
 	0x74, 0x74, 0x70, 0x52, 0x70, 0x63, 0x12, 0x30, 0x0a, 0x06, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65,
 	0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64,
 	0x2e, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x48, 0x00,
-	0x52, 0x06, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x42, 0x09, 0x0a, 0x07, 0x62, 0x61, 0x63, 0x6b,
-	0x65, 0x6e, 0x64, 0x22, 0x42, 0x0a, 0x0b, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x75,
-	0x6c, 0x65, 0x12, 0x33, 0x0a, 0x08, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x73, 0x18, 0x01,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2e, 0x42,
-	0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x52, 0x08, 0x62,
-	0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x73, 0x22, 0xdd, 0x01, 0x0a, 0x0d, 0x42, 0x61, 0x63, 0x6b,
+	0x52, 0x06, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x12, 0x45, 0x0a, 0x11, 0x77, 0x65, 0x69, 0x67,
+	0x68, 0x74, 0x65, 0x64, 0x5f, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x73, 0x18, 0x05, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2e, 0x57, 0x65,
+	0x69, 0x67, 0x68, 0x74, 0x65, 0x64, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x10, 0x77,
+	0x65, 0x69, 0x67, 0x68, 0x74, 0x65, 0x64, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x73, 0x12,
+	0x36, 0x0a, 0x09, 0x66, 0x61, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x73, 0x18, 0x06, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x18, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2e, 0x46, 0x61, 0x6c,
+	0x6c, 0x62, 0x61, 0x63, 0x6b, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x09, 0x66, 0x61,
+	0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x73, 0x42, 0x09, 0x0a, 0x07, 0x62, 0x61, 0x63, 0x6b, 0x65,
+	0x6e, 0x64, 0x22, 0xb0, 0x01, 0x0a, 0x0f, 0x57, 0x65, 0x69, 0x67, 0x68, 0x74, 0x65, 0x64, 0x42,
+	0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x77, 0x65,
+	0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x77, 0x65, 0x69, 0x67,
+	0x68, 0x74, 0x12, 0x34, 0x0a, 0x08, 0x68, 0x74, 0x74, 0x70, 0x5f, 0x72, 0x70, 0x63, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2e, 0x48,
+	0x74, 0x74, 0x70, 0x52, 0x70, 0x63, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x48, 0x00, 0x52,
+	0x07, 0x68, 0x74, 0x74, 0x70, 0x52, 0x70, 0x63, 0x12, 0x30, 0x0a, 0x06, 0x72, 0x65, 0x6d, 0x6f,
+	0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65,
+	0x6e, 0x64, 0x2e, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64,
+	0x48, 0x00, 0x52, 0x06, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x42, 0x09, 0x0a, 0x07, 0x62, 0x61,
+	0x63, 0x6b, 0x65, 0x6e, 0x64, 0x22, 0x98, 0x01, 0x0a, 0x0f, 0x46, 0x61, 0x6c, 0x6c, 0x62, 0x61,
+	0x63, 0x6b, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x34, 0x0a,
+	0x08, 0x68, 0x74, 0x74, 0x70, 0x5f, 0x72, 0x70, 0x63, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x17, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2e, 0x48, 0x74, 0x74, 0x70, 0x52, 0x70,
+	0x63, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x48, 0x00, 0x52, 0x07, 0x68, 0x74, 0x74, 0x70,
+	0x52, 0x70, 0x63, 0x12, 0x30, 0x0a, 0x06, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2e, 0x52, 0x65,
+	0x6d, 0x6f, 0x74, 0x65, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x48, 0x00, 0x52, 0x06, 0x72,
+	0x65, 0x6d, 0x6f, 0x74, 0x65, 0x42, 0x09, 0x0a, 0x07, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64,
+	0x22, 0xd7, 0x01, 0x0a, 0x0b, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b,
+	0x12, 0x35, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x69,
+	0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x33, 0x0a, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x12, 0x2f, 0x0a, 0x13,
+	0x75, 0x6e, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x79, 0x5f, 0x74, 0x68, 0x72, 0x65, 0x73, 0x68,
+	0x6f, 0x6c, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x12, 0x75, 0x6e, 0x68, 0x65, 0x61,
+	0x6c, 0x74, 0x68, 0x79, 0x54, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x12, 0x2b, 0x0a,
+	0x11, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x79, 0x5f, 0x74, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f,
+	0x6c, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x10, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68,
+	0x79, 0x54, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x22, 0xab, 0x01, 0x0a, 0x0b, 0x42,
+	0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x52, 0x75, 0x6c, 0x65, 0x12, 0x33, 0x0a, 0x08, 0x62, 0x61,
+	0x63, 0x6b, 0x65, 0x6e, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x62,
+	0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2e, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x4d, 0x61,
+	0x70, 0x70, 0x69, 0x6e, 0x67, 0x52, 0x08, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x73, 0x12,
+	0x37, 0x0a, 0x0c, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x5f, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2e,
+	0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x0b, 0x68, 0x65, 0x61,
+	0x6c, 0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x12, 0x2e, 0x0a, 0x06, 0x73, 0x68, 0x61, 0x64,
+	0x6f, 0x77, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65,
+	0x6e, 0x64, 0x2e, 0x53, 0x68, 0x61, 0x64, 0x6f, 0x77, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64,
+	0x52, 0x06, 0x73, 0x68, 0x61, 0x64, 0x6f, 0x77, 0x22, 0xec, 0x01, 0x0a, 0x0d, 0x53, 0x68, 0x61,
+	0x64, 0x6f, 0x77, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x34,
+	0x0a, 0x08, 0x68, 0x74, 0x74, 0x70, 0x5f, 0x72, 0x70, 0x63, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x17, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2e, 0x48, 0x74, 0x74, 0x70, 0x52,
+	0x70, 0x63, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x48, 0x00, 0x52, 0x07, 0x68, 0x74, 0x74,
+	0x70, 0x52, 0x70, 0x63, 0x12, 0x30, 0x0a, 0x06, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2e, 0x52,
+	0x65, 0x6d, 0x6f, 0x74, 0x65, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x48, 0x00, 0x52, 0x06,
+	0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65,
+	0x5f, 0x72, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0a, 0x73, 0x61, 0x6d,
+	0x70, 0x6c, 0x65, 0x52, 0x61, 0x74, 0x65, 0x12, 0x33, 0x0a, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x42, 0x09, 0x0a, 0x07,
+	0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x22, 0xdd, 0x01, 0x0a, 0x0d, 0x42, 0x61, 0x63, 0x6b,
 	0x65, 0x6e, 0x64, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x2d, 0x0a, 0x05, 0x6c, 0x6f, 0x63,
 	0x61, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x62, 0x61, 0x63, 0x6b, 0x65,
 	0x6e, 0x64, 0x2e, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x48,
@@ -617,30 +1103,48 @@ func file_backend_backend_proto_rawDescGZIP() []byte {
 	return file_backend_backend_proto_rawDescData
 }
 
-var file_backend_backend_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_backend_backend_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_backend_backend_proto_goTypes = []interface{}{
 	(*LocalBackend)(nil),             // 0: backend.LocalBackend
 	(*HttpRpcBackend)(nil),           // 1: backend.HttpRpcBackend
 	(*RemoteBackend)(nil),            // 2: backend.RemoteBackend
 	(*BackendMapping)(nil),           // 3: backend.BackendMapping
-	(*BackendRule)(nil),              // 4: backend.BackendRule
-	(*BackendConfig)(nil),            // 5: backend.BackendConfig
-	(*LocalBackend_TraceOption)(nil), // 6: backend.LocalBackend.TraceOption
+	(*WeightedBackend)(nil),          // 4: backend.WeightedBackend
+	(*FallbackBackend)(nil),          // 5: backend.FallbackBackend
+	(*HealthCheck)(nil),              // 6: backend.HealthCheck
+	(*BackendRule)(nil),              // 7: backend.BackendRule
+	(*ShadowBackend)(nil),            // 8: backend.ShadowBackend
+	(*BackendConfig)(nil),            // 9: backend.BackendConfig
+	(*LocalBackend_TraceOption)(nil), // 10: backend.LocalBackend.TraceOption
+	(*durationpb.Duration)(nil),      // 11: google.protobuf.Duration
 }
 var file_backend_backend_proto_depIdxs = []int32{
-	6, // 0: backend.LocalBackend.trace_option:type_name -> backend.LocalBackend.TraceOption
-	1, // 1: backend.BackendMapping.http_rpc:type_name -> backend.HttpRpcBackend
-	2, // 2: backend.BackendMapping.remote:type_name -> backend.RemoteBackend
-	3, // 3: backend.BackendRule.backends:type_name -> backend.BackendMapping
-	0, // 4: backend.BackendConfig.local:type_name -> backend.LocalBackend
-	1, // 5: backend.BackendConfig.http_rpc:type_name -> backend.HttpRpcBackend
-	2, // 6: backend.BackendConfig.remote:type_name -> backend.RemoteBackend
-	4, // 7: backend.BackendConfig.rule:type_name -> backend.BackendRule
-	8, // [8:8] is the sub-list for method output_type
-	8, // [8:8] is the sub-list for method input_type
-	8, // [8:8] is the sub-list for extension type_name
-	8, // [8:8] is the sub-list for extension extendee
-	0, // [0:8] is the sub-list for field type_name
+	10, // 0: backend.LocalBackend.trace_option:type_name -> backend.LocalBackend.TraceOption
+	1,  // 1: backend.BackendMapping.http_rpc:type_name -> backend.HttpRpcBackend
+	2,  // 2: backend.BackendMapping.remote:type_name -> backend.RemoteBackend
+	4,  // 3: backend.BackendMapping.weighted_backends:type_name -> backend.WeightedBackend
+	5,  // 4: backend.BackendMapping.fallbacks:type_name -> backend.FallbackBackend
+	1,  // 5: backend.WeightedBackend.http_rpc:type_name -> backend.HttpRpcBackend
+	2,  // 6: backend.WeightedBackend.remote:type_name -> backend.RemoteBackend
+	1,  // 7: backend.FallbackBackend.http_rpc:type_name -> backend.HttpRpcBackend
+	2,  // 8: backend.FallbackBackend.remote:type_name -> backend.RemoteBackend
+	11, // 9: backend.HealthCheck.interval:type_name -> google.protobuf.Duration
+	11, // 10: backend.HealthCheck.timeout:type_name -> google.protobuf.Duration
+	3,  // 11: backend.BackendRule.backends:type_name -> backend.BackendMapping
+	6,  // 12: backend.BackendRule.health_check:type_name -> backend.HealthCheck
+	8,  // 13: backend.BackendRule.shadow:type_name -> backend.ShadowBackend
+	1,  // 14: backend.ShadowBackend.http_rpc:type_name -> backend.HttpRpcBackend
+	2,  // 15: backend.ShadowBackend.remote:type_name -> backend.RemoteBackend
+	11, // 16: backend.ShadowBackend.timeout:type_name -> google.protobuf.Duration
+	0,  // 17: backend.BackendConfig.local:type_name -> backend.LocalBackend
+	1,  // 18: backend.BackendConfig.http_rpc:type_name -> backend.HttpRpcBackend
+	2,  // 19: backend.BackendConfig.remote:type_name -> backend.RemoteBackend
+	7,  // 20: backend.BackendConfig.rule:type_name -> backend.BackendRule
+	21, // [21:21] is the sub-list for method output_type
+	21, // [21:21] is the sub-list for method input_type
+	21, // [21:21] is the sub-list for extension type_name
+	21, // [21:21] is the sub-list for extension extendee
+	0,  // [0:21] is the sub-list for field type_name
 }
 
 func init() { file_backend_backend_proto_init() }
@@ -698,7 +1202,7 @@ func file_backend_backend_proto_init() {
 			}
 		}
 		file_backend_backend_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BackendRule); i {
+			switch v := v.(*WeightedBackend); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -710,7 +1214,7 @@ func file_backend_backend_proto_init() {
 			}
 		}
 		file_backend_backend_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BackendConfig); i {
+			switch v := v.(*FallbackBackend); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -722,6 +1226,54 @@ func file_backend_backend_proto_init() {
 			}
 		}
 		file_backend_backend_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HealthCheck); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_backend_backend_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BackendRule); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_backend_backend_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ShadowBackend); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_backend_backend_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BackendConfig); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_backend_backend_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*LocalBackend_TraceOption); i {
 			case 0:
 				return &v.state
@@ -738,7 +1290,19 @@ func file_backend_backend_proto_init() {
 		(*BackendMapping_HttpRpc)(nil),
 		(*BackendMapping_Remote)(nil),
 	}
+	file_backend_backend_proto_msgTypes[4].OneofWrappers = []interface{}{
+		(*WeightedBackend_HttpRpc)(nil),
+		(*WeightedBackend_Remote)(nil),
+	}
 	file_backend_backend_proto_msgTypes[5].OneofWrappers = []interface{}{
+		(*FallbackBackend_HttpRpc)(nil),
+		(*FallbackBackend_Remote)(nil),
+	}
+	file_backend_backend_proto_msgTypes[8].OneofWrappers = []interface{}{
+		(*ShadowBackend_HttpRpc)(nil),
+		(*ShadowBackend_Remote)(nil),
+	}
+	file_backend_backend_proto_msgTypes[9].OneofWrappers = []interface{}{
 		(*BackendConfig_Local)(nil),
 		(*BackendConfig_HttpRpc)(nil),
 		(*BackendConfig_Remote)(nil),
@@ -750,7 +1314,7 @@ func file_backend_backend_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_backend_backend_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -407,7 +407,7 @@ func (x *BuildInfo) GetToolchain() string {
 }
 
 // CmdDescriptor is a command descriptor.
-// NEXT ID TO USE: 7
+// NEXT ID TO USE: 8
 type CmdDescriptor struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -417,6 +417,9 @@ type CmdDescriptor struct {
 	Setup         *CmdDescriptor_Setup         `protobuf:"bytes,2,opt,name=setup,proto3" json:"setup,omitempty"`
 	Cross         *CmdDescriptor_Cross         `protobuf:"bytes,3,opt,name=cross,proto3" json:"cross,omitempty"`
 	EmulationOpts *CmdDescriptor_EmulationOpts `protobuf:"bytes,6,opt,name=emulation_opts,json=emulationOpts,proto3" json:"emulation_opts,omitempty"`
+	// tool_spec describes command line of the tool, to run the tool
+	// that has no built-in handler in remoteexec (e.g. protoc, mojom, flatc).
+	ToolSpec *ToolSpec `protobuf:"bytes,7,opt,name=tool_spec,json=toolSpec,proto3" json:"tool_spec,omitempty"`
 }
 
 func (x *CmdDescriptor) Reset() {
@@ -479,6 +482,123 @@ func (x *CmdDescriptor) GetEmulationOpts() *CmdDescriptor_EmulationOpts {
 	return nil
 }
 
+func (x *CmdDescriptor) GetToolSpec() *ToolSpec {
+	if x != nil {
+		return x.ToolSpec
+	}
+	return nil
+}
+
+// ToolSpec is a declarative spec of a tool's command line.
+// remoteexec uses it to check relocatability, inputs and outputs
+// of the request for the tool.
+//
+// e.g. protoc
+//
+//	path_flags { name: "--proto_path=" }
+//	path_flags { name: "-I" joined: true }
+//	output_flags { name: "--descriptor_set_out=" }
+//	output_dir_flags { name: "--cpp_out=" value_delimiter: ":" }
+//	positional_inputs: true
+type ToolSpec struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// flags that take input file.
+	// input files are requested to client if they are not in the request.
+	InputFlags []*ToolSpec_Flag `protobuf:"bytes,1,rep,name=input_flags,json=inputFlags,proto3" json:"input_flags,omitempty"`
+	// flags that take output file.
+	OutputFlags []*ToolSpec_Flag `protobuf:"bytes,2,rep,name=output_flags,json=outputFlags,proto3" json:"output_flags,omitempty"`
+	// flags that take output directory.
+	OutputDirFlags []*ToolSpec_Flag `protobuf:"bytes,3,rep,name=output_dir_flags,json=outputDirFlags,proto3" json:"output_dir_flags,omitempty"`
+	// flags that make the request non relocatable
+	// (e.g. flags that embed cwd in outputs).
+	// arg matches if it equals to the flag, or it has the flag as prefix
+	// with "=".
+	NonRelocatableFlags []string `protobuf:"bytes,4,rep,name=non_relocatable_flags,json=nonRelocatableFlags,proto3" json:"non_relocatable_flags,omitempty"`
+	// If positional_inputs is true, args that are not flags nor
+	// flag values are input files.
+	PositionalInputs bool `protobuf:"varint,5,opt,name=positional_inputs,json=positionalInputs,proto3" json:"positional_inputs,omitempty"`
+	// flags that take other path (e.g. include directory).
+	// they are used for relocatability check only.
+	PathFlags []*ToolSpec_Flag `protobuf:"bytes,6,rep,name=path_flags,json=pathFlags,proto3" json:"path_flags,omitempty"`
+}
+
+func (x *ToolSpec) Reset() {
+	*x = ToolSpec{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_command_command_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ToolSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolSpec) ProtoMessage() {}
+
+func (x *ToolSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_command_command_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolSpec.ProtoReflect.Descriptor instead.
+func (*ToolSpec) Descriptor() ([]byte, []int) {
+	return file_command_command_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ToolSpec) GetInputFlags() []*ToolSpec_Flag {
+	if x != nil {
+		return x.InputFlags
+	}
+	return nil
+}
+
+func (x *ToolSpec) GetOutputFlags() []*ToolSpec_Flag {
+	if x != nil {
+		return x.OutputFlags
+	}
+	return nil
+}
+
+func (x *ToolSpec) GetOutputDirFlags() []*ToolSpec_Flag {
+	if x != nil {
+		return x.OutputDirFlags
+	}
+	return nil
+}
+
+func (x *ToolSpec) GetNonRelocatableFlags() []string {
+	if x != nil {
+		return x.NonRelocatableFlags
+	}
+	return nil
+}
+
+func (x *ToolSpec) GetPositionalInputs() bool {
+	if x != nil {
+		return x.PositionalInputs
+	}
+	return false
+}
+
+func (x *ToolSpec) GetPathFlags() []*ToolSpec_Flag {
+	if x != nil {
+		return x.PathFlags
+	}
+	return nil
+}
+
 // RemoteexecPlatform is a set of requirements, such as hardware,
 // operating system, for an remoteexec API.
 type RemoteexecPlatform struct {
@@ -497,7 +617,7 @@ type RemoteexecPlatform struct {
 func (x *RemoteexecPlatform) Reset() {
 	*x = RemoteexecPlatform{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[5]
+		mi := &file_command_command_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -510,7 +630,7 @@ func (x *RemoteexecPlatform) String() string {
 func (*RemoteexecPlatform) ProtoMessage() {}
 
 func (x *RemoteexecPlatform) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[5]
+	mi := &file_command_command_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -523,7 +643,7 @@ func (x *RemoteexecPlatform) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RemoteexecPlatform.ProtoReflect.Descriptor instead.
 func (*RemoteexecPlatform) Descriptor() ([]byte, []int) {
-	return file_command_command_proto_rawDescGZIP(), []int{5}
+	return file_command_command_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *RemoteexecPlatform) GetProperties() []*RemoteexecPlatform_Property {
@@ -566,7 +686,7 @@ type Config struct {
 func (x *Config) Reset() {
 	*x = Config{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[6]
+		mi := &file_command_command_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -579,7 +699,7 @@ func (x *Config) String() string {
 func (*Config) ProtoMessage() {}
 
 func (x *Config) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[6]
+	mi := &file_command_command_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -592,7 +712,7 @@ func (x *Config) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Config.ProtoReflect.Descriptor instead.
 func (*Config) Descriptor() ([]byte, []int) {
-	return file_command_command_proto_rawDescGZIP(), []int{6}
+	return file_command_command_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *Config) GetTarget() *Target {
@@ -658,7 +778,7 @@ type ACL struct {
 func (x *ACL) Reset() {
 	*x = ACL{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[7]
+		mi := &file_command_command_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -671,7 +791,7 @@ func (x *ACL) String() string {
 func (*ACL) ProtoMessage() {}
 
 func (x *ACL) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[7]
+	mi := &file_command_command_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -684,7 +804,7 @@ func (x *ACL) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ACL.ProtoReflect.Descriptor instead.
 func (*ACL) Descriptor() ([]byte, []int) {
-	return file_command_command_proto_rawDescGZIP(), []int{7}
+	return file_command_command_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *ACL) GetAllowedGroups() []string {
@@ -715,7 +835,7 @@ type Platform struct {
 func (x *Platform) Reset() {
 	*x = Platform{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[8]
+		mi := &file_command_command_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -728,7 +848,7 @@ func (x *Platform) String() string {
 func (*Platform) ProtoMessage() {}
 
 func (x *Platform) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[8]
+	mi := &file_command_command_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -741,7 +861,7 @@ func (x *Platform) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Platform.ProtoReflect.Descriptor instead.
 func (*Platform) Descriptor() ([]byte, []int) {
-	return file_command_command_proto_rawDescGZIP(), []int{8}
+	return file_command_command_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *Platform) GetProperties() []*Platform_Property {
@@ -796,7 +916,7 @@ type RuntimeConfig struct {
 func (x *RuntimeConfig) Reset() {
 	*x = RuntimeConfig{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[9]
+		mi := &file_command_command_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -809,7 +929,7 @@ func (x *RuntimeConfig) String() string {
 func (*RuntimeConfig) ProtoMessage() {}
 
 func (x *RuntimeConfig) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[9]
+	mi := &file_command_command_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -822,7 +942,7 @@ func (x *RuntimeConfig) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RuntimeConfig.ProtoReflect.Descriptor instead.
 func (*RuntimeConfig) Descriptor() ([]byte, []int) {
-	return file_command_command_proto_rawDescGZIP(), []int{9}
+	return file_command_command_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *RuntimeConfig) GetName() string {
@@ -896,7 +1016,7 @@ type PlatformRuntimeConfig struct {
 func (x *PlatformRuntimeConfig) Reset() {
 	*x = PlatformRuntimeConfig{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[10]
+		mi := &file_command_command_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -909,7 +1029,7 @@ func (x *PlatformRuntimeConfig) String() string {
 func (*PlatformRuntimeConfig) ProtoMessage() {}
 
 func (x *PlatformRuntimeConfig) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[10]
+	mi := &file_command_command_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -922,7 +1042,7 @@ func (x *PlatformRuntimeConfig) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PlatformRuntimeConfig.ProtoReflect.Descriptor instead.
 func (*PlatformRuntimeConfig) Descriptor() ([]byte, []int) {
-	return file_command_command_proto_rawDescGZIP(), []int{10}
+	return file_command_command_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *PlatformRuntimeConfig) GetDimensions() []string {
@@ -953,7 +1073,7 @@ type ConfigMap struct {
 func (x *ConfigMap) Reset() {
 	*x = ConfigMap{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[11]
+		mi := &file_command_command_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -966,7 +1086,7 @@ func (x *ConfigMap) String() string {
 func (*ConfigMap) ProtoMessage() {}
 
 func (x *ConfigMap) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[11]
+	mi := &file_command_command_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -979,7 +1099,7 @@ func (x *ConfigMap) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ConfigMap.ProtoReflect.Descriptor instead.
 func (*ConfigMap) Descriptor() ([]byte, []int) {
-	return file_command_command_proto_rawDescGZIP(), []int{11}
+	return file_command_command_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *ConfigMap) GetRuntimes() []*RuntimeConfig {
@@ -1002,7 +1122,7 @@ type ConfigResp struct {
 func (x *ConfigResp) Reset() {
 	*x = ConfigResp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[12]
+		mi := &file_command_command_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1015,7 +1135,7 @@ func (x *ConfigResp) String() string {
 func (*ConfigResp) ProtoMessage() {}
 
 func (x *ConfigResp) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[12]
+	mi := &file_command_command_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1028,7 +1148,7 @@ func (x *ConfigResp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ConfigResp.ProtoReflect.Descriptor instead.
 func (*ConfigResp) Descriptor() ([]byte, []int) {
-	return file_command_command_proto_rawDescGZIP(), []int{12}
+	return file_command_command_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *ConfigResp) GetVersionId() string {
@@ -1075,7 +1195,7 @@ type CmdDescriptor_Setup struct {
 func (x *CmdDescriptor_Setup) Reset() {
 	*x = CmdDescriptor_Setup{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[13]
+		mi := &file_command_command_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1088,7 +1208,7 @@ func (x *CmdDescriptor_Setup) String() string {
 func (*CmdDescriptor_Setup) ProtoMessage() {}
 
 func (x *CmdDescriptor_Setup) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[13]
+	mi := &file_command_command_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1150,7 +1270,7 @@ type CmdDescriptor_Cross struct {
 func (x *CmdDescriptor_Cross) Reset() {
 	*x = CmdDescriptor_Cross{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[14]
+		mi := &file_command_command_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1163,7 +1283,7 @@ func (x *CmdDescriptor_Cross) String() string {
 func (*CmdDescriptor_Cross) ProtoMessage() {}
 
 func (x *CmdDescriptor_Cross) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[14]
+	mi := &file_command_command_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1209,7 +1329,7 @@ type CmdDescriptor_EmulationOpts struct {
 func (x *CmdDescriptor_EmulationOpts) Reset() {
 	*x = CmdDescriptor_EmulationOpts{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[15]
+		mi := &file_command_command_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1222,7 +1342,7 @@ func (x *CmdDescriptor_EmulationOpts) String() string {
 func (*CmdDescriptor_EmulationOpts) ProtoMessage() {}
 
 func (x *CmdDescriptor_EmulationOpts) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[15]
+	mi := &file_command_command_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1245,6 +1365,79 @@ func (x *CmdDescriptor_EmulationOpts) GetRespectClientIncludePaths() bool {
 	return false
 }
 
+type ToolSpec_Flag struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// name of the flag. e.g. "-o", "--out-dir", "--cpp_out=".
+	// If name ends with "=", value is joined to the flag
+	// (e.g. --cpp_out=dir).
+	// Otherwise, value is the next arg, or also joined to the flag
+	// (e.g. -Idir) if joined is true.
+	Name   string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Joined bool   `protobuf:"varint,2,opt,name=joined,proto3" json:"joined,omitempty"`
+	// If value_delimiter is set, path is the part of value after
+	// the first delimiter.
+	// e.g. ":" for protoc's --cpp_out=OPTIONS:DIR.
+	// value starting with windows drive letter (e.g. C:\out) is
+	// used as path as is.
+	ValueDelimiter string `protobuf:"bytes,3,opt,name=value_delimiter,json=valueDelimiter,proto3" json:"value_delimiter,omitempty"`
+}
+
+func (x *ToolSpec_Flag) Reset() {
+	*x = ToolSpec_Flag{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_command_command_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ToolSpec_Flag) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolSpec_Flag) ProtoMessage() {}
+
+func (x *ToolSpec_Flag) ProtoReflect() protoreflect.Message {
+	mi := &file_command_command_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolSpec_Flag.ProtoReflect.Descriptor instead.
+func (*ToolSpec_Flag) Descriptor() ([]byte, []int) {
+	return file_command_command_proto_rawDescGZIP(), []int{5, 0}
+}
+
+func (x *ToolSpec_Flag) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ToolSpec_Flag) GetJoined() bool {
+	if x != nil {
+		return x.Joined
+	}
+	return false
+}
+
+func (x *ToolSpec_Flag) GetValueDelimiter() string {
+	if x != nil {
+		return x.ValueDelimiter
+	}
+	return ""
+}
+
 type RemoteexecPlatform_Property struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1259,7 +1452,7 @@ type RemoteexecPlatform_Property struct {
 func (x *RemoteexecPlatform_Property) Reset() {
 	*x = RemoteexecPlatform_Property{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[16]
+		mi := &file_command_command_proto_msgTypes[18]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1272,7 +1465,7 @@ func (x *RemoteexecPlatform_Property) String() string {
 func (*RemoteexecPlatform_Property) ProtoMessage() {}
 
 func (x *RemoteexecPlatform_Property) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[16]
+	mi := &file_command_command_proto_msgTypes[18]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1285,7 +1478,7 @@ func (x *RemoteexecPlatform_Property) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RemoteexecPlatform_Property.ProtoReflect.Descriptor instead.
 func (*RemoteexecPlatform_Property) Descriptor() ([]byte, []int) {
-	return file_command_command_proto_rawDescGZIP(), []int{5, 0}
+	return file_command_command_proto_rawDescGZIP(), []int{6, 0}
 }
 
 func (x *RemoteexecPlatform_Property) GetName() string {
@@ -1314,7 +1507,7 @@ type Platform_Property struct {
 func (x *Platform_Property) Reset() {
 	*x = Platform_Property{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_command_command_proto_msgTypes[17]
+		mi := &file_command_command_proto_msgTypes[19]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1327,7 +1520,7 @@ func (x *Platform_Property) String() string {
 func (*Platform_Property) ProtoMessage() {}
 
 func (x *Platform_Property) ProtoReflect() protoreflect.Message {
-	mi := &file_command_command_proto_msgTypes[17]
+	mi := &file_command_command_proto_msgTypes[19]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1340,7 +1533,7 @@ func (x *Platform_Property) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Platform_Property.ProtoReflect.Descriptor instead.
 func (*Platform_Property) Descriptor() ([]byte, []int) {
-	return file_command_command_proto_rawDescGZIP(), []int{8, 0}
+	return file_command_command_proto_rawDescGZIP(), []int{9, 0}
 }
 
 func (x *Platform_Property) GetName() string {
@@ -1403,7 +1596,7 @@ var file_command_command_proto_rawDesc = []byte{
 	0x63, 0x68, 0x61, 0x69, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x74, 0x6f, 0x6f,
 	0x6c, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x4a, 0x04, 0x08, 0x01, 0x10, 0x02, 0x4a, 0x04, 0x08, 0x06,
 	0x10, 0x07, 0x52, 0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x52, 0x09, 0x75, 0x70, 0x73, 0x74,
-	0x72, 0x65, 0x61, 0x6d, 0x73, 0x22, 0xb5, 0x05, 0x0a, 0x0d, 0x43, 0x6d, 0x64, 0x44, 0x65, 0x73,
+	0x72, 0x65, 0x61, 0x6d, 0x73, 0x22, 0xe5, 0x05, 0x0a, 0x0d, 0x43, 0x6d, 0x64, 0x44, 0x65, 0x73,
 	0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x12, 0x2d, 0x0a, 0x08, 0x73, 0x65, 0x6c, 0x65, 0x63,
 	0x74, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x63, 0x6f, 0x6d, 0x6d,
 	0x61, 0x6e, 0x64, 0x2e, 0x53, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x08, 0x73, 0x65,
@@ -1418,7 +1611,10 @@ var file_command_command_proto_rawDesc = []byte{
 	0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64,
 	0x2e, 0x43, 0x6d, 0x64, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x45,
 	0x6d, 0x75, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4f, 0x70, 0x74, 0x73, 0x52, 0x0d, 0x65, 0x6d,
-	0x75, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4f, 0x70, 0x74, 0x73, 0x1a, 0xb5, 0x01, 0x0a, 0x05,
+	0x75, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4f, 0x70, 0x74, 0x73, 0x12, 0x2e, 0x0a, 0x09, 0x74,
+	0x6f, 0x6f, 0x6c, 0x5f, 0x73, 0x70, 0x65, 0x63, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11,
+	0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x54, 0x6f, 0x6f, 0x6c, 0x53, 0x70, 0x65,
+	0x63, 0x52, 0x08, 0x74, 0x6f, 0x6f, 0x6c, 0x53, 0x70, 0x65, 0x63, 0x1a, 0xb5, 0x01, 0x0a, 0x05,
 	0x53, 0x65, 0x74, 0x75, 0x70, 0x12, 0x2c, 0x0a, 0x08, 0x63, 0x6d, 0x64, 0x5f, 0x66, 0x69, 0x6c,
 	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
 	0x64, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x53, 0x70, 0x65, 0x63, 0x52, 0x07, 0x63, 0x6d, 0x64, 0x46,
@@ -1446,101 +1642,128 @@ var file_command_command_proto_rawDesc = []byte{
 	0x10, 0x00, 0x12, 0x09, 0x0a, 0x05, 0x50, 0x4f, 0x53, 0x49, 0x58, 0x10, 0x01, 0x12, 0x0b, 0x0a,
 	0x07, 0x57, 0x49, 0x4e, 0x44, 0x4f, 0x57, 0x53, 0x10, 0x02, 0x4a, 0x04, 0x08, 0x04, 0x10, 0x05,
 	0x4a, 0x04, 0x08, 0x05, 0x10, 0x06, 0x52, 0x08, 0x63, 0x6d, 0x64, 0x5f, 0x6f, 0x70, 0x74, 0x73,
-	0x52, 0x0b, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x5f, 0x6f, 0x70, 0x74, 0x22, 0xe3, 0x01,
-	0x0a, 0x12, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x65, 0x78, 0x65, 0x63, 0x50, 0x6c, 0x61, 0x74,
-	0x66, 0x6f, 0x72, 0x6d, 0x12, 0x44, 0x0a, 0x0a, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69,
-	0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61,
-	0x6e, 0x64, 0x2e, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x65, 0x78, 0x65, 0x63, 0x50, 0x6c, 0x61,
-	0x74, 0x66, 0x6f, 0x72, 0x6d, 0x2e, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x52, 0x0a,
-	0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x12, 0x32, 0x0a, 0x15, 0x72, 0x62,
-	0x65, 0x5f, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x62, 0x61, 0x73, 0x65, 0x6e,
-	0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x13, 0x72, 0x62, 0x65, 0x49, 0x6e,
-	0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x42, 0x61, 0x73, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1d,
-	0x0a, 0x0a, 0x68, 0x61, 0x73, 0x5f, 0x6e, 0x73, 0x6a, 0x61, 0x69, 0x6c, 0x18, 0x03, 0x20, 0x01,
-	0x28, 0x08, 0x52, 0x09, 0x68, 0x61, 0x73, 0x4e, 0x73, 0x6a, 0x61, 0x69, 0x6c, 0x1a, 0x34, 0x0a,
-	0x08, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
-	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a,
-	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61,
-	0x6c, 0x75, 0x65, 0x22, 0xbe, 0x02, 0x0a, 0x06, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x27,
-	0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f,
-	0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x52,
-	0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x31, 0x0a, 0x0a, 0x62, 0x75, 0x69, 0x6c, 0x64,
-	0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x63, 0x6f,
-	0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x52,
-	0x09, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x3d, 0x0a, 0x0e, 0x63, 0x6d,
-	0x64, 0x5f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01,
-	0x28, 0x0b, 0x32, 0x16, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x43, 0x6d, 0x64,
-	0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x52, 0x0d, 0x63, 0x6d, 0x64, 0x44,
-	0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x12, 0x4c, 0x0a, 0x13, 0x72, 0x65, 0x6d,
-	0x6f, 0x74, 0x65, 0x65, 0x78, 0x65, 0x63, 0x5f, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d,
-	0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64,
-	0x2e, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x65, 0x78, 0x65, 0x63, 0x50, 0x6c, 0x61, 0x74, 0x66,
-	0x6f, 0x72, 0x6d, 0x52, 0x12, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x65, 0x78, 0x65, 0x63, 0x50,
-	0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x12, 0x1e, 0x0a, 0x0a, 0x64, 0x69, 0x6d, 0x65, 0x6e,
-	0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x64, 0x69, 0x6d,
-	0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1e, 0x0a, 0x03, 0x61, 0x63, 0x6c, 0x18, 0x07,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x41,
-	0x43, 0x4c, 0x52, 0x03, 0x61, 0x63, 0x6c, 0x4a, 0x04, 0x08, 0x02, 0x10, 0x03, 0x52, 0x05, 0x69,
-	0x6d, 0x61, 0x67, 0x65, 0x22, 0x59, 0x0a, 0x03, 0x41, 0x43, 0x4c, 0x12, 0x25, 0x0a, 0x0e, 0x61,
-	0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x18, 0x01, 0x20,
-	0x03, 0x28, 0x09, 0x52, 0x0d, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x47, 0x72, 0x6f, 0x75,
-	0x70, 0x73, 0x12, 0x2b, 0x0a, 0x11, 0x64, 0x69, 0x73, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64,
-	0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x10, 0x64,
-	0x69, 0x73, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x22,
-	0x7c, 0x0a, 0x08, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x12, 0x3a, 0x0a, 0x0a, 0x70,
-	0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x1a, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f,
-	0x72, 0x6d, 0x2e, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x52, 0x0a, 0x70, 0x72, 0x6f,
-	0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x1a, 0x34, 0x0a, 0x08, 0x50, 0x72, 0x6f, 0x70, 0x65,
-	0x72, 0x74, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0xae, 0x03,
-	0x0a, 0x0d, 0x52, 0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12,
-	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
-	0x61, 0x6d, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x61,
-	0x64, 0x64, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x69,
-	0x63, 0x65, 0x41, 0x64, 0x64, 0x72, 0x12, 0x56, 0x0a, 0x17, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f,
-	0x72, 0x6d, 0x5f, 0x72, 0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69,
-	0x67, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
-	0x64, 0x2e, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x52, 0x75, 0x6e, 0x74, 0x69, 0x6d,
-	0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x15, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72,
-	0x6d, 0x52, 0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x2d,
-	0x0a, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b,
-	0x32, 0x11, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x50, 0x6c, 0x61, 0x74, 0x66,
-	0x6f, 0x72, 0x6d, 0x52, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x12, 0x2b, 0x0a,
-	0x11, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x70, 0x72, 0x65, 0x62, 0x75, 0x69, 0x6c,
-	0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x10, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65,
-	0x64, 0x50, 0x72, 0x65, 0x62, 0x75, 0x69, 0x6c, 0x74, 0x73, 0x12, 0x31, 0x0a, 0x14, 0x64, 0x69,
-	0x73, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x70, 0x72, 0x65, 0x62, 0x75, 0x69, 0x6c,
-	0x74, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x13, 0x64, 0x69, 0x73, 0x61, 0x6c, 0x6c,
-	0x6f, 0x77, 0x65, 0x64, 0x50, 0x72, 0x65, 0x62, 0x75, 0x69, 0x6c, 0x74, 0x73, 0x12, 0x42, 0x0a,
-	0x13, 0x64, 0x69, 0x73, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x63, 0x6f, 0x6d, 0x6d,
-	0x61, 0x6e, 0x64, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x63, 0x6f, 0x6d,
-	0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x53, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x12, 0x64,
-	0x69, 0x73, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64,
-	0x73, 0x12, 0x1e, 0x0a, 0x03, 0x61, 0x63, 0x6c, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c,
-	0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x41, 0x43, 0x4c, 0x52, 0x03, 0x61, 0x63,
-	0x6c, 0x4a, 0x04, 0x08, 0x07, 0x10, 0x08, 0x52, 0x15, 0x72, 0x62, 0x65, 0x5f, 0x69, 0x6e, 0x73,
-	0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x62, 0x61, 0x73, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x56,
-	0x0a, 0x15, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x52, 0x75, 0x6e, 0x74, 0x69, 0x6d,
-	0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x1e, 0x0a, 0x0a, 0x64, 0x69, 0x6d, 0x65, 0x6e,
-	0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x64, 0x69, 0x6d,
-	0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x68, 0x61, 0x73, 0x5f, 0x6e,
-	0x73, 0x6a, 0x61, 0x69, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x68, 0x61, 0x73,
-	0x4e, 0x73, 0x6a, 0x61, 0x69, 0x6c, 0x22, 0x3f, 0x0a, 0x09, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
-	0x4d, 0x61, 0x70, 0x12, 0x32, 0x0a, 0x08, 0x72, 0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e,
-	0x52, 0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x08, 0x72,
-	0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x22, 0x56, 0x0a, 0x0a, 0x43, 0x6f, 0x6e, 0x66, 0x69,
-	0x67, 0x52, 0x65, 0x73, 0x70, 0x12, 0x1d, 0x0a, 0x0a, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
-	0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x76, 0x65, 0x72, 0x73, 0x69,
-	0x6f, 0x6e, 0x49, 0x64, 0x12, 0x29, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e,
-	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73, 0x42,
-	0x2b, 0x5a, 0x29, 0x67, 0x6f, 0x2e, 0x63, 0x68, 0x72, 0x6f, 0x6d, 0x69, 0x75, 0x6d, 0x2e, 0x6f,
-	0x72, 0x67, 0x2f, 0x67, 0x6f, 0x6d, 0x61, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+	0x52, 0x0b, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x5f, 0x6f, 0x70, 0x74, 0x22, 0xb5, 0x03,
+	0x0a, 0x08, 0x54, 0x6f, 0x6f, 0x6c, 0x53, 0x70, 0x65, 0x63, 0x12, 0x37, 0x0a, 0x0b, 0x69, 0x6e,
+	0x70, 0x75, 0x74, 0x5f, 0x66, 0x6c, 0x61, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x16, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x54, 0x6f, 0x6f, 0x6c, 0x53, 0x70,
+	0x65, 0x63, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x0a, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x46, 0x6c,
+	0x61, 0x67, 0x73, 0x12, 0x39, 0x0a, 0x0c, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x5f, 0x66, 0x6c,
+	0x61, 0x67, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x63, 0x6f, 0x6d, 0x6d,
+	0x61, 0x6e, 0x64, 0x2e, 0x54, 0x6f, 0x6f, 0x6c, 0x53, 0x70, 0x65, 0x63, 0x2e, 0x46, 0x6c, 0x61,
+	0x67, 0x52, 0x0b, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x12, 0x40,
+	0x0a, 0x10, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x5f, 0x64, 0x69, 0x72, 0x5f, 0x66, 0x6c, 0x61,
+	0x67, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61,
+	0x6e, 0x64, 0x2e, 0x54, 0x6f, 0x6f, 0x6c, 0x53, 0x70, 0x65, 0x63, 0x2e, 0x46, 0x6c, 0x61, 0x67,
+	0x52, 0x0e, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x44, 0x69, 0x72, 0x46, 0x6c, 0x61, 0x67, 0x73,
+	0x12, 0x32, 0x0a, 0x15, 0x6e, 0x6f, 0x6e, 0x5f, 0x72, 0x65, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x61,
+	0x62, 0x6c, 0x65, 0x5f, 0x66, 0x6c, 0x61, 0x67, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x13, 0x6e, 0x6f, 0x6e, 0x52, 0x65, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x46,
+	0x6c, 0x61, 0x67, 0x73, 0x12, 0x2b, 0x0a, 0x11, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e,
+	0x61, 0x6c, 0x5f, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x10, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x49, 0x6e, 0x70, 0x75, 0x74,
+	0x73, 0x12, 0x35, 0x0a, 0x0a, 0x70, 0x61, 0x74, 0x68, 0x5f, 0x66, 0x6c, 0x61, 0x67, 0x73, 0x18,
+	0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e,
+	0x54, 0x6f, 0x6f, 0x6c, 0x53, 0x70, 0x65, 0x63, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x09, 0x70,
+	0x61, 0x74, 0x68, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x1a, 0x5b, 0x0a, 0x04, 0x46, 0x6c, 0x61, 0x67,
+	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x6a, 0x6f, 0x69, 0x6e, 0x65, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x6a, 0x6f, 0x69, 0x6e, 0x65, 0x64, 0x12, 0x27, 0x0a, 0x0f,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x5f, 0x64, 0x65, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x65, 0x72, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x44, 0x65, 0x6c, 0x69,
+	0x6d, 0x69, 0x74, 0x65, 0x72, 0x22, 0xe3, 0x01, 0x0a, 0x12, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65,
+	0x65, 0x78, 0x65, 0x63, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x12, 0x44, 0x0a, 0x0a,
+	0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x24, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x52, 0x65, 0x6d, 0x6f, 0x74,
+	0x65, 0x65, 0x78, 0x65, 0x63, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x2e, 0x50, 0x72,
+	0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x52, 0x0a, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69,
+	0x65, 0x73, 0x12, 0x32, 0x0a, 0x15, 0x72, 0x62, 0x65, 0x5f, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e,
+	0x63, 0x65, 0x5f, 0x62, 0x61, 0x73, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x13, 0x72, 0x62, 0x65, 0x49, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x42, 0x61,
+	0x73, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x68, 0x61, 0x73, 0x5f, 0x6e, 0x73,
+	0x6a, 0x61, 0x69, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x68, 0x61, 0x73, 0x4e,
+	0x73, 0x6a, 0x61, 0x69, 0x6c, 0x1a, 0x34, 0x0a, 0x08, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74,
+	0x79, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0xbe, 0x02, 0x0a, 0x06,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x27, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64,
+	0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12,
+	0x31, 0x0a, 0x0a, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x42, 0x75,
+	0x69, 0x6c, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x09, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x49, 0x6e,
+	0x66, 0x6f, 0x12, 0x3d, 0x0a, 0x0e, 0x63, 0x6d, 0x64, 0x5f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69,
+	0x70, 0x74, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x63, 0x6f, 0x6d,
+	0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x43, 0x6d, 0x64, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74,
+	0x6f, 0x72, 0x52, 0x0d, 0x63, 0x6d, 0x64, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f,
+	0x72, 0x12, 0x4c, 0x0a, 0x13, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x65, 0x78, 0x65, 0x63, 0x5f,
+	0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b,
+	0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x65,
+	0x78, 0x65, 0x63, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x52, 0x12, 0x72, 0x65, 0x6d,
+	0x6f, 0x74, 0x65, 0x65, 0x78, 0x65, 0x63, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x12,
+	0x1e, 0x0a, 0x0a, 0x64, 0x69, 0x6d, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x06, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x0a, 0x64, 0x69, 0x6d, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12,
+	0x1e, 0x0a, 0x03, 0x61, 0x63, 0x6c, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x63,
+	0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x41, 0x43, 0x4c, 0x52, 0x03, 0x61, 0x63, 0x6c, 0x4a,
+	0x04, 0x08, 0x02, 0x10, 0x03, 0x52, 0x05, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x22, 0x59, 0x0a, 0x03,
+	0x41, 0x43, 0x4c, 0x12, 0x25, 0x0a, 0x0e, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x67,
+	0x72, 0x6f, 0x75, 0x70, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0d, 0x61, 0x6c, 0x6c,
+	0x6f, 0x77, 0x65, 0x64, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x12, 0x2b, 0x0a, 0x11, 0x64, 0x69,
+	0x73, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x10, 0x64, 0x69, 0x73, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65,
+	0x64, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x22, 0x7c, 0x0a, 0x08, 0x50, 0x6c, 0x61, 0x74, 0x66,
+	0x6f, 0x72, 0x6d, 0x12, 0x3a, 0x0a, 0x0a, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
+	0x64, 0x2e, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x2e, 0x50, 0x72, 0x6f, 0x70, 0x65,
+	0x72, 0x74, 0x79, 0x52, 0x0a, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x1a,
+	0x34, 0x0a, 0x08, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0xae, 0x03, 0x0a, 0x0d, 0x52, 0x75, 0x6e, 0x74, 0x69, 0x6d,
+	0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x41, 0x64, 0x64, 0x72, 0x12, 0x56,
+	0x0a, 0x17, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x5f, 0x72, 0x75, 0x6e, 0x74, 0x69,
+	0x6d, 0x65, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1e, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f,
+	0x72, 0x6d, 0x52, 0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52,
+	0x15, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x52, 0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x2d, 0x0a, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f,
+	0x72, 0x6d, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61,
+	0x6e, 0x64, 0x2e, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x52, 0x08, 0x70, 0x6c, 0x61,
+	0x74, 0x66, 0x6f, 0x72, 0x6d, 0x12, 0x2b, 0x0a, 0x11, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64,
+	0x5f, 0x70, 0x72, 0x65, 0x62, 0x75, 0x69, 0x6c, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x10, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x50, 0x72, 0x65, 0x62, 0x75, 0x69, 0x6c,
+	0x74, 0x73, 0x12, 0x31, 0x0a, 0x14, 0x64, 0x69, 0x73, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64,
+	0x5f, 0x70, 0x72, 0x65, 0x62, 0x75, 0x69, 0x6c, 0x74, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x13, 0x64, 0x69, 0x73, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x50, 0x72, 0x65, 0x62,
+	0x75, 0x69, 0x6c, 0x74, 0x73, 0x12, 0x42, 0x0a, 0x13, 0x64, 0x69, 0x73, 0x61, 0x6c, 0x6c, 0x6f,
+	0x77, 0x65, 0x64, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x18, 0x05, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x11, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x53, 0x65, 0x6c,
+	0x65, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x12, 0x64, 0x69, 0x73, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65,
+	0x64, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x12, 0x1e, 0x0a, 0x03, 0x61, 0x63, 0x6c,
+	0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64,
+	0x2e, 0x41, 0x43, 0x4c, 0x52, 0x03, 0x61, 0x63, 0x6c, 0x4a, 0x04, 0x08, 0x07, 0x10, 0x08, 0x52,
+	0x15, 0x72, 0x62, 0x65, 0x5f, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x62, 0x61,
+	0x73, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x56, 0x0a, 0x15, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f,
+	0x72, 0x6d, 0x52, 0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12,
+	0x1e, 0x0a, 0x0a, 0x64, 0x69, 0x6d, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x0a, 0x64, 0x69, 0x6d, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12,
+	0x1d, 0x0a, 0x0a, 0x68, 0x61, 0x73, 0x5f, 0x6e, 0x73, 0x6a, 0x61, 0x69, 0x6c, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x09, 0x68, 0x61, 0x73, 0x4e, 0x73, 0x6a, 0x61, 0x69, 0x6c, 0x22, 0x3f,
+	0x0a, 0x09, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x4d, 0x61, 0x70, 0x12, 0x32, 0x0a, 0x08, 0x72,
+	0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e,
+	0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x52, 0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x08, 0x72, 0x75, 0x6e, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x22,
+	0x56, 0x0a, 0x0a, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x73, 0x70, 0x12, 0x1d, 0x0a,
+	0x0a, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x09, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x29, 0x0a, 0x07,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e,
+	0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x07,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73, 0x42, 0x2b, 0x5a, 0x29, 0x67, 0x6f, 0x2e, 0x63, 0x68,
+	0x72, 0x6f, 0x6d, 0x69, 0x75, 0x6d, 0x2e, 0x6f, 0x72, 0x67, 0x2f, 0x67, 0x6f, 0x6d, 0x61, 0x2f,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6d,
+	0x6d, 0x61, 0x6e, 0x64, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -1556,7 +1779,7 @@ func file_command_command_proto_rawDescGZIP() []byte {
 }
 
 var file_command_command_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_command_command_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_command_command_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
 var file_command_command_proto_goTypes = []interface{}{
 	(CmdDescriptor_PathType)(0),         // 0: command.CmdDescriptor.PathType
 	(*Selector)(nil),                    // 1: command.Selector
@@ -1564,50 +1787,57 @@ var file_command_command_proto_goTypes = []interface{}{
 	(*Target)(nil),                      // 3: command.Target
 	(*BuildInfo)(nil),                   // 4: command.BuildInfo
 	(*CmdDescriptor)(nil),               // 5: command.CmdDescriptor
-	(*RemoteexecPlatform)(nil),          // 6: command.RemoteexecPlatform
-	(*Config)(nil),                      // 7: command.Config
-	(*ACL)(nil),                         // 8: command.ACL
-	(*Platform)(nil),                    // 9: command.Platform
-	(*RuntimeConfig)(nil),               // 10: command.RuntimeConfig
-	(*PlatformRuntimeConfig)(nil),       // 11: command.PlatformRuntimeConfig
-	(*ConfigMap)(nil),                   // 12: command.ConfigMap
-	(*ConfigResp)(nil),                  // 13: command.ConfigResp
-	(*CmdDescriptor_Setup)(nil),         // 14: command.CmdDescriptor.Setup
-	(*CmdDescriptor_Cross)(nil),         // 15: command.CmdDescriptor.Cross
-	(*CmdDescriptor_EmulationOpts)(nil), // 16: command.CmdDescriptor.EmulationOpts
-	(*RemoteexecPlatform_Property)(nil), // 17: command.RemoteexecPlatform.Property
-	(*Platform_Property)(nil),           // 18: command.Platform.Property
-	(*api.FileBlob)(nil),                // 19: devtools_goma.FileBlob
-	(*timestamppb.Timestamp)(nil),       // 20: google.protobuf.Timestamp
+	(*ToolSpec)(nil),                    // 6: command.ToolSpec
+	(*RemoteexecPlatform)(nil),          // 7: command.RemoteexecPlatform
+	(*Config)(nil),                      // 8: command.Config
+	(*ACL)(nil),                         // 9: command.ACL
+	(*Platform)(nil),                    // 10: command.Platform
+	(*RuntimeConfig)(nil),               // 11: command.RuntimeConfig
+	(*PlatformRuntimeConfig)(nil),       // 12: command.PlatformRuntimeConfig
+	(*ConfigMap)(nil),                   // 13: command.ConfigMap
+	(*ConfigResp)(nil),                  // 14: command.ConfigResp
+	(*CmdDescriptor_Setup)(nil),         // 15: command.CmdDescriptor.Setup
+	(*CmdDescriptor_Cross)(nil),         // 16: command.CmdDescriptor.Cross
+	(*CmdDescriptor_EmulationOpts)(nil), // 17: command.CmdDescriptor.EmulationOpts
+	(*ToolSpec_Flag)(nil),               // 18: command.ToolSpec.Flag
+	(*RemoteexecPlatform_Property)(nil), // 19: command.RemoteexecPlatform.Property
+	(*Platform_Property)(nil),           // 20: command.Platform.Property
+	(*api.FileBlob)(nil),                // 21: devtools_goma.FileBlob
+	(*timestamppb.Timestamp)(nil),       // 22: google.protobuf.Timestamp
 }
 var file_command_command_proto_depIdxs = []int32{
-	19, // 0: command.FileSpec.blob:type_name -> devtools_goma.FileBlob
-	20, // 1: command.BuildInfo.timestamp:type_name -> google.protobuf.Timestamp
+	21, // 0: command.FileSpec.blob:type_name -> devtools_goma.FileBlob
+	22, // 1: command.BuildInfo.timestamp:type_name -> google.protobuf.Timestamp
 	1,  // 2: command.CmdDescriptor.selector:type_name -> command.Selector
-	14, // 3: command.CmdDescriptor.setup:type_name -> command.CmdDescriptor.Setup
-	15, // 4: command.CmdDescriptor.cross:type_name -> command.CmdDescriptor.Cross
-	16, // 5: command.CmdDescriptor.emulation_opts:type_name -> command.CmdDescriptor.EmulationOpts
-	17, // 6: command.RemoteexecPlatform.properties:type_name -> command.RemoteexecPlatform.Property
-	3,  // 7: command.Config.target:type_name -> command.Target
-	4,  // 8: command.Config.build_info:type_name -> command.BuildInfo
-	5,  // 9: command.Config.cmd_descriptor:type_name -> command.CmdDescriptor
-	6,  // 10: command.Config.remoteexec_platform:type_name -> command.RemoteexecPlatform
-	8,  // 11: command.Config.acl:type_name -> command.ACL
-	18, // 12: command.Platform.properties:type_name -> command.Platform.Property
-	11, // 13: command.RuntimeConfig.platform_runtime_config:type_name -> command.PlatformRuntimeConfig
-	9,  // 14: command.RuntimeConfig.platform:type_name -> command.Platform
-	1,  // 15: command.RuntimeConfig.disallowed_commands:type_name -> command.Selector
-	8,  // 16: command.RuntimeConfig.acl:type_name -> command.ACL
-	10, // 17: command.ConfigMap.runtimes:type_name -> command.RuntimeConfig
-	7,  // 18: command.ConfigResp.configs:type_name -> command.Config
-	2,  // 19: command.CmdDescriptor.Setup.cmd_file:type_name -> command.FileSpec
-	2,  // 20: command.CmdDescriptor.Setup.files:type_name -> command.FileSpec
-	0,  // 21: command.CmdDescriptor.Setup.path_type:type_name -> command.CmdDescriptor.PathType
-	22, // [22:22] is the sub-list for method output_type
-	22, // [22:22] is the sub-list for method input_type
-	22, // [22:22] is the sub-list for extension type_name
-	22, // [22:22] is the sub-list for extension extendee
-	0,  // [0:22] is the sub-list for field type_name
+	15, // 3: command.CmdDescriptor.setup:type_name -> command.CmdDescriptor.Setup
+	16, // 4: command.CmdDescriptor.cross:type_name -> command.CmdDescriptor.Cross
+	17, // 5: command.CmdDescriptor.emulation_opts:type_name -> command.CmdDescriptor.EmulationOpts
+	6,  // 6: command.CmdDescriptor.tool_spec:type_name -> command.ToolSpec
+	18, // 7: command.ToolSpec.input_flags:type_name -> command.ToolSpec.Flag
+	18, // 8: command.ToolSpec.output_flags:type_name -> command.ToolSpec.Flag
+	18, // 9: command.ToolSpec.output_dir_flags:type_name -> command.ToolSpec.Flag
+	18, // 10: command.ToolSpec.path_flags:type_name -> command.ToolSpec.Flag
+	19, // 11: command.RemoteexecPlatform.properties:type_name -> command.RemoteexecPlatform.Property
+	3,  // 12: command.Config.target:type_name -> command.Target
+	4,  // 13: command.Config.build_info:type_name -> command.BuildInfo
+	5,  // 14: command.Config.cmd_descriptor:type_name -> command.CmdDescriptor
+	7,  // 15: command.Config.remoteexec_platform:type_name -> command.RemoteexecPlatform
+	9,  // 16: command.Config.acl:type_name -> command.ACL
+	20, // 17: command.Platform.properties:type_name -> command.Platform.Property
+	12, // 18: command.RuntimeConfig.platform_runtime_config:type_name -> command.PlatformRuntimeConfig
+	10, // 19: command.RuntimeConfig.platform:type_name -> command.Platform
+	1,  // 20: command.RuntimeConfig.disallowed_commands:type_name -> command.Selector
+	9,  // 21: command.RuntimeConfig.acl:type_name -> command.ACL
+	11, // 22: command.ConfigMap.runtimes:type_name -> command.RuntimeConfig
+	8,  // 23: command.ConfigResp.configs:type_name -> command.Config
+	2,  // 24: command.CmdDescriptor.Setup.cmd_file:type_name -> command.FileSpec
+	2,  // 25: command.CmdDescriptor.Setup.files:type_name -> command.FileSpec
+	0,  // 26: command.CmdDescriptor.Setup.path_type:type_name -> command.CmdDescriptor.PathType
+	27, // [27:27] is the sub-list for method output_type
+	27, // [27:27] is the sub-list for method input_type
+	27, // [27:27] is the sub-list for extension type_name
+	27, // [27:27] is the sub-list for extension extendee
+	0,  // [0:27] is the sub-list for field type_name
 }
 
 func init() { file_command_command_proto_init() }
@@ -1677,7 +1907,7 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RemoteexecPlatform); i {
+			switch v := v.(*ToolSpec); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1689,7 +1919,7 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Config); i {
+			switch v := v.(*RemoteexecPlatform); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1701,7 +1931,7 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ACL); i {
+			switch v := v.(*Config); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1713,7 +1943,7 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Platform); i {
+			switch v := v.(*ACL); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1725,7 +1955,7 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RuntimeConfig); i {
+			switch v := v.(*Platform); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1737,7 +1967,7 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PlatformRuntimeConfig); i {
+			switch v := v.(*RuntimeConfig); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1749,7 +1979,7 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ConfigMap); i {
+			switch v := v.(*PlatformRuntimeConfig); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1761,7 +1991,7 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ConfigResp); i {
+			switch v := v.(*ConfigMap); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1773,7 +2003,7 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CmdDescriptor_Setup); i {
+			switch v := v.(*ConfigResp); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1785,7 +2015,7 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CmdDescriptor_Cross); i {
+			switch v := v.(*CmdDescriptor_Setup); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1797,7 +2027,7 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CmdDescriptor_EmulationOpts); i {
+			switch v := v.(*CmdDescriptor_Cross); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1809,7 +2039,7 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RemoteexecPlatform_Property); i {
+			switch v := v.(*CmdDescriptor_EmulationOpts); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1821,6 +2051,30 @@ func file_command_command_proto_init() {
 			}
 		}
 		file_command_command_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ToolSpec_Flag); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_command_command_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RemoteexecPlatform_Property); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_command_command_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Platform_Property); i {
 			case 0:
 				return &v.state
@@ -1839,7 +2093,7 @@ func file_command_command_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_command_command_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   18,
+			NumMessages:   20,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -14,6 +14,7 @@ import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
+	sync "sync"
 )
 
 const (
@@ -23,24 +24,328 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type RegisterToolchainReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// runtime name to register the toolchain in.
+	Runtime string `protobuf:"bytes,1,opt,name=runtime,proto3" json:"runtime,omitempty"`
+	// prebuilt name of the toolchain. e.g. "chromium-clang-r123456".
+	// it must be allowed by the runtime config.
+	Prebuilt    string         `protobuf:"bytes,2,opt,name=prebuilt,proto3" json:"prebuilt,omitempty"`
+	Descriptor_ *CmdDescriptor `protobuf:"bytes,3,opt,name=descriptor,proto3" json:"descriptor,omitempty"`
+	// if true, checks the request, but doesn't register the toolchain.
+	DryRun bool `protobuf:"varint,5,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+}
+
+func (x *RegisterToolchainReq) Reset() {
+	*x = RegisterToolchainReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_command_command_service_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RegisterToolchainReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterToolchainReq) ProtoMessage() {}
+
+func (x *RegisterToolchainReq) ProtoReflect() protoreflect.Message {
+	mi := &file_command_command_service_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterToolchainReq.ProtoReflect.Descriptor instead.
+func (*RegisterToolchainReq) Descriptor() ([]byte, []int) {
+	return file_command_command_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RegisterToolchainReq) GetRuntime() string {
+	if x != nil {
+		return x.Runtime
+	}
+	return ""
+}
+
+func (x *RegisterToolchainReq) GetPrebuilt() string {
+	if x != nil {
+		return x.Prebuilt
+	}
+	return ""
+}
+
+func (x *RegisterToolchainReq) GetDescriptor_() *CmdDescriptor {
+	if x != nil {
+		return x.Descriptor_
+	}
+	return nil
+}
+
+func (x *RegisterToolchainReq) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type RegisterToolchainResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// object name of the descriptor in toolchain config.
+	// i.e. <runtime>/<prebuilt>/descriptors/<descriptorHash>
+	DescriptorName string `protobuf:"bytes,1,opt,name=descriptor_name,json=descriptorName,proto3" json:"descriptor_name,omitempty"`
+	// new seq of the runtime. empty in dry run.
+	Seq string `protobuf:"bytes,3,opt,name=seq,proto3" json:"seq,omitempty"`
+}
+
+func (x *RegisterToolchainResp) Reset() {
+	*x = RegisterToolchainResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_command_command_service_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RegisterToolchainResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterToolchainResp) ProtoMessage() {}
+
+func (x *RegisterToolchainResp) ProtoReflect() protoreflect.Message {
+	mi := &file_command_command_service_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterToolchainResp.ProtoReflect.Descriptor instead.
+func (*RegisterToolchainResp) Descriptor() ([]byte, []int) {
+	return file_command_command_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RegisterToolchainResp) GetDescriptorName() string {
+	if x != nil {
+		return x.DescriptorName
+	}
+	return ""
+}
+
+func (x *RegisterToolchainResp) GetSeq() string {
+	if x != nil {
+		return x.Seq
+	}
+	return ""
+}
+
+type UploadToolchainFileReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// sha256 hash of the file content, in hex.
+	// must be set in the first message.
+	Hash string `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	// chunk of the file content. chunks are concatenated in order.
+	// each chunk should be smaller than max request message size
+	// of exec_server. e.g. 2MiB.
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *UploadToolchainFileReq) Reset() {
+	*x = UploadToolchainFileReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_command_command_service_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UploadToolchainFileReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadToolchainFileReq) ProtoMessage() {}
+
+func (x *UploadToolchainFileReq) ProtoReflect() protoreflect.Message {
+	mi := &file_command_command_service_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadToolchainFileReq.ProtoReflect.Descriptor instead.
+func (*UploadToolchainFileReq) Descriptor() ([]byte, []int) {
+	return file_command_command_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *UploadToolchainFileReq) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *UploadToolchainFileReq) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type UploadToolchainFileResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// true if the file was already in cmd files storage.
+	AlreadyStored bool `protobuf:"varint,1,opt,name=already_stored,json=alreadyStored,proto3" json:"already_stored,omitempty"`
+}
+
+func (x *UploadToolchainFileResp) Reset() {
+	*x = UploadToolchainFileResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_command_command_service_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UploadToolchainFileResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadToolchainFileResp) ProtoMessage() {}
+
+func (x *UploadToolchainFileResp) ProtoReflect() protoreflect.Message {
+	mi := &file_command_command_service_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadToolchainFileResp.ProtoReflect.Descriptor instead.
+func (*UploadToolchainFileResp) Descriptor() ([]byte, []int) {
+	return file_command_command_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UploadToolchainFileResp) GetAlreadyStored() bool {
+	if x != nil {
+		return x.AlreadyStored
+	}
+	return false
+}
+
 var File_command_command_service_proto protoreflect.FileDescriptor
 
 var file_command_command_service_proto_rawDesc = []byte{
 	0x0a, 0x1d, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
 	0x64, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
-	0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x42, 0x2b, 0x5a, 0x29, 0x67, 0x6f, 0x2e, 0x63,
-	0x68, 0x72, 0x6f, 0x6d, 0x69, 0x75, 0x6d, 0x2e, 0x6f, 0x72, 0x67, 0x2f, 0x67, 0x6f, 0x6d, 0x61,
-	0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f,
-	0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x1a, 0x15, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
+	0x64, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22,
+	0xaa, 0x01, 0x0a, 0x14, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x54, 0x6f, 0x6f, 0x6c,
+	0x63, 0x68, 0x61, 0x69, 0x6e, 0x52, 0x65, 0x71, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x75, 0x6e, 0x74,
+	0x69, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x72, 0x75, 0x6e, 0x74, 0x69,
+	0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x65, 0x62, 0x75, 0x69, 0x6c, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x72, 0x65, 0x62, 0x75, 0x69, 0x6c, 0x74, 0x12, 0x36,
+	0x0a, 0x0a, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x16, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x2e, 0x43, 0x6d, 0x64,
+	0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x52, 0x0a, 0x64, 0x65, 0x73, 0x63,
+	0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x12, 0x17, 0x0a, 0x07, 0x64, 0x72, 0x79, 0x5f, 0x72, 0x75,
+	0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x64, 0x72, 0x79, 0x52, 0x75, 0x6e, 0x4a,
+	0x04, 0x08, 0x04, 0x10, 0x05, 0x52, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x22, 0x66, 0x0a, 0x15,
+	0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x54, 0x6f, 0x6f, 0x6c, 0x63, 0x68, 0x61, 0x69,
+	0x6e, 0x52, 0x65, 0x73, 0x70, 0x12, 0x27, 0x0a, 0x0f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70,
+	0x74, 0x6f, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e,
+	0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x10,
+	0x0a, 0x03, 0x73, 0x65, 0x71, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x73, 0x65, 0x71,
+	0x4a, 0x04, 0x08, 0x02, 0x10, 0x03, 0x52, 0x0c, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x64, 0x5f, 0x66,
+	0x69, 0x6c, 0x65, 0x73, 0x22, 0x40, 0x0a, 0x16, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x54, 0x6f,
+	0x6f, 0x6c, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x12, 0x12,
+	0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x61,
+	0x73, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x40, 0x0a, 0x17, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64,
+	0x54, 0x6f, 0x6f, 0x6c, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x73,
+	0x70, 0x12, 0x25, 0x0a, 0x0e, 0x61, 0x6c, 0x72, 0x65, 0x61, 0x64, 0x79, 0x5f, 0x73, 0x74, 0x6f,
+	0x72, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x61, 0x6c, 0x72, 0x65, 0x61,
+	0x64, 0x79, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x64, 0x32, 0xb4, 0x01, 0x0a, 0x10, 0x54, 0x6f, 0x6f,
+	0x6c, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x4b, 0x0a,
+	0x08, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x12, 0x1d, 0x2e, 0x63, 0x6f, 0x6d, 0x6d,
+	0x61, 0x6e, 0x64, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x54, 0x6f, 0x6f, 0x6c,
+	0x63, 0x68, 0x61, 0x69, 0x6e, 0x52, 0x65, 0x71, 0x1a, 0x1e, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61,
+	0x6e, 0x64, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x54, 0x6f, 0x6f, 0x6c, 0x63,
+	0x68, 0x61, 0x69, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x22, 0x00, 0x12, 0x53, 0x0a, 0x0a, 0x55, 0x70,
+	0x6c, 0x6f, 0x61, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x1f, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x61,
+	0x6e, 0x64, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x54, 0x6f, 0x6f, 0x6c, 0x63, 0x68, 0x61,
+	0x69, 0x6e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x1a, 0x20, 0x2e, 0x63, 0x6f, 0x6d, 0x6d,
+	0x61, 0x6e, 0x64, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x54, 0x6f, 0x6f, 0x6c, 0x63, 0x68,
+	0x61, 0x69, 0x6e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x73, 0x70, 0x22, 0x00, 0x28, 0x01, 0x42,
+	0x2b, 0x5a, 0x29, 0x67, 0x6f, 0x2e, 0x63, 0x68, 0x72, 0x6f, 0x6d, 0x69, 0x75, 0x6d, 0x2e, 0x6f,
+	0x72, 0x67, 0x2f, 0x67, 0x6f, 0x6d, 0x61, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
 }
 
-var file_command_command_service_proto_goTypes = []interface{}{}
+var (
+	file_command_command_service_proto_rawDescOnce sync.Once
+	file_command_command_service_proto_rawDescData = file_command_command_service_proto_rawDesc
+)
+
+func file_command_command_service_proto_rawDescGZIP() []byte {
+	file_command_command_service_proto_rawDescOnce.Do(func() {
+		file_command_command_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_command_command_service_proto_rawDescData)
+	})
+	return file_command_command_service_proto_rawDescData
+}
+
+var file_command_command_service_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_command_command_service_proto_goTypes = []interface{}{
+	(*RegisterToolchainReq)(nil),    // 0: command.RegisterToolchainReq
+	(*RegisterToolchainResp)(nil),   // 1: command.RegisterToolchainResp
+	(*UploadToolchainFileReq)(nil),  // 2: command.UploadToolchainFileReq
+	(*UploadToolchainFileResp)(nil), // 3: command.UploadToolchainFileResp
+	(*CmdDescriptor)(nil),           // 4: command.CmdDescriptor
+}
 var file_command_command_service_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	4, // 0: command.RegisterToolchainReq.descriptor:type_name -> command.CmdDescriptor
+	0, // 1: command.ToolchainService.Register:input_type -> command.RegisterToolchainReq
+	2, // 2: command.ToolchainService.UploadFile:input_type -> command.UploadToolchainFileReq
+	1, // 3: command.ToolchainService.Register:output_type -> command.RegisterToolchainResp
+	3, // 4: command.ToolchainService.UploadFile:output_type -> command.UploadToolchainFileResp
+	3, // [3:5] is the sub-list for method output_type
+	1, // [1:3] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_command_command_service_proto_init() }
@@ -48,18 +353,70 @@ func file_command_command_service_proto_init() {
 	if File_command_command_service_proto != nil {
 		return
 	}
+	file_command_command_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_command_command_service_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RegisterToolchainReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_command_command_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RegisterToolchainResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_command_command_service_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UploadToolchainFileReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_command_command_service_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UploadToolchainFileResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_command_command_service_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   0,
+			NumMessages:   4,
 			NumExtensions: 0,
-			NumServices:   0,
+			NumServices:   1,
 		},
 		GoTypes:           file_command_command_service_proto_goTypes,
 		DependencyIndexes: file_command_command_service_proto_depIdxs,
+		MessageInfos:      file_command_command_service_proto_msgTypes,
 	}.Build()
 	File_command_command_service_proto = out.File
 	file_command_command_service_proto_rawDesc = nil
@@ -0,0 +1,188 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package command
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ToolchainServiceClient is the client API for ToolchainService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ToolchainServiceClient interface {
+	// Register registers a toolchain in toolchain config.
+	// It writes the descriptor in toolchain config and bumps seq of
+	// the runtime, then reloads toolchain config.
+	// Files in setup of the descriptor must be uploaded by UploadFile
+	// beforehand.
+	Register(ctx context.Context, in *RegisterToolchainReq, opts ...grpc.CallOption) (*RegisterToolchainResp, error)
+	// UploadFile uploads a file of toolchain in cmd files storage.
+	// Content is sent in chunks, so file size is not limited by max
+	// message size.
+	UploadFile(ctx context.Context, opts ...grpc.CallOption) (ToolchainService_UploadFileClient, error)
+}
+
+type toolchainServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewToolchainServiceClient(cc grpc.ClientConnInterface) ToolchainServiceClient {
+	return &toolchainServiceClient{cc}
+}
+
+func (c *toolchainServiceClient) Register(ctx context.Context, in *RegisterToolchainReq, opts ...grpc.CallOption) (*RegisterToolchainResp, error) {
+	out := new(RegisterToolchainResp)
+	err := c.cc.Invoke(ctx, "/command.ToolchainService/Register", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *toolchainServiceClient) UploadFile(ctx context.Context, opts ...grpc.CallOption) (ToolchainService_UploadFileClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ToolchainService_ServiceDesc.Streams[0], "/command.ToolchainService/UploadFile", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &toolchainServiceUploadFileClient{stream}
+	return x, nil
+}
+
+type ToolchainService_UploadFileClient interface {
+	Send(*UploadToolchainFileReq) error
+	CloseAndRecv() (*UploadToolchainFileResp, error)
+	grpc.ClientStream
+}
+
+type toolchainServiceUploadFileClient struct {
+	grpc.ClientStream
+}
+
+func (x *toolchainServiceUploadFileClient) Send(m *UploadToolchainFileReq) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *toolchainServiceUploadFileClient) CloseAndRecv() (*UploadToolchainFileResp, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadToolchainFileResp)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ToolchainServiceServer is the server API for ToolchainService service.
+// All implementations must embed UnimplementedToolchainServiceServer
+// for forward compatibility
+type ToolchainServiceServer interface {
+	// Register registers a toolchain in toolchain config.
+	// It writes the descriptor in toolchain config and bumps seq of
+	// the runtime, then reloads toolchain config.
+	// Files in setup of the descriptor must be uploaded by UploadFile
+	// beforehand.
+	Register(context.Context, *RegisterToolchainReq) (*RegisterToolchainResp, error)
+	// UploadFile uploads a file of toolchain in cmd files storage.
+	// Content is sent in chunks, so file size is not limited by max
+	// message size.
+	UploadFile(ToolchainService_UploadFileServer) error
+	mustEmbedUnimplementedToolchainServiceServer()
+}
+
+// UnimplementedToolchainServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedToolchainServiceServer struct {
+}
+
+func (UnimplementedToolchainServiceServer) Register(context.Context, *RegisterToolchainReq) (*RegisterToolchainResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedToolchainServiceServer) UploadFile(ToolchainService_UploadFileServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadFile not implemented")
+}
+func (UnimplementedToolchainServiceServer) mustEmbedUnimplementedToolchainServiceServer() {}
+
+// UnsafeToolchainServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ToolchainServiceServer will
+// result in compilation errors.
+type UnsafeToolchainServiceServer interface {
+	mustEmbedUnimplementedToolchainServiceServer()
+}
+
+func RegisterToolchainServiceServer(s grpc.ServiceRegistrar, srv ToolchainServiceServer) {
+	s.RegisterService(&ToolchainService_ServiceDesc, srv)
+}
+
+func _ToolchainService_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterToolchainReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ToolchainServiceServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/command.ToolchainService/Register",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ToolchainServiceServer).Register(ctx, req.(*RegisterToolchainReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ToolchainService_UploadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ToolchainServiceServer).UploadFile(&toolchainServiceUploadFileServer{stream})
+}
+
+type ToolchainService_UploadFileServer interface {
+	SendAndClose(*UploadToolchainFileResp) error
+	Recv() (*UploadToolchainFileReq, error)
+	grpc.ServerStream
+}
+
+type toolchainServiceUploadFileServer struct {
+	grpc.ServerStream
+}
+
+func (x *toolchainServiceUploadFileServer) SendAndClose(m *UploadToolchainFileResp) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *toolchainServiceUploadFileServer) Recv() (*UploadToolchainFileReq, error) {
+	m := new(UploadToolchainFileReq)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ToolchainService_ServiceDesc is the grpc.ServiceDesc for ToolchainService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ToolchainService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "command.ToolchainService",
+	HandlerType: (*ToolchainServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _ToolchainService_Register_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadFile",
+			Handler:       _ToolchainService_UploadFile_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "command/command_service.proto",
+}
@@ -0,0 +1,223 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        v3.19.0
+// source: frontend/client_version.proto
+
+package frontend
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ClientVersionPolicy is policy of goma client version.
+// goma client version is identified by commit hash and commit time
+// in user-agent of compiler_proxy.
+type ClientVersionPolicy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// clients built from commit older than min_commit_time violate
+	// the policy.
+	MinCommitTime *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=min_commit_time,json=minCommitTime,proto3" json:"min_commit_time,omitempty"`
+	// clients built from these commits violate the policy.
+	// prefix of commit hash (e.g. abbreviated hash) is allowed.
+	BlockedCommitHash []string `protobuf:"bytes,2,rep,name=blocked_commit_hash,json=blockedCommitHash,proto3" json:"blocked_commit_hash,omitempty"`
+	// clients whose user-agent is not compiler_proxy violate
+	// the policy if true.
+	RejectUnknownClient bool `protobuf:"varint,3,opt,name=reject_unknown_client,json=rejectUnknownClient,proto3" json:"reject_unknown_client,omitempty"`
+	// ids of group exempted from the policy.
+	// group id matches with group id in ACL.
+	ExemptGroupId []string `protobuf:"bytes,4,rep,name=exempt_group_id,json=exemptGroupId,proto3" json:"exempt_group_id,omitempty"`
+	// if true, requests violating the policy are not rejected,
+	// but warned in response header.
+	WarnOnly bool `protobuf:"varint,5,opt,name=warn_only,json=warnOnly,proto3" json:"warn_only,omitempty"`
+	// message to tell users how to fix, e.g. how to upgrade client.
+	Message string `protobuf:"bytes,6,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *ClientVersionPolicy) Reset() {
+	*x = ClientVersionPolicy{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_frontend_client_version_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClientVersionPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientVersionPolicy) ProtoMessage() {}
+
+func (x *ClientVersionPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_frontend_client_version_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientVersionPolicy.ProtoReflect.Descriptor instead.
+func (*ClientVersionPolicy) Descriptor() ([]byte, []int) {
+	return file_frontend_client_version_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ClientVersionPolicy) GetMinCommitTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.MinCommitTime
+	}
+	return nil
+}
+
+func (x *ClientVersionPolicy) GetBlockedCommitHash() []string {
+	if x != nil {
+		return x.BlockedCommitHash
+	}
+	return nil
+}
+
+func (x *ClientVersionPolicy) GetRejectUnknownClient() bool {
+	if x != nil {
+		return x.RejectUnknownClient
+	}
+	return false
+}
+
+func (x *ClientVersionPolicy) GetExemptGroupId() []string {
+	if x != nil {
+		return x.ExemptGroupId
+	}
+	return nil
+}
+
+func (x *ClientVersionPolicy) GetWarnOnly() bool {
+	if x != nil {
+		return x.WarnOnly
+	}
+	return false
+}
+
+func (x *ClientVersionPolicy) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+var File_frontend_client_version_proto protoreflect.FileDescriptor
+
+var file_frontend_client_version_proto_rawDesc = []byte{
+	0x0a, 0x1d, 0x66, 0x72, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x64, 0x2f, 0x63, 0x6c, 0x69, 0x65, 0x6e,
+	0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x08, 0x66, 0x72, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x64, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x9c, 0x02, 0x0a, 0x13, 0x43,
+	0x6c, 0x69, 0x65, 0x6e, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x50, 0x6f, 0x6c, 0x69,
+	0x63, 0x79, 0x12, 0x42, 0x0a, 0x0f, 0x6d, 0x69, 0x6e, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74,
+	0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0d, 0x6d, 0x69, 0x6e, 0x43, 0x6f, 0x6d, 0x6d,
+	0x69, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x2e, 0x0a, 0x13, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x65,
+	0x64, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x02, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x11, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x43, 0x6f, 0x6d, 0x6d,
+	0x69, 0x74, 0x48, 0x61, 0x73, 0x68, 0x12, 0x32, 0x0a, 0x15, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74,
+	0x5f, 0x75, 0x6e, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x5f, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x13, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x55, 0x6e, 0x6b,
+	0x6e, 0x6f, 0x77, 0x6e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x12, 0x26, 0x0a, 0x0f, 0x65, 0x78,
+	0x65, 0x6d, 0x70, 0x74, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x0d, 0x65, 0x78, 0x65, 0x6d, 0x70, 0x74, 0x47, 0x72, 0x6f, 0x75, 0x70,
+	0x49, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x77, 0x61, 0x72, 0x6e, 0x5f, 0x6f, 0x6e, 0x6c, 0x79, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x4f, 0x6e, 0x6c, 0x79, 0x12,
+	0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x42, 0x2c, 0x5a, 0x2a, 0x67, 0x6f, 0x2e,
+	0x63, 0x68, 0x72, 0x6f, 0x6d, 0x69, 0x75, 0x6d, 0x2e, 0x6f, 0x72, 0x67, 0x2f, 0x67, 0x6f, 0x6d,
+	0x61, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x66,
+	0x72, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x64, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_frontend_client_version_proto_rawDescOnce sync.Once
+	file_frontend_client_version_proto_rawDescData = file_frontend_client_version_proto_rawDesc
+)
+
+func file_frontend_client_version_proto_rawDescGZIP() []byte {
+	file_frontend_client_version_proto_rawDescOnce.Do(func() {
+		file_frontend_client_version_proto_rawDescData = protoimpl.X.CompressGZIP(file_frontend_client_version_proto_rawDescData)
+	})
+	return file_frontend_client_version_proto_rawDescData
+}
+
+var file_frontend_client_version_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_frontend_client_version_proto_goTypes = []interface{}{
+	(*ClientVersionPolicy)(nil),   // 0: frontend.ClientVersionPolicy
+	(*timestamppb.Timestamp)(nil), // 1: google.protobuf.Timestamp
+}
+var file_frontend_client_version_proto_depIdxs = []int32{
+	1, // 0: frontend.ClientVersionPolicy.min_commit_time:type_name -> google.protobuf.Timestamp
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_frontend_client_version_proto_init() }
+func file_frontend_client_version_proto_init() {
+	if File_frontend_client_version_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_frontend_client_version_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClientVersionPolicy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_frontend_client_version_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_frontend_client_version_proto_goTypes,
+		DependencyIndexes: file_frontend_client_version_proto_depIdxs,
+		MessageInfos:      file_frontend_client_version_proto_msgTypes,
+	}.Build()
+	File_frontend_client_version_proto = out.File
+	file_frontend_client_version_proto_rawDesc = nil
+	file_frontend_client_version_proto_goTypes = nil
+	file_frontend_client_version_proto_depIdxs = nil
+}
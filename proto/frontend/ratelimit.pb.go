@@ -0,0 +1,403 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        v3.19.0
+// source: frontend/ratelimit.proto
+
+package frontend
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Endpoint is goma API endpoint served by frontend.
+type Endpoint int32
+
+const (
+	// matches any endpoint.
+	Endpoint_ANY_ENDPOINT Endpoint = 0
+	// /e
+	Endpoint_EXEC Endpoint = 1
+	// /s
+	Endpoint_STORE_FILE Endpoint = 2
+	// /l
+	Endpoint_LOOKUP_FILE Endpoint = 3
+	// /sl
+	Endpoint_EXECLOG Endpoint = 4
+)
+
+// Enum value maps for Endpoint.
+var (
+	Endpoint_name = map[int32]string{
+		0: "ANY_ENDPOINT",
+		1: "EXEC",
+		2: "STORE_FILE",
+		3: "LOOKUP_FILE",
+		4: "EXECLOG",
+	}
+	Endpoint_value = map[string]int32{
+		"ANY_ENDPOINT": 0,
+		"EXEC":         1,
+		"STORE_FILE":   2,
+		"LOOKUP_FILE":  3,
+		"EXECLOG":      4,
+	}
+)
+
+func (x Endpoint) Enum() *Endpoint {
+	p := new(Endpoint)
+	*p = x
+	return p
+}
+
+func (x Endpoint) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Endpoint) Descriptor() protoreflect.EnumDescriptor {
+	return file_frontend_ratelimit_proto_enumTypes[0].Descriptor()
+}
+
+func (Endpoint) Type() protoreflect.EnumType {
+	return &file_frontend_ratelimit_proto_enumTypes[0]
+}
+
+func (x Endpoint) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Endpoint.Descriptor instead.
+func (Endpoint) EnumDescriptor() ([]byte, []int) {
+	return file_frontend_ratelimit_proto_rawDescGZIP(), []int{0}
+}
+
+// RateLimit is token bucket rate limit.
+type RateLimit struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// number of tokens refilled per second.
+	Qps float64 `protobuf:"fixed64,1,opt,name=qps,proto3" json:"qps,omitempty"`
+	// bucket size. if zero, ceil(qps) is used.
+	Burst int32 `protobuf:"varint,2,opt,name=burst,proto3" json:"burst,omitempty"`
+}
+
+func (x *RateLimit) Reset() {
+	*x = RateLimit{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_frontend_ratelimit_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RateLimit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RateLimit) ProtoMessage() {}
+
+func (x *RateLimit) ProtoReflect() protoreflect.Message {
+	mi := &file_frontend_ratelimit_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RateLimit.ProtoReflect.Descriptor instead.
+func (*RateLimit) Descriptor() ([]byte, []int) {
+	return file_frontend_ratelimit_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RateLimit) GetQps() float64 {
+	if x != nil {
+		return x.Qps
+	}
+	return 0
+}
+
+func (x *RateLimit) GetBurst() int32 {
+	if x != nil {
+		return x.Burst
+	}
+	return 0
+}
+
+type RateLimitRule struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// endpoint to apply the rule.
+	Endpoint Endpoint `protobuf:"varint,1,opt,name=endpoint,proto3,enum=frontend.Endpoint" json:"endpoint,omitempty"`
+	// id of group to apply the rule.
+	// group id matches with group id in ACL.
+	// empty group_id matches any group.
+	GroupId string `protobuf:"bytes,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	// rate limit for each end user (email) in the group.
+	// if not set, no limit per end user.
+	PerUser *RateLimit `protobuf:"bytes,3,opt,name=per_user,json=perUser,proto3" json:"per_user,omitempty"`
+	// rate limit shared by all end users in the group.
+	// if not set, no limit per group.
+	PerGroup *RateLimit `protobuf:"bytes,4,opt,name=per_group,json=perGroup,proto3" json:"per_group,omitempty"`
+}
+
+func (x *RateLimitRule) Reset() {
+	*x = RateLimitRule{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_frontend_ratelimit_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RateLimitRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RateLimitRule) ProtoMessage() {}
+
+func (x *RateLimitRule) ProtoReflect() protoreflect.Message {
+	mi := &file_frontend_ratelimit_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RateLimitRule.ProtoReflect.Descriptor instead.
+func (*RateLimitRule) Descriptor() ([]byte, []int) {
+	return file_frontend_ratelimit_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RateLimitRule) GetEndpoint() Endpoint {
+	if x != nil {
+		return x.Endpoint
+	}
+	return Endpoint_ANY_ENDPOINT
+}
+
+func (x *RateLimitRule) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *RateLimitRule) GetPerUser() *RateLimit {
+	if x != nil {
+		return x.PerUser
+	}
+	return nil
+}
+
+func (x *RateLimitRule) GetPerGroup() *RateLimit {
+	if x != nil {
+		return x.PerGroup
+	}
+	return nil
+}
+
+type RateLimitConfig struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// first matched rule will be used.
+	Rules []*RateLimitRule `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
+}
+
+func (x *RateLimitConfig) Reset() {
+	*x = RateLimitConfig{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_frontend_ratelimit_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RateLimitConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RateLimitConfig) ProtoMessage() {}
+
+func (x *RateLimitConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_frontend_ratelimit_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RateLimitConfig.ProtoReflect.Descriptor instead.
+func (*RateLimitConfig) Descriptor() ([]byte, []int) {
+	return file_frontend_ratelimit_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RateLimitConfig) GetRules() []*RateLimitRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+var File_frontend_ratelimit_proto protoreflect.FileDescriptor
+
+var file_frontend_ratelimit_proto_rawDesc = []byte{
+	0x0a, 0x18, 0x66, 0x72, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x64, 0x2f, 0x72, 0x61, 0x74, 0x65, 0x6c,
+	0x69, 0x6d, 0x69, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x66, 0x72, 0x6f, 0x6e,
+	0x74, 0x65, 0x6e, 0x64, 0x22, 0x33, 0x0a, 0x09, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69,
+	0x74, 0x12, 0x10, 0x0a, 0x03, 0x71, 0x70, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03,
+	0x71, 0x70, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x75, 0x72, 0x73, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x05, 0x62, 0x75, 0x72, 0x73, 0x74, 0x22, 0xbc, 0x01, 0x0a, 0x0d, 0x52, 0x61,
+	0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x75, 0x6c, 0x65, 0x12, 0x2e, 0x0a, 0x08, 0x65,
+	0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x12, 0x2e,
+	0x66, 0x72, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x64, 0x2e, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e,
+	0x74, 0x52, 0x08, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x67,
+	0x72, 0x6f, 0x75, 0x70, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x67,
+	0x72, 0x6f, 0x75, 0x70, 0x49, 0x64, 0x12, 0x2e, 0x0a, 0x08, 0x70, 0x65, 0x72, 0x5f, 0x75, 0x73,
+	0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x66, 0x72, 0x6f, 0x6e, 0x74,
+	0x65, 0x6e, 0x64, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x07, 0x70,
+	0x65, 0x72, 0x55, 0x73, 0x65, 0x72, 0x12, 0x30, 0x0a, 0x09, 0x70, 0x65, 0x72, 0x5f, 0x67, 0x72,
+	0x6f, 0x75, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x66, 0x72, 0x6f, 0x6e,
+	0x74, 0x65, 0x6e, 0x64, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x08,
+	0x70, 0x65, 0x72, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x22, 0x40, 0x0a, 0x0f, 0x52, 0x61, 0x74, 0x65,
+	0x4c, 0x69, 0x6d, 0x69, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x2d, 0x0a, 0x05, 0x72,
+	0x75, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x66, 0x72, 0x6f,
+	0x6e, 0x74, 0x65, 0x6e, 0x64, 0x2e, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52,
+	0x75, 0x6c, 0x65, 0x52, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x2a, 0x54, 0x0a, 0x08, 0x45, 0x6e,
+	0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x10, 0x0a, 0x0c, 0x41, 0x4e, 0x59, 0x5f, 0x45, 0x4e,
+	0x44, 0x50, 0x4f, 0x49, 0x4e, 0x54, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x45, 0x58, 0x45, 0x43,
+	0x10, 0x01, 0x12, 0x0e, 0x0a, 0x0a, 0x53, 0x54, 0x4f, 0x52, 0x45, 0x5f, 0x46, 0x49, 0x4c, 0x45,
+	0x10, 0x02, 0x12, 0x0f, 0x0a, 0x0b, 0x4c, 0x4f, 0x4f, 0x4b, 0x55, 0x50, 0x5f, 0x46, 0x49, 0x4c,
+	0x45, 0x10, 0x03, 0x12, 0x0b, 0x0a, 0x07, 0x45, 0x58, 0x45, 0x43, 0x4c, 0x4f, 0x47, 0x10, 0x04,
+	0x42, 0x2c, 0x5a, 0x2a, 0x67, 0x6f, 0x2e, 0x63, 0x68, 0x72, 0x6f, 0x6d, 0x69, 0x75, 0x6d, 0x2e,
+	0x6f, 0x72, 0x67, 0x2f, 0x67, 0x6f, 0x6d, 0x61, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x66, 0x72, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x64, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_frontend_ratelimit_proto_rawDescOnce sync.Once
+	file_frontend_ratelimit_proto_rawDescData = file_frontend_ratelimit_proto_rawDesc
+)
+
+func file_frontend_ratelimit_proto_rawDescGZIP() []byte {
+	file_frontend_ratelimit_proto_rawDescOnce.Do(func() {
+		file_frontend_ratelimit_proto_rawDescData = protoimpl.X.CompressGZIP(file_frontend_ratelimit_proto_rawDescData)
+	})
+	return file_frontend_ratelimit_proto_rawDescData
+}
+
+var file_frontend_ratelimit_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_frontend_ratelimit_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_frontend_ratelimit_proto_goTypes = []interface{}{
+	(Endpoint)(0),           // 0: frontend.Endpoint
+	(*RateLimit)(nil),       // 1: frontend.RateLimit
+	(*RateLimitRule)(nil),   // 2: frontend.RateLimitRule
+	(*RateLimitConfig)(nil), // 3: frontend.RateLimitConfig
+}
+var file_frontend_ratelimit_proto_depIdxs = []int32{
+	0, // 0: frontend.RateLimitRule.endpoint:type_name -> frontend.Endpoint
+	1, // 1: frontend.RateLimitRule.per_user:type_name -> frontend.RateLimit
+	1, // 2: frontend.RateLimitRule.per_group:type_name -> frontend.RateLimit
+	2, // 3: frontend.RateLimitConfig.rules:type_name -> frontend.RateLimitRule
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_frontend_ratelimit_proto_init() }
+func file_frontend_ratelimit_proto_init() {
+	if File_frontend_ratelimit_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_frontend_ratelimit_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RateLimit); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_frontend_ratelimit_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RateLimitRule); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_frontend_ratelimit_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RateLimitConfig); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_frontend_ratelimit_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_frontend_ratelimit_proto_goTypes,
+		DependencyIndexes: file_frontend_ratelimit_proto_depIdxs,
+		EnumInfos:         file_frontend_ratelimit_proto_enumTypes,
+		MessageInfos:      file_frontend_ratelimit_proto_msgTypes,
+	}.Build()
+	File_frontend_ratelimit_proto = out.File
+	file_frontend_ratelimit_proto_rawDesc = nil
+	file_frontend_ratelimit_proto_goTypes = nil
+	file_frontend_ratelimit_proto_depIdxs = nil
+}
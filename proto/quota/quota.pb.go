@@ -0,0 +1,465 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        v3.19.0
+// source: quota/quota.proto
+
+package quota
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GroupQuota_Period int32
+
+const (
+	GroupQuota_DAILY   GroupQuota_Period = 0
+	GroupQuota_MONTHLY GroupQuota_Period = 1
+)
+
+// Enum value maps for GroupQuota_Period.
+var (
+	GroupQuota_Period_name = map[int32]string{
+		0: "DAILY",
+		1: "MONTHLY",
+	}
+	GroupQuota_Period_value = map[string]int32{
+		"DAILY":   0,
+		"MONTHLY": 1,
+	}
+)
+
+func (x GroupQuota_Period) Enum() *GroupQuota_Period {
+	p := new(GroupQuota_Period)
+	*p = x
+	return p
+}
+
+func (x GroupQuota_Period) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (GroupQuota_Period) Descriptor() protoreflect.EnumDescriptor {
+	return file_quota_quota_proto_enumTypes[0].Descriptor()
+}
+
+func (GroupQuota_Period) Type() protoreflect.EnumType {
+	return &file_quota_quota_proto_enumTypes[0]
+}
+
+func (x GroupQuota_Period) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use GroupQuota_Period.Descriptor instead.
+func (GroupQuota_Period) EnumDescriptor() ([]byte, []int) {
+	return file_quota_quota_proto_rawDescGZIP(), []int{1, 0}
+}
+
+type GroupQuota_Action int32
+
+const (
+	// allow request.
+	GroupQuota_ALLOW GroupQuota_Action = 0
+	// serve only cached results. request without cached result
+	// will fall back to local.
+	GroupQuota_CACHE_ONLY GroupQuota_Action = 1
+	// all requests will fall back to local.
+	GroupQuota_LOCAL_FALLBACK GroupQuota_Action = 2
+)
+
+// Enum value maps for GroupQuota_Action.
+var (
+	GroupQuota_Action_name = map[int32]string{
+		0: "ALLOW",
+		1: "CACHE_ONLY",
+		2: "LOCAL_FALLBACK",
+	}
+	GroupQuota_Action_value = map[string]int32{
+		"ALLOW":          0,
+		"CACHE_ONLY":     1,
+		"LOCAL_FALLBACK": 2,
+	}
+)
+
+func (x GroupQuota_Action) Enum() *GroupQuota_Action {
+	p := new(GroupQuota_Action)
+	*p = x
+	return p
+}
+
+func (x GroupQuota_Action) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (GroupQuota_Action) Descriptor() protoreflect.EnumDescriptor {
+	return file_quota_quota_proto_enumTypes[1].Descriptor()
+}
+
+func (GroupQuota_Action) Type() protoreflect.EnumType {
+	return &file_quota_quota_proto_enumTypes[1]
+}
+
+func (x GroupQuota_Action) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use GroupQuota_Action.Descriptor instead.
+func (GroupQuota_Action) EnumDescriptor() ([]byte, []int) {
+	return file_quota_quota_proto_rawDescGZIP(), []int{1, 1}
+}
+
+// Limit is resource usage limit in a period.
+// zero value means no limit.
+type Limit struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// remote worker time in seconds.
+	CpuSeconds int64 `protobuf:"varint,1,opt,name=cpu_seconds,json=cpuSeconds,proto3" json:"cpu_seconds,omitempty"`
+	// bytes uploaded by StoreFile.
+	UploadBytes int64 `protobuf:"varint,2,opt,name=upload_bytes,json=uploadBytes,proto3" json:"upload_bytes,omitempty"`
+	// bytes downloaded by LookupFile and Exec response.
+	DownloadBytes int64 `protobuf:"varint,3,opt,name=download_bytes,json=downloadBytes,proto3" json:"download_bytes,omitempty"`
+}
+
+func (x *Limit) Reset() {
+	*x = Limit{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_quota_quota_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Limit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Limit) ProtoMessage() {}
+
+func (x *Limit) ProtoReflect() protoreflect.Message {
+	mi := &file_quota_quota_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Limit.ProtoReflect.Descriptor instead.
+func (*Limit) Descriptor() ([]byte, []int) {
+	return file_quota_quota_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Limit) GetCpuSeconds() int64 {
+	if x != nil {
+		return x.CpuSeconds
+	}
+	return 0
+}
+
+func (x *Limit) GetUploadBytes() int64 {
+	if x != nil {
+		return x.UploadBytes
+	}
+	return 0
+}
+
+func (x *Limit) GetDownloadBytes() int64 {
+	if x != nil {
+		return x.DownloadBytes
+	}
+	return 0
+}
+
+type GroupQuota struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// id of group to apply the quota.
+	// group id matches with group id in ACL.
+	// empty group_id matches any group, and usage is accounted per group.
+	GroupId string `protobuf:"bytes,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	// period to accumulate usage. period is in UTC.
+	Period GroupQuota_Period `protobuf:"varint,2,opt,name=period,proto3,enum=quota.GroupQuota_Period" json:"period,omitempty"`
+	// if usage exceeds soft_limit, it logs warning.
+	SoftLimit *Limit `protobuf:"bytes,3,opt,name=soft_limit,json=softLimit,proto3" json:"soft_limit,omitempty"`
+	// if usage exceeds hard_limit, action will be taken
+	// for Exec requests.
+	HardLimit *Limit            `protobuf:"bytes,4,opt,name=hard_limit,json=hardLimit,proto3" json:"hard_limit,omitempty"`
+	Action    GroupQuota_Action `protobuf:"varint,5,opt,name=action,proto3,enum=quota.GroupQuota_Action" json:"action,omitempty"`
+}
+
+func (x *GroupQuota) Reset() {
+	*x = GroupQuota{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_quota_quota_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GroupQuota) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupQuota) ProtoMessage() {}
+
+func (x *GroupQuota) ProtoReflect() protoreflect.Message {
+	mi := &file_quota_quota_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupQuota.ProtoReflect.Descriptor instead.
+func (*GroupQuota) Descriptor() ([]byte, []int) {
+	return file_quota_quota_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GroupQuota) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *GroupQuota) GetPeriod() GroupQuota_Period {
+	if x != nil {
+		return x.Period
+	}
+	return GroupQuota_DAILY
+}
+
+func (x *GroupQuota) GetSoftLimit() *Limit {
+	if x != nil {
+		return x.SoftLimit
+	}
+	return nil
+}
+
+func (x *GroupQuota) GetHardLimit() *Limit {
+	if x != nil {
+		return x.HardLimit
+	}
+	return nil
+}
+
+func (x *GroupQuota) GetAction() GroupQuota_Action {
+	if x != nil {
+		return x.Action
+	}
+	return GroupQuota_ALLOW
+}
+
+type QuotaConfig struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// all matched quotas will be applied.
+	Quotas []*GroupQuota `protobuf:"bytes,1,rep,name=quotas,proto3" json:"quotas,omitempty"`
+}
+
+func (x *QuotaConfig) Reset() {
+	*x = QuotaConfig{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_quota_quota_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QuotaConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaConfig) ProtoMessage() {}
+
+func (x *QuotaConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_quota_quota_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaConfig.ProtoReflect.Descriptor instead.
+func (*QuotaConfig) Descriptor() ([]byte, []int) {
+	return file_quota_quota_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *QuotaConfig) GetQuotas() []*GroupQuota {
+	if x != nil {
+		return x.Quotas
+	}
+	return nil
+}
+
+var File_quota_quota_proto protoreflect.FileDescriptor
+
+var file_quota_quota_proto_rawDesc = []byte{
+	0x0a, 0x11, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x2f, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x05, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x22, 0x72, 0x0a, 0x05, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x63, 0x70, 0x75, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e,
+	0x64, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x63, 0x70, 0x75, 0x53, 0x65, 0x63,
+	0x6f, 0x6e, 0x64, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x5f, 0x62,
+	0x79, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x75, 0x70, 0x6c, 0x6f,
+	0x61, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x64, 0x6f, 0x77, 0x6e, 0x6c,
+	0x6f, 0x61, 0x64, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0d, 0x64, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x22, 0xc0,
+	0x02, 0x0a, 0x0a, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x51, 0x75, 0x6f, 0x74, 0x61, 0x12, 0x19, 0x0a,
+	0x08, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x49, 0x64, 0x12, 0x30, 0x0a, 0x06, 0x70, 0x65, 0x72, 0x69,
+	0x6f, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x71, 0x75, 0x6f, 0x74, 0x61,
+	0x2e, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x51, 0x75, 0x6f, 0x74, 0x61, 0x2e, 0x50, 0x65, 0x72, 0x69,
+	0x6f, 0x64, 0x52, 0x06, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x12, 0x2b, 0x0a, 0x0a, 0x73, 0x6f,
+	0x66, 0x74, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c,
+	0x2e, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x2e, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x09, 0x73, 0x6f,
+	0x66, 0x74, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x2b, 0x0a, 0x0a, 0x68, 0x61, 0x72, 0x64, 0x5f,
+	0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x71, 0x75,
+	0x6f, 0x74, 0x61, 0x2e, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x52, 0x09, 0x68, 0x61, 0x72, 0x64, 0x4c,
+	0x69, 0x6d, 0x69, 0x74, 0x12, 0x30, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x2e, 0x47, 0x72, 0x6f,
+	0x75, 0x70, 0x51, 0x75, 0x6f, 0x74, 0x61, 0x2e, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06,
+	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x20, 0x0a, 0x06, 0x50, 0x65, 0x72, 0x69, 0x6f, 0x64,
+	0x12, 0x09, 0x0a, 0x05, 0x44, 0x41, 0x49, 0x4c, 0x59, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x4d,
+	0x4f, 0x4e, 0x54, 0x48, 0x4c, 0x59, 0x10, 0x01, 0x22, 0x37, 0x0a, 0x06, 0x41, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x09, 0x0a, 0x05, 0x41, 0x4c, 0x4c, 0x4f, 0x57, 0x10, 0x00, 0x12, 0x0e, 0x0a,
+	0x0a, 0x43, 0x41, 0x43, 0x48, 0x45, 0x5f, 0x4f, 0x4e, 0x4c, 0x59, 0x10, 0x01, 0x12, 0x12, 0x0a,
+	0x0e, 0x4c, 0x4f, 0x43, 0x41, 0x4c, 0x5f, 0x46, 0x41, 0x4c, 0x4c, 0x42, 0x41, 0x43, 0x4b, 0x10,
+	0x02, 0x22, 0x38, 0x0a, 0x0b, 0x51, 0x75, 0x6f, 0x74, 0x61, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x12, 0x29, 0x0a, 0x06, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x11, 0x2e, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x2e, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x51, 0x75,
+	0x6f, 0x74, 0x61, 0x52, 0x06, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x73, 0x42, 0x29, 0x5a, 0x27, 0x67,
+	0x6f, 0x2e, 0x63, 0x68, 0x72, 0x6f, 0x6d, 0x69, 0x75, 0x6d, 0x2e, 0x6f, 0x72, 0x67, 0x2f, 0x67,
+	0x6f, 0x6d, 0x61, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2f, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_quota_quota_proto_rawDescOnce sync.Once
+	file_quota_quota_proto_rawDescData = file_quota_quota_proto_rawDesc
+)
+
+func file_quota_quota_proto_rawDescGZIP() []byte {
+	file_quota_quota_proto_rawDescOnce.Do(func() {
+		file_quota_quota_proto_rawDescData = protoimpl.X.CompressGZIP(file_quota_quota_proto_rawDescData)
+	})
+	return file_quota_quota_proto_rawDescData
+}
+
+var file_quota_quota_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_quota_quota_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_quota_quota_proto_goTypes = []interface{}{
+	(GroupQuota_Period)(0), // 0: quota.GroupQuota.Period
+	(GroupQuota_Action)(0), // 1: quota.GroupQuota.Action
+	(*Limit)(nil),          // 2: quota.Limit
+	(*GroupQuota)(nil),     // 3: quota.GroupQuota
+	(*QuotaConfig)(nil),    // 4: quota.QuotaConfig
+}
+var file_quota_quota_proto_depIdxs = []int32{
+	0, // 0: quota.GroupQuota.period:type_name -> quota.GroupQuota.Period
+	2, // 1: quota.GroupQuota.soft_limit:type_name -> quota.Limit
+	2, // 2: quota.GroupQuota.hard_limit:type_name -> quota.Limit
+	1, // 3: quota.GroupQuota.action:type_name -> quota.GroupQuota.Action
+	3, // 4: quota.QuotaConfig.quotas:type_name -> quota.GroupQuota
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_quota_quota_proto_init() }
+func file_quota_quota_proto_init() {
+	if File_quota_quota_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_quota_quota_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Limit); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_quota_quota_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GroupQuota); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_quota_quota_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QuotaConfig); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_quota_quota_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_quota_quota_proto_goTypes,
+		DependencyIndexes: file_quota_quota_proto_depIdxs,
+		EnumInfos:         file_quota_quota_proto_enumTypes,
+		MessageInfos:      file_quota_quota_proto_msgTypes,
+	}.Build()
+	File_quota_quota_proto = out.File
+	file_quota_quota_proto_rawDesc = nil
+	file_quota_quota_proto_goTypes = nil
+	file_quota_quota_proto_depIdxs = nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package quota
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/encoding/prototext"
+
+	"go.chromium.org/goma/server/cache/redis"
+	"go.chromium.org/goma/server/log"
+	pb "go.chromium.org/goma/server/proto/quota"
+)
+
+// NewManagerFromConfig creates new Manager for config, text proto of
+// QuotaConfig.
+// Usage is stored in redis if redis is available by redis.AddrFromEnv,
+// so it is shared among servers using the same redis. Otherwise, it is
+// stored in LocalStore.
+// It returns nil Manager if config is empty.
+// Returned func would release resources associated with Manager.
+func NewManagerFromConfig(ctx context.Context, config string) (*Manager, func(), error) {
+	if config == "" {
+		return nil, func() {}, nil
+	}
+	logger := log.FromContext(ctx)
+	cfg := &pb.QuotaConfig{}
+	err := prototext.Unmarshal([]byte(config), cfg)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	m := &Manager{}
+	done := func() {}
+	addr, err := redis.AddrFromEnv()
+	if err != nil {
+		logger.Warnf("redis disabled for quota. usage is accounted in this server only: %v", err)
+		m.Store = &LocalStore{}
+	} else {
+		logger.Infof("redis enabled for quota: %v", addr)
+		s := NewRedisStore(ctx, addr, "quota:")
+		m.Store = s
+		done = func() { s.Close() }
+	}
+	err = m.Set(ctx, cfg)
+	if err != nil {
+		done()
+		return nil, func() {}, err
+	}
+	return m, done, nil
+}
@@ -0,0 +1,6 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// Package quota provides resource usage accounting and quota per group.
+package quota
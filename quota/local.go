@@ -0,0 +1,67 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LocalStore is usage store in memory.
+// Usage is not shared with other servers, and lost when server restarts.
+type LocalStore struct {
+	// Clock is used to get current time. If nil, time.Now is used.
+	Clock func() time.Time
+
+	mu sync.Mutex
+	m  map[string]localEntry
+}
+
+type localEntry struct {
+	usage   Usage
+	expires time.Time
+}
+
+func (s *LocalStore) now() time.Time {
+	if s.Clock == nil {
+		return time.Now()
+	}
+	return s.Clock()
+}
+
+// Add adds u to usage of key and returns accumulated usage.
+func (s *LocalStore) Add(ctx context.Context, key string, u Usage, expireAt time.Time) (Usage, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = make(map[string]localEntry)
+	}
+	for k, e := range s.m {
+		if !now.Before(e.expires) {
+			delete(s.m, k)
+		}
+	}
+	e, ok := s.m[key]
+	if !ok {
+		e.expires = expireAt
+	}
+	e.usage = e.usage.Add(u)
+	s.m[key] = e
+	return e.usage, nil
+}
+
+// Get gets usage of key.
+func (s *LocalStore) Get(ctx context.Context, key string) (Usage, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.m[key]
+	if !ok || !now.Before(e.expires) {
+		return Usage{}, nil
+	}
+	return e.usage, nil
+}
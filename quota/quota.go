@@ -0,0 +1,297 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package quota
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/log"
+	pb "go.chromium.org/goma/server/proto/quota"
+)
+
+var (
+	exceededCount = stats.Int64(
+		"go.chromium.org/goma/server/quota.exceeded",
+		"Number of usage records that exceeded quota",
+		stats.UnitDimensionless)
+
+	groupKey = tag.MustNewKey("group")
+	limitKey = tag.MustNewKey("limit")
+
+	// DefaultViews are the default views provided by this package.
+	// You need to register the view for data to actually be collected.
+	DefaultViews = []*view.View{
+		{
+			Description: "Number of usage records that exceeded quota",
+			TagKeys: []tag.Key{
+				groupKey,
+				limitKey,
+			},
+			Measure:     exceededCount,
+			Aggregation: view.Count(),
+		},
+	}
+)
+
+// Usage is resource usage.
+type Usage struct {
+	// CPUTime is remote worker time.
+	CPUTime time.Duration
+
+	// UploadBytes is bytes uploaded to file server.
+	UploadBytes int64
+
+	// DownloadBytes is bytes downloaded from file server or in exec response.
+	DownloadBytes int64
+}
+
+// Add returns sum of u and v.
+func (u Usage) Add(v Usage) Usage {
+	return Usage{
+		CPUTime:       u.CPUTime + v.CPUTime,
+		UploadBytes:   u.UploadBytes + v.UploadBytes,
+		DownloadBytes: u.DownloadBytes + v.DownloadBytes,
+	}
+}
+
+// IsZero reports whether u has no usage.
+func (u Usage) IsZero() bool {
+	return u == Usage{}
+}
+
+// exceeds reports whether u exceeds limit l.
+func (u Usage) exceeds(l *pb.Limit) bool {
+	if l == nil {
+		return false
+	}
+	if l.CpuSeconds > 0 && u.CPUTime >= time.Duration(l.CpuSeconds)*time.Second {
+		return true
+	}
+	if l.UploadBytes > 0 && u.UploadBytes >= l.UploadBytes {
+		return true
+	}
+	if l.DownloadBytes > 0 && u.DownloadBytes >= l.DownloadBytes {
+		return true
+	}
+	return false
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("cpu:%s upload:%d download:%d", u.CPUTime, u.UploadBytes, u.DownloadBytes)
+}
+
+// Store stores usage.
+type Store interface {
+	// Add adds u to usage of key and returns accumulated usage.
+	// Usage of key will be expired at expireAt.
+	Add(ctx context.Context, key string, u Usage, expireAt time.Time) (Usage, error)
+
+	// Get gets usage of key.
+	Get(ctx context.Context, key string) (Usage, error)
+}
+
+// DefaultRefreshInterval is default interval to refresh usage from store.
+const DefaultRefreshInterval = 10 * time.Second
+
+// Manager manages quota per group.
+type Manager struct {
+	Store Store
+
+	// Clock is used to get current time. If nil, time.Now is used.
+	Clock func() time.Time
+
+	// RefreshInterval is interval to refresh usage from store.
+	// If zero, DefaultRefreshInterval is used.
+	RefreshInterval time.Duration
+
+	mu     sync.Mutex
+	config *pb.QuotaConfig
+	// cached usage by key.
+	usages map[string]cachedUsage
+}
+
+type cachedUsage struct {
+	usage Usage
+	time  time.Time
+}
+
+func (m *Manager) now() time.Time {
+	if m.Clock == nil {
+		return time.Now()
+	}
+	return m.Clock()
+}
+
+func (m *Manager) refreshInterval() time.Duration {
+	if m.RefreshInterval == 0 {
+		return DefaultRefreshInterval
+	}
+	return m.RefreshInterval
+}
+
+// Set sets config in the manager.
+func (m *Manager) Set(ctx context.Context, config *pb.QuotaConfig) error {
+	for i, q := range config.GetQuotas() {
+		for _, l := range []*pb.Limit{q.SoftLimit, q.HardLimit} {
+			if l == nil {
+				continue
+			}
+			if l.CpuSeconds < 0 || l.UploadBytes < 0 || l.DownloadBytes < 0 {
+				return fmt.Errorf("quota[%d]: negative limit: %s", i, l)
+			}
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = proto.Clone(config).(*pb.QuotaConfig)
+	logger := log.FromContext(ctx)
+	logger.Infof("quota updated: %d quotas", len(config.GetQuotas()))
+	return nil
+}
+
+// periodKey returns key of usage of group for period at t,
+// and time when the period ends.
+func periodKey(group string, period pb.GroupQuota_Period, t time.Time) (string, time.Time) {
+	t = t.UTC()
+	switch period {
+	case pb.GroupQuota_MONTHLY:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return fmt.Sprintf("%s:%s", group, t.Format("2006-01")), start.AddDate(0, 1, 0)
+	default:
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return fmt.Sprintf("%s:%s", group, t.Format("2006-01-02")), start.AddDate(0, 0, 1)
+	}
+}
+
+// quotas returns quotas matched with group.
+func (m *Manager) quotas(group string) []*pb.GroupQuota {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var quotas []*pb.GroupQuota
+	for _, q := range m.config.GetQuotas() {
+		if q.GroupId != "" && q.GroupId != group {
+			continue
+		}
+		quotas = append(quotas, q)
+	}
+	return quotas
+}
+
+// Record records usage u of group.
+func (m *Manager) Record(ctx context.Context, group string, u Usage) {
+	if m == nil || u.IsZero() {
+		return
+	}
+	logger := log.FromContext(ctx)
+	now := m.now()
+	periods := make(map[pb.GroupQuota_Period]Usage)
+	for _, q := range m.quotas(group) {
+		total, ok := periods[q.Period]
+		if !ok {
+			key, expireAt := periodKey(group, q.Period, now)
+			var err error
+			total, err = m.Store.Add(ctx, key, u, expireAt)
+			if err != nil {
+				logger.Errorf("failed to record usage %s %s: %v", key, u, err)
+				continue
+			}
+			periods[q.Period] = total
+			m.mu.Lock()
+			if m.usages == nil {
+				m.usages = make(map[string]cachedUsage)
+			}
+			m.usages[key] = cachedUsage{usage: total, time: now}
+			m.mu.Unlock()
+		}
+		prev := Usage{
+			CPUTime:       total.CPUTime - u.CPUTime,
+			UploadBytes:   total.UploadBytes - u.UploadBytes,
+			DownloadBytes: total.DownloadBytes - u.DownloadBytes,
+		}
+		// report only when usage exceeds limit by this record.
+		if total.exceeds(q.SoftLimit) && !prev.exceeds(q.SoftLimit) {
+			logger.Warnf("group %s usage %s exceeds soft limit %s", group, total, q.SoftLimit)
+			recordExceeded(ctx, group, "soft")
+		}
+		if total.exceeds(q.HardLimit) && !prev.exceeds(q.HardLimit) {
+			logger.Errorf("group %s usage %s exceeds hard limit %s: action=%s", group, total, q.HardLimit, q.Action)
+			recordExceeded(ctx, group, "hard")
+		}
+	}
+}
+
+// usage returns usage of key, refreshing from store if cached usage is stale.
+func (m *Manager) usage(ctx context.Context, key string, now time.Time) (Usage, error) {
+	m.mu.Lock()
+	cu, ok := m.usages[key]
+	m.mu.Unlock()
+	if ok && now.Sub(cu.time) < m.refreshInterval() {
+		return cu.usage, nil
+	}
+	u, err := m.Store.Get(ctx, key)
+	if err != nil {
+		return Usage{}, err
+	}
+	m.mu.Lock()
+	if m.usages == nil {
+		m.usages = make(map[string]cachedUsage)
+	}
+	for k, v := range m.usages {
+		if now.Sub(v.time) >= m.refreshInterval() {
+			delete(m.usages, k)
+		}
+	}
+	m.usages[key] = cachedUsage{usage: u, time: now}
+	m.mu.Unlock()
+	return u, nil
+}
+
+// Check checks usage of group and returns action to take.
+// If group exceeds hard limits of several quotas, it returns
+// the most restrictive action.
+// If it failed to get usage, it returns ALLOW.
+func (m *Manager) Check(ctx context.Context, group string) pb.GroupQuota_Action {
+	if m == nil {
+		return pb.GroupQuota_ALLOW
+	}
+	logger := log.FromContext(ctx)
+	now := m.now()
+	action := pb.GroupQuota_ALLOW
+	for _, q := range m.quotas(group) {
+		if q.Action <= action || q.HardLimit == nil {
+			continue
+		}
+		key, _ := periodKey(group, q.Period, now)
+		u, err := m.usage(ctx, key, now)
+		if err != nil {
+			logger.Errorf("failed to get usage %s: %v", key, err)
+			continue
+		}
+		if u.exceeds(q.HardLimit) {
+			action = q.Action
+		}
+	}
+	return action
+}
+
+func recordExceeded(ctx context.Context, group, limit string) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(groupKey, group),
+		tag.Upsert(limitKey, limit))
+	if err != nil {
+		logger := log.FromContext(ctx)
+		logger.Errorf("failed to record quota exceeded: %v", err)
+		return
+	}
+	stats.Record(ctx, exceededCount.M(1))
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package quota
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	pb "go.chromium.org/goma/server/proto/quota"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 1, 31, 23, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	m := &Manager{
+		Store: &LocalStore{Clock: clock},
+		Clock: clock,
+	}
+	err := m.Set(ctx, &pb.QuotaConfig{
+		Quotas: []*pb.GroupQuota{
+			{
+				GroupId: "bots",
+				Period:  pb.GroupQuota_DAILY,
+				SoftLimit: &pb.Limit{
+					CpuSeconds: 30,
+				},
+				HardLimit: &pb.Limit{
+					CpuSeconds: 60,
+				},
+				Action: pb.GroupQuota_CACHE_ONLY,
+			},
+			{
+				Period: pb.GroupQuota_MONTHLY,
+				HardLimit: &pb.Limit{
+					UploadBytes: 1000,
+				},
+				Action: pb.GroupQuota_LOCAL_FALLBACK,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Set=%v; want nil error", err)
+	}
+
+	check := func(group string, want pb.GroupQuota_Action) {
+		t.Helper()
+		got := m.Check(ctx, group)
+		if got != want {
+			t.Errorf("Check(ctx, %q)=%v; want=%v", group, got, want)
+		}
+	}
+
+	check("bots", pb.GroupQuota_ALLOW)
+	m.Record(ctx, "bots", Usage{CPUTime: 40 * time.Second})
+	check("bots", pb.GroupQuota_ALLOW)
+	m.Record(ctx, "bots", Usage{CPUTime: 20 * time.Second})
+	check("bots", pb.GroupQuota_CACHE_ONLY)
+	// other group is not limited by bots quota.
+	m.Record(ctx, "users", Usage{CPUTime: 120 * time.Second})
+	check("users", pb.GroupQuota_ALLOW)
+
+	m.Record(ctx, "users", Usage{UploadBytes: 1000})
+	check("users", pb.GroupQuota_LOCAL_FALLBACK)
+	check("bots", pb.GroupQuota_CACHE_ONLY)
+	m.Record(ctx, "bots", Usage{UploadBytes: 1000})
+	check("bots", pb.GroupQuota_LOCAL_FALLBACK)
+
+	// next month, both daily and monthly quotas are reset.
+	now = now.Add(2 * time.Hour)
+	check("bots", pb.GroupQuota_ALLOW)
+	check("users", pb.GroupQuota_ALLOW)
+}
+
+func TestPeriodKey(t *testing.T) {
+	tm := time.Date(2021, 12, 31, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		period     pb.GroupQuota_Period
+		want       string
+		wantExpire time.Time
+	}{
+		{
+			period:     pb.GroupQuota_DAILY,
+			want:       "g:2021-12-31",
+			wantExpire: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			period:     pb.GroupQuota_MONTHLY,
+			want:       "g:2021-12",
+			wantExpire: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		got, gotExpire := periodKey("g", tc.period, tm)
+		if got != tc.want || !gotExpire.Equal(tc.wantExpire) {
+			t.Errorf("periodKey(%q, %v, %v)=%q, %v; want %q, %v", "g", tc.period, tm, got, gotExpire, tc.want, tc.wantExpire)
+		}
+	}
+}
+
+func TestNewManagerFromConfig(t *testing.T) {
+	ctx := context.Background()
+	defer os.Setenv("REDISHOST", os.Getenv("REDISHOST"))
+	os.Unsetenv("REDISHOST")
+
+	m, done, err := NewManagerFromConfig(ctx, "")
+	if m != nil || err != nil {
+		t.Errorf(`NewManagerFromConfig(ctx, "")=%v, %v; want nil, nil`, m, err)
+	}
+	done()
+
+	_, done, err = NewManagerFromConfig(ctx, "bad config")
+	if err == nil {
+		t.Errorf("NewManagerFromConfig(ctx, bad)=_, nil; want error")
+	}
+	done()
+
+	m, done, err = NewManagerFromConfig(ctx, `quotas { group_id: "bots" hard_limit { cpu_seconds: 60 } action: LOCAL_FALLBACK }`)
+	if err != nil {
+		t.Fatalf("NewManagerFromConfig(ctx, cfg)=_, %v; want nil error", err)
+	}
+	defer done()
+	if _, ok := m.Store.(*LocalStore); !ok {
+		t.Errorf("Store=%T; want *LocalStore", m.Store)
+	}
+	if got := m.Check(ctx, "bots"); got != pb.GroupQuota_ALLOW {
+		t.Errorf("Check(ctx, bots)=%v; want %v", got, pb.GroupQuota_ALLOW)
+	}
+}
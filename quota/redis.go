@@ -0,0 +1,98 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package quota
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// RedisStore is usage store in redis.
+// Usage is stored in hash of key, so it is shared with other servers
+// using the same redis.
+type RedisStore struct {
+	// Prefix is key prefix used by the store.
+	Prefix string
+
+	pool *redis.Pool
+}
+
+// NewRedisStore creates new redis store at addr.
+func NewRedisStore(ctx context.Context, addr, prefix string) *RedisStore {
+	return &RedisStore{
+		Prefix: prefix,
+		pool: &redis.Pool{
+			DialContext: func(ctx context.Context) (redis.Conn, error) {
+				return redis.DialContext(ctx, "tcp", addr)
+			},
+			MaxIdle: 2,
+		},
+	}
+}
+
+// Close releases the resources used by the store.
+func (s *RedisStore) Close() error {
+	return s.pool.Close()
+}
+
+const (
+	cpuMillisField     = "cpu_millis"
+	uploadBytesField   = "upload_bytes"
+	downloadBytesField = "download_bytes"
+)
+
+// Add adds u to usage of key and returns accumulated usage.
+func (s *RedisStore) Add(ctx context.Context, key string, u Usage, expireAt time.Time) (Usage, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return Usage{}, err
+	}
+	defer conn.Close()
+	key = s.Prefix + key
+	conn.Send("MULTI")
+	conn.Send("HINCRBY", key, cpuMillisField, u.CPUTime.Milliseconds())
+	conn.Send("HINCRBY", key, uploadBytesField, u.UploadBytes)
+	conn.Send("HINCRBY", key, downloadBytesField, u.DownloadBytes)
+	conn.Send("EXPIREAT", key, expireAt.Unix())
+	v, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return Usage{}, err
+	}
+	var cpuMillis, uploadBytes, downloadBytes int64
+	_, err = redis.Scan(v, &cpuMillis, &uploadBytes, &downloadBytes)
+	if err != nil {
+		return Usage{}, err
+	}
+	return Usage{
+		CPUTime:       time.Duration(cpuMillis) * time.Millisecond,
+		UploadBytes:   uploadBytes,
+		DownloadBytes: downloadBytes,
+	}, nil
+}
+
+// Get gets usage of key.
+func (s *RedisStore) Get(ctx context.Context, key string) (Usage, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return Usage{}, err
+	}
+	defer conn.Close()
+	v, err := redis.Values(conn.Do("HMGET", s.Prefix+key, cpuMillisField, uploadBytesField, downloadBytesField))
+	if err != nil {
+		return Usage{}, err
+	}
+	var cpuMillis, uploadBytes, downloadBytes int64
+	_, err = redis.Scan(v, &cpuMillis, &uploadBytes, &downloadBytes)
+	if err != nil {
+		return Usage{}, err
+	}
+	return Usage{
+		CPUTime:       time.Duration(cpuMillis) * time.Millisecond,
+		UploadBytes:   uploadBytes,
+		DownloadBytes: downloadBytes,
+	}, nil
+}
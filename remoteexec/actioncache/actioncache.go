@@ -0,0 +1,269 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// Package actioncache provides read-through cache of ActionResult
+// in remoteexec API's ActionCache.
+package actioncache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	rpb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/golang/groupcache/lru"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/log"
+	cachepb "go.chromium.org/goma/server/proto/cache"
+)
+
+var (
+	cacheOps = stats.Int64(
+		"go.chromium.org/goma/server/remoteexec/actioncache.ops",
+		"action cache operations",
+		stats.UnitDimensionless)
+
+	// op is "hit", "negative-hit", "cache-hit", "miss", "not-found",
+	// "error" or "put".
+	opKey = tag.MustNewKey("op")
+
+	DefaultViews = []*view.View{
+		{
+			Description: `action cache operations`,
+			Measure:     cacheOps,
+			TagKeys: []tag.Key{
+				opKey,
+			},
+			Aggregation: view.Count(),
+		},
+	}
+)
+
+const (
+	// DefaultTTL is default TTL of cached ActionResult.
+	// output blobs of ActionResult might be removed from CAS
+	// after TTL of CAS, so ActionResult should not be cached
+	// for long.
+	DefaultTTL = 10 * time.Minute
+
+	// DefaultNegativeTTL is default TTL of missing ActionResult.
+	DefaultNegativeTTL = 10 * time.Second
+)
+
+// Opts is options of Cache.
+type Opts struct {
+	// MaxEntries is maximum number of entries in memory.
+	// 0 means unlimited.
+	MaxEntries int
+
+	// TTL is how long ActionResult is cached.
+	// If 0, DefaultTTL is used.
+	TTL time.Duration
+
+	// NegativeTTL is how long missing ActionResult is cached in memory.
+	// If 0, DefaultNegativeTTL is used.
+	// If negative, missing ActionResult is not cached.
+	NegativeTTL time.Duration
+}
+
+type entry struct {
+	// result is nil for missing ActionResult.
+	result *rpb.ActionResult
+	expire time.Time
+}
+
+// Cache is read-through cache of ActionResult.
+// ActionResults are cached in memory, and in cache service
+// (e.g. redis) if given.
+type Cache struct {
+	c    cachepb.CacheServiceClient
+	opts Opts
+
+	// clock is used to get current time.
+	clock func() time.Time
+
+	mu  sync.Mutex
+	lru lru.Cache
+}
+
+// New creates new cache.  c may be nil to cache only in memory.
+func New(c cachepb.CacheServiceClient, opts Opts) *Cache {
+	if opts.TTL == 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.NegativeTTL == 0 {
+		opts.NegativeTTL = DefaultNegativeTTL
+	}
+	cache := &Cache{
+		c:     c,
+		opts:  opts,
+		clock: time.Now,
+	}
+	cache.lru.MaxEntries = opts.MaxEntries
+	return cache
+}
+
+func cacheKey(instanceName string, d *rpb.Digest) string {
+	return fmt.Sprintf("%s/%s/%d", instanceName, d.GetHash(), d.GetSizeBytes())
+}
+
+func record(ctx context.Context, op string) {
+	stats.RecordWithTags(ctx, []tag.Mutator{
+		tag.Upsert(opKey, op),
+	}, cacheOps.M(1))
+}
+
+func (c *Cache) memGet(key string, now time.Time) (entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.lru.Get(lru.Key(key))
+	if !ok {
+		return entry{}, false
+	}
+	e := v.(entry)
+	if now.After(e.expire) {
+		c.lru.Remove(lru.Key(key))
+		return entry{}, false
+	}
+	return e, true
+}
+
+func (c *Cache) memAdd(key string, e entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Add(lru.Key(key), e)
+}
+
+var errNoCacheClient = errors.New("no cache client")
+
+// value in cache service is 8 bytes of expiration time in unix nano,
+// followed by serialized ActionResult.
+
+func (c *Cache) cacheGet(ctx context.Context, key string, now time.Time) (entry, error) {
+	if c.c == nil {
+		return entry{}, errNoCacheClient
+	}
+	resp, err := c.c.Get(ctx, &cachepb.GetReq{
+		Key: key,
+	})
+	if err != nil {
+		return entry{}, err
+	}
+	v := resp.GetKv().GetValue()
+	if len(v) < 8 {
+		return entry{}, fmt.Errorf("bad cache value for %s: size=%d", key, len(v))
+	}
+	expire := time.Unix(0, int64(binary.BigEndian.Uint64(v[:8])))
+	if now.After(expire) {
+		return entry{}, status.Errorf(codes.NotFound, "expired %s", key)
+	}
+	result := &rpb.ActionResult{}
+	err = proto.Unmarshal(v[8:], result)
+	if err != nil {
+		return entry{}, err
+	}
+	return entry{
+		result: result,
+		expire: expire,
+	}, nil
+}
+
+func (c *Cache) cachePut(ctx context.Context, key string, e entry) error {
+	if c.c == nil {
+		return errNoCacheClient
+	}
+	b, err := proto.Marshal(e.result)
+	if err != nil {
+		return err
+	}
+	v := make([]byte, 8+len(b))
+	binary.BigEndian.PutUint64(v, uint64(e.expire.UnixNano()))
+	copy(v[8:], b)
+	_, err = c.c.Put(ctx, &cachepb.PutReq{
+		Kv: &cachepb.KV{
+			Key:   key,
+			Value: v,
+		},
+	})
+	return err
+}
+
+// GetActionResult gets ActionResult for req from the cache, or from ac
+// if it is not cached.
+// It returns NotFound error if missing ActionResult is cached.
+func (c *Cache) GetActionResult(ctx context.Context, ac rpb.ActionCacheClient, req *rpb.GetActionResultRequest) (*rpb.ActionResult, error) {
+	if c == nil || req.InlineStdout || req.InlineStderr || len(req.InlineOutputFiles) > 0 {
+		return ac.GetActionResult(ctx, req)
+	}
+	logger := log.FromContext(ctx)
+	key := cacheKey(req.InstanceName, req.ActionDigest)
+	now := c.clock()
+	if e, ok := c.memGet(key, now); ok {
+		if e.result == nil {
+			record(ctx, "negative-hit")
+			return nil, status.Errorf(codes.NotFound, "action %s not found (negative cache)", key)
+		}
+		record(ctx, "hit")
+		return proto.Clone(e.result).(*rpb.ActionResult), nil
+	}
+	e, err := c.cacheGet(ctx, key, now)
+	if err == nil {
+		record(ctx, "cache-hit")
+		c.memAdd(key, e)
+		return proto.Clone(e.result).(*rpb.ActionResult), nil
+	}
+	if err != errNoCacheClient && status.Code(err) != codes.NotFound {
+		logger.Warnf("action cache get %s: %v", key, err)
+	}
+	result, err := ac.GetActionResult(ctx, req)
+	switch {
+	case err == nil:
+		record(ctx, "miss")
+		c.put(ctx, key, result)
+	case status.Code(err) == codes.NotFound:
+		record(ctx, "not-found")
+		if c.opts.NegativeTTL > 0 {
+			c.memAdd(key, entry{
+				expire: now.Add(c.opts.NegativeTTL),
+			})
+		}
+	default:
+		record(ctx, "error")
+	}
+	return result, err
+}
+
+// Put puts result of the action in the cache.
+// It should be called when the action is executed and its result
+// is stored in remote ActionCache, so that negative cache entry
+// is replaced.
+func (c *Cache) Put(ctx context.Context, instanceName string, d *rpb.Digest, result *rpb.ActionResult) {
+	if c == nil {
+		return
+	}
+	record(ctx, "put")
+	c.put(ctx, cacheKey(instanceName, d), result)
+}
+
+func (c *Cache) put(ctx context.Context, key string, result *rpb.ActionResult) {
+	e := entry{
+		result: proto.Clone(result).(*rpb.ActionResult),
+		expire: c.clock().Add(c.opts.TTL),
+	}
+	c.memAdd(key, e)
+	err := c.cachePut(ctx, key, e)
+	if err != nil && err != errNoCacheClient {
+		logger := log.FromContext(ctx)
+		logger.Warnf("action cache put %s: %v", key, err)
+	}
+}
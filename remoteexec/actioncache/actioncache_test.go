@@ -0,0 +1,158 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package actioncache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rpb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"go.chromium.org/goma/server/cache"
+)
+
+type fakeActionCache struct {
+	rpb.ActionCacheClient
+	results map[string]*rpb.ActionResult
+	calls   int
+}
+
+func (f *fakeActionCache) GetActionResult(ctx context.Context, req *rpb.GetActionResultRequest, opts ...grpc.CallOption) (*rpb.ActionResult, error) {
+	f.calls++
+	r, ok := f.results[req.ActionDigest.GetHash()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	return r, nil
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New(nil, Opts{
+		TTL:         time.Minute,
+		NegativeTTL: 10 * time.Second,
+	})
+	c.clock = func() time.Time { return now }
+
+	result := &rpb.ActionResult{
+		ExitCode:  0,
+		StdoutRaw: []byte("ok"),
+	}
+	ac := &fakeActionCache{
+		results: map[string]*rpb.ActionResult{
+			"hit": result,
+		},
+	}
+	req := func(hash string) *rpb.GetActionResultRequest {
+		return &rpb.GetActionResultRequest{
+			InstanceName: "instance",
+			ActionDigest: &rpb.Digest{
+				Hash:      hash,
+				SizeBytes: 10,
+			},
+		}
+	}
+
+	get := func(hash string, wantCode codes.Code, wantCalls int) {
+		t.Helper()
+		got, err := c.GetActionResult(ctx, ac, req(hash))
+		if status.Code(err) != wantCode {
+			t.Errorf("GetActionResult(%q)=_, %v; want %v", hash, err, wantCode)
+		}
+		if err == nil {
+			if diff := cmp.Diff(result, got, protocmp.Transform()); diff != "" {
+				t.Errorf("GetActionResult(%q) diff -want +got:\n%s", hash, diff)
+			}
+		}
+		if ac.calls != wantCalls {
+			t.Errorf("GetActionResult(%q): calls=%d; want=%d", hash, ac.calls, wantCalls)
+		}
+	}
+
+	get("hit", codes.OK, 1)
+	get("hit", codes.OK, 1)
+
+	get("miss", codes.NotFound, 2)
+	get("miss", codes.NotFound, 2)
+
+	now = now.Add(11 * time.Second)
+	get("miss", codes.NotFound, 3)
+
+	// executed result replaces negative cache.
+	c.Put(ctx, "instance", req("miss").ActionDigest, result)
+	get("miss", codes.OK, 3)
+
+	now = now.Add(2 * time.Minute)
+	get("hit", codes.OK, 4)
+}
+
+func TestCacheSharedByCacheService(t *testing.T) {
+	ctx := context.Background()
+	cs, err := cache.New(cache.Config{
+		MaxBytes: 1 << 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	newCache := func() *Cache {
+		c := New(cache.LocalClient{CacheServiceServer: cs}, Opts{
+			TTL: time.Minute,
+		})
+		c.clock = func() time.Time { return now }
+		return c
+	}
+	c1 := newCache()
+	c2 := newCache()
+
+	result := &rpb.ActionResult{
+		ExitCode:  1,
+		StderrRaw: []byte("error"),
+	}
+	ac := &fakeActionCache{
+		results: map[string]*rpb.ActionResult{
+			"hit": result,
+		},
+	}
+	req := &rpb.GetActionResultRequest{
+		InstanceName: "instance",
+		ActionDigest: &rpb.Digest{
+			Hash:      "hit",
+			SizeBytes: 10,
+		},
+	}
+	_, err = c1.GetActionResult(ctx, ac, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c2.GetActionResult(ctx, ac, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(result, got, protocmp.Transform()); diff != "" {
+		t.Errorf("GetActionResult diff -want +got:\n%s", diff)
+	}
+	if ac.calls != 1 {
+		t.Errorf("calls=%d; want=1", ac.calls)
+	}
+
+	// expired in cache service.
+	now = now.Add(2 * time.Minute)
+	c3 := newCache()
+	_, err = c3.GetActionResult(ctx, ac, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ac.calls != 2 {
+		t.Errorf("calls=%d after expired; want=2", ac.calls)
+	}
+}
@@ -32,6 +32,8 @@ import (
 	gomapb "go.chromium.org/goma/server/proto/api"
 	execpb "go.chromium.org/goma/server/proto/exec"
 	fpb "go.chromium.org/goma/server/proto/file"
+	quotapb "go.chromium.org/goma/server/proto/quota"
+	"go.chromium.org/goma/server/quota"
 	"go.chromium.org/goma/server/remoteexec/cas"
 	"go.chromium.org/goma/server/remoteexec/digest"
 	"go.chromium.org/goma/server/server"
@@ -42,6 +44,16 @@ type DigestCache interface {
 	Get(context.Context, string, digest.Source) (digest.Data, error)
 }
 
+// ActionCache is a cache in front of remoteexec API's ActionCache.
+type ActionCache interface {
+	// GetActionResult gets ActionResult for req from cache, or
+	// from ac if not cached.
+	GetActionResult(ctx context.Context, ac rpb.ActionCacheClient, req *rpb.GetActionResultRequest) (*rpb.ActionResult, error)
+
+	// Put puts result of the action executed in remoteexec API.
+	Put(ctx context.Context, instanceName string, d *rpb.Digest, result *rpb.ActionResult)
+}
+
 // SpanTimeout specifies Timeout for exec span.
 // 0 is no time out.
 type SpanTimeout struct {
@@ -53,6 +65,9 @@ type SpanTimeout struct {
 	UploadBlobs  time.Duration
 	Execute      time.Duration
 	Response     time.Duration
+	// Coalesce is timeout to wait for in-flight execution of
+	// the same action by other request.
+	Coalesce time.Duration
 }
 
 // DefaultSpanTimeout is default timeout.
@@ -65,6 +80,7 @@ var DefaultSpanTimeout = SpanTimeout{
 	UploadBlobs:  60 * time.Second,
 	Execute:      0,
 	Response:     30 * time.Second,
+	Coalesce:     5 * time.Minute,
 }
 
 // Adapter is an adapter from goma API to remoteexec API.
@@ -95,6 +111,10 @@ type Adapter struct {
 	// key: goma file hash.
 	DigestCache DigestCache
 
+	// ActionCache caches ActionResult to reduce GetActionResult calls,
+	// if set.
+	ActionCache ActionCache
+
 	// CmdStorage is a storage for command files.
 	CmdStorage CmdStorage
 
@@ -120,6 +140,28 @@ type Adapter struct {
 	// sha256 file hash to disable hardening.
 	DisableHardenings []string
 
+	// NormalizePaths enables to rewrite absolute paths under the input
+	// root in args into cwd relative paths, so non relocatable requests
+	// could run with relocatable wrapper and share cache across
+	// checkouts.
+	NormalizePaths bool
+
+	// LinkPlatformProperties are platform properties for link
+	// requests (e.g. high-memory worker), which override toolchain's
+	// platform properties.
+	LinkPlatformProperties []*rpb.Platform_Property
+
+	// Quota accounts remote worker time and exec response bytes
+	// per group, and limits requests of groups that exceed hard
+	// limit, if client can fall back to local.
+	Quota *quota.Manager
+
+	// CoalesceExec enables coalescing concurrent requests of the same
+	// action. Requests wait for the result of in-flight execution
+	// instead of executing the action in RBE.
+	CoalesceExec bool
+	execGroup    execGroup
+
 	capMu        sync.Mutex
 	capabilities *rpb.ServerCapabilities
 }
@@ -301,6 +343,7 @@ var spanMeasures = map[string]*stats.Float64Measure{
 	"upload blobs":  execUploadBlobsTime,
 	"execute":       execExecuteTime,
 	"response":      execResponseTime,
+	"coalesce":      execCoalesceTime,
 }
 
 func (s *execSpan) Close(ctx context.Context) {
@@ -331,12 +374,52 @@ func (s *execSpan) Do(ctx context.Context, desc string, d time.Duration, f func(
 	return duration
 }
 
+// execute uploads missing blobs and executes action of r.
+// It returns resp if it fails fast.
+func (f *Adapter) execute(ctx context.Context, r *request, espan *execSpan) (*gomapb.ExecResp, *rpb.ExecuteResponse, error) {
+	logger := log.FromContext(ctx)
+	var blobs []*rpb.Digest
+	var err error
+	espan.Do(ctx, "check missing", f.SpanTimeout.CheckMissing, func(ctx context.Context) {
+		blobs, err = r.missingBlobs(ctx)
+	})
+	if err != nil {
+		logger.Errorf("exec call: error in check missing blobs: %v", err)
+		return nil, nil, err
+	}
+
+	var resp *gomapb.ExecResp
+	espan.Do(ctx, "upload blobs", f.SpanTimeout.UploadBlobs, func(ctx context.Context) {
+		resp, err = r.uploadBlobs(ctx, blobs)
+	})
+	if err != nil {
+		logger.Errorf("exec call: error in upload blobs: %v", err)
+		return nil, nil, err
+	}
+	if resp != nil {
+		logger.Infof("fail fast for uploading missing blobs: %v", resp)
+		return resp, nil, nil
+	}
+
+	var eresp *rpb.ExecuteResponse
+	espan.Do(ctx, "execute", f.SpanTimeout.Execute, func(ctx context.Context) {
+		eresp, err = r.executeAction(ctx)
+	})
+	if err != nil {
+		logger.Errorf("exec call: execute err=%v", err)
+		return nil, nil, err
+	}
+	return nil, eresp, nil
+}
+
 // Exec handles goma Exec requests with remoteexec backend.
 //
 //  1. compute input tree and Action.
 //  1.1 construct input tree from req.
 //  1.2. construct Action message from req.
 //  2. checks the ActionCache using GetActionResult. if hit, go to 7.
+//  2.1 if the same action is being executed by other request,
+//      waits for its result. if succeeded, go to 7.
 //  3. queries the ContentAddressableStorage using FindMissingBlobs
 //  4. uploads any missing blobs to the ContentAddressableStorage
 //     using bytestream.Write and BatchUpdateBlobs.
@@ -381,6 +464,18 @@ func (f *Adapter) Exec(ctx context.Context, req *gomapb.ExecReq) (resp *gomapb.E
 	defer r.Close()
 	espan.req = r
 
+	quotaAction := f.Quota.Check(ctx, r.userGroup)
+	if quotaAction != quotapb.GroupQuota_ALLOW && !canFallback(req) {
+		// BAD_REQUEST fails the compile if client doesn't
+		// fall back to local, so don't degrade the request.
+		logger.Warnf("group %s exceeds quota (%s), but client doesn't fall back", r.userGroup, quotaAction)
+		quotaAction = quotapb.GroupQuota_ALLOW
+	}
+	if quotaAction == quotapb.GroupQuota_LOCAL_FALLBACK {
+		logger.Warnf("fail fast: group %s exceeds quota", r.userGroup)
+		return r.quotaExceeded(), nil
+	}
+
 	dur := espan.Do(ctx, "inventory", f.SpanTimeout.Inventory, func(ctx context.Context) {
 		resp = r.getInventoryData(ctx)
 	})
@@ -406,42 +501,37 @@ func (f *Adapter) Exec(ctx context.Context, req *gomapb.ExecReq) (resp *gomapb.E
 	espan.Do(ctx, "check cache", f.SpanTimeout.CheckCache, func(ctx context.Context) {
 		eresp.Result, cached = r.checkCache(ctx)
 	})
+	if !cached && quotaAction == quotapb.GroupQuota_CACHE_ONLY {
+		logger.Warnf("fail fast: group %s exceeds quota and no cached result", r.userGroup)
+		return r.quotaExceeded(), nil
+	}
 	if !cached {
-		var blobs []*rpb.Digest
 		var err error
-		espan.Do(ctx, "check missing", f.SpanTimeout.CheckMissing, func(ctx context.Context) {
-			blobs, err = r.missingBlobs(ctx)
-		})
+		// coalesced result is treated as cached, since the action
+		// was not executed for this request.
+		resp, eresp, cached, err = f.executeCoalesced(ctx, r, espan)
 		if err != nil {
-			logger.Errorf("exec call: error in check missing blobs: %v", err)
-			return nil, err
-		}
-
-		espan.Do(ctx, "upload blobs", f.SpanTimeout.UploadBlobs, func(ctx context.Context) {
-			resp, err = r.uploadBlobs(ctx, blobs)
-		})
-		if err != nil {
-			logger.Errorf("exec call: error in upload blobs: %v", err)
 			return nil, err
 		}
 		if resp != nil {
-			logger.Infof("fail fast for uploading missing blobs: %v", resp)
 			return resp, nil
 		}
-
-		espan.Do(ctx, "execute", f.SpanTimeout.Execute, func(ctx context.Context) {
-			eresp, err = r.executeAction(ctx)
-		})
-		if err != nil {
-			logger.Errorf("exec call: execute err=%v", err)
-			return nil, err
-		}
 	}
 	espan.Do(ctx, "response", f.SpanTimeout.Response, func(ctx context.Context) {
 		resp, err = r.newResp(ctx, eresp, cached)
 	})
 	if err != nil {
 		logger.Errorf("exec call: resp err=%v", err)
+		return resp, err
 	}
-	return resp, err
+	f.Quota.Record(ctx, r.userGroup, quota.Usage{DownloadBytes: int64(proto.Size(resp))})
+	return resp, nil
+}
+
+// canFallback reports whether client would fall back to local
+// for BAD_REQUEST response.
+// client falls back by default (GOMA_FALLBACK=true).
+func canFallback(req *gomapb.ExecReq) bool {
+	env := req.GetRequesterEnv()
+	return env == nil || env.Fallback == nil || env.GetFallback()
 }
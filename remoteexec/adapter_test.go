@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -23,6 +24,8 @@ import (
 	cachepb "go.chromium.org/goma/server/proto/cache"
 	cmdpb "go.chromium.org/goma/server/proto/command"
 	fpb "go.chromium.org/goma/server/proto/file"
+	quotapb "go.chromium.org/goma/server/proto/quota"
+	"go.chromium.org/goma/server/quota"
 	"go.chromium.org/goma/server/remoteexec/cas"
 	"go.chromium.org/goma/server/remoteexec/digest"
 )
@@ -625,6 +628,402 @@ func TestAdapterHandleOutputsWithoutExpectedOutputs(t *testing.T) {
 	}
 }
 
+func TestAdapterHandleModules(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cluster := &fakeCluster{
+		rbe: newFakeRBE(),
+	}
+	err := cluster.setup(ctx, cluster.rbe.instancePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.teardown()
+
+	clang := newFakeClang(&cluster.cmdStorage, "1234", "x86-64-linux-gnu")
+
+	err = cluster.pushToolchains(ctx, clang)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var localFiles fakeLocalFiles
+	localFiles.Add("/b/c/w/src/hello.cc", randomSize())
+	localFiles.Add("/b/c/w/include/hello.h", randomSize())
+	localFiles.Add("/b/c/w/include/module.modulemap", randomSize())
+
+	req := &gomapb.ExecReq{
+		CommandSpec: clang.CommandSpec("clang", "bin/clang"),
+		Arg: []string{
+			"bin/clang", "-I../../include",
+			"-fmodules", "-fmodules-cache-path=module-cache",
+			"-fmodule-map-file=../../include/module.modulemap",
+			"-c", "../../src/hello.cc",
+		},
+		Env: []string{},
+		Cwd: proto.String("/b/c/w/out/Release"),
+		Input: []*gomapb.ExecReq_Input{
+			localFiles.mustInput(ctx, t, cluster.adapter.GomaFile, "/b/c/w/src/hello.cc", "../../src/hello.cc"),
+			localFiles.mustInput(ctx, t, cluster.adapter.GomaFile, "/b/c/w/include/hello.h", "../../include/hello.h"),
+		},
+		Subprogram:          []*gomapb.SubprogramSpec{},
+		RequesterInfo:       &gomapb.RequesterInfo{},
+		HermeticMode:        proto.Bool(true),
+		ExpectedOutputFiles: []string{"hello.o"},
+	}
+
+	t.Logf("module map not in request")
+	resp, err := cluster.adapter.Exec(ctx, req)
+	if err != nil {
+		t.Fatalf("Exec(ctx, req)=%v; %v; want nil error", resp, err)
+	}
+	if resp.GetError() != gomapb.ExecResp_BAD_REQUEST {
+		t.Errorf("Exec error=%v; want=%v", resp.GetError(), gomapb.ExecResp_BAD_REQUEST)
+	}
+	if len(resp.MissingInput) > 0 {
+		t.Errorf("missing=%q; want no missing", resp.MissingInput)
+	}
+	if cluster.rbe.gotCommand != nil {
+		t.Errorf("gotCommand=%v; want nil", cluster.rbe.gotCommand)
+	}
+
+	t.Logf("module map in request")
+	req.Input = append(req.Input, localFiles.mustInput(ctx, t, cluster.adapter.GomaFile, "/b/c/w/include/module.modulemap", "../../include/module.modulemap"))
+	resp, err = cluster.adapter.Exec(ctx, req)
+	if err != nil {
+		t.Fatalf("Exec(ctx, req)=%v; %v; want nil error", resp, err)
+	}
+	if resp.GetError() != gomapb.ExecResp_OK {
+		t.Errorf("Exec error=%v; want=%v", resp.GetError(), gomapb.ExecResp_OK)
+	}
+	command := cluster.rbe.gotCommand
+	if command == nil {
+		t.Fatalf("gotCommand is nil")
+	}
+	wantOutputFiles := []string{
+		"out/Release/hello.o",
+	}
+	wantOutputDirs := []string{
+		"out/Release/module-cache",
+	}
+	if !reflect.DeepEqual(command.OutputFiles, wantOutputFiles) {
+		t.Errorf("output files: got=%v, want=%v", command.OutputFiles, wantOutputFiles)
+	}
+	if !reflect.DeepEqual(command.OutputDirectories, wantOutputDirs) {
+		t.Errorf("output dirs: got=%v, want=%v", command.OutputDirectories, wantOutputDirs)
+	}
+}
+
+func TestAdapterHandleSwiftc(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cluster := &fakeCluster{
+		rbe: newFakeRBE(),
+	}
+	err := cluster.setup(ctx, cluster.rbe.instancePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.teardown()
+
+	swiftcFile := cluster.cmdStorage.newFileSpec("bin/swiftc", true)
+	swiftc := &fakeToolchain{
+		descs: []*cmdpb.CmdDescriptor{
+			{
+				Selector: &cmdpb.Selector{
+					Name:       "swiftc",
+					Version:    "5.5",
+					Target:     "x86_64-unknown-linux-gnu",
+					BinaryHash: swiftcFile.Hash,
+				},
+				Setup: &cmdpb.CmdDescriptor_Setup{
+					CmdFile:  swiftcFile,
+					PathType: cmdpb.CmdDescriptor_POSIX,
+				},
+			},
+		},
+		RemoteexecPlatform: &cmdpb.RemoteexecPlatform{
+			Properties: []*cmdpb.RemoteexecPlatform_Property{
+				{
+					Name:  "container-image",
+					Value: "docker://grpc.io/goma-dev/container-image@sha256:xxxx",
+				},
+			},
+		},
+	}
+	err = cluster.pushToolchains(ctx, swiftc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var localFiles fakeLocalFiles
+	localFiles.Add("/b/c/w/src/hello.swift", randomSize())
+
+	req := &gomapb.ExecReq{
+		CommandSpec: swiftc.CommandSpec("swiftc", "bin/swiftc"),
+		Arg: []string{
+			"bin/swiftc",
+			"-module-name", "Hello",
+			"-emit-module",
+			"-emit-module-path", "Hello.swiftmodule",
+			"-module-cache-path", "module-cache",
+			"-c",
+			"-o", "hello.o",
+			"../../src/hello.swift",
+		},
+		Env: []string{},
+		Cwd: proto.String("/b/c/w/out/Release"),
+		Input: []*gomapb.ExecReq_Input{
+			localFiles.mustInput(ctx, t, cluster.adapter.GomaFile, "/b/c/w/src/hello.swift", "../../src/hello.swift"),
+		},
+		Subprogram:    []*gomapb.SubprogramSpec{},
+		RequesterInfo: &gomapb.RequesterInfo{},
+		HermeticMode:  proto.Bool(true),
+	}
+
+	resp, err := cluster.adapter.Exec(ctx, req)
+	if err != nil {
+		t.Fatalf("Exec(ctx, req)=%v; %v; want nil error", resp, err)
+	}
+	if resp.GetError() != gomapb.ExecResp_OK {
+		t.Errorf("Exec error=%v; want=%v", resp.GetError(), gomapb.ExecResp_OK)
+	}
+	command := cluster.rbe.gotCommand
+	if command == nil {
+		t.Fatalf("gotCommand is nil")
+	}
+	wantOutputFiles := []string{
+		"out/Release/Hello.swiftdoc",
+		"out/Release/Hello.swiftmodule",
+		"out/Release/hello.o",
+	}
+	wantOutputDirs := []string{
+		"out/Release/module-cache",
+	}
+	if !reflect.DeepEqual(command.OutputFiles, wantOutputFiles) {
+		t.Errorf("output files: got=%v, want=%v", command.OutputFiles, wantOutputFiles)
+	}
+	if !reflect.DeepEqual(command.OutputDirectories, wantOutputDirs) {
+		t.Errorf("output dirs: got=%v, want=%v", command.OutputDirectories, wantOutputDirs)
+	}
+	for _, p := range command.Platform.GetProperties() {
+		if p.Name == "InputRootAbsolutePath" {
+			t.Errorf("InputRootAbsolutePath=%q; want relocatable", p.Value)
+		}
+	}
+}
+
+func TestAdapterHandleToolSpecInputs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cluster := &fakeCluster{
+		rbe: newFakeRBE(),
+	}
+	err := cluster.setup(ctx, cluster.rbe.instancePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.teardown()
+
+	flatcFile := cluster.cmdStorage.newFileSpec("bin/flatc", true)
+	flatc := &fakeToolchain{
+		descs: []*cmdpb.CmdDescriptor{
+			{
+				Selector: &cmdpb.Selector{
+					Name:       "flatc",
+					Version:    "2.0.0",
+					Target:     "x86_64-unknown-linux-gnu",
+					BinaryHash: flatcFile.Hash,
+				},
+				Setup: &cmdpb.CmdDescriptor_Setup{
+					CmdFile:  flatcFile,
+					PathType: cmdpb.CmdDescriptor_POSIX,
+				},
+				ToolSpec: toolSpec(t, flatcToolSpec),
+			},
+		},
+		RemoteexecPlatform: &cmdpb.RemoteexecPlatform{
+			Properties: []*cmdpb.RemoteexecPlatform_Property{
+				{
+					Name:  "container-image",
+					Value: "docker://grpc.io/goma-dev/container-image@sha256:xxxx",
+				},
+			},
+		},
+	}
+	err = cluster.pushToolchains(ctx, flatc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var localFiles fakeLocalFiles
+	localFiles.Add("/b/c/w/src/foo.fbs", randomSize())
+	localFiles.Add("/b/c/w/src/base.fbs", randomSize())
+
+	req := &gomapb.ExecReq{
+		CommandSpec: flatc.CommandSpec("flatc", "bin/flatc"),
+		Arg: []string{
+			"bin/flatc",
+			"--cpp",
+			"--conform", "../../src/base.fbs",
+			"-ogen",
+			"../../src/foo.fbs",
+		},
+		Env: []string{},
+		Cwd: proto.String("/b/c/w/out/Release"),
+		Input: []*gomapb.ExecReq_Input{
+			localFiles.mustInput(ctx, t, cluster.adapter.GomaFile, "/b/c/w/src/foo.fbs", "../../src/foo.fbs"),
+		},
+		Subprogram:    []*gomapb.SubprogramSpec{},
+		RequesterInfo: &gomapb.RequesterInfo{},
+		HermeticMode:  proto.Bool(true),
+	}
+
+	t.Logf("tool input not in request")
+	resp, err := cluster.adapter.Exec(ctx, req)
+	if err != nil {
+		t.Fatalf("Exec(ctx, req)=%v; %v; want nil error", resp, err)
+	}
+	if resp.GetError() != gomapb.ExecResp_BAD_REQUEST {
+		t.Errorf("Exec error=%v; want=%v", resp.GetError(), gomapb.ExecResp_BAD_REQUEST)
+	}
+	if len(resp.MissingInput) > 0 {
+		t.Errorf("missing=%q; want no missing", resp.MissingInput)
+	}
+	if cluster.rbe.gotCommand != nil {
+		t.Errorf("gotCommand=%v; want nil", cluster.rbe.gotCommand)
+	}
+
+	t.Logf("tool input in request")
+	req.Input = append(req.Input, localFiles.mustInput(ctx, t, cluster.adapter.GomaFile, "/b/c/w/src/base.fbs", "../../src/base.fbs"))
+	resp, err = cluster.adapter.Exec(ctx, req)
+	if err != nil {
+		t.Fatalf("Exec(ctx, req)=%v; %v; want nil error", resp, err)
+	}
+	if resp.GetError() != gomapb.ExecResp_OK {
+		t.Errorf("Exec error=%v; want=%v", resp.GetError(), gomapb.ExecResp_OK)
+	}
+	command := cluster.rbe.gotCommand
+	if command == nil {
+		t.Fatalf("gotCommand is nil")
+	}
+	wantOutputDirs := []string{
+		"out/Release/gen",
+	}
+	if !reflect.DeepEqual(command.OutputDirectories, wantOutputDirs) {
+		t.Errorf("output dirs: got=%v, want=%v", command.OutputDirectories, wantOutputDirs)
+	}
+}
+
+func TestAdapterHandleThinArchiveMembers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cluster := &fakeCluster{
+		rbe: newFakeRBE(),
+	}
+	err := cluster.setup(ctx, cluster.rbe.instancePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.teardown()
+
+	lldFile := cluster.cmdStorage.newFileSpec("bin/ld.lld", true)
+	lld := &fakeToolchain{
+		descs: []*cmdpb.CmdDescriptor{
+			{
+				Selector: &cmdpb.Selector{
+					Name:       "ld.lld",
+					Version:    "14.0.0",
+					Target:     "x86_64-unknown-linux-gnu",
+					BinaryHash: lldFile.Hash,
+				},
+				Setup: &cmdpb.CmdDescriptor_Setup{
+					CmdFile:  lldFile,
+					PathType: cmdpb.CmdDescriptor_POSIX,
+				},
+			},
+		},
+		RemoteexecPlatform: &cmdpb.RemoteexecPlatform{
+			Properties: []*cmdpb.RemoteexecPlatform_Property{
+				{
+					Name:  "container-image",
+					Value: "docker://grpc.io/goma-dev/container-image@sha256:xxxx",
+				},
+			},
+		},
+	}
+	err = cluster.pushToolchains(ctx, lld)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var localFiles fakeLocalFiles
+	localFiles.Add("/b/c/w/out/Release/obj/main.o", randomSize())
+	localFiles.AddContent("/b/c/w/out/Release/obj/libfoo.a", thinArchive("foo.o"))
+	localFiles.Add("/b/c/w/out/Release/obj/foo.o", randomSize())
+
+	archive := localFiles.mustInput(ctx, t, cluster.adapter.GomaFile, "/b/c/w/out/Release/obj/libfoo.a", "obj/libfoo.a")
+	// archive is read again in retried request by hash key,
+	// so it needs to be stored in file service.
+	_, err = cluster.adapter.GomaFile.StoreFile(ctx, &gomapb.StoreFileReq{
+		Blob: []*gomapb.FileBlob{archive.Content},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &gomapb.ExecReq{
+		CommandSpec: lld.CommandSpec("ld.lld", "bin/ld.lld"),
+		Arg: []string{
+			"bin/ld.lld",
+			"-o", "hello",
+			"obj/main.o",
+			"obj/libfoo.a",
+		},
+		Env: []string{},
+		Cwd: proto.String("/b/c/w/out/Release"),
+		Input: []*gomapb.ExecReq_Input{
+			localFiles.mustInput(ctx, t, cluster.adapter.GomaFile, "/b/c/w/out/Release/obj/main.o", "obj/main.o"),
+			archive,
+		},
+		Subprogram:    []*gomapb.SubprogramSpec{},
+		RequesterInfo: &gomapb.RequesterInfo{},
+		HermeticMode:  proto.Bool(true),
+	}
+
+	t.Logf("thin archive member not in request")
+	resp, err := cluster.adapter.Exec(ctx, req)
+	if err != nil {
+		t.Fatalf("Exec(ctx, req)=%v; %v; want nil error", resp, err)
+	}
+	if resp.GetError() != gomapb.ExecResp_BAD_REQUEST {
+		t.Errorf("Exec error=%v; want=%v", resp.GetError(), gomapb.ExecResp_BAD_REQUEST)
+	}
+	if len(resp.MissingInput) > 0 {
+		t.Errorf("missing=%q; want no missing", resp.MissingInput)
+	}
+	if cluster.rbe.gotCommand != nil {
+		t.Errorf("gotCommand=%v; want nil", cluster.rbe.gotCommand)
+	}
+
+	t.Logf("thin archive member in request")
+	req.Input = append(req.Input, localFiles.mustInput(ctx, t, cluster.adapter.GomaFile, "/b/c/w/out/Release/obj/foo.o", "obj/foo.o"))
+	resp, err = cluster.adapter.Exec(ctx, req)
+	if err != nil {
+		t.Fatalf("Exec(ctx, req)=%v; %v; want nil error", resp, err)
+	}
+	if resp.GetError() != gomapb.ExecResp_OK {
+		t.Errorf("Exec error=%v; want=%v", resp.GetError(), gomapb.ExecResp_OK)
+	}
+	if cluster.rbe.gotCommand == nil {
+		t.Errorf("gotCommand is nil")
+	}
+}
+
 func TestAdapterHandleCrossCompile(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -1301,3 +1700,204 @@ func TestAdapterNsjailHardening(t *testing.T) {
 		t.Errorf("platform.Properties diff want->got\n%s", diff)
 	}
 }
+
+// fakeActionCache is ActionCache that caches results in memory.
+type fakeActionCache struct {
+	mu sync.Mutex
+	m  map[string]*rpb.ActionResult
+}
+
+func (c *fakeActionCache) GetActionResult(ctx context.Context, ac rpb.ActionCacheClient, req *rpb.GetActionResultRequest) (*rpb.ActionResult, error) {
+	c.mu.Lock()
+	result, ok := c.m[cas.ResName(req.InstanceName, req.ActionDigest)]
+	c.mu.Unlock()
+	if ok {
+		return result, nil
+	}
+	return ac.GetActionResult(ctx, req)
+}
+
+func (c *fakeActionCache) Put(ctx context.Context, instanceName string, d *rpb.Digest, result *rpb.ActionResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = make(map[string]*rpb.ActionResult)
+	}
+	c.m[cas.ResName(instanceName, d)] = result
+}
+
+func TestAdapterActionCachePolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy    gomapb.ExecReq_CachePolicy
+		wantExecs int
+	}{
+		{
+			policy:    gomapb.ExecReq_LOOKUP_AND_STORE,
+			wantExecs: 1,
+		},
+		{
+			// action has do_not_cache, so results must not
+			// be served from ActionCache.
+			policy:    gomapb.ExecReq_LOOKUP_ONLY,
+			wantExecs: 2,
+		},
+	} {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			cluster := &fakeCluster{
+				rbe: newFakeRBE(),
+			}
+			err := cluster.setup(ctx, cluster.rbe.instancePrefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cluster.teardown()
+			ac := &fakeActionCache{}
+			cluster.adapter.ActionCache = ac
+
+			var execs int
+			fakeExec := cluster.rbe.fakeExec
+			cluster.rbe.fakeExec = func(ctx context.Context, req *rpb.ExecuteRequest) (*rpb.ExecuteResponse, error) {
+				execs++
+				return fakeExec(ctx, req)
+			}
+
+			clang := newFakeClang(&cluster.cmdStorage, "1234", "x86-64-linux-gnu")
+			err = cluster.pushToolchains(ctx, clang)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var localFiles fakeLocalFiles
+			localFiles.Add("/b/c/w/src/hello.cc", randomSize())
+
+			req := &gomapb.ExecReq{
+				CommandSpec: clang.CommandSpec("clang", "bin/clang"),
+				Arg: []string{
+					"bin/clang", "-c", "../../src/hello.cc",
+				},
+				Env: []string{},
+				Cwd: proto.String("/b/c/w/out/Release"),
+				Input: []*gomapb.ExecReq_Input{
+					localFiles.mustInput(ctx, t, cluster.adapter.GomaFile, "/b/c/w/src/hello.cc", "../../src/hello.cc"),
+				},
+				Subprogram:          []*gomapb.SubprogramSpec{},
+				RequesterInfo:       &gomapb.RequesterInfo{},
+				HermeticMode:        proto.Bool(true),
+				ExpectedOutputFiles: []string{"hello.o"},
+				CachePolicy:         tc.policy.Enum(),
+			}
+			for i := 0; i < 2; i++ {
+				resp, err := cluster.adapter.Exec(ctx, proto.Clone(req).(*gomapb.ExecReq))
+				if err != nil {
+					t.Fatalf("Exec(ctx, req)=%v; %v; want nil error", resp, err)
+				}
+				if resp.GetError() != gomapb.ExecResp_OK {
+					t.Errorf("Exec error=%v; want=%v", resp.GetError(), gomapb.ExecResp_OK)
+				}
+			}
+			if execs != tc.wantExecs {
+				t.Errorf("executed %d times; want %d", execs, tc.wantExecs)
+			}
+			wantEntries := 1
+			if tc.wantExecs > 1 {
+				wantEntries = 0
+			}
+			if len(ac.m) != wantEntries {
+				t.Errorf("action cache has %d entries; want %d", len(ac.m), wantEntries)
+			}
+		})
+	}
+}
+
+func TestAdapterQuotaExceeded(t *testing.T) {
+	for _, tc := range []struct {
+		desc      string
+		env       *gomapb.RequesterEnv
+		wantError gomapb.ExecResp_ExecError
+	}{
+		{
+			desc:      "no requester env",
+			wantError: gomapb.ExecResp_BAD_REQUEST,
+		},
+		{
+			desc:      "fallback",
+			env:       &gomapb.RequesterEnv{Fallback: proto.Bool(true)},
+			wantError: gomapb.ExecResp_BAD_REQUEST,
+		},
+		{
+			desc:      "no fallback",
+			env:       &gomapb.RequesterEnv{Fallback: proto.Bool(false)},
+			wantError: gomapb.ExecResp_OK,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			cluster := &fakeCluster{
+				rbe: newFakeRBE(),
+			}
+			err := cluster.setup(ctx, cluster.rbe.instancePrefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cluster.teardown()
+
+			m := &quota.Manager{
+				Store: &quota.LocalStore{},
+			}
+			err = m.Set(ctx, &quotapb.QuotaConfig{
+				Quotas: []*quotapb.GroupQuota{
+					{
+						HardLimit: &quotapb.Limit{
+							CpuSeconds: 1,
+						},
+						Action: quotapb.GroupQuota_LOCAL_FALLBACK,
+					},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// request without enduser is accounted as unknown-group.
+			m.Record(ctx, "unknown-group", quota.Usage{CPUTime: 2 * time.Second})
+			cluster.adapter.Quota = m
+
+			clang := newFakeClang(&cluster.cmdStorage, "1234", "x86-64-linux-gnu")
+			err = cluster.pushToolchains(ctx, clang)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var localFiles fakeLocalFiles
+			localFiles.Add("/b/c/w/src/hello.cc", randomSize())
+
+			req := &gomapb.ExecReq{
+				CommandSpec: clang.CommandSpec("clang", "bin/clang"),
+				Arg: []string{
+					"bin/clang", "-c", "../../src/hello.cc",
+				},
+				Env: []string{},
+				Cwd: proto.String("/b/c/w/out/Release"),
+				Input: []*gomapb.ExecReq_Input{
+					localFiles.mustInput(ctx, t, cluster.adapter.GomaFile, "/b/c/w/src/hello.cc", "../../src/hello.cc"),
+				},
+				Subprogram:          []*gomapb.SubprogramSpec{},
+				RequesterInfo:       &gomapb.RequesterInfo{},
+				RequesterEnv:        tc.env,
+				HermeticMode:        proto.Bool(true),
+				ExpectedOutputFiles: []string{"hello.o"},
+			}
+			resp, err := cluster.adapter.Exec(ctx, req)
+			if err != nil {
+				t.Fatalf("Exec(ctx, req)=%v; %v; want nil error", resp, err)
+			}
+			if resp.GetError() != tc.wantError {
+				t.Errorf("Exec error=%v; want=%v", resp.GetError(), tc.wantError)
+			}
+		})
+	}
+}
@@ -36,7 +36,10 @@ var bufPool = sync.Pool{
 func ioCopyBuffer(wr io.Writer, rd io.Reader) (int64, error) {
 	buf := bufPool.Get().([]byte)
 	defer bufPool.Put(buf)
-	return io.CopyBuffer(wr, rd, buf)
+	// hide WriterTo of rd (e.g. *bytes.Reader) and ReaderFrom of wr,
+	// so that io.CopyBuffer writes in chunks of maxChunkSizeBytes.
+	// otherwise, it may send a message larger than grpc limit.
+	return io.CopyBuffer(struct{ io.Writer }{wr}, struct{ io.Reader }{rd}, buf)
 }
 
 // ParseResName parses resource name; digest string formatted as "blobs/<hash>/<sizebytes>".
@@ -0,0 +1,70 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package cas
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	bpb "google.golang.org/genproto/googleapis/bytestream"
+	"google.golang.org/grpc"
+)
+
+// fakeByteStreamWriter records data size of each WriteRequest.
+type fakeByteStreamWriter struct {
+	bpb.ByteStream_WriteClient
+	sizes     []int
+	committed int64
+}
+
+func (f *fakeByteStreamWriter) Send(req *bpb.WriteRequest) error {
+	if req.FinishWrite {
+		return nil
+	}
+	f.sizes = append(f.sizes, len(req.Data))
+	f.committed += int64(len(req.Data))
+	return nil
+}
+
+func (f *fakeByteStreamWriter) CloseAndRecv() (*bpb.WriteResponse, error) {
+	return &bpb.WriteResponse{
+		CommittedSize: f.committed,
+	}, nil
+}
+
+type fakeByteStreamClient struct {
+	bpb.ByteStreamClient
+	wr *fakeByteStreamWriter
+}
+
+func (f fakeByteStreamClient) Write(ctx context.Context, opts ...grpc.CallOption) (bpb.ByteStream_WriteClient, error) {
+	return f.wr, nil
+}
+
+func TestUploadInChunks(t *testing.T) {
+	ctx := context.Background()
+	wr := &fakeByteStreamWriter{}
+	bs := fakeByteStreamClient{wr: wr}
+
+	data := make([]byte, 2*maxChunkSizeBytes+1)
+	// ioutil.NopCloser of *bytes.Reader implements io.WriterTo.
+	rd := ioutil.NopCloser(bytes.NewReader(data))
+	err := Upload(ctx, bs, "instance/uploads/uuid/blobs/hash/size", int64(len(data)), rd)
+	if err != nil {
+		t.Fatalf("Upload(ctx, bs, resname, %d, rd)=%v; want nil error", len(data), err)
+	}
+	want := []int{maxChunkSizeBytes, maxChunkSizeBytes, 1}
+	if len(wr.sizes) != len(want) {
+		t.Fatalf("chunk sizes=%d; want %d", wr.sizes, want)
+	}
+	for i := range want {
+		if wr.sizes[i] != want[i] {
+			t.Errorf("chunk sizes=%d; want %d", wr.sizes, want)
+			break
+		}
+	}
+}
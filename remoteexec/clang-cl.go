@@ -332,38 +332,69 @@ func clangclArgRelocatable(filepath clientFilePath, args []string) error {
 // clangclOutputs returns output files from clang-cl command line.
 // https://clang.llvm.org/docs/UsersManual.html#id8
 //  /Fo<obj> and /Fd<pdb> is used in Cross-compiling Chrome/win
-//  but clang-cl currently doesn't emit pdb.
 // https://chromium.googlesource.com/chromium/src/+/lkcr/docs/win_cross.md
-// TODO: support output directory (ends in / or \)?
+// If /c is given without /Fo, object file is generated in cwd.
+// Other outputs generated by flags (e.g. /Fd, /FR, /sourceDependencies)
+// are discovered by clangclOutputRules.
 func clangclOutputs(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
 	var outputs []string
+	var objout, input string
+	compile := false
 	outputArg := false
+	explicitOutput := false
 
-	for _, arg := range args {
+	for _, arg := range args[1:] {
 		switch {
 		case outputArg:
 			outputs = append(outputs, arg)
 			outputArg = false
+			explicitOutput = true
 
 		case arg == "/o" || arg == "-o": // /o <file or directory>
 			outputArg = true
 
+		case arg == "/c" || arg == "-c":
+			compile = true
+
 		case len(arg) > 2 &&
 			(arg[0] == '-' || arg[0] == '/') &&
 			arg[1] == 'o':
 			outputs = append(outputs, arg[2:])
+			explicitOutput = true
+
+		case len(arg) > 3 &&
+			(arg[0] == '-' || arg[0] == '/') &&
+			arg[1] == 'F' &&
+			arg[2] == 'o': // /Fo<obj>
+			objout = arg[3:]
 
 		case len(arg) > 3 &&
 			(arg[0] == '-' || arg[0] == '/') &&
 			arg[1] == 'F' &&
-			(arg[2] == 'o' || // /Fo<obj>
-				arg[2] == 'i' || // /Fi<file>  preproc output
+			(arg[2] == 'i' || // /Fi<file>  preproc output
 				arg[2] == 'a' || // /Fa<file>  asm output
 				arg[2] == 'e' || // /Fe<exec>
 				arg[2] == 'p'): // /Fp<pch>
-			// TODO: /Fd<pdb> if clang-cl emits pdb.
 			outputs = append(outputs, arg[3:])
+
+		case input == "" && !strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "/") && sourceExt(arg) != "":
+			input = arg
 		}
 	}
-	return outputs
+	if input != "" && (isDirPath(objout) || (objout == "" && compile && !explicitOutput)) {
+		// /Fo<dir>\ or no /Fo.
+		objout += pathStem(input) + ".obj"
+	}
+	if objout != "" {
+		outputs = append(outputs, objout)
+	}
+	c := outputContext{
+		obj:   objout,
+		input: input,
+		clang: true,
+	}
+	return append(outputs, applyOutputRules(clangclOutputRules, args[1:], c)...)
 }
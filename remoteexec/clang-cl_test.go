@@ -396,8 +396,7 @@ func TestClangclOutputs(t *testing.T) {
 				"/FoA/test.o",
 				"/FdA/test.pdb",
 			},
-			// TODO: capture pdb.
-			want: []string{"A/test.o"},
+			want: []string{"A/test.o", "A/test.pdb"},
 		},
 		{
 			desc: "basic dash",
@@ -0,0 +1,135 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"context"
+	"sync"
+
+	rpb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/log"
+	gomapb "go.chromium.org/goma/server/proto/api"
+)
+
+// execCall is an in-flight execution of an action.
+type execCall struct {
+	done chan struct{}
+
+	// eresp is set if the execution succeeded.
+	// it must not be modified after done is closed.
+	eresp *rpb.ExecuteResponse
+}
+
+// execGroup coalesces concurrent executions of the same action.
+// Zero value is ready to use.
+type execGroup struct {
+	mu    sync.Mutex
+	calls map[string]*execCall
+}
+
+// join returns in-flight call for key, and reports whether caller
+// becomes leader of the call.  Leader must call finish when
+// the execution has finished.
+func (g *execGroup) join(key string) (*execCall, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c, ok := g.calls[key]; ok {
+		return c, false
+	}
+	if g.calls == nil {
+		g.calls = make(map[string]*execCall)
+	}
+	c := &execCall{
+		done: make(chan struct{}),
+	}
+	g.calls[key] = c
+	return c, true
+}
+
+// finish sets result of leader's call and wakes up followers.
+// eresp is nil if the execution failed.
+func (g *execGroup) finish(key string, c *execCall, eresp *rpb.ExecuteResponse) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	c.eresp = eresp
+	close(c.done)
+}
+
+// coalescable reports whether eresp can be shared with other requests.
+func coalescable(eresp *rpb.ExecuteResponse) bool {
+	return eresp != nil && eresp.GetStatus().GetCode() == 0 && eresp.Result != nil
+}
+
+func recordCoalesce(ctx context.Context, result string) {
+	ctx, err := tag.New(ctx, tag.Upsert(coalesceResultKey, result))
+	if err != nil {
+		logger := log.FromContext(ctx)
+		logger.Errorf("failed to record coalesce stats: %v", err)
+		return
+	}
+	stats.Record(ctx, execCoalesceCount.M(1))
+}
+
+// executeCoalesced executes action of r, unless the same action is
+// being executed by other request.  In that case, it waits for the
+// result of the other request, and reports coalesced=true.
+// If the other request failed or didn't finish in time, it executes
+// the action by itself.
+func (f *Adapter) executeCoalesced(ctx context.Context, r *request, espan *execSpan) (resp *gomapb.ExecResp, eresp *rpb.ExecuteResponse, coalesced bool, err error) {
+	if !f.CoalesceExec || r.err != nil || r.actionDigest == nil || skipCacheLookup(r.gomaReq) {
+		resp, eresp, err = f.execute(ctx, r, espan)
+		return resp, eresp, false, err
+	}
+	logger := log.FromContext(ctx)
+	key := r.instanceName() + "/" + r.actionDigest.GetHash()
+	c, leader := f.execGroup.join(key)
+	if leader {
+		recordCoalesce(ctx, "leader")
+		defer func() {
+			if resp != nil || err != nil || !coalescable(eresp) {
+				f.execGroup.finish(key, c, nil)
+				return
+			}
+			f.execGroup.finish(key, c, proto.Clone(eresp).(*rpb.ExecuteResponse))
+		}()
+		resp, eresp, err = f.execute(ctx, r, espan)
+		return resp, eresp, false, err
+	}
+
+	logger.Infof("wait for in-flight execution of %s", key)
+	var result string
+	espan.Do(ctx, "coalesce", f.SpanTimeout.Coalesce, func(ctx context.Context) {
+		select {
+		case <-c.done:
+			if c.eresp == nil {
+				result = "leader_failed"
+				return
+			}
+			result = "coalesced"
+			eresp = proto.Clone(c.eresp).(*rpb.ExecuteResponse)
+		case <-ctx.Done():
+			result = "timeout"
+		}
+	})
+	if result == "timeout" && ctx.Err() != nil {
+		result = "canceled"
+	}
+	recordCoalesce(ctx, result)
+	switch result {
+	case "coalesced":
+		logger.Infof("coalesced with in-flight execution of %s", key)
+		return nil, eresp, true, nil
+	case "canceled":
+		return nil, nil, false, ctx.Err()
+	}
+	logger.Warnf("in-flight execution of %s: %s. execute by itself", key, result)
+	resp, eresp, err = f.execute(ctx, r, espan)
+	return resp, eresp, false, err
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"sync"
+	"testing"
+
+	rpb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+)
+
+func TestExecGroup(t *testing.T) {
+	var g execGroup
+	leaderCall, leader := g.join("action")
+	if !leader {
+		t.Fatalf("join(action) for first request: leader=false; want true")
+	}
+
+	const followers = 3
+	var wg sync.WaitGroup
+	results := make(chan *rpb.ExecuteResponse, followers)
+	for i := 0; i < followers; i++ {
+		c, leader := g.join("action")
+		if leader {
+			t.Fatalf("join(action) while in flight: leader=true; want false")
+		}
+		if c != leaderCall {
+			t.Errorf("join(action) returned different call")
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-c.done
+			results <- c.eresp
+		}()
+	}
+	if _, leader := g.join("other-action"); !leader {
+		t.Errorf("join(other-action): leader=false; want true")
+	}
+
+	eresp := &rpb.ExecuteResponse{
+		Result: &rpb.ActionResult{
+			ExitCode: 1,
+		},
+	}
+	g.finish("action", leaderCall, eresp)
+	wg.Wait()
+	close(results)
+	for got := range results {
+		if got != eresp {
+			t.Errorf("follower got %v; want %v", got, eresp)
+		}
+	}
+
+	if _, leader := g.join("action"); !leader {
+		t.Errorf("join(action) after finish: leader=false; want true")
+	}
+}
+
+func TestCoalescable(t *testing.T) {
+	for _, tc := range []struct {
+		desc  string
+		eresp *rpb.ExecuteResponse
+		want  bool
+	}{
+		{
+			desc: "nil",
+		},
+		{
+			desc:  "no result",
+			eresp: &rpb.ExecuteResponse{},
+		},
+		{
+			desc: "error status",
+			eresp: &rpb.ExecuteResponse{
+				Status: &spb.Status{Code: 14},
+				Result: &rpb.ActionResult{},
+			},
+		},
+		{
+			desc: "command failed",
+			eresp: &rpb.ExecuteResponse{
+				Result: &rpb.ActionResult{
+					ExitCode: 1,
+				},
+			},
+			want: true,
+		},
+	} {
+		if got := coalescable(tc.eresp); got != tc.want {
+			t.Errorf("coalescable(%s)=%t; want=%t", tc.desc, got, tc.want)
+		}
+	}
+}
@@ -35,6 +35,7 @@ import (
 	"go.chromium.org/goma/server/log"
 	gomapb "go.chromium.org/goma/server/proto/api"
 	cmdpb "go.chromium.org/goma/server/proto/command"
+	"go.chromium.org/goma/server/quota"
 	"go.chromium.org/goma/server/remoteexec/cas"
 	"go.chromium.org/goma/server/remoteexec/digest"
 	"go.chromium.org/goma/server/remoteexec/merkletree"
@@ -61,6 +62,7 @@ type request struct {
 
 	args         []string
 	envs         []string
+	expandedArgs []string
 	outputs      []string
 	outputDirs   []string
 	platform     *rpb.Platform
@@ -173,6 +175,15 @@ func (r *request) Err() error {
 	}
 }
 
+// quotaExceeded returns response to make client fall back to local
+// since user's group exceeds quota.
+// It must be used only if client can fall back, i.e. canFallback.
+func (r *request) quotaExceeded() *gomapb.ExecResp {
+	r.gomaResp.Error = gomapb.ExecResp_BAD_REQUEST.Enum()
+	r.gomaResp.ErrorMessage = append(r.gomaResp.ErrorMessage, fmt.Sprintf("quota exceeded for group %s", r.userGroup))
+	return r.gomaResp
+}
+
 func (r *request) instanceName() string {
 	basename := r.cmdConfig.GetRemoteexecPlatform().GetRbeInstanceBasename()
 	if basename == "" {
@@ -186,6 +197,7 @@ func (r *request) instanceName() string {
 // It returns non-nil ExecResp for:
 // - compiler/subprogram not found
 // - bad path_type in command config
+// - rustc request that needs linker, or for other target
 func (r *request) getInventoryData(ctx context.Context) *gomapb.ExecResp {
 	if r.err != nil {
 		return nil
@@ -220,6 +232,11 @@ func (r *request) getInventoryData(ctx context.Context) *gomapb.ExecResp {
 	for _, prop := range cmdConfig.GetRemoteexecPlatform().GetProperties() {
 		r.addPlatformProperty(ctx, prop.Name, prop.Value)
 	}
+	if isLinker(cmdConfig.GetCmdDescriptor().GetSelector().GetName()) {
+		for _, prop := range r.f.LinkPlatformProperties {
+			r.addPlatformProperty(ctx, prop.Name, prop.Value)
+		}
+	}
 	if len(r.gomaReq.GetRequesterInfo().GetPlatformProperties()) > 0 {
 		for _, pp := range r.gomaReq.GetRequesterInfo().GetPlatformProperties() {
 			if !isSafePlatformProperty(pp.GetName(), pp.GetValue()) {
@@ -235,6 +252,18 @@ func (r *request) getInventoryData(ctx context.Context) *gomapb.ExecResp {
 			return r.gomaResp
 		}
 	}
+	if cmdConfig.GetCmdDescriptor().GetSelector().GetName() == "rustc" {
+		err := rustcLinkerFreeReq(r.gomaReq.Arg)
+		if err == nil {
+			err = rustcTargetReq(r.gomaReq.Arg, cmdConfig.GetCmdDescriptor().GetSelector().GetTarget())
+		}
+		if err != nil {
+			logger.Infof("unsupported rustc request: %v", err)
+			r.gomaResp.Error = gomapb.ExecResp_BAD_REQUEST.Enum()
+			r.gomaResp.ErrorMessage = append(r.gomaResp.ErrorMessage, fmt.Sprintf("unsupported rustc request: %v", err))
+			return r.gomaResp
+		}
+	}
 	r.allowChroot = cmdConfig.GetRemoteexecPlatform().GetHasNsjail()
 	logger.Infof("platform: %s, allowChroot=%t path_tpye=%s windows_cross=%t", r.platform, r.allowChroot, cmdConfig.GetCmdDescriptor().GetSetup().GetPathType(), cmdConfig.GetCmdDescriptor().GetCross().GetWindowsCross())
 	return nil
@@ -573,6 +602,75 @@ func (r *request) newInputTree(ctx context.Context) *gomapb.ExecResp {
 		}
 		files = append(files, in.file)
 	}
+	name := r.cmdConfig.GetCmdDescriptor().GetSelector().GetName()
+	switch name {
+	case "gcc", "g++", "clang", "clang++", "clang-cl", "ld.lld", "lld":
+		if !hasRspArg(r.gomaReq.Arg) && !isLinker(name) {
+			break
+		}
+		// expand response files to analyze args, and ask client
+		// to send response files if they are not in the request.
+		// args of the request is sent as is.
+		files := newRequestFiles(r.filepath, cleanCWD, reqInputs, results)
+		missing, err := r.expandArgs(ctx, name, files)
+		if err != nil {
+			logger.Errorf("bad args input: %v", err)
+			r.gomaResp.Error = gomapb.ExecResp_BAD_REQUEST.Enum()
+			r.gomaResp.ErrorMessage = append(r.gomaResp.ErrorMessage, fmt.Sprintf("bad args input: %v", err))
+			return r.gomaResp
+		}
+		for _, fname := range missing {
+			missingInputs = append(missingInputs, fname)
+			missingReason = append(missingReason, "args input: not in request")
+		}
+		if len(missing) > 0 || !isLinker(name) {
+			break
+		}
+		// client doesn't know thin archive members, so it
+		// can't send them even if we ask by missing input.
+		// let client fall back to local if they are not in
+		// the request.
+		members, err := missingThinArchiveMembers(ctx, files, r.analysisArgs())
+		if err == nil && len(members) > 0 {
+			err = fmt.Errorf("thin archive members not in request: %q", members)
+		}
+		if err != nil {
+			logger.Errorf("bad args input: %v", err)
+			r.gomaResp.Error = gomapb.ExecResp_BAD_REQUEST.Enum()
+			r.gomaResp.ErrorMessage = append(r.gomaResp.ErrorMessage, fmt.Sprintf("bad args input: %v", err))
+			return r.gomaResp
+		}
+	}
+	switch name {
+	case "gcc", "g++", "clang", "clang++":
+		// client doesn't know explicit module inputs unless its
+		// include processor finds them, so it can't send them
+		// even if we ask by missing input.
+		// let client fall back to local if they are not in
+		// the request.
+		if missing := missingModuleInputs(r.filepath, cleanCWD, r.analysisArgs(), reqInputs); len(missing) > 0 {
+			logger.Errorf("module inputs not in request: %q", missing)
+			r.gomaResp.Error = gomapb.ExecResp_BAD_REQUEST.Enum()
+			r.gomaResp.ErrorMessage = append(r.gomaResp.ErrorMessage, fmt.Sprintf("module inputs not in request: %q", missing))
+			return r.gomaResp
+		}
+	default:
+		spec := r.cmdConfig.GetCmdDescriptor().GetToolSpec()
+		if spec == nil {
+			break
+		}
+		// client doesn't know input files described in tool
+		// spec, so it can't send them even if we ask by
+		// missing input.
+		// let client fall back to local if they are not in
+		// the request.
+		if missing := missingFiles(r.filepath, cleanCWD, toolSpecInputs(spec, r.gomaReq.Arg), reqInputs); len(missing) > 0 {
+			logger.Errorf("tool inputs not in request: %q", missing)
+			r.gomaResp.Error = gomapb.ExecResp_BAD_REQUEST.Enum()
+			r.gomaResp.ErrorMessage = append(r.gomaResp.ErrorMessage, fmt.Sprintf("tool inputs not in request: %q", missing))
+			return r.gomaResp
+		}
+	}
 	if len(missingInputs) > 0 {
 		logger.Infof("missing %d inputs out of %d. need to uploads=%d", len(missingInputs), len(reqInputs), len(uploads))
 
@@ -659,13 +757,13 @@ func (r *request) newInputTree(ctx context.Context) *gomapb.ExecResp {
 	addDirs("system framework path", r.gomaReq.GetCommandSpec().GetSystemFrameworkPath())
 
 	// prepare output dirs.
-	r.outputs = outputs(ctx, r.cmdConfig, r.gomaReq)
+	r.outputs = outputs(ctx, r.cmdConfig, r.gomaReq, r.analysisArgs())
 	var outDirs []string
 	for _, d := range r.outputs {
 		outDirs = append(outDirs, r.filepath.Dir(d))
 	}
 	addDirs("output file", outDirs)
-	r.outputDirs = outputDirs(ctx, r.cmdConfig, r.gomaReq)
+	r.outputDirs = outputDirs(ctx, r.cmdConfig, r.gomaReq, r.analysisArgs())
 	addDirs("output dir", r.outputDirs)
 	if r.err != nil {
 		return nil
@@ -773,25 +871,29 @@ func (r *request) newWrapperScript(ctx context.Context, cmdConfig *cmdpb.Config,
 	// if needed.
 	var files []merkletree.Entry
 
-	args := buildArgs(ctx, cmdConfig, argv0, r.gomaReq)
+	args := buildArgs(ctx, cmdConfig, argv0, r.gomaReq, r.expandedArgs)
 	// TODO: only allow specific envs.
 	r.crossTarget = targetFromArgs(args)
+	if r.crossTarget == "" && r.expandedArgs != nil {
+		r.crossTarget = targetFromArgs(r.expandedArgs)
+	}
 
 	var relocatableErr error
+	var normalizedArgs []string
 	wt := wrapperRelocatable
 	switch r.filepath.(type) {
 	case posixpath.FilePath:
 		if r.needChroot {
 			wt = wrapperNsjailChroot
 		} else {
-			relocatableErr = relocatableReq(ctx, cmdConfig, r.filepath, r.gomaReq.Arg, r.gomaReq.Env)
+			normalizedArgs, relocatableErr = r.normalizedArgs(ctx, cmdConfig, cleanCWD, cleanRootDir)
 			if relocatableErr != nil {
 				wt = wrapperInputRootAbsolutePath
 				logger.Infof("non relocatable: %v", relocatableErr)
 			}
 		}
 	case winpath.FilePath:
-		relocatableErr = relocatableReq(ctx, cmdConfig, r.filepath, r.gomaReq.Arg, r.gomaReq.Env)
+		normalizedArgs, relocatableErr = r.normalizedArgs(ctx, cmdConfig, cleanCWD, cleanRootDir)
 		if relocatableErr != nil {
 			wt = wrapperWinInputRootAbsolutePath
 			logger.Infof("non relocatable: %v", relocatableErr)
@@ -815,6 +917,9 @@ func (r *request) newWrapperScript(ctx context.Context, cmdConfig *cmdpb.Config,
 		// internal error? maybe toolchain config is broken.
 		return fmt.Errorf("bad path type: %T", r.filepath)
 	}
+	if normalizedArgs != nil {
+		args = append([]string{args[0]}, normalizedArgs[1:]...)
+	}
 
 	const posixWrapperName = "run.sh"
 	switch wt {
@@ -1012,7 +1117,10 @@ func disableHardening(hashes []string, cmdFiles []*cmdpb.FileSpec) (*cmdpb.FileS
 // TODO: refactor with exec/clang.go, exec/clangcl.go?
 
 // buildArgs builds args in RBE from arg0 and req, respecting cmdConfig.
-func buildArgs(ctx context.Context, cmdConfig *cmdpb.Config, arg0 string, req *gomapb.ExecReq) []string {
+// expandedArgs is args of req with response files expanded, or nil
+// if req has no response file. It is used to check flags in response
+// files, and response files are kept as is in args in RBE.
+func buildArgs(ctx context.Context, cmdConfig *cmdpb.Config, arg0 string, req *gomapb.ExecReq, expandedArgs []string) []string {
 	// TODO: need compiler specific handling?
 	args := append([]string{arg0}, req.Arg[1:]...)
 	if cmdConfig.GetCmdDescriptor().GetCross().GetWindowsCross() {
@@ -1074,6 +1182,10 @@ func buildArgs(ctx context.Context, cmdConfig *cmdpb.Config, arg0 string, req *g
 
 	}
 	if cmdConfig.GetCmdDescriptor().GetCross().GetClangNeedTarget() {
+		if expandedArgs != nil && targetFromArgs(expandedArgs) != "" {
+			// target is given in response file.
+			return args
+		}
 		args = addTargetIfNotExist(args, req.GetCommandSpec().GetTarget())
 	}
 	return args
@@ -1125,12 +1237,22 @@ func relocatableReq(ctx context.Context, cmdConfig *cmdpb.Config, filepath clien
 		err = gccRelocatableReq(filepath, args, envs)
 	case "clang-cl":
 		err = clangclRelocatableReq(filepath, args, envs)
+	case "rustc":
+		err = rustcRelocatableReq(filepath, args, envs)
+	case "swiftc":
+		err = swiftcRelocatableReq(filepath, args, envs)
+	case "ld.lld", "lld":
+		err = lldRelocatableReq(filepath, args, envs)
 	case "javac":
 		// Currently, javac in Chromium is fully relocatable. Simpler just to
 		// support only the relocatable case and let it fail if the client passed
 		// in invalid absolute paths.
 		err = nil
 	default:
+		if spec := cmdConfig.GetCmdDescriptor().GetToolSpec(); spec != nil {
+			err = toolSpecRelocatableReq(spec, filepath, args)
+			break
+		}
 		// "cl.exe", "clang-tidy"
 		err = fmt.Errorf("no relocatable check for %s", name)
 	}
@@ -1150,18 +1272,26 @@ func relocatableReq(ctx context.Context, cmdConfig *cmdpb.Config, filepath clien
 // If either expected_output_files or expected_output_dirs is specified,
 // expected_output_files is used.
 // Otherwise, it's calculated from args.
-func outputs(ctx context.Context, cmdConfig *cmdpb.Config, gomaReq *gomapb.ExecReq) []string {
+func outputs(ctx context.Context, cmdConfig *cmdpb.Config, gomaReq *gomapb.ExecReq, args []string) []string {
 	if len(gomaReq.ExpectedOutputFiles) > 0 || len(gomaReq.ExpectedOutputDirs) > 0 {
 		return gomaReq.GetExpectedOutputFiles()
 	}
 
-	args := gomaReq.Arg
 	switch name := cmdConfig.GetCmdDescriptor().GetSelector().GetName(); name {
 	case "gcc", "g++", "clang", "clang++":
 		return gccOutputs(args)
 	case "clang-cl":
 		return clangclOutputs(args)
+	case "rustc":
+		return rustcOutputs(args)
+	case "swiftc":
+		return swiftcOutputs(args)
+	case "ld.lld", "lld":
+		return lldOutputs(args)
 	default:
+		if spec := cmdConfig.GetCmdDescriptor().GetToolSpec(); spec != nil {
+			return toolSpecOutputs(spec, args)
+		}
 		// "cl.exe", "javac", "clang-tidy"
 		return nil
 	}
@@ -1171,18 +1301,54 @@ func outputs(ctx context.Context, cmdConfig *cmdpb.Config, gomaReq *gomapb.ExecR
 // If either expected_output_files or expected_output_dirs is specified,
 // expected_output_dirs is used.
 // Otherwise, it's calculated from args.
-func outputDirs(ctx context.Context, cmdConfig *cmdpb.Config, gomaReq *gomapb.ExecReq) []string {
+// Module cache directory of clang modules build and swiftc is always
+// included.
+func outputDirs(ctx context.Context, cmdConfig *cmdpb.Config, gomaReq *gomapb.ExecReq, args []string) []string {
+	name := cmdConfig.GetCmdDescriptor().GetSelector().GetName()
+	var dirs []string
 	if len(gomaReq.ExpectedOutputFiles) > 0 || len(gomaReq.ExpectedOutputDirs) > 0 {
-		return gomaReq.GetExpectedOutputDirs()
+		dirs = append([]string(nil), gomaReq.GetExpectedOutputDirs()...)
+	} else {
+		switch name {
+		case "javac":
+			dirs = javacOutputDirs(args)
+		case "ld.lld", "lld":
+			dirs = lldOutputDirs(args)
+		default:
+			if spec := cmdConfig.GetCmdDescriptor().GetToolSpec(); spec != nil {
+				dirs = toolSpecOutputDirs(spec, args)
+			}
+		}
 	}
+	// client doesn't expect module cache as output,
+	// but it would be updated by implicit modules build.
+	var cacheDirs []string
+	switch name {
+	case "gcc", "g++", "clang", "clang++":
+		cacheDirs = gccModuleCacheDirs(args)
+	case "swiftc":
+		cacheDirs = swiftcModuleCacheDirs(args)
+	}
+Loop:
+	for _, d := range cacheDirs {
+		for _, dir := range dirs {
+			if dir == d {
+				continue Loop
+			}
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs
+}
 
-	args := gomaReq.Arg
-	switch cmdConfig.GetCmdDescriptor().GetSelector().GetName() {
-	case "javac":
-		return javacOutputDirs(args)
-	default:
-		return nil
+// analysisArgs returns args to analyze the request (e.g. outputs,
+// relocatability). It is args of the request with response files
+// expanded if any.
+func (r *request) analysisArgs() []string {
+	if r.expandedArgs != nil {
+		return r.expandedArgs
 	}
+	return r.gomaReq.Arg
 }
 
 func (r *request) setupNewAction(ctx context.Context) {
@@ -1275,10 +1441,17 @@ func (r *request) checkCache(ctx context.Context) (*rpb.ActionResult, bool) {
 		logger.Infof("store_only; skip cache lookup")
 		return nil, false
 	}
-	resp, err := r.client.Cache().GetActionResult(ctx, &rpb.GetActionResultRequest{
+	req := &rpb.GetActionResultRequest{
 		InstanceName: r.instanceName(),
 		ActionDigest: r.actionDigest,
-	})
+	}
+	var resp *rpb.ActionResult
+	var err error
+	if r.f.ActionCache != nil {
+		resp, err = r.f.ActionCache.GetActionResult(ctx, r.client.Cache(), req)
+	} else {
+		resp, err = r.client.Cache().GetActionResult(ctx, req)
+	}
 	if err != nil {
 		switch status.Code(err) {
 		case codes.NotFound:
@@ -1444,6 +1617,11 @@ func (r *request) executeAction(ctx context.Context) (*rpb.ExecuteResponse, erro
 		r.err = err
 		return nil, r.Err()
 	}
+	// successful result is stored in remote ActionCache,
+	// unless the action is not cacheable.
+	if r.f.ActionCache != nil && !r.action.GetDoNotCache() && resp.GetStatus().GetCode() == 0 && resp.Result != nil && resp.Result.ExitCode == 0 {
+		r.f.ActionCache.Put(ctx, r.instanceName(), r.actionDigest, resp.Result)
+	}
 	return resp, nil
 }
 
@@ -1515,6 +1693,9 @@ func (r *request) newResp(ctx context.Context, eresp *rpb.ExecuteResponse, cache
 	stats.RecordWithTags(ctx, tags, rbeInputTime.M(float64(inputTime.Nanoseconds())/1e6))
 	stats.RecordWithTags(ctx, tags, rbeExecTime.M(float64(execTime.Nanoseconds())/1e6))
 	stats.RecordWithTags(ctx, tags, rbeOutputTime.M(float64(outputTime.Nanoseconds())/1e6))
+	if !cached && !eresp.CachedResult {
+		r.f.Quota.Record(ctx, r.userGroup, quota.Usage{CPUTime: workerTime})
+	}
 
 	r.gomaResp.ExecutionStats = &gomapb.ExecutionStats{
 		ExecutionStartTimestamp:     md.GetExecutionStartTimestamp(),
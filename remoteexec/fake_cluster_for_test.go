@@ -332,6 +332,14 @@ func (f *fakeLocalFiles) Add(fname string, size uint64) {
 	f.m[fname] = string(buf)
 }
 
+// AddContent adds fake file with content.
+func (f *fakeLocalFiles) AddContent(fname string, content []byte) {
+	if f.m == nil {
+		f.m = make(map[string]string)
+	}
+	f.m[fname] = string(content)
+}
+
 // Dup dups oldname as newname.
 func (f *fakeLocalFiles) Dup(oldname, newname string) {
 	f.m[newname] = f.m[oldname]
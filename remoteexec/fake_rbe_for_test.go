@@ -404,7 +404,11 @@ func (f *fakeRBE) Execute(req *rpb.ExecuteRequest, s rpb.Execution_ExecuteServer
 
 	err = status.FromProto(resp.GetStatus()).Err()
 	if err == nil && resp != nil && resp.Result != nil && resp.Result.ExitCode == 0 {
-		f.cache.Set(req.ActionDigest, proto.Clone(resp.Result).(*rpb.ActionResult))
+		// as RBE does, don't cache result of do_not_cache action.
+		action := &rpb.Action{}
+		if f.getProto(ctx, req.ActionDigest, action) == nil && !action.DoNotCache {
+			f.cache.Set(req.ActionDigest, proto.Clone(resp.Result).(*rpb.ActionResult))
+		}
 	}
 	ops := f.ops.Get(opname)
 	for _, op := range ops {
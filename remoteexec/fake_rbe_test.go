@@ -327,7 +327,9 @@ func bytestreamUpload(ctx context.Context, client bpb.ByteStreamClient, resname
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(wr, rd)
+	// Writer accepts chunk up to server's max chunk size, so hide
+	// WriterTo of rd to write in chunks.
+	_, err = io.CopyBuffer(wr, struct{ io.Reader }{rd}, make([]byte, 2*1024*1024))
 	if err != nil {
 		wr.Close()
 		return err
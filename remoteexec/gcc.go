@@ -16,11 +16,16 @@ var pathFlags = []string{
 	"-fcrash-diagnostics-dir=",
 	"-fdebug-compilation-dir=",
 	"-ffile-compilation-dir=",
+	"-fprebuilt-module-path=",
 	"-fprofile-sample-use=",
 	"-fsanitize-blacklist=",
+	"-fmodules-cache-path=",
 	"-fprofile-instr-use=",
+	"-fmodule-map-file=",
+	"-fmodule-output=",
 	"-fprofile-list=",
 	"-resource-dir=",
+	"-fmodule-file=",
 	"--include=",
 	"--sysroot=",
 	"--include",
@@ -46,7 +51,8 @@ var pathFlags = []string{
 // outputs that would contain absolute path names (DW_AT_comp_dir etc),
 // if
 //  debug build (-g* except -g0) -> DW_AT_comp_dir or other filepaths.
-//    this will be canceled by -fdebug-compilation-dir
+//    this will be canceled by -fdebug-compilation-dir, or
+//    -fdebug-prefix-map/-ffile-prefix-map that maps to relative path.
 //  --pnacl-allow-translate  crbug.com/685461
 //
 // The following flags would NOT be relocatable
@@ -58,8 +64,10 @@ var pathFlags = []string{
 //      directory table in debug info (debug build)
 //  -B<path>
 //  -isystem<path> --sysroot=<path>
+//  -fmodule-file=<path> -fmodule-map-file=<path> -fmodules-cache-path=<path>
+//      module file has paths of its inputs.
 //  ...
-//  TODO: these could be normalized to cwd relative?
+//  these could be normalized to cwd relative by normalizeArgs.
 //
 // ref:
 // https://docs.google.com/spreadsheets/d/1_-ZJhqy7WhSFYuZU2QkmQ4Ed9182bWfKg09EfBAkVf8/edit#gid=759603323
@@ -90,6 +98,13 @@ Loop:
 		if strings.HasPrefix(arg, "-I") && strings.HasSuffix(arg, ".hmap") {
 			return fmt.Errorf("hmap file: %s", arg)
 		}
+		if strings.HasPrefix(arg, "-fmodule-file=") {
+			// -fmodule-file=[<name>=]<file>
+			if filepath.IsAbs(moduleFilePath(arg)) {
+				return fmt.Errorf("abs path: %s", arg)
+			}
+			continue
+		}
 		for _, fp := range pathFlags {
 			if arg != fp && strings.HasPrefix(arg, fp) {
 				if filepath.IsAbs(arg[len(fp):]) {
@@ -138,6 +153,18 @@ Loop:
 			subArgs[subCmd] = append(subArgs[subCmd], arg)
 			subCmd = ""
 
+		case strings.HasPrefix(arg, "-fdebug-prefix-map="), strings.HasPrefix(arg, "-ffile-prefix-map="):
+			// -fdebug-prefix-map=<old>=<new>
+			v := arg[strings.Index(arg, "=")+1:]
+			i := strings.Index(v, "=")
+			if i < 0 {
+				return fmt.Errorf("bad prefix map: %s", arg)
+			}
+			if filepath.IsAbs(v[i+1:]) {
+				return fmt.Errorf("abs path: %s", arg)
+			}
+			debugCompilationDir = true
+
 		case strings.HasPrefix(arg, "-g"):
 			if arg == "-g0" {
 				debugFlags = nil
@@ -357,16 +384,25 @@ func asArgRelocatable(filepath clientFilePath, args []string) error {
 }
 
 // gccOutputs returns output files from gcc command line.
-// TODO: implicit obj output (without -o, but -c).
+// If -c is given without -o, object file is generated in cwd.
+// Other outputs generated by flags (e.g. -gsplit-dwarf, --coverage)
+// are discovered by gccOutputRules.
 // TODO: -MD / -MMD without -MF case.
 func gccOutputs(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
 	var outputs []string
 	var objout string
+	var input, lang string
 	outputArg := false
-	splitDwarf := false
+	compile := false
+	moduleOutput := false
 	mfArg := false
+	xArg := false
+	var ldArgs []string
 
-	for _, arg := range args {
+	for _, arg := range args[1:] {
 		switch {
 		case arg == "-o":
 			outputArg = true
@@ -376,8 +412,21 @@ func gccOutputs(args []string) []string {
 		case strings.HasPrefix(arg, "-o"):
 			objout = arg[2:]
 
-		case arg == "-gsplit-dwarf":
-			splitDwarf = true
+		case arg == "-c":
+			compile = true
+
+		case arg == "-x":
+			xArg = true
+		case xArg:
+			lang = arg
+			xArg = false
+		case strings.HasPrefix(arg, "-x"):
+			lang = arg[2:]
+
+		case arg == "-fmodule-output":
+			moduleOutput = true
+		case strings.HasPrefix(arg, "-fmodule-output="):
+			outputs = append(outputs, strings.TrimPrefix(arg, "-fmodule-output="))
 
 		case arg == "-MF":
 			mfArg = true
@@ -387,13 +436,36 @@ func gccOutputs(args []string) []string {
 		case strings.HasPrefix(arg, "-MF"):
 			outputs = append(outputs, arg[3:])
 
+		case strings.HasPrefix(arg, "-Wl,"):
+			ldArgs = append(ldArgs, strings.Split(arg[len("-Wl,"):], ",")...)
+
+		case input == "" && !strings.HasPrefix(arg, "-") && sourceExt(arg) != "":
+			input = arg
 		}
 	}
+	if objout == "" && compile && input != "" {
+		objout = pathStem(input) + ".o"
+	}
+	// linker map file (e.g. -Wl,--Map=foo.map, -Wl,-Map,foo.map)
+	outputs = append(outputs, lldMapOutputs(ldArgs)...)
 	if objout != "" {
 		outputs = append(outputs, objout)
-		if splitDwarf {
-			outputs = append(outputs, strings.TrimSuffix(objout, ".o")+".dwo")
-		}
+	}
+	c := outputContext{
+		obj:             objout,
+		input:           input,
+		preprocessedExt: sourceExts[sourceExt(input)],
+		clang:           len(args) > 0 && strings.Contains(pathBase(args[0]), "clang"),
+	}
+	if ext, ok := langPreprocessedExts[lang]; ok {
+		c.preprocessedExt = ext
+	}
+	if c.preprocessedExt == "" {
+		c.preprocessedExt = ".i"
+	}
+	outputs = append(outputs, applyOutputRules(gccOutputRules, args[1:], c)...)
+	if objout != "" && moduleOutput {
+		outputs = append(outputs, replaceExt(objout, ".pcm"))
 	}
 	return outputs
 }
@@ -471,6 +471,30 @@ func TestGccRelocatableReq(t *testing.T) {
 				"-ffile-compilation-dir=./out/data/file-compilation-dir"),
 			relocatable: true,
 		},
+		{
+			desc: "-fdebug-prefix-map= relocatable with debug build",
+			args: append(append([]string{}, baseDebugArgs...),
+				"-fdebug-prefix-map=/b/c/b/linux/src/out/Debug=."),
+			relocatable: true,
+		},
+		{
+			desc: "-ffile-prefix-map= relocatable with debug build",
+			args: append(append([]string{}, baseDebugArgs...),
+				"-ffile-prefix-map=/b/c/b/linux/src=../.."),
+			relocatable: true,
+		},
+		{
+			desc: "-fdebug-prefix-map= to abs path unrelocatable",
+			args: append(append([]string{}, baseDebugArgs...),
+				"-fdebug-prefix-map=/b/c/b/linux/src/out/Debug=/src"),
+			relocatable: false,
+		},
+		{
+			desc: "-fdebug-prefix-map without new unrelocatable",
+			args: append(append([]string{}, baseDebugArgs...),
+				"-fdebug-prefix-map=/b/c/b/linux/src/out/Debug"),
+			relocatable: false,
+		},
 		{
 			desc: "-ffile-compilation-dir= unrelocatable with release build",
 			args: append(append([]string{}, baseReleaseArgs...),
@@ -510,6 +534,41 @@ func TestGccRelocatableReq(t *testing.T) {
 				"--rtlib=libgcc"),
 			relocatable: true,
 		},
+		{
+			desc: "clang modules relocatable",
+			args: append(append([]string{}, baseReleaseArgs...),
+				"-fmodules",
+				"-fmodules-cache-path=./module-cache",
+				"-fmodule-map-file=../../base/module.modulemap",
+				"-fmodule-file=base=obj/base/base.pcm",
+				"-fmodule-file=obj/std/std.pcm"),
+			relocatable: true,
+		},
+		{
+			desc: "-fmodules-cache-path= unrelocatable",
+			args: append(append([]string{}, baseReleaseArgs...),
+				"-fmodules",
+				"-fmodules-cache-path=/b/c/b/linux/src/out/module-cache"),
+			relocatable: false,
+		},
+		{
+			desc: "-fmodule-map-file= unrelocatable",
+			args: append(append([]string{}, baseReleaseArgs...),
+				"-fmodule-map-file=/b/c/b/linux/src/base/module.modulemap"),
+			relocatable: false,
+		},
+		{
+			desc: "-fmodule-file= unrelocatable",
+			args: append(append([]string{}, baseReleaseArgs...),
+				"-fmodule-file=/b/c/b/linux/src/out/obj/base/base.pcm"),
+			relocatable: false,
+		},
+		{
+			desc: "-fmodule-file= with name unrelocatable",
+			args: append(append([]string{}, baseReleaseArgs...),
+				"-fmodule-file=base=/b/c/b/linux/src/out/obj/base/base.pcm"),
+			relocatable: false,
+		},
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
 			err := gccRelocatableReq(posixpath.FilePath{}, tc.args, tc.envs)
@@ -666,6 +725,25 @@ func TestGccOutputs(t *testing.T) {
 			},
 			want: []string{"test.d", "A/test.o", "A/test.dwo"},
 		},
+		{
+			desc: "with module output",
+			args: []string{
+				"clang++", "-c", "A/test.cppm",
+				"-fmodules",
+				"-fmodule-output",
+				"-o", "A/test.o",
+			},
+			want: []string{"A/test.o", "A/test.pcm"},
+		},
+		{
+			desc: "with module output path",
+			args: []string{
+				"clang++", "-c", "A/test.cppm",
+				"-fmodule-output=B/test.pcm",
+				"-o", "A/test.o",
+			},
+			want: []string{"B/test.pcm", "A/test.o"},
+		},
 		{
 			desc: "prefix with dwo",
 			args: []string{
@@ -678,6 +756,26 @@ func TestGccOutputs(t *testing.T) {
 			},
 			want: []string{"test.d", "A/test.o", "A/test.dwo"},
 		},
+		{
+			desc: "link with map",
+			args: []string{
+				"clang++", "-fuse-ld=lld",
+				"-Wl,--Map=A/test.map",
+				"-o", "A/test",
+				"A/test.o",
+			},
+			want: []string{"A/test.map", "A/test"},
+		},
+		{
+			desc: "link with separate map",
+			args: []string{
+				"clang++", "-fuse-ld=lld",
+				"-Wl,-Map,A/test.map",
+				"-oA/test",
+				"A/test.o",
+			},
+			want: []string{"A/test.map", "A/test"},
+		},
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
 			if got := gccOutputs(tc.args); !reflect.DeepEqual(got, tc.want) {
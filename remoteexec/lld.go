@@ -0,0 +1,381 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"go.chromium.org/goma/server/remoteexec/digest"
+)
+
+// isLinker reports whether name is linker's selector name.
+func isLinker(name string) bool {
+	switch name {
+	case "ld.lld", "lld":
+		return true
+	}
+	return false
+}
+
+// lldValueFlags are lld flags that take value.
+// lld accepts both -flag and --flag, and value in the next arg or
+// after '='.
+// https://lld.llvm.org/ELF/
+var lldValueFlags = map[string]bool{
+	"Map":                      true,
+	"call-graph-ordering-file": true,
+	"defsym":                   true,
+	"dynamic-linker":           true,
+	"dynamic-list":             true,
+	"entry":                    true,
+	"exclude-libs":             true,
+	"export-dynamic-symbol":    true,
+	"fini":                     true,
+	"format":                   true,
+	"hash-style":               true,
+	"init":                     true,
+	"just-symbols":             true,
+	"library":                  true,
+	"library-path":             true,
+	"mllvm":                    true,
+	"output":                   true,
+	"plugin-opt":               true,
+	"retain-symbols-file":      true,
+	"rpath":                    true,
+	"script":                   true,
+	"soname":                   true,
+	"symbol-ordering-file":     true,
+	"sysroot":                  true,
+	"thinlto-cache-dir":        true,
+	"trace-symbol":             true,
+	"undefined":                true,
+	"version-script":           true,
+	"wrap":                     true,
+}
+
+// lldShortValueFlags are single letter lld flags that take value in
+// the next arg or joined (e.g. -o <file>, -o<file>).
+var lldShortValueFlags = map[string]string{
+	"L": "library-path",
+	"R": "rpath",
+	"T": "script",
+	"b": "format",
+	"e": "entry",
+	"h": "soname",
+	"l": "library",
+	"m": "emulation",
+	"o": "output",
+	"u": "undefined",
+	"y": "trace-symbol",
+	"z": "z",
+}
+
+// lldPathFlags are lld flags that take local path.
+// -rpath and -dynamic-linker are path in runtime, so not included.
+var lldPathFlags = map[string]bool{
+	"Map":                      true,
+	"call-graph-ordering-file": true,
+	"dynamic-list":             true,
+	"just-symbols":             true,
+	"library-path":             true,
+	"output":                   true,
+	"retain-symbols-file":      true,
+	"script":                   true,
+	"symbol-ordering-file":     true,
+	"sysroot":                  true,
+	"thinlto-cache-dir":        true,
+	"version-script":           true,
+}
+
+// lldFlag is a flag and its value in lld command line.
+// name is canonical name of the flag (i.e. long name without dashes),
+// and empty for input file.
+type lldFlag struct {
+	name  string
+	value string
+}
+
+// parseLldArgs parses lld command line into flags.
+// Unknown flags are treated as flags without value.
+func parseLldArgs(args []string) []lldFlag {
+	var flags []lldFlag
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			flags = append(flags, lldFlag{value: arg})
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		if kv := strings.SplitN(name, "=", 2); len(kv) == 2 {
+			flags = append(flags, lldFlag{name: kv[0], value: kv[1]})
+			continue
+		}
+		if lldValueFlags[name] {
+			if i+1 < len(args) {
+				i++
+				flags = append(flags, lldFlag{name: name, value: args[i]})
+				continue
+			}
+			flags = append(flags, lldFlag{name: name})
+			continue
+		}
+		if !strings.HasPrefix(arg, "--") && len(name) > 0 {
+			if long, ok := lldShortValueFlags[name[:1]]; ok {
+				if len(name) > 1 {
+					// -L<dir>, -l<name>, -o<file>
+					flags = append(flags, lldFlag{name: long, value: name[1:]})
+					continue
+				}
+				if i+1 < len(args) {
+					i++
+					flags = append(flags, lldFlag{name: long, value: args[i]})
+					continue
+				}
+			}
+		}
+		flags = append(flags, lldFlag{name: name})
+	}
+	return flags
+}
+
+// lldRelocatableReq checks if the request (args) uses relative paths
+// only, so will generate cwd-agnostic outputs.
+// args[0] is command name.
+//
+// The request will NOT be relocatable, if
+//  absolute path in input, -o, -L, -T, -Map, --sysroot etc.
+//  absolute path in -plugin-opt=dwo_dir=
+// -rpath or -dynamic-linker may be absolute path, since it is used
+// in runtime.
+func lldRelocatableReq(filepath clientFilePath, args, envs []string) error {
+	if len(args) == 0 {
+		return errors.New("no args")
+	}
+	if filepath.IsAbs(args[0]) {
+		return fmt.Errorf("abs path: %s", args[0])
+	}
+	return lldArgRelocatable(filepath, args[1:])
+}
+
+// lldArgRelocatable checks lld args (without command name) is relocatable.
+func lldArgRelocatable(filepath clientFilePath, args []string) error {
+	for _, f := range parseLldArgs(args) {
+		switch {
+		case f.name == "":
+			if strings.HasPrefix(f.value, "@") {
+				// response file should be expanded.
+				if filepath.IsAbs(f.value[1:]) {
+					return fmt.Errorf("abs path: %s", f.value)
+				}
+				continue
+			}
+			if filepath.IsAbs(f.value) {
+				return fmt.Errorf("abs path: %s", f.value)
+			}
+		case lldPathFlags[f.name]:
+			if filepath.IsAbs(f.value) {
+				return fmt.Errorf("abs path: --%s=%s", f.name, f.value)
+			}
+		case f.name == "plugin-opt":
+			if strings.HasPrefix(f.value, "dwo_dir=") && filepath.IsAbs(strings.TrimPrefix(f.value, "dwo_dir=")) {
+				return fmt.Errorf("abs path: --plugin-opt=%s", f.value)
+			}
+		}
+	}
+	return nil
+}
+
+// lldOutputs returns output files from lld command line.
+// args[0] is command name.
+func lldOutputs(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	output := "a.out"
+	var outputs []string
+	for _, f := range parseLldArgs(args[1:]) {
+		switch f.name {
+		case "output":
+			output = f.value
+		case "Map":
+			outputs = append(outputs, f.value)
+		}
+	}
+	return append([]string{output}, outputs...)
+}
+
+// lldMapOutputs returns map file outputs from linker args
+// (e.g. -Wl,--Map=foo.map or -Wl,-Map,foo.map).
+func lldMapOutputs(args []string) []string {
+	var outputs []string
+	for _, f := range parseLldArgs(args) {
+		if f.name == "Map" && f.value != "" {
+			outputs = append(outputs, f.value)
+		}
+	}
+	return outputs
+}
+
+// lldOutputDirs returns output directories from lld command line.
+// split dwarf *.dwo files in ThinLTO are generated in dwo_dir.
+func lldOutputDirs(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	var dirs []string
+	for _, f := range parseLldArgs(args[1:]) {
+		if f.name == "plugin-opt" && strings.HasPrefix(f.value, "dwo_dir=") {
+			dirs = append(dirs, strings.TrimPrefix(f.value, "dwo_dir="))
+		}
+	}
+	return dirs
+}
+
+const (
+	arMagic     = "!<arch>\n"
+	thinArMagic = "!<thin>\n"
+	arHeaderLen = 60
+)
+
+// thinArchiveMembers returns member names of thin archive.
+// member names are relative to the directory of the archive.
+// It returns nil if data is not thin archive.
+// https://sourceware.org/binutils/docs/binutils/ar.html
+func thinArchiveMembers(data []byte) ([]string, error) {
+	if !bytes.HasPrefix(data, []byte(thinArMagic)) {
+		return nil, nil
+	}
+	data = data[len(thinArMagic):]
+	var members []string
+	var longNames []byte
+	for len(data) > 0 {
+		if len(data) < arHeaderLen {
+			return nil, fmt.Errorf("short ar header: %d", len(data))
+		}
+		hdr := data[:arHeaderLen]
+		data = data[arHeaderLen:]
+		if string(hdr[58:60]) != "`\n" {
+			return nil, fmt.Errorf("bad ar header: %q", hdr)
+		}
+		name := strings.TrimRight(string(hdr[:16]), " ")
+		size, err := strconv.ParseInt(strings.TrimSpace(string(hdr[48:58])), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad ar member size %q: %v", hdr[48:58], err)
+		}
+		switch {
+		case name == "/" || name == "/SYM64/":
+			// symbol table is stored in thin archive.
+		case name == "//":
+			// long name table is stored in thin archive.
+			if int64(len(data)) < size {
+				return nil, fmt.Errorf("short long name table: %d < %d", len(data), size)
+			}
+			longNames = data[:size]
+		case strings.HasPrefix(name, "/"):
+			off, err := strconv.Atoi(name[1:])
+			if err != nil || off < 0 || off >= len(longNames) {
+				return nil, fmt.Errorf("bad long name %q", name)
+			}
+			n := longNames[off:]
+			i := bytes.Index(n, []byte("/\n"))
+			if i < 0 {
+				return nil, fmt.Errorf("bad long name entry %q", name)
+			}
+			members = append(members, string(n[:i]))
+			// member content is not stored in thin archive.
+			continue
+		default:
+			members = append(members, strings.TrimSuffix(name, "/"))
+			continue
+		}
+		if size%2 == 1 {
+			size++
+		}
+		if int64(len(data)) < size {
+			size = int64(len(data))
+		}
+		data = data[size:]
+	}
+	return members, nil
+}
+
+// missingThinArchiveMembers returns members of thin archives in args
+// that are not in the request inputs.
+// thin archive has member's path only, so members need to be sent
+// from client, but client doesn't know them unless it reads the
+// archive.
+// args[0] is command name.
+func missingThinArchiveMembers(ctx context.Context, files requestFiles, args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	filepath := files.filepath
+	seen := make(map[string]bool)
+	var missing []string
+	for _, f := range parseLldArgs(args[1:]) {
+		if f.name != "" || !strings.HasSuffix(f.value, ".a") {
+			continue
+		}
+		data, ok := files.lookup(f.value)
+		if !ok {
+			// may be found in library path.
+			continue
+		}
+		thin, err := isThinArchive(ctx, data)
+		if err != nil {
+			return nil, fmt.Errorf("archive %s: %v", f.value, err)
+		}
+		if !thin {
+			continue
+		}
+		b, err := files.readFile(ctx, f.value)
+		if err != nil {
+			return nil, fmt.Errorf("archive %s: %v", f.value, err)
+		}
+		members, err := thinArchiveMembers(b)
+		if err != nil {
+			return nil, fmt.Errorf("archive %s: %v", f.value, err)
+		}
+		dir := filepath.Dir(f.value)
+		for _, m := range members {
+			if !filepath.IsAbs(m) {
+				m = filepath.Clean(filepath.Join(dir, m))
+			}
+			p := files.absPath(m)
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			if _, ok := files.lookup(p); !ok {
+				missing = append(missing, m)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// isThinArchive reports whether data is thin archive.
+func isThinArchive(ctx context.Context, data digest.Data) (bool, error) {
+	if data.Digest().GetSizeBytes() < int64(len(thinArMagic)) {
+		return false, nil
+	}
+	rd, err := data.Open(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer rd.Close()
+	magic := make([]byte, len(thinArMagic))
+	_, err = io.ReadFull(rd, magic)
+	if err != nil {
+		return false, err
+	}
+	return string(magic) == thinArMagic, nil
+}
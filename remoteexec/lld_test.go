@@ -0,0 +1,195 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.chromium.org/goma/server/command/descriptor/posixpath"
+	"go.chromium.org/goma/server/remoteexec/digest"
+)
+
+func TestLldRelocatableReq(t *testing.T) {
+	baseArgs := []string{
+		"../../third_party/llvm-build/Release+Asserts/bin/ld.lld",
+		"--build-id",
+		"-z", "relro",
+		"--hash-style=gnu",
+		"-o", "./chrome",
+		"-L../../build/linux/debian_sid_amd64-sysroot/usr/lib",
+		"--sysroot=../../build/linux/debian_sid_amd64-sysroot",
+		"-rpath=/opt/chrome",
+		"-dynamic-linker", "/lib64/ld-linux-x86-64.so.2",
+		"--version-script=../../build/linux/chrome.map",
+		"--start-group",
+		"@chrome.rsp",
+		"obj/base/libbase.a",
+		"--end-group",
+		"-lpthread",
+	}
+	for _, tc := range []struct {
+		desc    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			desc: "basic",
+			args: baseArgs,
+		},
+		{
+			desc:    "abs input",
+			args:    append(append([]string{}, baseArgs...), "/home/user/src/out/Release/obj/foo.o"),
+			wantErr: true,
+		},
+		{
+			desc:    "abs output",
+			args:    append(append([]string{}, baseArgs...), "-o/home/user/src/out/Release/chrome"),
+			wantErr: true,
+		},
+		{
+			desc:    "abs library path",
+			args:    append(append([]string{}, baseArgs...), "-L", "/usr/lib"),
+			wantErr: true,
+		},
+		{
+			desc:    "abs map",
+			args:    append(append([]string{}, baseArgs...), "--Map=/tmp/chrome.map"),
+			wantErr: true,
+		},
+		{
+			desc:    "abs dwo_dir",
+			args:    append(append([]string{}, baseArgs...), "-plugin-opt=dwo_dir=/tmp/chrome.dwo"),
+			wantErr: true,
+		},
+		{
+			desc: "relative dwo_dir",
+			args: append(append([]string{}, baseArgs...), "--plugin-opt=dwo_dir=chrome.dwo", "--thinlto-cache-dir=thinlto-cache"),
+		},
+		{
+			desc:    "abs rsp",
+			args:    append(append([]string{}, baseArgs...), "@/tmp/chrome.rsp"),
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := lldRelocatableReq(posixpath.FilePath{}, tc.args, nil)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("lldRelocatableReq(%q)=%v; wantErr=%t", tc.args, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestLldOutputs(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		args []string
+		want []string
+		dirs []string
+	}{
+		{
+			desc: "default",
+			args: []string{"ld.lld", "foo.o"},
+			want: []string{"a.out"},
+		},
+		{
+			desc: "output and map",
+			args: []string{"ld.lld", "-o", "chrome", "-Map", "chrome.map", "foo.o"},
+			want: []string{"chrome", "chrome.map"},
+		},
+		{
+			desc: "joined",
+			args: []string{"ld.lld", "-ochrome", "--Map=chrome.map", "foo.o"},
+			want: []string{"chrome", "chrome.map"},
+		},
+		{
+			desc: "long output",
+			args: []string{"ld.lld", "--output=chrome", "foo.o", "--plugin-opt=dwo_dir=chrome.dwo"},
+			want: []string{"chrome"},
+			dirs: []string{"chrome.dwo"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := lldOutputs(tc.args); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("lldOutputs(%q)=%q; want %q", tc.args, got, tc.want)
+			}
+			if got := lldOutputDirs(tc.args); !reflect.DeepEqual(got, tc.dirs) {
+				t.Errorf("lldOutputDirs(%q)=%q; want %q", tc.args, got, tc.dirs)
+			}
+		})
+	}
+}
+
+// thinArchive returns thin archive data of members.
+func thinArchive(members ...string) []byte {
+	var sb strings.Builder
+	sb.WriteString(thinArMagic)
+	var longNames strings.Builder
+	var headers []string
+	for _, m := range members {
+		if len(m) < 16 {
+			headers = append(headers, fmt.Sprintf("%-16s%-32s%-10d`\n", m+"/", "0", 100))
+			continue
+		}
+		headers = append(headers, fmt.Sprintf("%-16s%-32s%-10d`\n", fmt.Sprintf("/%d", longNames.Len()), "0", 100))
+		longNames.WriteString(m + "/\n")
+	}
+	// symbol table.
+	sb.WriteString(fmt.Sprintf("%-16s%-32s%-10d`\n", "/", "0", 4))
+	sb.WriteString("\x00\x00\x00\x00")
+	if longNames.Len() > 0 {
+		sb.WriteString(fmt.Sprintf("%-16s%-32s%-10d`\n", "//", "0", longNames.Len()))
+		sb.WriteString(longNames.String())
+		if longNames.Len()%2 == 1 {
+			sb.WriteString("\n")
+		}
+	}
+	for _, h := range headers {
+		sb.WriteString(h)
+	}
+	return []byte(sb.String())
+}
+
+func TestThinArchiveMembers(t *testing.T) {
+	members := []string{"foo.o", "../base/very_long_file_name.o"}
+	got, err := thinArchiveMembers(thinArchive(members...))
+	if err != nil || !reflect.DeepEqual(got, members) {
+		t.Errorf("thinArchiveMembers(%q)=%q, %v; want %q, nil", members, got, err, members)
+	}
+
+	got, err = thinArchiveMembers([]byte(arMagic))
+	if err != nil || got != nil {
+		t.Errorf("thinArchiveMembers(normal archive)=%q, %v; want nil, nil", got, err)
+	}
+
+	_, err = thinArchiveMembers([]byte(thinArMagic + "broken"))
+	if err == nil {
+		t.Errorf("thinArchiveMembers(broken)=_, nil; want error")
+	}
+}
+
+func TestThinArchiveInputs(t *testing.T) {
+	ctx := context.Background()
+	files := requestFiles{
+		filepath: posixpath.FilePath{},
+		cwd:      "/b/c/out/Release",
+		data: map[string]digest.Data{
+			"/b/c/out/Release/obj/main.o":         digest.Bytes("main.o", []byte("main")),
+			"/b/c/out/Release/obj/base/libbase.a": digest.Bytes("libbase.a", thinArchive("foo.o", "bar.o", "../util/util.o")),
+			"/b/c/out/Release/obj/base/foo.o":     digest.Bytes("foo.o", []byte("foo")),
+			"/b/c/out/Release/obj/libnormal.a":    digest.Bytes("libnormal.a", []byte(arMagic)),
+		},
+	}
+	args := []string{"ld.lld", "-o", "chrome", "obj/main.o", "obj/base/libbase.a", "obj/libnormal.a", "obj/libnotfound.a"}
+	got, err := missingThinArchiveMembers(ctx, files, args)
+	want := []string{"obj/base/bar.o", "obj/util/util.o"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("missingThinArchiveMembers(%q)=%q, %v; want %q, nil", args, got, err, want)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"strings"
+
+	gomapb "go.chromium.org/goma/server/proto/api"
+)
+
+// moduleFilePath returns file path of -fmodule-file=[<name>=]<file>.
+func moduleFilePath(arg string) string {
+	v := strings.TrimPrefix(arg, "-fmodule-file=")
+	if i := strings.Index(v, "="); i >= 0 {
+		return v[i+1:]
+	}
+	return v
+}
+
+// gccModuleInputs returns explicit module inputs (module map files and
+// precompiled module files) from gcc/clang command line.
+// https://clang.llvm.org/docs/Modules.html#command-line-parameters
+// Swift modules are found in import search paths (-I), so swiftc
+// relies on inputs sent by client. See swiftc.go.
+func gccModuleInputs(args []string) []string {
+	var inputs []string
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "-fmodule-map-file="):
+			inputs = append(inputs, strings.TrimPrefix(arg, "-fmodule-map-file="))
+		case strings.HasPrefix(arg, "-fmodule-file="):
+			inputs = append(inputs, moduleFilePath(arg))
+		}
+	}
+	return inputs
+}
+
+// gccModuleCacheDirs returns module cache directory of implicit modules
+// build from gcc/clang command line.
+// clang writes implicitly built modules (*.pcm) in the module cache,
+// so it should be output directory.
+func gccModuleCacheDirs(args []string) []string {
+	modules := false
+	implicitModules := true
+	var cachePath string
+	for _, arg := range args {
+		switch {
+		case arg == "-fmodules", arg == "-fcxx-modules":
+			modules = true
+		case arg == "-fno-modules":
+			modules = false
+		case arg == "-fimplicit-modules":
+			implicitModules = true
+		case arg == "-fno-implicit-modules":
+			implicitModules = false
+		case strings.HasPrefix(arg, "-fmodules-cache-path="):
+			cachePath = strings.TrimPrefix(arg, "-fmodules-cache-path=")
+		}
+	}
+	if !modules || !implicitModules || cachePath == "" {
+		return nil
+	}
+	return []string{cachePath}
+}
+
+// missingModuleInputs returns explicit module inputs in args that are
+// not included in inputs of the request.
+// module map files and module files are not discovered by include
+// processor, so client may not send them. Such request can't be
+// executed remotely.
+func missingModuleInputs(filepath clientFilePath, cwd string, args []string, inputs []*gomapb.ExecReq_Input) []string {
+	return missingFiles(filepath, cwd, gccModuleInputs(args), inputs)
+}
+
+// missingFiles returns files in fnames that are not included in
+// inputs of the request.
+func missingFiles(filepath clientFilePath, cwd string, fnames []string, inputs []*gomapb.ExecReq_Input) []string {
+	if len(fnames) == 0 {
+		return nil
+	}
+	absPath := func(fname string) string {
+		if !filepath.IsAbs(fname) {
+			fname = filepath.Join(cwd, fname)
+		}
+		return filepath.Clean(fname)
+	}
+	seen := make(map[string]bool)
+	for _, input := range inputs {
+		seen[absPath(input.GetFilename())] = true
+	}
+	var missing []string
+	for _, fname := range fnames {
+		p := absPath(fname)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		missing = append(missing, fname)
+	}
+	return missing
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/command/descriptor/posixpath"
+	gomapb "go.chromium.org/goma/server/proto/api"
+)
+
+func TestGccModuleCacheDirs(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		args []string
+		want []string
+	}{
+		{
+			desc: "no modules",
+			args: []string{"clang", "-c", "foo.m", "-fmodules-cache-path=module-cache"},
+		},
+		{
+			desc: "implicit modules",
+			args: []string{"clang", "-c", "foo.m", "-fmodules", "-fmodules-cache-path=module-cache"},
+			want: []string{"module-cache"},
+		},
+		{
+			desc: "explicit modules",
+			args: []string{"clang", "-c", "foo.m", "-fmodules", "-fno-implicit-modules", "-fmodules-cache-path=module-cache"},
+		},
+		{
+			desc: "no cache path",
+			args: []string{"clang", "-c", "foo.m", "-fmodules"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := gccModuleCacheDirs(tc.args)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("gccModuleCacheDirs(%q)=%q; want %q", tc.args, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMissingModuleInputs(t *testing.T) {
+	args := []string{
+		"clang", "-c", "../../foo/foo.m",
+		"-fmodules",
+		"-fno-implicit-modules",
+		"-fmodule-map-file=../../foo/module.modulemap",
+		"-fmodule-map-file=../../bar/module.modulemap",
+		"-fmodule-file=foo=obj/foo/foo.pcm",
+		"-fmodule-file=/home/user/src/out/Release/obj/bar/bar.pcm",
+	}
+	inputs := []*gomapb.ExecReq_Input{
+		{
+			Filename: proto.String("../../foo/foo.m"),
+		},
+		{
+			Filename: proto.String("/home/user/src/foo/module.modulemap"),
+		},
+		{
+			Filename: proto.String("obj/bar/bar.pcm"),
+		},
+	}
+	got := missingModuleInputs(posixpath.FilePath{}, "/home/user/src/out/Release", args, inputs)
+	want := []string{
+		"../../bar/module.modulemap",
+		"obj/foo/foo.pcm",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("missingModuleInputs(%q, inputs)=%q; want %q", args, got, want)
+	}
+}
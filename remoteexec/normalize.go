@@ -0,0 +1,250 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"context"
+	"strings"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+
+	"go.chromium.org/goma/server/log"
+	cmdpb "go.chromium.org/goma/server/proto/command"
+)
+
+// gccSeparatePathFlags are gcc flags that take path in next arg.
+var gccSeparatePathFlags = []string{
+	"-o",
+	"-I",
+	"-B",
+	"-F",
+	"-MF",
+	"-isystem",
+	"-include",
+	"-isysroot",
+	"-idirafter",
+	"--sysroot",
+}
+
+// clangclSeparatePathFlags are clang-cl flags that take path in next arg.
+var clangclSeparatePathFlags = []string{
+	"-o",
+	"-I",
+	"-B",
+	"-MF",
+	"-isystem",
+	"-include",
+	"-isysroot",
+}
+
+// pathNormalizer rewrites absolute paths in args into cwd relative paths.
+type pathNormalizer struct {
+	filepath clientFilePath
+	// cwd and rootDir should be clean path, and cwd is under rootDir.
+	cwd     string
+	rootDir string
+
+	// joinedFlags are flags that take path in the same arg, longest first.
+	joinedFlags []string
+	// separateFlags are flags that take path in the next arg.
+	separateFlags []string
+	// flagPrefixes are prefixes of flags. other args are input files.
+	flagPrefixes []string
+}
+
+// rel returns cwd relative path of p, and reports whether it is rewritten.
+// It doesn't rewrite relative path, nor absolute path out of rootDir.
+func (n pathNormalizer) rel(p string) (string, bool) {
+	if !n.filepath.IsAbs(p) {
+		return p, false
+	}
+	cp := n.filepath.Clean(p)
+	if _, err := rootRel(n.filepath, cp, n.cwd, n.rootDir); err != nil {
+		return p, false
+	}
+	r, err := n.filepath.Rel(n.cwd, cp)
+	if err != nil {
+		return p, false
+	}
+	return r, true
+}
+
+func (n pathNormalizer) isFlag(arg string) bool {
+	for _, p := range n.flagPrefixes {
+		if strings.HasPrefix(arg, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize returns args with absolute paths under rootDir rewritten
+// into cwd relative, and reports whether any arg is rewritten.
+// args[0] is command name, and is rewritten as input file.
+// args of subcommands (e.g. -Xclang, -mllvm) are not rewritten.
+func (n pathNormalizer) normalize(args []string) ([]string, bool) {
+	nargs := make([]string, 0, len(args))
+	changed := false
+	pathFlag := false
+	subCmdFlag := false
+Loop:
+	for _, arg := range args {
+		switch {
+		case pathFlag:
+			pathFlag = false
+			if r, ok := n.rel(arg); ok {
+				nargs = append(nargs, r)
+				changed = true
+				continue Loop
+			}
+			nargs = append(nargs, arg)
+			continue Loop
+		case subCmdFlag:
+			subCmdFlag = false
+			nargs = append(nargs, arg)
+			continue Loop
+		case arg == "-Xclang", arg == "-mllvm":
+			subCmdFlag = true
+			nargs = append(nargs, arg)
+			continue Loop
+		}
+		for _, f := range n.separateFlags {
+			if arg == f {
+				pathFlag = true
+				nargs = append(nargs, arg)
+				continue Loop
+			}
+		}
+		for _, f := range n.joinedFlags {
+			if arg != f && strings.HasPrefix(arg, f) {
+				if r, ok := n.rel(arg[len(f):]); ok {
+					nargs = append(nargs, f+r)
+					changed = true
+					continue Loop
+				}
+				nargs = append(nargs, arg)
+				continue Loop
+			}
+		}
+		if n.isFlag(arg) {
+			nargs = append(nargs, arg)
+			continue
+		}
+		// input file.
+		if r, ok := n.rel(arg); ok {
+			nargs = append(nargs, r)
+			changed = true
+			continue
+		}
+		nargs = append(nargs, arg)
+	}
+	return nargs, changed
+}
+
+// needsDebugPrefixMap reports whether gcc/clang args is debug build
+// without -fdebug-compilation-dir nor prefix map.
+func needsDebugPrefixMap(args []string) bool {
+	debug := false
+	for _, arg := range args {
+		switch {
+		case arg == "-g0":
+			debug = false
+		case strings.HasPrefix(arg, "-g"):
+			debug = true
+		case arg == "-fdebug-compilation-dir",
+			strings.HasPrefix(arg, "-fdebug-compilation-dir="),
+			strings.HasPrefix(arg, "-ffile-compilation-dir="),
+			strings.HasPrefix(arg, "-fdebug-prefix-map="),
+			strings.HasPrefix(arg, "-ffile-prefix-map="):
+			return false
+		}
+	}
+	return debug
+}
+
+// normalizeArgs rewrites absolute paths under rootDir in args into
+// cwd relative paths, respecting cmdConfig.
+// For gcc/clang debug build, it also adds -fdebug-prefix-map=<cwd>=.
+// so DW_AT_comp_dir will not have cwd, and gccRelocatableReq accepts
+// the debug build.  -fdebug-prefix-map is used rather than
+// -ffile-prefix-map, since the latter is not supported by older
+// compilers (gcc < 8, clang < 10), and paths other than
+// DW_AT_comp_dir are already rewritten into cwd relative.
+// It reports whether args is rewritten.
+func normalizeArgs(cmdConfig *cmdpb.Config, filepath clientFilePath, cwd, rootDir string, args []string) ([]string, bool) {
+	name := cmdConfig.GetCmdDescriptor().GetSelector().GetName()
+	n := pathNormalizer{
+		filepath: filepath,
+		cwd:      cwd,
+		rootDir:  rootDir,
+	}
+	switch name {
+	case "gcc", "g++", "clang", "clang++":
+		n.joinedFlags = pathFlags
+		n.separateFlags = gccSeparatePathFlags
+		n.flagPrefixes = []string{"-"}
+	case "clang-cl":
+		n.joinedFlags = clangClPathFlags
+		n.separateFlags = clangclSeparatePathFlags
+		n.flagPrefixes = []string{"-", "/"}
+	default:
+		return args, false
+	}
+	nargs, changed := n.normalize(args)
+	if !changed {
+		return args, false
+	}
+	switch name {
+	case "gcc", "g++", "clang", "clang++":
+		// prefix map is split at first '='.
+		if needsDebugPrefixMap(nargs) && !strings.Contains(cwd, "=") {
+			nargs = append(nargs, "-fdebug-prefix-map="+cwd+"=.")
+		}
+	}
+	return nargs, true
+}
+
+// normalizedArgs checks the request is relocatable.
+// If the request is not relocatable and path normalization is enabled,
+// it tries to rewrite absolute paths in args into cwd relative paths,
+// and returns rewritten args if it makes the request relocatable.
+// It returns nil args if args of the request is used as is, and
+// returns error if the request is not relocatable.
+func (r *request) normalizedArgs(ctx context.Context, cmdConfig *cmdpb.Config, cwd, rootDir string) ([]string, error) {
+	err := relocatableReq(ctx, cmdConfig, r.filepath, r.analysisArgs(), r.gomaReq.Env)
+	if err == nil || !r.f.NormalizePaths || r.expandedArgs != nil {
+		// args in response files can't be normalized.
+		return nil, err
+	}
+	if cmdConfig.GetCmdDescriptor().GetCross().GetWindowsCross() {
+		// buildArgs converts windows paths to posix paths.
+		return nil, err
+	}
+	nargs, changed := normalizeArgs(cmdConfig, r.filepath, cwd, rootDir, r.gomaReq.Arg)
+	if !changed {
+		return nil, err
+	}
+	logger := log.FromContext(ctx)
+	nerr := relocatableReq(ctx, cmdConfig, r.filepath, nargs, r.gomaReq.Env)
+	result := "relocatable"
+	if nerr != nil {
+		result = "non-relocatable"
+	}
+	name := cmdConfig.GetCmdDescriptor().GetSelector().GetName()
+	serr := stats.RecordWithTags(ctx, []tag.Mutator{
+		tag.Upsert(compilerNameKey, name),
+		tag.Upsert(pathNormalizeResultKey, result),
+	}, pathNormalizeCount.M(1))
+	if serr != nil {
+		logger.Errorf("record path-normalize %s: %v", name, serr)
+	}
+	if nerr != nil {
+		logger.Infof("non relocatable after path normalization: %v", nerr)
+		return nil, err
+	}
+	logger.Infof("relocatable by path normalization: %v", err)
+	return nargs, nil
+}
@@ -0,0 +1,267 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.chromium.org/goma/server/command/descriptor/posixpath"
+	"go.chromium.org/goma/server/command/descriptor/winpath"
+	cmdpb "go.chromium.org/goma/server/proto/command"
+)
+
+func cmdConfigForName(name string) *cmdpb.Config {
+	return &cmdpb.Config{
+		CmdDescriptor: &cmdpb.CmdDescriptor{
+			Selector: &cmdpb.Selector{
+				Name: name,
+			},
+		},
+	}
+}
+
+func TestNormalizeArgs(t *testing.T) {
+	for _, tc := range []struct {
+		desc        string
+		name        string
+		filepath    clientFilePath
+		cwd         string
+		rootDir     string
+		args        []string
+		want        []string
+		wantChanged bool
+	}{
+		{
+			desc:     "relative",
+			name:     "clang++",
+			filepath: posixpath.FilePath{},
+			cwd:      "/home/user/src/out/Release",
+			rootDir:  "/home/user/src",
+			args: []string{
+				"../../third_party/llvm-build/Release+Asserts/bin/clang++",
+				"-I../..",
+				"-c", "../../base/foo.cc",
+				"-o", "obj/base/foo.o",
+			},
+			want: []string{
+				"../../third_party/llvm-build/Release+Asserts/bin/clang++",
+				"-I../..",
+				"-c", "../../base/foo.cc",
+				"-o", "obj/base/foo.o",
+			},
+		},
+		{
+			desc:     "abs paths under root",
+			name:     "gcc",
+			filepath: posixpath.FilePath{},
+			cwd:      "/home/user/src/out/Release",
+			rootDir:  "/home/user/src",
+			args: []string{
+				"/home/user/src/third_party/gcc/bin/gcc",
+				"-I/home/user/src",
+				"-isystem", "/home/user/src/third_party/include",
+				"--sysroot=/home/user/src/build/sysroot",
+				"-MF", "/home/user/src/out/Release/obj/base/foo.o.d",
+				"-g",
+				"-c", "/home/user/src/base/foo.c",
+				"-o", "/home/user/src/out/Release/obj/base/foo.o",
+			},
+			want: []string{
+				"../../third_party/gcc/bin/gcc",
+				"-I../..",
+				"-isystem", "../../third_party/include",
+				"--sysroot=../../build/sysroot",
+				"-MF", "obj/base/foo.o.d",
+				"-g",
+				"-c", "../../base/foo.c",
+				"-o", "obj/base/foo.o",
+				"-fdebug-prefix-map=/home/user/src/out/Release=.",
+			},
+			wantChanged: true,
+		},
+		{
+			desc:     "abs paths out of root",
+			name:     "clang",
+			filepath: posixpath.FilePath{},
+			cwd:      "/home/user/src/out/Release",
+			rootDir:  "/home/user/src",
+			args: []string{
+				"clang",
+				"-I/usr/include",
+				"-Xclang", "-load",
+				"-Xclang", "/home/user/src/plugin.so",
+				"-c", "../../base/foo.c",
+			},
+			want: []string{
+				"clang",
+				"-I/usr/include",
+				"-Xclang", "-load",
+				"-Xclang", "/home/user/src/plugin.so",
+				"-c", "../../base/foo.c",
+			},
+		},
+		{
+			desc:     "clang debug build",
+			name:     "clang",
+			filepath: posixpath.FilePath{},
+			cwd:      "/home/user/src/out/Debug",
+			rootDir:  "/home/user/src",
+			args: []string{
+				"clang",
+				"-g2",
+				"-I/home/user/src",
+				"-c", "../../base/foo.c",
+			},
+			want: []string{
+				"clang",
+				"-g2",
+				"-I../..",
+				"-c", "../../base/foo.c",
+				"-fdebug-prefix-map=/home/user/src/out/Debug=.",
+			},
+			wantChanged: true,
+		},
+		{
+			desc:     "gcc debug build",
+			name:     "gcc",
+			filepath: posixpath.FilePath{},
+			cwd:      "/home/user/src/out/Debug",
+			rootDir:  "/home/user/src",
+			args: []string{
+				"gcc",
+				"-g",
+				"-I/home/user/src",
+				"-c", "../../base/foo.c",
+			},
+			want: []string{
+				"gcc",
+				"-g",
+				"-I../..",
+				"-c", "../../base/foo.c",
+				"-fdebug-prefix-map=/home/user/src/out/Debug=.",
+			},
+			wantChanged: true,
+		},
+		{
+			desc:     "gcc debug build with -ffile-prefix-map",
+			name:     "gcc",
+			filepath: posixpath.FilePath{},
+			cwd:      "/home/user/src/out/Debug",
+			rootDir:  "/home/user/src",
+			args: []string{
+				"gcc",
+				"-g",
+				"-ffile-prefix-map=/home/user/src=../..",
+				"-I/home/user/src",
+				"-c", "../../base/foo.c",
+			},
+			want: []string{
+				"gcc",
+				"-g",
+				"-ffile-prefix-map=/home/user/src=../..",
+				"-I../..",
+				"-c", "../../base/foo.c",
+			},
+			wantChanged: true,
+		},
+		{
+			desc:     "clang debug build with -fdebug-compilation-dir",
+			name:     "clang",
+			filepath: posixpath.FilePath{},
+			cwd:      "/home/user/src/out/Debug",
+			rootDir:  "/home/user/src",
+			args: []string{
+				"clang",
+				"-g2",
+				"-fdebug-compilation-dir=/home/user/src/out/Debug",
+				"-I/home/user/src",
+				"-c", "../../base/foo.c",
+			},
+			want: []string{
+				"clang",
+				"-g2",
+				"-fdebug-compilation-dir=.",
+				"-I../..",
+				"-c", "../../base/foo.c",
+			},
+			wantChanged: true,
+		},
+		{
+			desc:     "clang-cl",
+			name:     "clang-cl",
+			filepath: winpath.FilePath{},
+			cwd:      `C:\src\chromium\src\out\Release`,
+			rootDir:  `C:\src\chromium\src`,
+			args: []string{
+				`..\..\third_party\llvm-build\Release+Asserts\bin\clang-cl.exe`,
+				`/nologo`,
+				`-IC:\src\chromium\src\base`,
+				`/FoC:\src\chromium\src\out\Release\obj\base\foo.obj`,
+				`/c`,
+				`C:\src\chromium\src\base\foo.cc`,
+			},
+			want: []string{
+				`..\..\third_party\llvm-build\Release+Asserts\bin\clang-cl.exe`,
+				`/nologo`,
+				`-I..\..\base`,
+				`/Foobj\base\foo.obj`,
+				`/c`,
+				`..\..\base\foo.cc`,
+			},
+			wantChanged: true,
+		},
+		{
+			desc:     "unsupported",
+			name:     "javac",
+			filepath: posixpath.FilePath{},
+			cwd:      "/home/user/src/out/Release",
+			rootDir:  "/home/user/src",
+			args: []string{
+				"javac",
+				"/home/user/src/Foo.java",
+			},
+			want: []string{
+				"javac",
+				"/home/user/src/Foo.java",
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, changed := normalizeArgs(cmdConfigForName(tc.name), tc.filepath, tc.cwd, tc.rootDir, tc.args)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("normalizeArgs(%q) diff -want +got:\n%s", tc.args, diff)
+			}
+			if changed != tc.wantChanged {
+				t.Errorf("normalizeArgs(%q) changed=%t; want=%t", tc.args, changed, tc.wantChanged)
+			}
+		})
+	}
+}
+
+func TestNormalizeArgsRelocatable(t *testing.T) {
+	args := []string{
+		"/home/user/src/third_party/llvm-build/Release+Asserts/bin/clang++",
+		"-MMD",
+		"-MF", "obj/base/foo.o.d",
+		"-I/home/user/src",
+		"-isystem/home/user/src/third_party/include",
+		"-g2",
+		"-c", "/home/user/src/base/foo.cc",
+		"-o", "/home/user/src/out/Debug/obj/base/foo.o",
+	}
+	filepath := posixpath.FilePath{}
+	if err := gccRelocatableReq(filepath, args, nil); err == nil {
+		t.Fatalf("gccRelocatableReq(%q)=nil; want error", args)
+	}
+	nargs, changed := normalizeArgs(cmdConfigForName("clang++"), filepath, "/home/user/src/out/Debug", "/home/user/src", args)
+	if !changed {
+		t.Fatalf("normalizeArgs(%q) changed=false; want true", args)
+	}
+	if err := gccRelocatableReq(filepath, nargs, nil); err != nil {
+		t.Errorf("gccRelocatableReq(%q)=%v; want nil", nargs, err)
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"strings"
+)
+
+// outputRuleValue is how a flag in outputRule takes its value.
+type outputRuleValue int
+
+const (
+	noValue       outputRuleValue = iota // -flag
+	joinedValue                          // -flag<value>
+	separateValue                        // -flag <value>
+)
+
+// outputRule is a rule to discover output files generated by a flag.
+type outputRule struct {
+	// flags are names of the flag.
+	flags []string
+	value outputRuleValue
+
+	// group is a name of flags that override each other
+	// (e.g. -gsplit-dwarf and -gno-split-dwarf).
+	// The last matched rule in the group is applied.
+	group string
+
+	// outputs returns output files generated by the flag with value.
+	outputs func(c outputContext, value string) []string
+}
+
+// outputContext is a context of compile to discover output files.
+type outputContext struct {
+	// obj is object file output. empty if no object file is generated.
+	obj string
+	// input is primary input file.
+	input string
+	// preprocessedExt is filename extension of preprocessed output of
+	// input (e.g. ".i", ".ii").
+	preprocessedExt string
+	// clang is true if compiler is clang.
+	clang bool
+}
+
+// objSibling returns obj filename with ext replaced.
+func (c outputContext) objSibling(ext string) []string {
+	if c.obj == "" {
+		return nil
+	}
+	return []string{replaceExt(c.obj, ext)}
+}
+
+// saveTemps returns intermediate files of -save-temps in dir with stem.
+func (c outputContext) saveTemps(dir, stem string) []string {
+	outputs := []string{dir + stem + c.preprocessedExt}
+	if c.clang {
+		outputs = append(outputs, dir+stem+".bc")
+	}
+	return append(outputs, dir+stem+".s")
+}
+
+// applyOutputRules returns output files in args discovered by rules.
+func applyOutputRules(rules []outputRule, args []string, c outputContext) []string {
+	type match struct {
+		rule  *outputRule
+		value string
+	}
+	matched := make(map[string]match)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+	Rules:
+		for j := range rules {
+			r := &rules[j]
+			for _, f := range r.flags {
+				switch r.value {
+				case noValue:
+					if arg != f {
+						continue
+					}
+					matched[r.group] = match{rule: r}
+				case joinedValue:
+					if !strings.HasPrefix(arg, f) {
+						continue
+					}
+					matched[r.group] = match{rule: r, value: arg[len(f):]}
+				case separateValue:
+					if arg != f || i+1 >= len(args) {
+						continue
+					}
+					i++
+					matched[r.group] = match{rule: r, value: args[i]}
+				}
+				break Rules
+			}
+		}
+	}
+	var outputs []string
+	seen := make(map[string]bool)
+	for _, r := range rules {
+		if seen[r.group] {
+			continue
+		}
+		seen[r.group] = true
+		m, ok := matched[r.group]
+		if !ok {
+			continue
+		}
+		outputs = append(outputs, m.rule.outputs(c, m.value)...)
+	}
+	return outputs
+}
+
+// gccOutputRules are rules to discover output files other than
+// -o, -MF etc in gcc/clang command line.
+var gccOutputRules = []outputRule{
+	// split dwarf: <obj>.dwo
+	{
+		flags: []string{"-gsplit-dwarf"},
+		group: "split-dwarf",
+		outputs: func(c outputContext, _ string) []string {
+			return c.objSibling(".dwo")
+		},
+	},
+	{
+		// -gsplit-dwarf=split or -gsplit-dwarf=single
+		flags: []string{"-gsplit-dwarf="},
+		value: joinedValue,
+		group: "split-dwarf",
+		outputs: func(c outputContext, v string) []string {
+			if v == "single" {
+				// debug info is in object file.
+				return nil
+			}
+			return c.objSibling(".dwo")
+		},
+	},
+	{
+		flags:   []string{"-gno-split-dwarf"},
+		group:   "split-dwarf",
+		outputs: func(outputContext, string) []string { return nil },
+	},
+
+	// coverage notes: <obj>.gcno
+	{
+		flags: []string{"--coverage", "-coverage", "-ftest-coverage"},
+		group: "coverage",
+		outputs: func(c outputContext, _ string) []string {
+			return c.objSibling(".gcno")
+		},
+	},
+	{
+		flags:   []string{"-fno-test-coverage"},
+		group:   "coverage",
+		outputs: func(outputContext, string) []string { return nil },
+	},
+
+	// time trace: <obj>.json
+	{
+		flags: []string{"-ftime-trace"},
+		group: "time-trace",
+		outputs: func(c outputContext, _ string) []string {
+			return c.objSibling(".json")
+		},
+	},
+	{
+		// -ftime-trace=<file> or -ftime-trace=<dir>/
+		// clang also accepts existing directory without trailing
+		// path separator, but it can't be detected here.
+		flags: []string{"-ftime-trace="},
+		value: joinedValue,
+		group: "time-trace",
+		outputs: func(c outputContext, v string) []string {
+			if !isDirPath(v) {
+				return []string{v}
+			}
+			if c.obj == "" {
+				return nil
+			}
+			return []string{v + pathStem(c.obj) + ".json"}
+		},
+	},
+
+	// intermediate files.
+	{
+		// -save-temps, -save-temps=cwd: <input stem>.{i,bc,s} in cwd.
+		flags: []string{"-save-temps", "--save-temps", "-save-temps=cwd"},
+		group: "save-temps",
+		outputs: func(c outputContext, _ string) []string {
+			if c.input == "" {
+				return nil
+			}
+			return c.saveTemps("", pathStem(c.input))
+		},
+	},
+	{
+		// -save-temps=obj: <obj stem>.{i,bc,s} in obj dir.
+		flags: []string{"-save-temps=obj"},
+		group: "save-temps",
+		outputs: func(c outputContext, _ string) []string {
+			if c.obj == "" {
+				return nil
+			}
+			return c.saveTemps(pathDir(c.obj), pathStem(c.obj))
+		},
+	},
+	{
+		flags:   []string{"-no-save-temps"},
+		group:   "save-temps",
+		outputs: func(outputContext, string) []string { return nil },
+	},
+}
+
+// clangclOutputRules are rules to discover output files other than
+// /Fo, /o etc in clang-cl command line.
+var clangclOutputRules = []outputRule{
+	{
+		// /Fd<pdb>
+		// clang-cl accepts /Fd but doesn't emit pdb at compile now.
+		// it is declared as output in case it emits pdb.
+		// default pdb name for /Fd<dir>\ is not supported.
+		flags: []string{"/Fd", "-Fd"},
+		value: joinedValue,
+		group: "pdb",
+		outputs: func(c outputContext, v string) []string {
+			if v == "" || isDirPath(v) {
+				return nil
+			}
+			return []string{v}
+		},
+	},
+	{
+		// /FR[<file>], /Fr[<file>]: browse info.
+		flags: []string{"/FR", "-FR", "/Fr", "-Fr"},
+		value: joinedValue,
+		group: "browse",
+		outputs: func(c outputContext, v string) []string {
+			if v == "" {
+				return c.objSibling(".sbr")
+			}
+			if isDirPath(v) {
+				if c.obj == "" {
+					return nil
+				}
+				return []string{v + pathStem(c.obj) + ".sbr"}
+			}
+			return []string{v}
+		},
+	},
+	{
+		// /sourceDependencies <file> or /sourceDependencies <dir>\
+		flags: []string{"/sourceDependencies", "-sourceDependencies"},
+		value: separateValue,
+		group: "source-dependencies",
+		outputs: func(c outputContext, v string) []string {
+			if v == "-" {
+				// stdout
+				return nil
+			}
+			if !isDirPath(v) {
+				return []string{v}
+			}
+			if c.input == "" {
+				return nil
+			}
+			return []string{v + pathBase(c.input) + ".json"}
+		},
+	},
+	{
+		flags: []string{"-ftime-trace", "/clang:-ftime-trace"},
+		group: "time-trace",
+		outputs: func(c outputContext, _ string) []string {
+			return c.objSibling(".json")
+		},
+	},
+	{
+		flags: []string{"-ftime-trace=", "/clang:-ftime-trace="},
+		value: joinedValue,
+		group: "time-trace",
+		outputs: func(c outputContext, v string) []string {
+			if !isDirPath(v) {
+				return []string{v}
+			}
+			if c.obj == "" {
+				return nil
+			}
+			return []string{v + pathStem(c.obj) + ".json"}
+		},
+	},
+}
+
+// isPathSep reports whether c is path separator of posix or windows.
+func isPathSep(c byte) bool {
+	return c == '/' || c == '\\'
+}
+
+// isDirPath reports whether p is directory path (i.e. ends with path
+// separator).
+func isDirPath(p string) bool {
+	return p != "" && isPathSep(p[len(p)-1])
+}
+
+// pathDir returns directory part of p including trailing path
+// separator, or empty if p has no directory part.
+func pathDir(p string) string {
+	i := strings.LastIndexAny(p, `/\`)
+	return p[:i+1]
+}
+
+// pathBase returns the last element of p.
+func pathBase(p string) string {
+	return p[len(pathDir(p)):]
+}
+
+// pathStem returns the last element of p without extension.
+func pathStem(p string) string {
+	base := pathBase(p)
+	if i := strings.LastIndex(base, "."); i > 0 {
+		return base[:i]
+	}
+	return base
+}
+
+// replaceExt replaces filename extension of p with ext.
+// ext is added if p has no extension.
+func replaceExt(p, ext string) string {
+	return pathDir(p) + pathStem(p) + ext
+}
+
+// sourceExts are filename extensions of source files, and its
+// preprocessed output extension.
+var sourceExts = map[string]string{
+	".c":   ".i",
+	".cc":  ".ii",
+	".cpp": ".ii",
+	".cxx": ".ii",
+	".c++": ".ii",
+	".C":   ".ii",
+	".m":   ".mi",
+	".mm":  ".mii",
+	".S":   ".s",
+}
+
+// langPreprocessedExts are preprocessed output extension of language
+// specified by -x.
+var langPreprocessedExts = map[string]string{
+	"c":                    ".i",
+	"c++":                  ".ii",
+	"objective-c":          ".mi",
+	"objective-c++":        ".mii",
+	"assembler-with-cpp":   ".s",
+	"c-header":             ".i",
+	"c++-header":           ".ii",
+	"objective-c-header":   ".mi",
+	"objective-c++-header": ".mii",
+}
+
+// sourceExt returns filename extension of source file fname, or empty
+// if fname is not source file.
+func sourceExt(fname string) string {
+	base := pathBase(fname)
+	i := strings.LastIndex(base, ".")
+	if i <= 0 {
+		return ""
+	}
+	ext := base[i:]
+	if _, ok := sourceExts[ext]; !ok {
+		return ""
+	}
+	return ext
+}
@@ -0,0 +1,343 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"reflect"
+	"testing"
+)
+
+// chromiumLinuxClangArgs is a compile command line of chromium
+// linux debug build with split dwarf.
+var chromiumLinuxClangArgs = []string{
+	"../../third_party/llvm-build/Release+Asserts/bin/clang++",
+	"-MMD",
+	"-MF", "obj/base/base/values.o.d",
+	"-DDCHECK_ALWAYS_ON=1",
+	"-DUSE_UDEV",
+	"-DUSE_AURA=1",
+	"-DUSE_GLIB=1",
+	"-DUSE_NSS_CERTS=1",
+	"-DUSE_OZONE=1",
+	"-DUSE_X11=1",
+	"-D_FILE_OFFSET_BITS=64",
+	"-D_LARGEFILE_SOURCE",
+	"-D_LARGEFILE64_SOURCE",
+	"-D_GNU_SOURCE",
+	"-DCR_CLANG_REVISION=\"llvmorg-14-init-3191-g0e1ae0a7-1\"",
+	"-D__STDC_CONSTANT_MACROS",
+	"-D__STDC_FORMAT_MACROS",
+	"-D_LIBCPP_ABI_UNSTABLE",
+	"-D_LIBCPP_ENABLE_NODISCARD",
+	"-DCR_LIBCXX_REVISION=79a2e924d96e2fc1e4b937c42efd08898fa472d7",
+	"-D_DEBUG",
+	"-DDYNAMIC_ANNOTATIONS_ENABLED=1",
+	"-DBASE_IMPLEMENTATION",
+	"-I../..",
+	"-Igen",
+	"-I../../third_party/perfetto/include",
+	"-Igen/third_party/perfetto/build_config",
+	"-fno-delete-null-pointer-checks",
+	"-fno-ident",
+	"-fno-strict-aliasing",
+	"--param=ssp-buffer-size=4",
+	"-fstack-protector",
+	"-funwind-tables",
+	"-fPIC",
+	"-pthread",
+	"-fcolor-diagnostics",
+	"-fmerge-all-constants",
+	"-fcrash-diagnostics-dir=../../tools/clang/crashreports",
+	"-mllvm", "-instcombine-lower-dbg-declare=0",
+	"-ffp-contract=off",
+	"-m64",
+	"-march=x86-64",
+	"-msse3",
+	"-ffile-compilation-dir=.",
+	"-no-canonical-prefixes",
+	"-O0",
+	"-fno-omit-frame-pointer",
+	"-g2",
+	"-gdwarf-aranges",
+	"-gsplit-dwarf",
+	"-ggnu-pubnames",
+	"-fvisibility=hidden",
+	"-Wheader-hygiene",
+	"-Wstring-conversion",
+	"-Wtautological-overlap-compare",
+	"-Wall",
+	"-Werror",
+	"-Wextra",
+	"-std=c++14",
+	"-fno-trigraphs",
+	"-Wno-trigraphs",
+	"-fno-exceptions",
+	"-fno-rtti",
+	"-nostdinc++",
+	"-isystem../../buildtools/third_party/libc++/trunk/include",
+	"-isystem../../buildtools/third_party/libc++abi/trunk/include",
+	"--sysroot=../../build/linux/debian_sid_amd64-sysroot",
+	"-fvisibility-inlines-hidden",
+	"-c", "../../base/values.cc",
+	"-o", "obj/base/base/values.o",
+}
+
+// gccCoverageArgs is a compile command line of gcc coverage build.
+var gccCoverageArgs = []string{
+	"/usr/bin/g++",
+	"-DNDEBUG",
+	"-I../src",
+	"-O0",
+	"-g",
+	"--coverage",
+	"-fprofile-arcs",
+	"-std=gnu++17",
+	"-MD",
+	"-MT", "src/CMakeFiles/foo.dir/foo.cc.o",
+	"-MF", "src/CMakeFiles/foo.dir/foo.cc.o.d",
+	"-o", "src/CMakeFiles/foo.dir/foo.cc.o",
+	"-c", "../src/foo.cc",
+}
+
+func TestGccOutputsFixtures(t *testing.T) {
+	withArgs := func(base []string, args ...string) []string {
+		return append(append([]string{}, base...), args...)
+	}
+	for _, tc := range []struct {
+		desc string
+		args []string
+		want []string
+	}{
+		{
+			desc: "split dwarf",
+			args: chromiumLinuxClangArgs,
+			want: []string{
+				"obj/base/base/values.o.d",
+				"obj/base/base/values.o",
+				"obj/base/base/values.dwo",
+			},
+		},
+		{
+			desc: "split dwarf canceled",
+			args: withArgs(chromiumLinuxClangArgs, "-gno-split-dwarf"),
+			want: []string{
+				"obj/base/base/values.o.d",
+				"obj/base/base/values.o",
+			},
+		},
+		{
+			desc: "split dwarf single",
+			args: withArgs(chromiumLinuxClangArgs, "-gsplit-dwarf=single"),
+			want: []string{
+				"obj/base/base/values.o.d",
+				"obj/base/base/values.o",
+			},
+		},
+		{
+			desc: "time trace",
+			args: withArgs(chromiumLinuxClangArgs, "-ftime-trace"),
+			want: []string{
+				"obj/base/base/values.o.d",
+				"obj/base/base/values.o",
+				"obj/base/base/values.dwo",
+				"obj/base/base/values.json",
+			},
+		},
+		{
+			desc: "time trace dir",
+			args: withArgs(chromiumLinuxClangArgs, "-ftime-trace=traces/", "-ftime-trace-granularity=50"),
+			want: []string{
+				"obj/base/base/values.o.d",
+				"obj/base/base/values.o",
+				"obj/base/base/values.dwo",
+				"traces/values.json",
+			},
+		},
+		{
+			desc: "time trace file",
+			args: withArgs(chromiumLinuxClangArgs, "-ftime-trace=values.trace.json"),
+			want: []string{
+				"obj/base/base/values.o.d",
+				"obj/base/base/values.o",
+				"obj/base/base/values.dwo",
+				"values.trace.json",
+			},
+		},
+		{
+			desc: "save temps",
+			args: withArgs(chromiumLinuxClangArgs, "-save-temps"),
+			want: []string{
+				"obj/base/base/values.o.d",
+				"obj/base/base/values.o",
+				"obj/base/base/values.dwo",
+				"values.ii",
+				"values.bc",
+				"values.s",
+			},
+		},
+		{
+			desc: "save temps obj",
+			args: withArgs(chromiumLinuxClangArgs, "-save-temps=obj"),
+			want: []string{
+				"obj/base/base/values.o.d",
+				"obj/base/base/values.o",
+				"obj/base/base/values.dwo",
+				"obj/base/base/values.ii",
+				"obj/base/base/values.bc",
+				"obj/base/base/values.s",
+			},
+		},
+		{
+			desc: "gcc coverage",
+			args: gccCoverageArgs,
+			want: []string{
+				"src/CMakeFiles/foo.dir/foo.cc.o.d",
+				"src/CMakeFiles/foo.dir/foo.cc.o",
+				"src/CMakeFiles/foo.dir/foo.cc.gcno",
+			},
+		},
+		{
+			desc: "gcc coverage canceled",
+			args: withArgs(gccCoverageArgs, "-fno-test-coverage"),
+			want: []string{
+				"src/CMakeFiles/foo.dir/foo.cc.o.d",
+				"src/CMakeFiles/foo.dir/foo.cc.o",
+			},
+		},
+		{
+			desc: "gcc save temps c",
+			args: []string{
+				"gcc", "-save-temps", "-gsplit-dwarf", "-c", "../src/bar.c",
+			},
+			want: []string{
+				"bar.o",
+				"bar.dwo",
+				"bar.i",
+				"bar.s",
+			},
+		},
+		{
+			desc: "gcc save temps -x",
+			args: []string{
+				"gcc", "-save-temps=obj", "-x", "c++", "-c", "../src/bar.c", "-o", "out/bar.o",
+			},
+			want: []string{
+				"out/bar.o",
+				"out/bar.ii",
+				"out/bar.s",
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := gccOutputs(tc.args); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("gccOutputs(%q)=%q; want %q", tc.args, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClangclOutputsFixtures(t *testing.T) {
+	withArgs := func(base []string, args ...string) []string {
+		return append(append([]string{}, base...), args...)
+	}
+	for _, tc := range []struct {
+		desc string
+		args []string
+		want []string
+	}{
+		{
+			desc: "chromium release",
+			args: fullChromiumReleaseBuildArgs,
+			want: []string{
+				"obj/base/base/com_init_util.obj",
+				"obj/base/base_cc.pdb",
+			},
+		},
+		{
+			desc: "chromium debug",
+			args: fullChromiumDebugBuildArgs,
+			want: []string{
+				"obj/base/third_party/double_conversion/double_conversion/fast-dtoa.obj",
+				"obj/base/third_party/double_conversion/double_conversion_cc.pdb",
+			},
+		},
+		{
+			desc: "llvm release pdb dir",
+			args: fullLLVMReleaseBuildArgs,
+			want: []string{
+				`tools\opt\CMakeFiles\opt.dir\PrintSCC.cpp.obj`,
+			},
+		},
+		{
+			desc: "browse info",
+			args: withArgs(fullChromiumReleaseBuildArgs, "/FR"),
+			want: []string{
+				"obj/base/base/com_init_util.obj",
+				"obj/base/base_cc.pdb",
+				"obj/base/base/com_init_util.sbr",
+			},
+		},
+		{
+			desc: "browse info file",
+			args: withArgs(fullChromiumReleaseBuildArgs, "/FRobj/base/base/com_init_util.browse.sbr"),
+			want: []string{
+				"obj/base/base/com_init_util.obj",
+				"obj/base/base_cc.pdb",
+				"obj/base/base/com_init_util.browse.sbr",
+			},
+		},
+		{
+			desc: "source dependencies",
+			args: withArgs(fullChromiumReleaseBuildArgs, "/sourceDependencies", "obj/base/base/com_init_util.json"),
+			want: []string{
+				"obj/base/base/com_init_util.obj",
+				"obj/base/base_cc.pdb",
+				"obj/base/base/com_init_util.json",
+			},
+		},
+		{
+			desc: "source dependencies dir",
+			args: withArgs(fullLLVMReleaseBuildArgs, "/sourceDependencies", `deps\`),
+			want: []string{
+				`tools\opt\CMakeFiles\opt.dir\PrintSCC.cpp.obj`,
+				`deps\PrintSCC.cpp.json`,
+			},
+		},
+		{
+			desc: "time trace",
+			args: withArgs(fullChromiumReleaseBuildArgs, "/clang:-ftime-trace"),
+			want: []string{
+				"obj/base/base/com_init_util.obj",
+				"obj/base/base_cc.pdb",
+				"obj/base/base/com_init_util.json",
+			},
+		},
+		{
+			desc: "implicit obj",
+			args: []string{
+				"clang-cl", "/c", `..\..\base\foo.cc`, "/Fdfoo.pdb",
+			},
+			want: []string{
+				"foo.obj",
+				"foo.pdb",
+			},
+		},
+		{
+			desc: "obj dir",
+			args: []string{
+				"clang-cl", "/c", `..\..\base\foo.cc`, `/Foobj\base\`,
+			},
+			want: []string{
+				`obj\base\foo.obj`,
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := clangclOutputs(tc.args); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("clangclOutputs(%q)=%q; want %q", tc.args, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"go.chromium.org/goma/server/command/descriptor/winpath"
+	gomapb "go.chromium.org/goma/server/proto/api"
+	"go.chromium.org/goma/server/remoteexec/digest"
+)
+
+// hasRspArg reports whether args has response file.
+// args[0] is command name.
+func hasRspArg(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "@") && len(arg) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// rspSplitter returns a function to split response file content into
+// args for the command.
+// gcc and lld use GNU quoting rules.
+// clang-cl, and clang on windows use MSVC quoting rules.
+// clang and lld respect --rsp-quoting=posix|windows.
+func rspSplitter(name string, filepath clientFilePath, args []string) func(string) ([]string, error) {
+	for _, arg := range args {
+		switch arg {
+		case "--rsp-quoting=posix":
+			return splitGNUArgs
+		case "--rsp-quoting=windows":
+			return splitWindowsArgs
+		}
+	}
+	switch name {
+	case "clang-cl":
+		return splitWindowsArgs
+	case "clang", "clang++":
+		if _, ok := filepath.(winpath.FilePath); ok {
+			return splitWindowsArgs
+		}
+	}
+	return splitGNUArgs
+}
+
+// splitGNUArgs splits response file content into args
+// by GNU quoting rules.
+// args are separated by white spaces, and white spaces in
+// single/double quotes or after backslash are preserved.
+func splitGNUArgs(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, c := range s {
+		switch {
+		case escaped:
+			arg.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+				continue
+			}
+			arg.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote %c", quote)
+	}
+	if inArg || escaped {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// splitWindowsArgs splits response file content into args
+// by MSVC quoting rules.
+// args are separated by white spaces, and white spaces in double quotes
+// are preserved.
+// 2n backslashes followed by double quote produce n backslashes,
+// and the double quote starts or ends quoting.
+// 2n+1 backslashes followed by double quote produce n backslashes
+// and literal double quote.
+// backslashes not followed by double quote are literal.
+// two double quotes in quoting produce literal double quote.
+// https://docs.microsoft.com/en-us/cpp/c-language/parsing-c-command-line-arguments
+func splitWindowsArgs(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	quoted := false
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+		switch {
+		case c == '\\':
+			n := 0
+			for i < len(rs) && rs[i] == '\\' {
+				n++
+				i++
+			}
+			inArg = true
+			if i < len(rs) && rs[i] == '"' {
+				arg.WriteString(strings.Repeat(`\`, n/2))
+				if n%2 == 1 {
+					arg.WriteRune('"')
+					continue
+				}
+			} else {
+				arg.WriteString(strings.Repeat(`\`, n))
+			}
+			// rs[i] is not consumed.
+			i--
+		case c == '"':
+			inArg = true
+			if quoted && i+1 < len(rs) && rs[i+1] == '"' {
+				arg.WriteRune('"')
+				i++
+				continue
+			}
+			quoted = !quoted
+		case !quoted && (c == ' ' || c == '\t' || c == '\r' || c == '\n'):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// maxRspDepth is max depth of nested response files.
+const maxRspDepth = 8
+
+// expandRspArgs expands response files (@file) in args.
+// args[0] is command name, and is not expanded.
+// split splits content of response file into args.
+// readFile reads content of the file, and returns errMissingFile if
+// the file is not available.
+func expandRspArgs(args []string, split func(string) ([]string, error), readFile func(string) ([]byte, error)) ([]string, error) {
+	if len(args) == 0 {
+		return args, nil
+	}
+	expanded, err := expandRsp(args[1:], split, readFile, 0)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{args[0]}, expanded...), nil
+}
+
+func expandRsp(args []string, split func(string) ([]string, error), readFile func(string) ([]byte, error), depth int) ([]string, error) {
+	var expanded []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "@") || len(arg) == 1 {
+			expanded = append(expanded, arg)
+			continue
+		}
+		if depth >= maxRspDepth {
+			return nil, fmt.Errorf("too deep response file: %s", arg)
+		}
+		b, err := readFile(arg[1:])
+		if err != nil {
+			return nil, err
+		}
+		rargs, err := split(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("response file %s: %v", arg[1:], err)
+		}
+		rargs, err = expandRsp(rargs, split, readFile, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, rargs...)
+	}
+	return expanded, nil
+}
+
+// errMissingFile is returned if file is not in request inputs.
+var errMissingFile = errors.New("missing file")
+
+// requestFiles is data of request inputs, to read file content
+// (e.g. response files) in the request.
+type requestFiles struct {
+	filepath clientFilePath
+	cwd      string
+	// data keyed by clean absolute path.
+	data map[string]digest.Data
+}
+
+// newRequestFiles creates requestFiles from inputs and its results.
+func newRequestFiles(filepath clientFilePath, cwd string, inputs []*gomapb.ExecReq_Input, results []inputFileResult) requestFiles {
+	f := requestFiles{
+		filepath: filepath,
+		cwd:      cwd,
+		data:     make(map[string]digest.Data),
+	}
+	for i, input := range inputs {
+		if results[i].file.Data == nil {
+			continue
+		}
+		f.data[f.absPath(input.GetFilename())] = results[i].file.Data
+	}
+	return f
+}
+
+func (f requestFiles) absPath(fname string) string {
+	if !f.filepath.IsAbs(fname) {
+		fname = f.filepath.Join(f.cwd, fname)
+	}
+	return f.filepath.Clean(fname)
+}
+
+// lookup returns data of fname.
+func (f requestFiles) lookup(fname string) (digest.Data, bool) {
+	data, ok := f.data[f.absPath(fname)]
+	return data, ok
+}
+
+// readFile reads content of fname.
+// It returns errMissingFile if fname is not in the request inputs.
+func (f requestFiles) readFile(ctx context.Context, fname string) ([]byte, error) {
+	data, ok := f.lookup(fname)
+	if !ok {
+		return nil, errMissingFile
+	}
+	rd, err := data.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	return ioutil.ReadAll(rd)
+}
+
+// expandRspInputs expands response files in args by reading them from
+// request files.
+// It returns expanded args, and response files that are not in the
+// request inputs. If some response file is missing, expanded args is nil.
+func expandRspInputs(ctx context.Context, files requestFiles, split func(string) ([]string, error), args []string) ([]string, []string, error) {
+	var missing []string
+	expanded, err := expandRspArgs(args, split, func(fname string) ([]byte, error) {
+		b, err := files.readFile(ctx, fname)
+		if err == errMissingFile {
+			missing = append(missing, fname)
+		}
+		return b, err
+	})
+	if err == errMissingFile {
+		return nil, missing, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return expanded, nil, nil
+}
+
+// expandArgs expands response files in args of the request into
+// r.expandedArgs, and returns response files that are not in the
+// request inputs.
+func (r *request) expandArgs(ctx context.Context, name string, files requestFiles) ([]string, error) {
+	args := r.gomaReq.Arg
+	if !hasRspArg(args) {
+		return nil, nil
+	}
+	expanded, missing, err := expandRspInputs(ctx, files, rspSplitter(name, r.filepath, args), args)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return missing, nil
+	}
+	r.expandedArgs = expanded
+	return nil, nil
+}
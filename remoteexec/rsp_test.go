@@ -0,0 +1,200 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.chromium.org/goma/server/command/descriptor/posixpath"
+	"go.chromium.org/goma/server/command/descriptor/winpath"
+	"go.chromium.org/goma/server/remoteexec/digest"
+)
+
+func TestSplitGNUArgs(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{
+			in:   "foo.o  bar.o\n-lpthread\n",
+			want: []string{"foo.o", "bar.o", "-lpthread"},
+		},
+		{
+			in:   `"foo bar.o" 'baz\qux.o' foo\ bar.o`,
+			want: []string{"foo bar.o", `baz\qux.o`, "foo bar.o"},
+		},
+		{
+			in:   `"a\"b" ""`,
+			want: []string{`a"b`, ""},
+		},
+		{
+			in:   `-DFOO="\"bar\""`,
+			want: []string{`-DFOO="bar"`},
+		},
+		{
+			in:      `"foo.o`,
+			wantErr: true,
+		},
+	} {
+		got, err := splitGNUArgs(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("splitGNUArgs(%q)=_, %v; wantErr=%t", tc.in, err, tc.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("splitGNUArgs(%q)=%q; want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSplitWindowsArgs(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []string
+	}{
+		{
+			in:   "/c foo.cc\r\n/Fofoo.obj\r\n",
+			want: []string{"/c", "foo.cc", "/Fofoo.obj"},
+		},
+		{
+			in:   `/I"C:\Program Files\foo" C:\src\foo.cc`,
+			want: []string{`/IC:\Program Files\foo`, `C:\src\foo.cc`},
+		},
+		{
+			in:   `"a\"b" "c\\" d\\\"e`,
+			want: []string{`a"b`, `c\`, `d\"e`},
+		},
+		{
+			in:   `"a""b" ""`,
+			want: []string{`a"b`, ""},
+		},
+		{
+			in:   `"foo bar.obj`,
+			want: []string{"foo bar.obj"},
+		},
+	} {
+		got, err := splitWindowsArgs(tc.in)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("splitWindowsArgs(%q)=%q, %v; want %q, nil", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestRspSplitter(t *testing.T) {
+	funcName := func(f func(string) ([]string, error)) string {
+		// can't compare func values, so check behavior.
+		args, _ := f(`a\b`)
+		if reflect.DeepEqual(args, []string{`a\b`}) {
+			return "windows"
+		}
+		return "gnu"
+	}
+	for _, tc := range []struct {
+		name     string
+		filepath clientFilePath
+		args     []string
+		want     string
+	}{
+		{
+			name:     "gcc",
+			filepath: posixpath.FilePath{},
+			args:     []string{"gcc", "@foo.rsp"},
+			want:     "gnu",
+		},
+		{
+			name:     "clang++",
+			filepath: winpath.FilePath{},
+			args:     []string{"clang++.exe", "@foo.rsp"},
+			want:     "windows",
+		},
+		{
+			name:     "clang++",
+			filepath: winpath.FilePath{},
+			args:     []string{"clang++.exe", "--rsp-quoting=posix", "@foo.rsp"},
+			want:     "gnu",
+		},
+		{
+			name:     "clang-cl",
+			filepath: posixpath.FilePath{},
+			args:     []string{"clang-cl", "@foo.rsp"},
+			want:     "windows",
+		},
+		{
+			name:     "ld.lld",
+			filepath: posixpath.FilePath{},
+			args:     []string{"ld.lld", "--rsp-quoting=windows", "@foo.rsp"},
+			want:     "windows",
+		},
+	} {
+		if got := funcName(rspSplitter(tc.name, tc.filepath, tc.args)); got != tc.want {
+			t.Errorf("rspSplitter(%q, %T, %q)=%s; want %s", tc.name, tc.filepath, tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestExpandRspArgs(t *testing.T) {
+	files := map[string]string{
+		"chrome.rsp": "foo.o bar.o @nested.rsp",
+		"nested.rsp": "baz.o",
+		"loop.rsp":   "@loop.rsp",
+	}
+	readFile := func(fname string) ([]byte, error) {
+		s, ok := files[fname]
+		if !ok {
+			return nil, errMissingFile
+		}
+		return []byte(s), nil
+	}
+
+	args := []string{"ld.lld", "-o", "chrome", "@chrome.rsp", "-lpthread"}
+	got, err := expandRspArgs(args, splitGNUArgs, readFile)
+	if err != nil {
+		t.Fatalf("expandRspArgs(%q)=_, %v; want nil error", args, err)
+	}
+	want := []string{"ld.lld", "-o", "chrome", "foo.o", "bar.o", "baz.o", "-lpthread"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandRspArgs(%q)=%q; want %q", args, got, want)
+	}
+
+	for _, args := range [][]string{
+		{"ld.lld", "@missing.rsp"},
+		{"ld.lld", "@loop.rsp"},
+	} {
+		_, err := expandRspArgs(args, splitGNUArgs, readFile)
+		if err == nil {
+			t.Errorf("expandRspArgs(%q)=_, nil; want error", args)
+		}
+	}
+}
+
+func TestExpandRspInputs(t *testing.T) {
+	ctx := context.Background()
+	files := requestFiles{
+		filepath: posixpath.FilePath{},
+		cwd:      "/b/c/out/Release",
+		data: map[string]digest.Data{
+			"/b/c/out/Release/obj/foo.o.rsp": digest.Bytes("foo.o.rsp", []byte("-I../../third_party/foo -DFOO=1 -gsplit-dwarf")),
+		},
+	}
+	args := []string{"clang++", "@obj/foo.o.rsp", "-c", "../../foo.cc", "-o", "obj/foo.o"}
+	got, missing, err := expandRspInputs(ctx, files, splitGNUArgs, args)
+	want := []string{"clang++", "-I../../third_party/foo", "-DFOO=1", "-gsplit-dwarf", "-c", "../../foo.cc", "-o", "obj/foo.o"}
+	if err != nil || missing != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("expandRspInputs(%q)=%q, %q, %v; want %q, nil, nil", args, got, missing, err, want)
+	}
+	// outputs are detected from expanded args.
+	if got, want := gccOutputs(got), []string{"obj/foo.o", "obj/foo.dwo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("gccOutputs(expanded)=%q; want %q", got, want)
+	}
+
+	args = []string{"clang++", "@obj/bar.o.rsp", "-c", "../../bar.cc", "-o", "obj/bar.o"}
+	got, missing, err = expandRspInputs(ctx, files, splitGNUArgs, args)
+	if err != nil || got != nil || !reflect.DeepEqual(missing, []string{"obj/bar.o.rsp"}) {
+		t.Errorf("expandRspInputs(%q)=%q, %q, %v; want nil, [obj/bar.o.rsp], nil", args, got, missing, err)
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// rustcValueFlags are rustc flags that take value.
+// https://doc.rust-lang.org/rustc/command-line-arguments.html
+var rustcValueFlags = []string{
+	"--remap-path-prefix",
+	"--error-format",
+	"--crate-name",
+	"--crate-type",
+	"--cap-lints",
+	"--out-dir",
+	"--edition",
+	"--codegen",
+	"--sysroot",
+	"--explain",
+	"--extern",
+	"--forbid",
+	"--target",
+	"--allow",
+	"--color",
+	"--print",
+	"--deny",
+	"--emit",
+	"--json",
+	"--warn",
+	"--cfg",
+	"-A",
+	"-C",
+	"-D",
+	"-F",
+	"-L",
+	"-W",
+	"-Z",
+	"-o",
+}
+
+// rustcFlag is a flag and its value in rustc command line.
+// name is empty for input file.
+type rustcFlag struct {
+	name  string
+	value string
+}
+
+// parseRustcArgs parses rustc command line into flags.
+// args[0] is command name, and is not included in the result.
+func parseRustcArgs(args []string) ([]rustcFlag, error) {
+	var flags []rustcFlag
+	if len(args) == 0 {
+		return nil, errors.New("no args")
+	}
+Loop:
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		for _, f := range rustcValueFlags {
+			switch {
+			case arg == f:
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("no value for %s", arg)
+				}
+				i++
+				flags = append(flags, rustcFlag{name: f, value: args[i]})
+				continue Loop
+			case strings.HasPrefix(f, "--") && strings.HasPrefix(arg, f+"="):
+				flags = append(flags, rustcFlag{name: f, value: arg[len(f)+1:]})
+				continue Loop
+			case !strings.HasPrefix(f, "--") && strings.HasPrefix(arg, f):
+				// -Cfoo, -Lfoo, -ofoo
+				flags = append(flags, rustcFlag{name: f, value: arg[len(f):]})
+				continue Loop
+			}
+		}
+		switch arg {
+		case "-g", "-O", "--test", "-v", "--verbose":
+			flags = append(flags, rustcFlag{name: arg})
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			return nil, unknownFlagError{arg: arg}
+		}
+		flags = append(flags, rustcFlag{value: arg})
+	}
+	return flags, nil
+}
+
+// rustcLibPath returns path of -L [KIND=]PATH.
+func rustcLibPath(v string) string {
+	i := strings.Index(v, "=")
+	if i < 0 {
+		return v
+	}
+	switch v[:i] {
+	case "dependency", "crate", "native", "framework", "all":
+		return v[i+1:]
+	}
+	return v
+}
+
+// rustcRelocatableReq checks if the request (args, envs) uses relative
+// paths only and doesn't use flags that generates output including cwd,
+// so will generate cwd-agnostic outputs.
+//
+// The request will NOT be relocatable, if
+//  absolute path in input, --out-dir, -o, -L, --extern, --emit etc.
+//  absolute path prefix in --remap-path-prefix FROM=TO
+//      remote path is not remapped.
+//  debug build (-g, -C debuginfo=N)
+//      DW_AT_comp_dir will have cwd.
+//      this will be canceled by -Z remap-cwd-prefix=
+func rustcRelocatableReq(filepath clientFilePath, args, envs []string) error {
+	flags, err := parseRustcArgs(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 && filepath.IsAbs(args[0]) {
+		return fmt.Errorf("abs path: %s", args[0])
+	}
+	var debugFlags []string
+	remapCwd := false
+	for _, f := range flags {
+		switch f.name {
+		case "", "-o", "--out-dir", "--sysroot":
+			if filepath.IsAbs(f.value) {
+				return fmt.Errorf("abs path: %s %s", f.name, f.value)
+			}
+		case "-L":
+			if filepath.IsAbs(rustcLibPath(f.value)) {
+				return fmt.Errorf("abs path: -L %s", f.value)
+			}
+		case "--extern":
+			// --extern NAME[=PATH]
+			i := strings.Index(f.value, "=")
+			if i >= 0 && filepath.IsAbs(f.value[i+1:]) {
+				return fmt.Errorf("abs path: --extern %s", f.value)
+			}
+		case "--emit":
+			// --emit KIND[=PATH],...
+			for _, e := range strings.Split(f.value, ",") {
+				i := strings.Index(e, "=")
+				if i >= 0 && filepath.IsAbs(e[i+1:]) {
+					return fmt.Errorf("abs path: --emit %s", e)
+				}
+			}
+		case "--remap-path-prefix":
+			// --remap-path-prefix FROM=TO
+			i := strings.LastIndex(f.value, "=")
+			if i < 0 {
+				return fmt.Errorf("bad --remap-path-prefix %s", f.value)
+			}
+			if filepath.IsAbs(f.value[:i]) {
+				return fmt.Errorf("abs path prefix: --remap-path-prefix %s", f.value)
+			}
+		case "-C", "--codegen":
+			kv := strings.SplitN(f.value, "=", 2)
+			switch kv[0] {
+			case "debuginfo":
+				if len(kv) == 2 && kv[1] != "0" {
+					debugFlags = append(debugFlags, "-C"+f.value)
+				} else {
+					debugFlags = nil
+				}
+			case "incremental", "linker", "profile-generate", "profile-use":
+				if len(kv) == 2 && filepath.IsAbs(kv[1]) {
+					return fmt.Errorf("abs path: -C %s", f.value)
+				}
+			}
+		case "-Z":
+			if strings.HasPrefix(f.value, "remap-cwd-prefix=") {
+				remapCwd = true
+			}
+		case "-g":
+			debugFlags = append(debugFlags, f.name)
+		}
+	}
+	if len(debugFlags) > 0 && !remapCwd {
+		return fmt.Errorf("debug build: %q", debugFlags)
+	}
+	for _, env := range envs {
+		e := strings.SplitN(env, "=", 2)
+		if len(e) != 2 {
+			return fmt.Errorf("bad environment variable: %s", env)
+		}
+		if e[0] == "PWD" {
+			continue
+		}
+		// env!() would embed the value in output.
+		if filepath.IsAbs(e[1]) {
+			return fmt.Errorf("abs path in env %s=%s", e[0], e[1])
+		}
+	}
+	return nil
+}
+
+// rustcLinkerFreeReq checks the request doesn't need the system linker.
+// rustc invokes the system linker (cc) to link bin, dylib, cdylib and
+// proc-macro crates, but the linker is not in toolchain of rustc, so
+// only lib and rlib crates (or emits other than link, e.g. metadata)
+// are supported remotely.
+// crate type is assumed as bin if --crate-type is not given, although
+// it might be specified by crate_type attribute in source.
+func rustcLinkerFreeReq(args []string) error {
+	flags, err := parseRustcArgs(args)
+	if err != nil {
+		return err
+	}
+	var crateTypes []string
+	emit, link := false, false
+	for _, f := range flags {
+		switch f.name {
+		case "--crate-type":
+			crateTypes = append(crateTypes, strings.Split(f.value, ",")...)
+		case "--emit":
+			emit = true
+			for _, e := range strings.Split(f.value, ",") {
+				if strings.SplitN(e, "=", 2)[0] == "link" {
+					link = true
+				}
+			}
+		case "--print":
+			// print info only.
+			return nil
+		}
+	}
+	if emit && !link {
+		return nil
+	}
+	if len(crateTypes) == 0 {
+		crateTypes = []string{"bin"}
+	}
+	for _, t := range crateTypes {
+		switch t {
+		case "lib", "rlib":
+		default:
+			return fmt.Errorf("crate type %s needs linker", t)
+		}
+	}
+	return nil
+}
+
+// rustcTargetReq checks --target of the request is target of the toolchain.
+// toolchain has sysroot libraries (e.g. libstd) of its target only,
+// so it can't compile for other target.
+func rustcTargetReq(args []string, target string) error {
+	flags, err := parseRustcArgs(args)
+	if err != nil {
+		return err
+	}
+	for _, f := range flags {
+		if f.name == "--target" && f.value != target {
+			return fmt.Errorf("target %s != toolchain target %s", f.value, target)
+		}
+	}
+	return nil
+}
+
+// rustcEmitExts is filename extension of each emit kind other than link.
+var rustcEmitExts = map[string]string{
+	"asm":      ".s",
+	"llvm-bc":  ".bc",
+	"llvm-ir":  ".ll",
+	"obj":      ".o",
+	"dep-info": ".d",
+	"mir":      ".mir",
+}
+
+// rustcLinkOutput returns filename of link output for crateType.
+// It assumes linux filename convention.
+func rustcLinkOutput(crateType, stem string) string {
+	switch crateType {
+	case "lib", "rlib":
+		return "lib" + stem + ".rlib"
+	case "dylib", "cdylib", "proc-macro":
+		return "lib" + stem + ".so"
+	case "staticlib":
+		return "lib" + stem + ".a"
+	default: // bin
+		return stem
+	}
+}
+
+// rustcOutputs returns output files from rustc command line.
+// output filenames are determined by --crate-name, --crate-type,
+// --emit, --out-dir, -o and -C extra-filename.
+// dep-info output is included if --emit has dep-info.
+func rustcOutputs(args []string) []string {
+	flags, err := parseRustcArgs(args)
+	if err != nil {
+		return nil
+	}
+	var crateName, input, outDir, outFile, extra string
+	var crateTypes []string
+	type emit struct {
+		kind, path string
+	}
+	var emits []emit
+	for _, f := range flags {
+		switch f.name {
+		case "":
+			input = f.value
+		case "--crate-name":
+			crateName = f.value
+		case "--crate-type":
+			crateTypes = append(crateTypes, strings.Split(f.value, ",")...)
+		case "--out-dir":
+			outDir = f.value
+		case "-o":
+			outFile = f.value
+		case "--emit":
+			for _, e := range strings.Split(f.value, ",") {
+				kv := strings.SplitN(e, "=", 2)
+				em := emit{kind: kv[0]}
+				if len(kv) == 2 {
+					em.path = kv[1]
+				}
+				emits = append(emits, em)
+			}
+		case "-C", "--codegen":
+			if strings.HasPrefix(f.value, "extra-filename=") {
+				extra = strings.TrimPrefix(f.value, "extra-filename=")
+			}
+		case "--print":
+			// print info only.
+			return nil
+		}
+	}
+	if crateName == "" {
+		if input == "" {
+			return nil
+		}
+		crateName = strings.TrimSuffix(path.Base(input), path.Ext(input))
+		crateName = strings.ReplaceAll(crateName, "-", "_")
+	}
+	if len(crateTypes) == 0 {
+		crateTypes = []string{"bin"}
+	}
+	if len(emits) == 0 {
+		emits = []emit{{kind: "link"}}
+	}
+	stem := crateName + extra
+	dir := outDir
+	if outFile != "" {
+		dir = path.Dir(outFile)
+		stem = strings.TrimSuffix(path.Base(outFile), path.Ext(outFile))
+	}
+	join := func(fname string) string {
+		if dir == "" || dir == "." {
+			return fname
+		}
+		return path.Join(dir, fname)
+	}
+
+	var outputs []string
+	for _, e := range emits {
+		if e.path != "" {
+			if e.path != "-" {
+				outputs = append(outputs, e.path)
+			}
+			continue
+		}
+		if e.kind == "link" {
+			if outFile != "" {
+				outputs = append(outputs, outFile)
+				continue
+			}
+			for _, t := range crateTypes {
+				outputs = append(outputs, join(rustcLinkOutput(t, stem)))
+			}
+			continue
+		}
+		if e.kind == "metadata" {
+			if outFile != "" {
+				outputs = append(outputs, join(stem+".rmeta"))
+				continue
+			}
+			outputs = append(outputs, join("lib"+stem+".rmeta"))
+			continue
+		}
+		ext, ok := rustcEmitExts[e.kind]
+		if !ok {
+			continue
+		}
+		outputs = append(outputs, join(stem+ext))
+	}
+	return outputs
+}
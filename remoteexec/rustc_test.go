@@ -0,0 +1,306 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"reflect"
+	"testing"
+
+	"go.chromium.org/goma/server/command/descriptor/posixpath"
+)
+
+func TestRustcRelocatableReq(t *testing.T) {
+	baseArgs := []string{
+		"../../third_party/rust-toolchain/bin/rustc",
+		"--crate-name", "base",
+		"--edition=2018",
+		"--crate-type", "rlib",
+		"--emit=dep-info=obj/base/libbase.rlib.d,link",
+		"-Copt-level=2",
+		"-C", "metadata=abcd",
+		"-Cextra-filename=-abcd",
+		"--out-dir", "obj/base",
+		"-L", "dependency=obj/deps",
+		"--extern", "log=obj/deps/liblog.rlib",
+		"--cfg", "feature=\"std\"",
+		"--cap-lints", "allow",
+		"--target", "x86_64-unknown-linux-gnu",
+		"-Dwarnings",
+		"../../base/lib.rs",
+	}
+	baseEnvs := []string{
+		"PWD=/home/user/src/out/Release",
+		"CARGO_PKG_NAME=base",
+	}
+	modifyArgs := func(args []string, prefix, replace string) []string {
+		var ret []string
+		found := false
+		for _, arg := range args {
+			if arg == prefix || (len(prefix) > 0 && len(arg) > len(prefix) && arg[:len(prefix)] == prefix) {
+				ret = append(ret, replace)
+				found = true
+				continue
+			}
+			ret = append(ret, arg)
+		}
+		if !found {
+			ret = append(ret, replace)
+		}
+		return ret
+	}
+
+	for _, tc := range []struct {
+		desc    string
+		args    []string
+		envs    []string
+		wantErr bool
+	}{
+		{
+			desc: "basic",
+			args: baseArgs,
+			envs: baseEnvs,
+		},
+		{
+			desc:    "abs input",
+			args:    modifyArgs(baseArgs, "../../base/lib.rs", "/home/user/src/base/lib.rs"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc:    "abs out-dir",
+			args:    append(append([]string{}, baseArgs...), "--out-dir=/home/user/src/out/Release/obj/base"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc:    "abs -L",
+			args:    append(append([]string{}, baseArgs...), "-Lnative=/usr/lib"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc:    "abs extern",
+			args:    append(append([]string{}, baseArgs...), "--extern", "serde=/home/user/src/out/Release/obj/deps/libserde.rlib"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc:    "abs emit",
+			args:    modifyArgs(baseArgs, "--emit=", "--emit=dep-info=/tmp/libbase.rlib.d,link"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc:    "abs remap-path-prefix",
+			args:    append(append([]string{}, baseArgs...), "--remap-path-prefix=/home/user/src=."),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc: "relative remap-path-prefix",
+			args: append(append([]string{}, baseArgs...), "--remap-path-prefix", "../..=src"),
+			envs: baseEnvs,
+		},
+		{
+			desc:    "debug build",
+			args:    append(append([]string{}, baseArgs...), "-Cdebuginfo=2"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc: "debug build with remap-cwd-prefix",
+			args: append(append([]string{}, baseArgs...), "-g", "-Zremap-cwd-prefix=."),
+			envs: baseEnvs,
+		},
+		{
+			desc: "debuginfo=0",
+			args: append(append([]string{}, baseArgs...), "-C", "debuginfo=0"),
+			envs: baseEnvs,
+		},
+		{
+			desc:    "abs env",
+			args:    baseArgs,
+			envs:    append(append([]string{}, baseEnvs...), "CARGO_MANIFEST_DIR=/home/user/src/base"),
+			wantErr: true,
+		},
+		{
+			desc:    "unknown flag",
+			args:    append(append([]string{}, baseArgs...), "--unknown-flag"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := rustcRelocatableReq(posixpath.FilePath{}, tc.args, tc.envs)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("rustcRelocatableReq(%q, %q)=%v; wantErr=%t", tc.args, tc.envs, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRustcLinkerFreeReq(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			desc: "rlib",
+			args: []string{"rustc", "--crate-type", "rlib", "--emit=dep-info,link", "lib.rs"},
+		},
+		{
+			desc: "lib",
+			args: []string{"rustc", "--crate-type=lib", "lib.rs"},
+		},
+		{
+			desc: "bin metadata only",
+			args: []string{"rustc", "--crate-type", "bin", "--emit=dep-info,metadata", "main.rs"},
+		},
+		{
+			desc: "print",
+			args: []string{"rustc", "--print", "sysroot"},
+		},
+		{
+			desc:    "default bin",
+			args:    []string{"rustc", "main.rs"},
+			wantErr: true,
+		},
+		{
+			desc:    "bin",
+			args:    []string{"rustc", "--crate-type", "bin", "--emit=link", "main.rs"},
+			wantErr: true,
+		},
+		{
+			desc:    "rlib and cdylib",
+			args:    []string{"rustc", "--crate-type", "rlib,cdylib", "lib.rs"},
+			wantErr: true,
+		},
+		{
+			desc:    "proc-macro",
+			args:    []string{"rustc", "--crate-type", "proc-macro", "--emit=metadata", "--emit=link", "lib.rs"},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := rustcLinkerFreeReq(tc.args)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("rustcLinkerFreeReq(%q)=%v; wantErr=%t", tc.args, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRustcOutputs(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		args []string
+		want []string
+	}{
+		{
+			desc: "cargo rlib",
+			args: []string{
+				"rustc",
+				"--crate-name", "base",
+				"--crate-type", "lib",
+				"--emit=dep-info,metadata,link",
+				"-C", "extra-filename=-abcd",
+				"--out-dir", "target/debug/deps",
+				"src/lib.rs",
+			},
+			want: []string{
+				"target/debug/deps/base-abcd.d",
+				"target/debug/deps/libbase-abcd.rmeta",
+				"target/debug/deps/libbase-abcd.rlib",
+			},
+		},
+		{
+			desc: "explicit dep-info path",
+			args: []string{
+				"rustc",
+				"--crate-name=base",
+				"--crate-type=rlib",
+				"--emit=dep-info=obj/base/libbase.rlib.d,link",
+				"-o", "obj/base/libbase.rlib",
+				"../../base/lib.rs",
+			},
+			want: []string{
+				"obj/base/libbase.rlib.d",
+				"obj/base/libbase.rlib",
+			},
+		},
+		{
+			desc: "bin without crate-name",
+			args: []string{
+				"rustc",
+				"hello-world.rs",
+			},
+			want: []string{
+				"hello_world",
+			},
+		},
+		{
+			desc: "multiple crate types",
+			args: []string{
+				"rustc",
+				"--crate-name", "foo",
+				"--crate-type", "staticlib,cdylib",
+				"--out-dir=out",
+				"foo.rs",
+			},
+			want: []string{
+				"out/libfoo.a",
+				"out/libfoo.so",
+			},
+		},
+		{
+			desc: "print",
+			args: []string{
+				"rustc",
+				"--print", "sysroot",
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := rustcOutputs(tc.args); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("rustcOutputs(%q)=%q; want %q", tc.args, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRustcTargetReq(t *testing.T) {
+	const target = "x86_64-unknown-linux-gnu"
+	for _, tc := range []struct {
+		desc    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			desc: "no target",
+			args: []string{"rustc", "--crate-type", "rlib", "lib.rs"},
+		},
+		{
+			desc: "same target",
+			args: []string{"rustc", "--crate-type", "rlib", "--target", target, "lib.rs"},
+		},
+		{
+			desc: "same target joined",
+			args: []string{"rustc", "--crate-type", "rlib", "--target=" + target, "lib.rs"},
+		},
+		{
+			desc:    "other target",
+			args:    []string{"rustc", "--crate-type", "rlib", "--target", "aarch64-linux-android", "lib.rs"},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := rustcTargetReq(tc.args, target)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("rustcTargetReq(%q, %q)=%v; wantErr=%t", tc.args, target, err, tc.wantErr)
+			}
+		})
+	}
+}
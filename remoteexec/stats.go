@@ -31,6 +31,14 @@ var (
 		stats.UnitDimensionless)
 	compilerNameKey = tag.MustNewKey("compiler")
 
+	pathNormalizeCount = stats.Int64(
+		"go.chromium.org/goma/server/remoteexec.path-normalize",
+		"Number of non relocatable requests tried path normalization",
+		stats.UnitDimensionless)
+
+	// normalize result is "relocatable" or "non-relocatable".
+	pathNormalizeResultKey = tag.MustNewKey("normalize")
+
 	inputBufferAllocSize = stats.Int64(
 		"go.chromium.org/goma/server/remoteexec.input-buffer-alloc",
 		"Size to allocate buffer for input files",
@@ -70,6 +78,19 @@ var (
 		"go.chromium.org/goma/server/remoteexec.exec-response",
 		"Time in response",
 		stats.UnitMilliseconds)
+	execCoalesceTime = stats.Float64(
+		"go.chromium.org/goma/server/remoteexec.exec-coalesce",
+		"Time to wait for in-flight execution of the same action",
+		stats.UnitMilliseconds)
+
+	execCoalesceCount = stats.Int64(
+		"go.chromium.org/goma/server/remoteexec.exec-coalesce-count",
+		"Number of requests by coalesce result",
+		stats.UnitDimensionless)
+
+	// coalesce result is "leader", "coalesced", "leader_failed",
+	// "timeout" or "canceled".
+	coalesceResultKey = tag.MustNewKey("coalesce")
 
 	rbeQueueTime = stats.Float64(
 		"go.chromium.org/goma/server/remoteexec.rbe-queue",
@@ -130,6 +151,15 @@ var (
 			},
 			Aggregation: view.Count(),
 		},
+		{
+			Description: "Number of non relocatable requests tried path normalization",
+			Measure:     pathNormalizeCount,
+			TagKeys: []tag.Key{
+				compilerNameKey,
+				pathNormalizeResultKey,
+			},
+			Aggregation: view.Count(),
+		},
 		{
 			Description: "Size to allocate buffer for input files",
 			TagKeys: []tag.Key{
@@ -178,6 +208,19 @@ var (
 			Measure:     execResponseTime,
 			Aggregation: defaultLatencyDistribution,
 		},
+		{
+			Description: "Time to wait for in-flight execution of the same action",
+			Measure:     execCoalesceTime,
+			Aggregation: defaultLatencyDistribution,
+		},
+		{
+			Description: "Number of requests by coalesce result",
+			Measure:     execCoalesceCount,
+			TagKeys: []tag.Key{
+				coalesceResultKey,
+			},
+			Aggregation: view.Count(),
+		},
 		{
 			Description: "Time in RBE queue",
 			Measure:     rbeQueueTime,
@@ -0,0 +1,322 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// swiftcValueFlags are swiftc flags that take value in next arg.
+// https://github.com/apple/swift/blob/main/include/swift/Option/Options.td
+var swiftcValueFlags = []string{
+	"-emit-module-source-info-path",
+	"-emit-module-interface-path",
+	"-serialize-diagnostics-path",
+	"-emit-dependencies-path",
+	"-emit-objc-header-path",
+	"-emit-module-doc-path",
+	"-import-objc-header",
+	"-emit-module-path",
+	"-module-cache-path",
+	"-working-directory",
+	"-debug-prefix-map",
+	"-module-link-name",
+	"-file-prefix-map",
+	"-output-file-map",
+	"-swift-version",
+	"-resource-dir",
+	"-module-name",
+	"-num-threads",
+	"-vfsoverlay",
+	"-Xfrontend",
+	"-Xlinker",
+	"-target",
+	"-Xllvm",
+	"-Xcc",
+	"-sdk",
+	"-o",
+}
+
+// swiftcJoinedFlags are swiftc flags that take value in the same arg
+// or next arg.
+var swiftcJoinedFlags = []string{
+	"-D",
+	"-F",
+	"-I",
+	"-L",
+	"-j",
+	"-l",
+}
+
+// swiftcPathFlags are swiftc flags that take path.
+var swiftcPathFlags = map[string]bool{
+	"-emit-module-source-info-path": true,
+	"-emit-module-interface-path":   true,
+	"-serialize-diagnostics-path":   true,
+	"-emit-dependencies-path":       true,
+	"-emit-objc-header-path":        true,
+	"-emit-module-doc-path":         true,
+	"-import-objc-header":           true,
+	"-emit-module-path":             true,
+	"-module-cache-path":            true,
+	"-working-directory":            true,
+	"-output-file-map":              true,
+	"-resource-dir":                 true,
+	"-vfsoverlay":                   true,
+	"-sdk":                          true,
+	"-o":                            true,
+	"-F":                            true,
+	"-I":                            true,
+	"-L":                            true,
+	// input file.
+	"": true,
+}
+
+// swiftcFlag is a flag and its value in swiftc command line.
+// name is empty for input file.
+type swiftcFlag struct {
+	name  string
+	value string
+}
+
+// parseSwiftcArgs parses swiftc command line into flags.
+// args[0] is command name, and is not included in the result.
+func parseSwiftcArgs(args []string) ([]swiftcFlag, error) {
+	var flags []swiftcFlag
+	if len(args) == 0 {
+		return nil, errors.New("no args")
+	}
+Loop:
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		for _, f := range swiftcValueFlags {
+			if arg == f {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("no value for %s", arg)
+				}
+				i++
+				flags = append(flags, swiftcFlag{name: f, value: args[i]})
+				continue Loop
+			}
+		}
+		for _, f := range swiftcJoinedFlags {
+			switch {
+			case arg == f:
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("no value for %s", arg)
+				}
+				i++
+				flags = append(flags, swiftcFlag{name: f, value: args[i]})
+				continue Loop
+			case strings.HasPrefix(arg, f):
+				// -Ifoo, -DFOO
+				flags = append(flags, swiftcFlag{name: f, value: arg[len(f):]})
+				continue Loop
+			}
+		}
+		switch {
+		case arg == "-c",
+			arg == "-emit-module",
+			arg == "-emit-object",
+			arg == "-emit-library",
+			arg == "-emit-executable",
+			arg == "-emit-dependencies",
+			arg == "-emit-objc-header",
+			arg == "-emit-module-interface",
+			arg == "-parse-as-library",
+			arg == "-static",
+			arg == "-static-stdlib",
+			arg == "-whole-module-optimization",
+			arg == "-wmo",
+			arg == "-no-whole-module-optimization",
+			arg == "-enable-testing",
+			arg == "-enable-library-evolution",
+			arg == "-enable-batch-mode",
+			arg == "-disable-batch-mode",
+			arg == "-application-extension",
+			arg == "-suppress-warnings",
+			arg == "-warnings-as-errors",
+			arg == "-serialize-diagnostics",
+			arg == "-parseable-output",
+			arg == "-color-diagnostics",
+			arg == "-no-color-diagnostics",
+			arg == "-profile-generate",
+			arg == "-profile-coverage-mapping",
+			arg == "-v",
+			arg == "-g",
+			arg == "-gnone",
+			arg == "-gline-tables-only",
+			arg == "-gdwarf-types",
+			arg == "-O",
+			arg == "-Onone",
+			arg == "-Osize",
+			arg == "-Ounchecked",
+			strings.HasPrefix(arg, "-sanitize="),
+			strings.HasPrefix(arg, "-enforce-exclusivity="),
+			strings.HasPrefix(arg, "-debug-info-format="):
+			flags = append(flags, swiftcFlag{name: arg})
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			return nil, unknownFlagError{arg: arg}
+		}
+		flags = append(flags, swiftcFlag{value: arg})
+	}
+	return flags, nil
+}
+
+// swiftcRelocatableReq checks if the request (args, envs) uses relative
+// paths only and doesn't use flags that generates output including cwd,
+// so will generate cwd-agnostic outputs.
+//
+// The request will NOT be relocatable, if
+//  absolute path in input, -o, -I, -F, -emit-module-path,
+//  -module-cache-path etc.
+//  absolute path in -Xcc args, checked as clang args.
+//  absolute path prefix replacement in -debug-prefix-map OLD=NEW
+//  debug build (-g, -gline-tables-only, -gdwarf-types)
+//      DW_AT_comp_dir will have cwd.
+//      this will be canceled by -debug-prefix-map or -file-prefix-map
+//      that maps to relative path.
+func swiftcRelocatableReq(filepath clientFilePath, args, envs []string) error {
+	flags, err := parseSwiftcArgs(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 && filepath.IsAbs(args[0]) {
+		return fmt.Errorf("abs path: %s", args[0])
+	}
+	var debugFlags []string
+	prefixMap := false
+	xccArgs := []string{"clang"}
+	for _, f := range flags {
+		switch {
+		case swiftcPathFlags[f.name]:
+			if filepath.IsAbs(f.value) {
+				return fmt.Errorf("abs path: %s %s", f.name, f.value)
+			}
+		case f.name == "-debug-prefix-map", f.name == "-file-prefix-map":
+			// -debug-prefix-map OLD=NEW
+			i := strings.Index(f.value, "=")
+			if i < 0 {
+				return fmt.Errorf("bad %s %s", f.name, f.value)
+			}
+			if filepath.IsAbs(f.value[i+1:]) {
+				return fmt.Errorf("abs path: %s %s", f.name, f.value)
+			}
+			prefixMap = true
+		case f.name == "-Xcc":
+			xccArgs = append(xccArgs, f.value)
+		case f.name == "-Xfrontend", f.name == "-Xllvm":
+			if filepath.IsAbs(f.value) {
+				return fmt.Errorf("abs path: %s %s", f.name, f.value)
+			}
+		case f.name == "-gnone":
+			debugFlags = nil
+		case f.name == "-g", f.name == "-gline-tables-only", f.name == "-gdwarf-types":
+			debugFlags = append(debugFlags, f.name)
+		}
+	}
+	if len(debugFlags) > 0 && !prefixMap {
+		return fmt.Errorf("debug build: %q", debugFlags)
+	}
+	if len(xccArgs) > 1 {
+		err := gccRelocatableReq(filepath, xccArgs, nil)
+		if err != nil {
+			return err
+		}
+	}
+	for _, env := range envs {
+		e := strings.SplitN(env, "=", 2)
+		if len(e) != 2 {
+			return fmt.Errorf("bad environment variable: %s", env)
+		}
+		if e[0] == "PWD" {
+			continue
+		}
+		if filepath.IsAbs(e[1]) {
+			return fmt.Errorf("abs path in env %s=%s", e[0], e[1])
+		}
+	}
+	return nil
+}
+
+// swiftcOutputs returns output files of swiftc command line.
+// -emit-module writes .swiftmodule at -emit-module-path (or
+// <module-name>.swiftmodule if not specified), and .swiftdoc next to it.
+// Outputs described in -output-file-map are not included.
+func swiftcOutputs(args []string) []string {
+	flags, err := parseSwiftcArgs(args)
+	if err != nil {
+		return nil
+	}
+	var outputs, inputs []string
+	var moduleName, modulePath string
+	emitModule, compile, hasOutput := false, false, false
+	for _, f := range flags {
+		switch f.name {
+		case "-o":
+			outputs = append(outputs, f.value)
+			hasOutput = true
+		case "-emit-module-path":
+			modulePath = f.value
+			emitModule = true
+		case "-emit-module":
+			emitModule = true
+		case "-module-name":
+			moduleName = f.value
+		case "-c", "-emit-object":
+			compile = true
+		case "-emit-module-doc-path",
+			"-emit-module-source-info-path",
+			"-emit-module-interface-path",
+			"-emit-objc-header-path",
+			"-emit-dependencies-path",
+			"-serialize-diagnostics-path":
+			outputs = append(outputs, f.value)
+		case "":
+			inputs = append(inputs, f.value)
+		}
+	}
+	if emitModule {
+		if modulePath == "" && moduleName != "" {
+			modulePath = moduleName + ".swiftmodule"
+		}
+		if modulePath != "" {
+			outputs = append(outputs, modulePath, strings.TrimSuffix(modulePath, ".swiftmodule")+".swiftdoc")
+		}
+	}
+	if compile && !hasOutput {
+		// object file for each input in cwd.
+		for _, in := range inputs {
+			if path.Ext(in) != ".swift" {
+				continue
+			}
+			outputs = append(outputs, strings.TrimSuffix(path.Base(in), ".swift")+".o")
+		}
+	}
+	return outputs
+}
+
+// swiftcModuleCacheDirs returns module cache directory of swiftc
+// command line.
+// swiftc writes implicitly built clang modules (*.pcm) and swift
+// modules in the module cache, so it should be output directory.
+func swiftcModuleCacheDirs(args []string) []string {
+	flags, err := parseSwiftcArgs(args)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, f := range flags {
+		if f.name == "-module-cache-path" {
+			dirs = append(dirs, f.value)
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,212 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.chromium.org/goma/server/command/descriptor/posixpath"
+)
+
+func TestSwiftcRelocatableReq(t *testing.T) {
+	baseArgs := []string{
+		"../../third_party/swift-toolchain/bin/swiftc",
+		"-module-name", "Base",
+		"-target", "x86_64-apple-macosx10.15",
+		"-sdk", "../../build/mac_files/xcode_binaries/MacOSX.sdk",
+		"-swift-version", "5",
+		"-O",
+		"-wmo",
+		"-parse-as-library",
+		"-I", "gen/swift",
+		"-Fgen/frameworks",
+		"-DNDEBUG",
+		"-emit-module",
+		"-emit-module-path", "obj/base/Base.swiftmodule",
+		"-module-cache-path", "obj/swift-module-cache",
+		"-Xcc", "-I../..",
+		"-c",
+		"-o", "obj/base/Base.o",
+		"../../base/foo.swift",
+		"../../base/bar.swift",
+	}
+	baseEnvs := []string{
+		"PWD=/home/user/src/out/Release",
+	}
+	for _, tc := range []struct {
+		desc    string
+		args    []string
+		envs    []string
+		wantErr bool
+	}{
+		{
+			desc: "basic",
+			args: baseArgs,
+			envs: baseEnvs,
+		},
+		{
+			desc:    "abs input",
+			args:    append(append([]string{}, baseArgs...), "/home/user/src/base/baz.swift"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc:    "abs -emit-module-path",
+			args:    append(append([]string{}, baseArgs...), "-emit-module-path", "/home/user/src/out/Release/obj/base/Base.swiftmodule"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc:    "abs -module-cache-path",
+			args:    append(append([]string{}, baseArgs...), "-module-cache-path", "/tmp/swift-module-cache"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc:    "abs -I",
+			args:    append(append([]string{}, baseArgs...), "-I/home/user/src/gen"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc:    "abs -Xcc",
+			args:    append(append([]string{}, baseArgs...), "-Xcc", "-I/home/user/src"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc:    "debug build",
+			args:    append(append([]string{}, baseArgs...), "-g"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc: "debug build with -debug-prefix-map",
+			args: append(append([]string{}, baseArgs...), "-g", "-debug-prefix-map", "/home/user/src/out/Release=."),
+			envs: baseEnvs,
+		},
+		{
+			desc:    "debug build with -debug-prefix-map to abs path",
+			args:    append(append([]string{}, baseArgs...), "-g", "-debug-prefix-map", "/home/user/src=/src"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc: "debug build disabled",
+			args: append(append([]string{}, baseArgs...), "-g", "-gnone"),
+			envs: baseEnvs,
+		},
+		{
+			desc:    "unknown flag",
+			args:    append(append([]string{}, baseArgs...), "-unknown-flag"),
+			envs:    baseEnvs,
+			wantErr: true,
+		},
+		{
+			desc:    "abs path in env",
+			args:    baseArgs,
+			envs:    append(append([]string{}, baseEnvs...), "SDKROOT=/Applications/Xcode.app/Contents/Developer"),
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := swiftcRelocatableReq(posixpath.FilePath{}, tc.args, tc.envs)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("swiftcRelocatableReq(posixpath.FilePath, args, envs)=%v; wantErr=%t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestSwiftcOutputs(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		args []string
+		want []string
+	}{
+		{
+			desc: "object and module",
+			args: []string{
+				"swiftc",
+				"-module-name", "Base",
+				"-emit-module",
+				"-emit-module-path", "obj/base/Base.swiftmodule",
+				"-emit-objc-header-path", "gen/base/Base-Swift.h",
+				"-emit-dependencies-path", "obj/base/Base.d",
+				"-wmo",
+				"-c",
+				"-o", "obj/base/Base.o",
+				"../../base/foo.swift",
+			},
+			want: []string{
+				"gen/base/Base-Swift.h",
+				"obj/base/Base.d",
+				"obj/base/Base.o",
+				"obj/base/Base.swiftmodule",
+				"obj/base/Base.swiftdoc",
+			},
+		},
+		{
+			desc: "module without path",
+			args: []string{
+				"swiftc",
+				"-module-name", "Base",
+				"-emit-module",
+				"-parse-as-library",
+				"../../base/foo.swift",
+			},
+			want: []string{
+				"Base.swiftmodule",
+				"Base.swiftdoc",
+			},
+		},
+		{
+			desc: "objects without -o",
+			args: []string{
+				"swiftc",
+				"-module-name", "Base",
+				"-c",
+				"../../base/foo.swift",
+				"../../base/bar.swift",
+			},
+			want: []string{
+				"foo.o",
+				"bar.o",
+			},
+		},
+		{
+			desc: "unknown flag",
+			args: []string{
+				"swiftc",
+				"-unknown-flag",
+				"-o", "obj/base/Base.o",
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := swiftcOutputs(tc.args)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("swiftcOutputs(%q) diff -want +got:\n%s", tc.args, diff)
+			}
+		})
+	}
+}
+
+func TestSwiftcModuleCacheDirs(t *testing.T) {
+	args := []string{
+		"swiftc",
+		"-module-name", "Base",
+		"-module-cache-path", "obj/swift-module-cache",
+		"-c",
+		"../../base/foo.swift",
+	}
+	got := swiftcModuleCacheDirs(args)
+	want := []string{"obj/swift-module-cache"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("swiftcModuleCacheDirs(%q) diff -want +got:\n%s", args, diff)
+	}
+}
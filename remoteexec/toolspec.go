@@ -0,0 +1,174 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	cmdpb "go.chromium.org/goma/server/proto/command"
+)
+
+// toolArgKind is a kind of path in tool's command line.
+type toolArgKind int
+
+const (
+	toolArgInput toolArgKind = iota
+	toolArgOutput
+	toolArgOutputDir
+	toolArgPath
+)
+
+// toolArg is a path in tool's command line.
+type toolArg struct {
+	kind toolArgKind
+	flag string
+	path string
+}
+
+// matchToolFlag checks args[i] matches with f.
+// It returns path of the flag's value, and number of args consumed.
+// n is 0 if args[i] doesn't match with f.
+func matchToolFlag(f *cmdpb.ToolSpec_Flag, args []string, i int) (string, int) {
+	name := f.GetName()
+	arg := args[i]
+	var value string
+	var n int
+	switch {
+	case name == "":
+		return "", 0
+	case strings.HasSuffix(name, "="):
+		if !strings.HasPrefix(arg, name) {
+			return "", 0
+		}
+		value, n = arg[len(name):], 1
+	case arg == name:
+		if i+1 >= len(args) {
+			return "", 0
+		}
+		value, n = args[i+1], 2
+	case f.GetJoined() && strings.HasPrefix(arg, name):
+		value, n = arg[len(name):], 1
+	default:
+		return "", 0
+	}
+	if d := f.GetValueDelimiter(); d != "" && !hasDriveLetter(value) {
+		// path may contain the delimiter (e.g. OPTIONS:C:\out),
+		// so split at the first delimiter.
+		if j := strings.Index(value, d); j >= 0 {
+			value = value[j+len(d):]
+		}
+	}
+	return value, n
+}
+
+// hasDriveLetter reports whether v starts with windows drive letter,
+// e.g. `C:\out`.
+func hasDriveLetter(v string) bool {
+	if len(v) < 3 || v[1] != ':' || (v[2] != '\\' && v[2] != '/') {
+		return false
+	}
+	c := v[0]
+	return ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z')
+}
+
+// parseToolArgs returns paths in args (without command name)
+// by spec.
+func parseToolArgs(spec *cmdpb.ToolSpec, args []string) []toolArg {
+	kinds := []struct {
+		kind  toolArgKind
+		flags []*cmdpb.ToolSpec_Flag
+	}{
+		{kind: toolArgInput, flags: spec.GetInputFlags()},
+		{kind: toolArgOutput, flags: spec.GetOutputFlags()},
+		{kind: toolArgOutputDir, flags: spec.GetOutputDirFlags()},
+		{kind: toolArgPath, flags: spec.GetPathFlags()},
+	}
+	var paths []toolArg
+Loop:
+	for i := 0; i < len(args); i++ {
+		for _, k := range kinds {
+			for _, f := range k.flags {
+				path, n := matchToolFlag(f, args, i)
+				if n == 0 {
+					continue
+				}
+				paths = append(paths, toolArg{
+					kind: k.kind,
+					flag: f.GetName(),
+					path: path,
+				})
+				i += n - 1
+				continue Loop
+			}
+		}
+		if spec.GetPositionalInputs() && !strings.HasPrefix(args[i], "-") {
+			paths = append(paths, toolArg{
+				kind: toolArgInput,
+				path: args[i],
+			})
+		}
+	}
+	return paths
+}
+
+// toolSpecRelocatableReq checks if the request (args) uses relative
+// paths only and doesn't use non relocatable flags in spec.
+//
+// The request will NOT be relocatable, if
+//  absolute path in input, output, output dir or path flags.
+//  non_relocatable_flags is used.
+func toolSpecRelocatableReq(spec *cmdpb.ToolSpec, filepath clientFilePath, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no args")
+	}
+	if filepath.IsAbs(args[0]) {
+		return fmt.Errorf("abs path: %s", args[0])
+	}
+	for _, arg := range args[1:] {
+		for _, f := range spec.GetNonRelocatableFlags() {
+			if arg == f || strings.HasPrefix(arg, f+"=") {
+				return fmt.Errorf("non relocatable flag: %s", arg)
+			}
+		}
+	}
+	for _, p := range parseToolArgs(spec, args[1:]) {
+		if filepath.IsAbs(p.path) {
+			return fmt.Errorf("abs path: %s %s", p.flag, p.path)
+		}
+	}
+	return nil
+}
+
+// toolSpecPaths returns paths of kind in args by spec.
+// args[0] is command name.
+func toolSpecPaths(spec *cmdpb.ToolSpec, args []string, kind toolArgKind) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	var paths []string
+	for _, p := range parseToolArgs(spec, args[1:]) {
+		if p.kind == kind && p.path != "" {
+			paths = append(paths, p.path)
+		}
+	}
+	return paths
+}
+
+// toolSpecInputs returns input files from args by spec.
+func toolSpecInputs(spec *cmdpb.ToolSpec, args []string) []string {
+	return toolSpecPaths(spec, args, toolArgInput)
+}
+
+// toolSpecOutputs returns output files from args by spec.
+func toolSpecOutputs(spec *cmdpb.ToolSpec, args []string) []string {
+	return toolSpecPaths(spec, args, toolArgOutput)
+}
+
+// toolSpecOutputDirs returns output directories from args by spec.
+func toolSpecOutputDirs(spec *cmdpb.ToolSpec, args []string) []string {
+	return toolSpecPaths(spec, args, toolArgOutputDir)
+}
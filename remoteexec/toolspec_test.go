@@ -0,0 +1,233 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package remoteexec
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/prototext"
+
+	"go.chromium.org/goma/server/command/descriptor/posixpath"
+	cmdpb "go.chromium.org/goma/server/proto/command"
+)
+
+const protocToolSpec = `
+path_flags { name: "--proto_path=" }
+path_flags { name: "-I" joined: true }
+output_flags { name: "--descriptor_set_out=" }
+output_flags { name: "--dependency_out=" }
+output_dir_flags { name: "--cpp_out=" value_delimiter: ":" }
+output_dir_flags { name: "--python_out=" value_delimiter: ":" }
+non_relocatable_flags: "--include_source_info"
+positional_inputs: true
+`
+
+const flatcToolSpec = `
+input_flags { name: "--conform" }
+path_flags { name: "-I" }
+output_dir_flags { name: "-o" joined: true }
+positional_inputs: true
+`
+
+func toolSpec(t *testing.T, s string) *cmdpb.ToolSpec {
+	t.Helper()
+	spec := &cmdpb.ToolSpec{}
+	err := prototext.Unmarshal([]byte(s), spec)
+	if err != nil {
+		t.Fatalf("prototext.Unmarshal(%q)=%v; want nil error", s, err)
+	}
+	return spec
+}
+
+func TestToolSpecRelocatableReq(t *testing.T) {
+	protoc := toolSpec(t, protocToolSpec)
+	flatc := toolSpec(t, flatcToolSpec)
+	for _, tc := range []struct {
+		desc    string
+		spec    *cmdpb.ToolSpec
+		args    []string
+		wantErr bool
+	}{
+		{
+			desc: "protoc",
+			spec: protoc,
+			args: []string{
+				"./protoc",
+				"--proto_path=../../third_party/protobuf/src",
+				"-I../../components/foo",
+				"--cpp_out=lite:gen/components/foo",
+				"../../components/foo/foo.proto",
+			},
+		},
+		{
+			desc: "protoc abs proto_path",
+			spec: protoc,
+			args: []string{
+				"./protoc",
+				"--proto_path=/usr/include",
+				"../../components/foo/foo.proto",
+			},
+			wantErr: true,
+		},
+		{
+			desc: "protoc abs cpp_out",
+			spec: protoc,
+			args: []string{
+				"./protoc",
+				"--cpp_out=lite:/tmp/gen",
+				"../../components/foo/foo.proto",
+			},
+			wantErr: true,
+		},
+		{
+			desc: "protoc non relocatable flag",
+			spec: protoc,
+			args: []string{
+				"./protoc",
+				"--include_source_info",
+				"--descriptor_set_out=foo.desc",
+				"../../components/foo/foo.proto",
+			},
+			wantErr: true,
+		},
+		{
+			desc: "protoc abs input",
+			spec: protoc,
+			args: []string{
+				"./protoc",
+				"/home/user/src/components/foo/foo.proto",
+			},
+			wantErr: true,
+		},
+		{
+			desc: "flatc",
+			spec: flatc,
+			args: []string{
+				"./flatc",
+				"--cpp",
+				"-I", "../../third_party/foo",
+				"-ogen/foo",
+				"../../third_party/foo/foo.fbs",
+			},
+		},
+		{
+			desc: "flatc abs -I",
+			spec: flatc,
+			args: []string{
+				"./flatc",
+				"--cpp",
+				"-I", "/usr/include",
+				"../../third_party/foo/foo.fbs",
+			},
+			wantErr: true,
+		},
+		{
+			desc: "abs command",
+			spec: flatc,
+			args: []string{
+				"/usr/bin/flatc",
+				"../../third_party/foo/foo.fbs",
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := toolSpecRelocatableReq(tc.spec, posixpath.FilePath{}, tc.args)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("toolSpecRelocatableReq(%q)=%v; wantErr=%t", tc.args, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestToolSpecPaths(t *testing.T) {
+	protoc := toolSpec(t, protocToolSpec)
+	flatc := toolSpec(t, flatcToolSpec)
+	for _, tc := range []struct {
+		desc       string
+		spec       *cmdpb.ToolSpec
+		args       []string
+		inputs     []string
+		outputs    []string
+		outputDirs []string
+	}{
+		{
+			desc: "protoc",
+			spec: protoc,
+			args: []string{
+				"./protoc",
+				"--proto_path=../../third_party/protobuf/src",
+				"-I../../components/foo",
+				"--cpp_out=lite:gen/components/foo",
+				"--python_out=gen/py",
+				"--descriptor_set_out=foo.desc",
+				"--dependency_out=foo.d",
+				"../../components/foo/foo.proto",
+				"../../components/foo/bar.proto",
+			},
+			inputs: []string{
+				"../../components/foo/foo.proto",
+				"../../components/foo/bar.proto",
+			},
+			outputs: []string{
+				"foo.desc",
+				"foo.d",
+			},
+			outputDirs: []string{
+				"gen/components/foo",
+				"gen/py",
+			},
+		},
+		{
+			desc: "protoc windows path",
+			spec: protoc,
+			args: []string{
+				`protoc.exe`,
+				`--cpp_out=C:\src\out\gen`,
+				`--python_out=lite:C:\src\out\py`,
+				`foo.proto`,
+			},
+			inputs: []string{
+				`foo.proto`,
+			},
+			outputDirs: []string{
+				`C:\src\out\gen`,
+				`C:\src\out\py`,
+			},
+		},
+		{
+			desc: "flatc",
+			spec: flatc,
+			args: []string{
+				"./flatc",
+				"--cpp",
+				"-I", "../../third_party/foo",
+				"--conform", "../../third_party/foo/base.fbs",
+				"-o", "gen/foo",
+				"../../third_party/foo/foo.fbs",
+			},
+			inputs: []string{
+				"../../third_party/foo/base.fbs",
+				"../../third_party/foo/foo.fbs",
+			},
+			outputDirs: []string{
+				"gen/foo",
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := toolSpecInputs(tc.spec, tc.args); !reflect.DeepEqual(got, tc.inputs) {
+				t.Errorf("toolSpecInputs(%q)=%q; want %q", tc.args, got, tc.inputs)
+			}
+			if got := toolSpecOutputs(tc.spec, tc.args); !reflect.DeepEqual(got, tc.outputs) {
+				t.Errorf("toolSpecOutputs(%q)=%q; want %q", tc.args, got, tc.outputs)
+			}
+			if got := toolSpecOutputDirs(tc.spec, tc.args); !reflect.DeepEqual(got, tc.outputDirs) {
+				t.Errorf("toolSpecOutputDirs(%q)=%q; want %q", tc.args, got, tc.outputDirs)
+			}
+		})
+	}
+}
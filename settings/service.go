@@ -0,0 +1,99 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package settings
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	"go.chromium.org/goma/server/log"
+	pb "go.chromium.org/goma/server/proto/settings"
+)
+
+// Service is settings service.
+// It returns settings of the first rule matched with use_case in
+// request and group of the requester.
+type Service struct {
+	pb.UnimplementedSettingsServiceServer
+
+	mu     sync.RWMutex
+	config *pb.SettingsConfig
+}
+
+// SetConfig sets config in the service.
+func (s *Service) SetConfig(ctx context.Context, config *pb.SettingsConfig) error {
+	for i, rule := range config.GetRules() {
+		if rule.Settings == nil {
+			return fmt.Errorf("rule[%d]: no settings", i)
+		}
+		if u := rule.Settings.EndpointUrl; u != "" {
+			eu, err := url.Parse(u)
+			if err != nil {
+				return fmt.Errorf("rule[%d]: bad endpoint_url: %v", i, err)
+			}
+			if !eu.IsAbs() || eu.Host == "" {
+				return fmt.Errorf("rule[%d]: endpoint_url must be absolute: %q", i, u)
+			}
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = proto.Clone(config).(*pb.SettingsConfig)
+	logger := log.FromContext(ctx)
+	logger.Infof("settings updated: %d rules", len(config.GetRules()))
+	return nil
+}
+
+func matchGroup(groups []string, group string) bool {
+	if len(groups) == 0 {
+		return true
+	}
+	for _, g := range groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) findRule(useCase, group string) *pb.SettingsRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, rule := range s.config.GetRules() {
+		if rule.UseCase != "" && rule.UseCase != useCase {
+			continue
+		}
+		if !matchGroup(rule.GroupId, group) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// Get returns settings for the request.
+func (s *Service) Get(ctx context.Context, req *pb.SettingsReq) (*pb.SettingsResp, error) {
+	logger := log.FromContext(ctx)
+	var group string
+	if user, ok := enduser.FromContext(ctx); ok {
+		group = user.Group
+	}
+	rule := s.findRule(req.UseCase, group)
+	if rule == nil {
+		logger.Warnf("no settings for use_case=%q group=%q", req.UseCase, group)
+		return nil, status.Errorf(codes.NotFound, "no settings for use_case=%q", req.UseCase)
+	}
+	logger.Infof("settings %q for use_case=%q group=%q", rule.Settings.Name, req.UseCase, group)
+	return &pb.SettingsResp{
+		Settings: proto.Clone(rule.Settings).(*pb.Settings),
+	}, nil
+}
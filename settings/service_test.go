@@ -0,0 +1,168 @@
+// Copyright 2021 The Goma Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package settings
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	"go.chromium.org/goma/server/auth/enduser"
+	pb "go.chromium.org/goma/server/proto/settings"
+)
+
+func TestServiceGet(t *testing.T) {
+	ctx := context.Background()
+	config := &pb.SettingsConfig{}
+	err := prototext.Unmarshal([]byte(`
+rules {
+  use_case: "ci"
+  group_id: "chrome-bot"
+  settings {
+    name: "ci-bot"
+    endpoint_url: "https://ci.example.com/cxx-compiler-service"
+    client_flags {
+      key: "GOMA_MAX_SUBPROCS"
+      value: "500"
+    }
+  }
+}
+rules {
+  group_id: "chrome-bot"
+  group_id: "v8-bot"
+  settings {
+    name: "bot"
+    endpoint_url: "https://bot.example.com/cxx-compiler-service"
+    oauth2_cloud: true
+  }
+}
+rules {
+  settings {
+    name: "default"
+    endpoint_url: "https://goma.example.com/cxx-compiler-service"
+  }
+}
+`), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{}
+	err = s.SetConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("SetConfig(ctx, config)=%v; want nil error", err)
+	}
+
+	for _, tc := range []struct {
+		useCase string
+		group   string
+		want    string
+	}{
+		{
+			useCase: "ci",
+			group:   "chrome-bot",
+			want:    "ci-bot",
+		},
+		{
+			useCase: "ci",
+			group:   "v8-bot",
+			want:    "bot",
+		},
+		{
+			group: "chrome-bot",
+			want:  "bot",
+		},
+		{
+			useCase: "ci",
+			group:   "googler",
+			want:    "default",
+		},
+		{
+			want: "default",
+		},
+	} {
+		ctx := ctx
+		if tc.group != "" {
+			ctx = enduser.NewContext(ctx, enduser.New("user@example.com", tc.group, &oauth2.Token{}))
+		}
+		resp, err := s.Get(ctx, &pb.SettingsReq{UseCase: tc.useCase})
+		if err != nil {
+			t.Errorf("Get(use_case=%q group=%q)=_, %v; want nil error", tc.useCase, tc.group, err)
+			continue
+		}
+		if got := resp.GetSettings().GetName(); got != tc.want {
+			t.Errorf("Get(use_case=%q group=%q)=%q; want=%q", tc.useCase, tc.group, got, tc.want)
+		}
+	}
+
+	resp, err := s.Get(enduser.NewContext(ctx, enduser.New("user@example.com", "chrome-bot", &oauth2.Token{})), &pb.SettingsReq{UseCase: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.GetSettings().GetClientFlags()["GOMA_MAX_SUBPROCS"], "500"; got != want {
+		t.Errorf("client_flags[GOMA_MAX_SUBPROCS]=%q; want=%q", got, want)
+	}
+	// response must not share config.
+	resp.Settings.Name = "modified"
+	resp, err = s.Get(enduser.NewContext(ctx, enduser.New("user@example.com", "chrome-bot", &oauth2.Token{})), &pb.SettingsReq{UseCase: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.GetSettings().GetName(), "ci-bot"; got != want {
+		t.Errorf("Get after modifying response=%q; want=%q", got, want)
+	}
+}
+
+func TestServiceNoMatch(t *testing.T) {
+	ctx := context.Background()
+	s := &Service{}
+	err := s.SetConfig(ctx, &pb.SettingsConfig{
+		Rules: []*pb.SettingsRule{
+			{
+				GroupId: []string{"chrome-bot"},
+				Settings: &pb.Settings{
+					Name: "bot",
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Get(ctx, &pb.SettingsReq{})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Get(ctx, req)=%v; want %v", err, codes.NotFound)
+	}
+}
+
+func TestServiceSetConfigError(t *testing.T) {
+	ctx := context.Background()
+	for _, rule := range []*pb.SettingsRule{
+		{
+			UseCase: "no-settings",
+		},
+		{
+			Settings: &pb.Settings{
+				EndpointUrl: "goma.example.com/cxx-compiler-service",
+			},
+		},
+		{
+			Settings: &pb.Settings{
+				EndpointUrl: "https://goma.example.com/%zz",
+			},
+		},
+	} {
+		s := &Service{}
+		err := s.SetConfig(ctx, &pb.SettingsConfig{
+			Rules: []*pb.SettingsRule{rule},
+		})
+		if err == nil {
+			t.Errorf("SetConfig(ctx, %s)=nil; want error", rule)
+		}
+	}
+}